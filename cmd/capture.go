@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/encodeous/nylon/core"
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/protocol"
+	"github.com/spf13/cobra"
+)
+
+var errCaptureDone = errors.New("capture done")
+
+var captureCmd = &cobra.Command{
+	Use:   "capture",
+	Short: "Capture the packets seen by the traffic control pipeline",
+	Long: `Streams the data packets that pass through nylon, along with their direction, peers and the action taken.
+Packets are written to a pcapng file with -w, or summarised on stdout. The capture starts and stops with this
+command, and is limited in rate and size so that it cannot slow down the data path.
+
+The filter follows tcpdump, primitives are combined with and, or, not and parentheses:
+  [src|dst] host ADDR, [src|dst] net PREFIX, [src|dst] port PORT[-PORT], or a bare ADDR or PREFIX
+  tcp, udp, sctp, icmp, proto NAME|NUMBER, ip, ip6
+  peer NODE, in, out, action forward|bounce|drop`,
+	Example: `  nylon capture -f "peer bob and tcp and port 22" -w ssh.pcapng
+  nylon capture -f "action drop" -c 10
+  nylon capture -w - | wireshark -k -i -`,
+	GroupID: "ny",
+	Run: func(cmd *cobra.Command, args []string) {
+		itf, _ := cmd.Flags().GetString("interface")
+		filter, _ := cmd.Flags().GetString("filter")
+		outPath, _ := cmd.Flags().GetString("write")
+		count, _ := cmd.Flags().GetUint64("count")
+		snaplen, _ := cmd.Flags().GetUint32("snaplen")
+		rate, _ := cmd.Flags().GetUint32("rate")
+
+		var pcap *core.PcapngWriter
+		if outPath != "" {
+			var out io.Writer = os.Stdout
+			if outPath != "-" {
+				f, err := os.Create(outPath)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+					os.Exit(1)
+				}
+				defer f.Close()
+				out = f
+			}
+			var err error
+			pcap, err = core.NewPcapngWriter(out, itf, snaplen)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		first := true
+		var captured uint64
+		err := core.SendIPCStream(itf, &protocol.IpcRequest{
+			Request: &protocol.IpcRequest_Capture{Capture: &protocol.CaptureRequest{
+				Filter:  filter,
+				Snaplen: snaplen,
+				Rate:    rate,
+			}},
+		}, func(resp *protocol.IpcResponse) error {
+			if first {
+				first = false
+				if !resp.Ok {
+					return errors.New(resp.Error)
+				}
+				return nil
+			}
+			packet := resp.GetCaptured()
+			if packet == nil {
+				return nil
+			}
+			if pcap != nil {
+				if err := pcap.WritePacket(packet); err != nil {
+					return err
+				}
+				if outPath != "-" && packet.Skipped != 0 {
+					fmt.Fprintf(os.Stderr, "%d packets skipped\n", packet.Skipped)
+				}
+			} else {
+				fmt.Println(describeCapturedPacket(packet))
+			}
+			captured++
+			if count != 0 && captured >= count {
+				return errCaptureDone
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errCaptureDone) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// describeCapturedPacket summarises a captured packet on one line, like tcpdump
+func describeCapturedPacket(packet *protocol.CapturedPacket) string {
+	ts := time.Unix(0, packet.TimestampNs).Format("15:04:05.000000")
+	dir := "out"
+	if packet.Incoming {
+		dir = "in "
+	}
+	desc := fmt.Sprintf("%d bytes", packet.Length)
+	elem := &device.TCElement{Packet: packet.Data}
+	if elem.Validate() {
+		proto := fmt.Sprintf("proto %d", elem.GetProtocol())
+		if name, ok := captureProtoNames[elem.GetProtocol()]; ok {
+			proto = name
+		}
+		src, dst := elem.GetSrc().String(), elem.GetDst().String()
+		if sport, dport, ok := elem.GetPorts(); ok {
+			src = netip.AddrPortFrom(elem.GetSrc(), sport).String()
+			dst = netip.AddrPortFrom(elem.GetDst(), dport).String()
+		}
+		desc = fmt.Sprintf("%s > %s %s, %s", src, dst, proto, desc)
+	}
+	return fmt.Sprintf("%s %s %s: %s", ts, dir, desc, core.FormatCapturedPacket(packet))
+}
+
+var captureProtoNames = map[uint8]string{
+	device.ProtoICMP:   "icmp",
+	device.ProtoTCP:    "tcp",
+	device.ProtoUDP:    "udp",
+	device.ProtoICMPv6: "icmp6",
+	device.ProtoSCTP:   "sctp",
+}
+
+func init() {
+	rootCmd.AddCommand(captureCmd)
+	captureCmd.Flags().StringP("interface", "i", "nylon", "Interface name")
+	captureCmd.Flags().StringP("filter", "f", "", "Capture filter expression, see above")
+	captureCmd.Flags().StringP("write", "w", "", `Write packets to a pcapng file, "-" for stdout`)
+	captureCmd.Flags().Uint64P("count", "c", 0, "Stop after this many packets (0 = until interrupted)")
+	captureCmd.Flags().Uint32P("snaplen", "s", 0, "Bytes kept of each packet (0 = whole packets, at most 65535)")
+	captureCmd.Flags().Uint32("rate", 0, "Packets captured per second at most (0 = 1000, at most 10000)")
+}
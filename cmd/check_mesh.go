@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/encodeous/nylon/core"
+	"github.com/encodeous/nylon/protocol"
+	"github.com/encodeous/nylon/state"
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+var checkMeshCmd = &cobra.Command{
+	Use:   "check-mesh [snapshot...]",
+	Short: "Check the routes selected across the mesh for loops and blackholes",
+	Long: `Collects status snapshots of the nodes, follows the next hops of every prefix through them, and reports
+forwarding loops, blackholes, asymmetric paths and nodes that disagree on seqnos.
+A snapshot is a file saved with "nylon status --json", or the http url of a node's observability server.
+Exits with status 1 if a loop or blackhole is found.`,
+	GroupID: "ny",
+	Run: func(cmd *cobra.Command, args []string) {
+		discover, _ := cmd.Flags().GetString("discover")
+		local, _ := cmd.Flags().GetBool("local")
+		itf, _ := cmd.Flags().GetString("interface")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		noColor, _ := cmd.Flags().GetBool("no-color")
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		client := &http.Client{}
+		statuses := make([]*protocol.StatusResponse, 0)
+		fail := func(err error) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+
+		if local {
+			resp, err := core.SendIPCRequest(itf, &protocol.IpcRequest{
+				Request: &protocol.IpcRequest_Status{Status: &protocol.StatusRequest{}},
+			})
+			if err != nil {
+				fail(err)
+			}
+			if !resp.Ok {
+				fail(fmt.Errorf("%s", resp.Error))
+			}
+			statuses = append(statuses, resp.GetStatus())
+		}
+		if discover != "" {
+			discoveryUrl, err := core.ObservabilityUrl(discover, "/discovery")
+			if err != nil {
+				fail(err)
+			}
+			found, failed, err := core.DiscoverStatusSnapshots(ctx, client, discoveryUrl)
+			if err != nil {
+				fail(err)
+			}
+			for _, id := range slices.Sorted(maps.Keys(failed)) {
+				fmt.Fprintf(os.Stderr, "Warning: no snapshot of %s: %v\n", id, failed[id])
+			}
+			for _, status := range found {
+				// the local snapshot replaces the one discovered for the same node
+				if local && status.GetNode().GetNodeId() == statuses[0].GetNode().GetNodeId() {
+					continue
+				}
+				statuses = append(statuses, status)
+			}
+		}
+		for _, arg := range args {
+			var status *protocol.StatusResponse
+			var err error
+			if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+				var statusUrl string
+				if statusUrl, err = core.ObservabilityUrl(arg, "/status"); err == nil {
+					status, err = core.FetchStatusSnapshot(ctx, client, statusUrl)
+				}
+			} else {
+				var data []byte
+				if data, err = os.ReadFile(arg); err == nil {
+					status, err = core.ParseStatusSnapshot(data)
+				}
+			}
+			if err != nil {
+				fail(fmt.Errorf("%s: %w", arg, err))
+			}
+			statuses = append(statuses, status)
+		}
+		if len(statuses) == 0 {
+			fail(fmt.Errorf("no snapshots, pass snapshot files or urls, --discover or --local"))
+		}
+
+		report, err := core.CheckMesh(statuses)
+		if err != nil {
+			fail(err)
+		}
+		if jsonOut {
+			out, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Println(string(out))
+		} else {
+			renderMeshReport(report, palette(!noColor && os.Getenv("NO_COLOR") == "" && term.IsTerminal(os.Stdout.Fd())))
+		}
+		if len(report.Problems) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func renderMeshReport(report *core.MeshReport, p paletteValues) {
+	fmt.Println(p.header("snapshots"))
+	printCommaList(p, 1, nodeIdStrings(report.Nodes))
+	if len(report.Missing) > 0 {
+		printKV(p, 1, "missing", p.warn(strings.Join(nodeIdStrings(report.Missing), ", ")))
+	}
+	fmt.Println()
+
+	for _, kind := range []string{core.MeshLoop, core.MeshBlackhole} {
+		headers := []string{"prefix", "path", "from"}
+		if kind == core.MeshLoop {
+			fmt.Println(p.header("forwarding loops"))
+		} else {
+			fmt.Println(p.header("blackholes"))
+			headers = []string{"prefix", "path", "reason", "from"}
+		}
+		rows := make([][]string, 0)
+		for _, problem := range report.Problems {
+			if problem.Kind != kind {
+				continue
+			}
+			path := nodeIdStrings(problem.Path)
+			from := strings.Join(nodeIdStrings(problem.From), ",")
+			if kind == core.MeshLoop {
+				path = append(path, path[0])
+				rows = append(rows, []string{prefixText(problem.Prefix, problem.SrcPrefix), p.bad(strings.Join(path, " -> ")), from})
+			} else {
+				rows = append(rows, []string{prefixText(problem.Prefix, problem.SrcPrefix), strings.Join(append(path, p.bad("drop")), " -> "), problem.Reason, from})
+			}
+		}
+		printMeshRows(p, headers, rows)
+	}
+
+	fmt.Println(p.header("asymmetric paths"))
+	rows := make([][]string, 0, len(report.Asymmetric))
+	for _, asym := range report.Asymmetric {
+		rows = append(rows, []string{
+			strings.Join(nodeIdStrings(asym.Forward), " -> "),
+			strings.Join(nodeIdStrings(asym.Reverse), " -> "),
+		})
+	}
+	printMeshRows(p, []string{"forward", "reverse"}, rows)
+
+	fmt.Println(p.header("seqno disagreements"))
+	rows = make([][]string, 0, len(report.Seqnos))
+	for _, conflict := range report.Seqnos {
+		views := make([]string, 0, len(conflict.Seqnos))
+		for _, id := range slices.Sorted(maps.Keys(conflict.Seqnos)) {
+			view := fmt.Sprintf("%s=%d", id, conflict.Seqnos[id])
+			if id == conflict.NodeId {
+				view = p.key(view)
+			}
+			views = append(views, view)
+		}
+		rows = append(rows, []string{prefixText(conflict.Prefix, conflict.SrcPrefix), string(conflict.NodeId), strings.Join(views, " ")})
+	}
+	printMeshRows(p, []string{"prefix", "router", "seqnos"}, rows)
+}
+
+func printMeshRows(p paletteValues, headers []string, rows [][]string) {
+	if len(rows) == 0 {
+		fmt.Println("  " + p.muted("none"))
+	} else {
+		printTable(p, 1, headers, rows)
+	}
+	fmt.Println()
+}
+
+func nodeIdStrings(ids []state.NodeId) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = string(id)
+	}
+	return out
+}
+
+func init() {
+	rootCmd.AddCommand(checkMeshCmd)
+	checkMeshCmd.Flags().String("discover", "", "Observability server to discover every node from, as host:port or url")
+	checkMeshCmd.Flags().Bool("local", false, "Include the snapshot of the local node")
+	checkMeshCmd.Flags().StringP("interface", "i", "nylon", "Interface name for --local")
+	checkMeshCmd.Flags().Duration("timeout", 10*time.Second, "Time limit for collecting snapshots")
+	checkMeshCmd.Flags().Bool("json", false, "Output as JSON")
+	checkMeshCmd.Flags().Bool("no-color", false, "Disable colored output")
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/encodeous/nylon/core"
+	"github.com/encodeous/nylon/protocol"
+	"github.com/spf13/cobra"
+)
+
+var drainCmd = &cobra.Command{
+	Use:   "drain",
+	Short: "Move traffic away from this router before maintenance",
+	Long: `Advertises every route through this router with a raised metric, so neighbours switch to other paths.
+Waits until the router no longer forwards traffic between peers. Routes through the router stay usable while
+no other path exists. Undo with "nylon undrain".`,
+	GroupID: "ny",
+	Run: func(cmd *cobra.Command, args []string) {
+		itf, _ := cmd.Flags().GetString("interface")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		deadline := time.Now().Add(timeout)
+		action := protocol.DrainAction_DRAIN
+		for {
+			r := sendDrainRequest(itf, action)
+			action = protocol.DrainAction_DRAIN_STATUS
+			if r.GetDrain().Drained || timeout <= 0 || time.Now().After(deadline) {
+				if jsonOut {
+					printJSON(r)
+				} else {
+					printDrain(r.GetDrain())
+				}
+				if !r.GetDrain().Drained && timeout > 0 {
+					os.Exit(1)
+				}
+				return
+			}
+			if !jsonOut && !r.GetDrain().Unobservable {
+				fmt.Printf("waiting for transit traffic to stop (%d packets forwarded)\n", r.GetDrain().TransitPackets)
+			}
+			time.Sleep(time.Second)
+		}
+	},
+}
+
+var undrainCmd = &cobra.Command{
+	Use:     "undrain",
+	Short:   "Carry traffic through this router again after drain",
+	GroupID: "ny",
+	Run: func(cmd *cobra.Command, args []string) {
+		itf, _ := cmd.Flags().GetString("interface")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		r := sendDrainRequest(itf, protocol.DrainAction_UNDRAIN)
+		if jsonOut {
+			printJSON(r)
+			return
+		}
+		printDrain(r.GetDrain())
+	},
+}
+
+func sendDrainRequest(itf string, action protocol.DrainAction) *protocol.IpcResponse {
+	resp, err := core.SendIPCRequest(itf, &protocol.IpcRequest{
+		Request: &protocol.IpcRequest_Drain{Drain: &protocol.DrainRequest{Action: action}},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if !resp.Ok {
+		fmt.Fprintln(os.Stderr, "Error:", resp.Error)
+		os.Exit(1)
+	}
+	return resp
+}
+
+func printDrain(d *protocol.DrainResponse) {
+	switch {
+	case !d.Draining:
+		fmt.Println("Router is carrying traffic")
+	case d.Drained:
+		fmt.Println("Router is drained, no transit traffic is forwarded")
+	default:
+		fmt.Println("Router is draining, but still forwards transit traffic")
+	}
+	if d.Unobservable {
+		fmt.Println("Transit traffic is forwarded by the system routing table and cannot be observed, the router is " +
+			"considered drained once neighbours had time to switch away")
+	} else if d.IdleMs >= 0 {
+		fmt.Printf("Last transit packet: %s ago\n", (time.Duration(d.IdleMs) * time.Millisecond).Round(time.Second))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(drainCmd)
+	drainCmd.Flags().StringP("interface", "i", "nylon", "Interface name")
+	drainCmd.Flags().Bool("json", false, "Output as JSON")
+	drainCmd.Flags().Duration("timeout", 2*time.Minute, "How long to wait for transit traffic to stop, 0 to return immediately")
+	rootCmd.AddCommand(undrainCmd)
+	undrainCmd.Flags().StringP("interface", "i", "nylon", "Interface name")
+	undrainCmd.Flags().Bool("json", false, "Output as JSON")
+}
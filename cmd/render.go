@@ -121,6 +121,14 @@ func metricText(p paletteValues, metric uint32) string {
 	return fmt.Sprintf("%d", metric)
 }
 
+// prefixText shows a destination prefix, followed by the source prefix of source-specific routes
+func prefixText(prefix, src string) string {
+	if src == "" {
+		return prefix
+	}
+	return prefix + " from " + src
+}
+
 func formatExpiry(unix int64) string {
 	if unix <= 0 {
 		return "never"
@@ -156,6 +164,10 @@ func formatBytes(v uint64) string {
 	return fmt.Sprintf("%.2f %ciB", float64(v)/float64(div), "KMGTPE"[exp])
 }
 
+func formatRatio(v float64) string {
+	return fmt.Sprintf("%.1f%%", v*100)
+}
+
 func formatDurationNs(ns int64) string {
 	if ns <= 0 {
 		return "-"
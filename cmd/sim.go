@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/encodeous/nylon/core"
+	"github.com/encodeous/nylon/state"
+	"github.com/goccy/go-yaml"
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+var simCmd = &cobra.Command{
+	Use:   "sim <central-config>",
+	Short: "Simulate the network described by a central config",
+	Long: `Runs every router of the central config in-process on a virtual network and clock, then reports how long
+routing took to converge, how the route tables changed, and any transient loops or blackholes.
+A scenario file adds timed events: link failures, latency and loss changes, router crashes and config changes.`,
+	Args:    cobra.ExactArgs(1),
+	GroupID: "cfg",
+	Run: func(cmd *cobra.Command, args []string) {
+		scenarioPath, _ := cmd.Flags().GetString("scenario")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		allTables, _ := cmd.Flags().GetBool("tables")
+		noColor, _ := cmd.Flags().GetBool("no-color")
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		var cfg state.CentralCfg
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		scenario := &state.SimScenario{}
+		if scenarioPath != "" {
+			scenario, err = state.ReadSimScenario(scenarioPath)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		sim, err := core.NewSimulation(&cfg, scenario, state.DefaultRouterTunables())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		report, err := sim.Run()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		if jsonOut {
+			out, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Println(string(out))
+			return
+		}
+		renderSimReport(report, palette(!noColor && os.Getenv("NO_COLOR") == "" && term.IsTerminal(os.Stdout.Fd())), allTables)
+	},
+}
+
+func renderSimReport(report *core.SimReport, p paletteValues, allTables bool) {
+	for i, phase := range report.Phases {
+		fmt.Printf("%s %s\n", p.header("t="+phase.At.String()), phase.Event)
+		converged := "no forwarding changes"
+		if len(phase.Changes) > 0 {
+			converged = "after " + phase.Converged.String()
+		}
+		printKV(p, 1, "converged", converged)
+
+		if i > 0 {
+			rows := make([][]string, 0, len(phase.Changes))
+			for _, change := range phase.Changes {
+				nh, metric := string(change.Nh), metricText(p, change.Metric)
+				if change.Removed {
+					nh, metric = p.muted("removed"), ""
+				} else if change.Metric == state.INF {
+					nh = p.bad("blackhole")
+				}
+				rows = append(rows, []string{change.At.String(), string(change.Node), change.Prefix, nh, metric})
+			}
+			fmt.Println("  " + p.section("route changes:"))
+			printTable(p, 2, []string{"time", "router", "prefix", "nh", "metric"}, rows)
+		} else {
+			printKV(p, 1, "routes installed", fmt.Sprint(len(phase.Changes)))
+		}
+
+		end := time.Duration(-1)
+		if i+1 < len(report.Phases) {
+			end = report.Phases[i+1].At
+		}
+		rows := make([][]string, 0)
+		for _, anomaly := range report.Anomalies {
+			if anomaly.Start < phase.At || end >= 0 && anomaly.Start >= end {
+				continue
+			}
+			duration := p.bad("until the end")
+			if anomaly.Resolved {
+				duration = (anomaly.End - anomaly.Start).String()
+			}
+			rows = append(rows, []string{p.warn(anomaly.Kind), anomaly.Prefix, simPathText(anomaly), anomaly.Start.String(), duration})
+		}
+		fmt.Println("  " + p.section("delivery problems:"))
+		printTable(p, 2, []string{"kind", "prefix", "path", "start", "duration"}, rows)
+
+		if allTables || i == len(report.Phases)-1 {
+			fmt.Println("  " + p.section("route tables:"))
+			printSimTables(p, phase.Tables)
+		}
+		fmt.Println()
+	}
+}
+
+func simPathText(anomaly *core.SimAnomaly) string {
+	ids := nodeIdStrings(anomaly.Path)
+	switch anomaly.Kind {
+	case core.SimLoop:
+		ids = append(ids, ids[0])
+	case core.SimBlackhole:
+		ids = append(ids, "drop")
+	}
+	return strings.Join(ids, " -> ")
+}
+
+func printSimTables(p paletteValues, tables map[state.NodeId][]core.SimRoute) {
+	ids := make([]state.NodeId, 0, len(tables))
+	for id := range tables {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		fmt.Println("    " + p.key(string(id)))
+		rows := make([][]string, 0, len(tables[id]))
+		for _, route := range tables[id] {
+			rows = append(rows, []string{route.Prefix, string(route.Nh), string(route.Origin), metricText(p, route.Metric)})
+		}
+		printTable(p, 3, []string{"prefix", "nh", "router", "metric"}, rows)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(simCmd)
+	simCmd.Flags().StringP("scenario", "s", "", "Scenario file with timed events")
+	simCmd.Flags().Bool("json", false, "Output as JSON")
+	simCmd.Flags().Bool("tables", false, "Show the route tables after every event, not only at the end")
+	simCmd.Flags().Bool("no-color", false, "Disable colored output")
+}
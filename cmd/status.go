@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/encodeous/nylon/core"
 	"github.com/encodeous/nylon/protocol"
@@ -63,6 +64,9 @@ func renderStatus(s *protocol.StatusResponse, opts statusRenderOptions) {
 	printKV(p, 1, "listening port", fmt.Sprint(node.ListenPort))
 	printKV(p, 1, "config timestamp", fmt.Sprint(node.ConfigTimestamp))
 	printKV(p, 1, "trace enabled", fmt.Sprint(node.TraceEnabled))
+	if node.Draining {
+		printKV(p, 1, "state", p.warn("draining"))
+	}
 	printTable(p, 1,
 		[]string{"neighbours", "active endpoints", "selected routes", "advertised", "tx", "rx"},
 		[][]string{{
@@ -74,6 +78,12 @@ func renderStatus(s *protocol.StatusResponse, opts statusRenderOptions) {
 			formatBytes(stats.RxBytes),
 		}},
 	)
+	if stats.DuplicatesSent != 0 || stats.DuplicatesDropped != 0 {
+		printKV(p, 1, "duplicates", fmt.Sprintf("%d sent, %d dropped", stats.DuplicatesSent, stats.DuplicatesDropped))
+	}
+	if stats.PortForwardConnections != 0 {
+		printKV(p, 1, "port forwards", fmt.Sprintf("%d connections", stats.PortForwardConnections))
+	}
 	fmt.Println()
 
 	if len(node.Seqnos) > 0 {
@@ -110,6 +120,18 @@ func renderStatus(s *protocol.StatusResponse, opts statusRenderOptions) {
 			statHeaders = append(statHeaders, "wireguard endpoint")
 			statRow = append(statRow, *wg.Endpoint)
 		}
+		if neigh.Bond != "" {
+			statHeaders = append(statHeaders, "bond")
+			statRow = append(statRow, neigh.Bond)
+		}
+		if neigh.SpoofedPackets > 0 {
+			statHeaders = append(statHeaders, "spoofed packets")
+			statRow = append(statRow, p.warn(fmt.Sprint(neigh.SpoofedPackets)))
+		}
+		if neigh.UnauthorizedUpdates > 0 {
+			statHeaders = append(statHeaders, "unauthorized updates")
+			statRow = append(statRow, p.warn(fmt.Sprint(neigh.UnauthorizedUpdates)))
+		}
 		printTable(p, 2, statHeaders, [][]string{statRow})
 		if len(neigh.Endpoints) > 0 {
 			fmt.Println("    " + p.section("endpoints:"))
@@ -137,12 +159,28 @@ func renderStatus(s *protocol.StatusResponse, opts statusRenderOptions) {
 	}
 	fmt.Println()
 
+	if len(s.AclRules) > 0 {
+		fmt.Println(p.header("acl"))
+		printACLRules(p, s.AclRules)
+		fmt.Println()
+	}
+
+	if len(s.QosClasses) > 0 {
+		fmt.Println(p.header("qos"))
+		printQoSClasses(p, s.QosClasses)
+		fmt.Println()
+	}
+
 	if opts.showFull {
 		fmt.Println(p.header("feasibility distances"))
 		if len(s.FeasibilityDistances) == 0 {
 			fmt.Println("  " + p.muted("none"))
 		}
 		printFeasibilityDistances(p, s.FeasibilityDistances)
+		fmt.Println()
+
+		fmt.Println(p.header("dampened sources"))
+		printDampenedSources(p, s.DampenedSources)
 	}
 }
 
@@ -158,7 +196,7 @@ func printSelectedRoutes(p paletteValues, routes []*protocol.SelRoute, full bool
 		pub := route.GetPubRoute()
 		src := pub.GetSource()
 		fd := pub.GetFd()
-		row := []string{src.Prefix, route.Nh, src.NodeId, fmt.Sprint(fd.Seqno), metricText(p, fd.Metric)}
+		row := []string{prefixText(src.Prefix, src.SrcPrefix), nextHopText(route.Nh, route.Multipath), src.NodeId, fmt.Sprint(fd.Seqno), metricText(p, fd.Metric)}
 		if full {
 			if len(headers) == 5 {
 				headers = append(headers, "expires", "retracted by")
@@ -182,15 +220,23 @@ func printTableRoutes(p paletteValues, name string, routes []*protocol.RouteTabl
 	}
 	rows := make([][]string, 0, len(routes))
 	for _, route := range routes {
-		action := route.Nh
+		action := nextHopText(route.Nh, route.Multipath)
 		if route.Blackhole {
 			action = p.bad("blackhole")
 		}
-		rows = append(rows, []string{route.Prefix, action})
+		rows = append(rows, []string{prefixText(route.Prefix, route.SrcPrefix), action})
 	}
 	printTable(p, 2, []string{"prefix", "nh"}, rows)
 }
 
+// nextHopText lists every multipath next hop, or just the selected one if the route has a single next hop
+func nextHopText(nh string, multipath []string) string {
+	if len(multipath) == 0 {
+		return nh
+	}
+	return strings.Join(multipath, ",")
+}
+
 func endpointFlags(p paletteValues, ep *protocol.EndpointInfo, best *protocol.EndpointInfo) string {
 	flags := make([]string, 0)
 	if ep.Active {
@@ -204,6 +250,15 @@ func endpointFlags(p paletteValues, ep *protocol.EndpointInfo, best *protocol.En
 	if ep.RemoteInit {
 		flags = append(flags, "remote")
 	}
+	if ep.Backup {
+		flags = append(flags, "backup")
+	}
+	if ep.Metered {
+		flags = append(flags, "metered")
+	}
+	if ep.BondShare > 0 {
+		flags = append(flags, fmt.Sprintf("bond %.0f%%", ep.BondShare*100))
+	}
 	if len(flags) == 0 {
 		return ""
 	}
@@ -216,6 +271,13 @@ func bestEndpoint(endpoints []*protocol.EndpointInfo) *protocol.EndpointInfo {
 		if !ep.Active {
 			continue
 		}
+		// backups are only used when no primary endpoint is active
+		if best != nil && ep.Backup != best.Backup {
+			if ep.Backup {
+				continue
+			}
+			best = nil
+		}
 		if best == nil || ep.Metric < best.Metric || (ep.Metric == best.Metric && ep.Address < best.Address) {
 			best = ep
 		}
@@ -226,7 +288,7 @@ func bestEndpoint(endpoints []*protocol.EndpointInfo) *protocol.EndpointInfo {
 func printEndpoints(p paletteValues, endpoints []*protocol.EndpointInfo, best *protocol.EndpointInfo, full bool) {
 	headers := []string{"address", "resolved", "metric", "state"}
 	if full {
-		headers = append(headers, "rtt", "stable rtt")
+		headers = append(headers, "rtt", "stable rtt", "jitter", "loss", "tx", "rx")
 	}
 	rows := make([][]string, 0, len(endpoints))
 	for _, ep := range endpoints {
@@ -236,7 +298,7 @@ func printEndpoints(p paletteValues, endpoints []*protocol.EndpointInfo, best *p
 		}
 		row := []string{ep.Address, resolved, metricText(p, ep.Metric), endpointFlags(p, ep, best)}
 		if full {
-			row = append(row, formatDurationNs(ep.FilteredRttNs), formatDurationNs(ep.StabilizedRttNs))
+			row = append(row, formatDurationNs(ep.FilteredRttNs), formatDurationNs(ep.StabilizedRttNs), formatDurationNs(ep.JitterNs), formatRatio(ep.LossRatio), formatBytes(ep.TxBytes), formatBytes(ep.RxBytes))
 		}
 		rows = append(rows, row)
 	}
@@ -253,7 +315,7 @@ func printNeighRoutes(p paletteValues, neigh string, routes []*protocol.NeighRou
 		pub := route.GetPubRoute()
 		src := pub.GetSource()
 		fd := pub.GetFd()
-		prefix := src.Prefix
+		prefix := prefixText(src.Prefix, src.SrcPrefix)
 		if src.NodeId == neigh {
 			prefix = p.good(prefix)
 		}
@@ -272,7 +334,7 @@ func printCondensedNeighRoutes(p paletteValues, neigh string, routes []*protocol
 		if route.PubRoute.Source.NodeId != neigh {
 			continue
 		}
-		prefixes = append(prefixes, route.PubRoute.GetSource().Prefix)
+		prefixes = append(prefixes, prefixText(route.PubRoute.GetSource().Prefix, route.PubRoute.GetSource().SrcPrefix))
 	}
 	printCommaList(p, indent, prefixes)
 }
@@ -288,7 +350,7 @@ func printAdvertisements(p paletteValues, advertisements []*protocol.Advertiseme
 		if adv.PassiveHold {
 			hold = p.warn("passive-hold")
 		}
-		rows = append(rows, []string{adv.Prefix, adv.NodeId, metricText(p, adv.Metric), formatExpiry(adv.ExpiryUnix), hold})
+		rows = append(rows, []string{prefixText(adv.Prefix, adv.SrcPrefix), adv.NodeId, metricText(p, adv.Metric), formatExpiry(adv.ExpiryUnix), hold})
 	}
 	printTable(p, indent, []string{"prefix", "router", "metric", "expires", "state"}, rows)
 }
@@ -296,7 +358,7 @@ func printAdvertisements(p paletteValues, advertisements []*protocol.Advertiseme
 func printSeqnos(p paletteValues, seqnos []*protocol.SeqnoEntry) {
 	rows := make([][]string, 0, len(seqnos))
 	for _, seq := range seqnos {
-		rows = append(rows, []string{seq.Prefix, fmt.Sprint(seq.Seqno)})
+		rows = append(rows, []string{prefixText(seq.Prefix, seq.SrcPrefix), fmt.Sprint(seq.Seqno)})
 	}
 	printTable(p, 1, []string{"prefix", "seqno"}, rows)
 }
@@ -306,11 +368,53 @@ func printFeasibilityDistances(p paletteValues, distances []*protocol.Feasibilit
 	for _, dist := range distances {
 		src := dist.GetSource()
 		fd := dist.GetFd()
-		rows = append(rows, []string{src.Prefix, src.NodeId, fmt.Sprint(fd.Seqno), metricText(p, fd.Metric)})
+		rows = append(rows, []string{prefixText(src.Prefix, src.SrcPrefix), src.NodeId, fmt.Sprint(fd.Seqno), metricText(p, fd.Metric)})
 	}
 	printTable(p, 1, []string{"prefix", "router", "seqno", "metric"}, rows)
 }
 
+func printDampenedSources(p paletteValues, sources []*protocol.DampenedSource) {
+	rows := make([][]string, 0, len(sources))
+	for _, dampened := range sources {
+		src := dampened.GetSource()
+		status := p.muted("penalised")
+		if dampened.Suppressed {
+			status = p.bad("suppressed")
+		}
+		reuse := formatDurationNs((time.Duration(dampened.ReuseInMs) * time.Millisecond).Round(time.Second).Nanoseconds())
+		rows = append(rows, []string{prefixText(src.Prefix, src.SrcPrefix), src.NodeId, fmt.Sprintf("%.0f", dampened.Penalty), status, reuse})
+	}
+	printTable(p, 1, []string{"prefix", "router", "penalty", "state", "reuse in"}, rows)
+}
+
+func printACLRules(p paletteValues, rules []*protocol.ACLRuleStats) {
+	rows := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		action := p.good(rule.Action)
+		if rule.Action == string(state.ACLDeny) {
+			action = p.bad(rule.Action)
+		}
+		rows = append(rows, []string{rule.Name, action, fmt.Sprint(rule.Hits), fmt.Sprint(rule.Drops)})
+	}
+	printTable(p, 1, []string{"rule", "action", "hits", "drops"}, rows)
+}
+
+func printQoSClasses(p paletteValues, classes []*protocol.QoSClassStats) {
+	rows := make([][]string, 0, len(classes))
+	for _, class := range classes {
+		rate := p.muted("unlimited")
+		if class.RateBps != 0 {
+			rate = state.Bitrate(class.RateBps).String()
+		}
+		drops := fmt.Sprint(class.Drops)
+		if class.Drops != 0 {
+			drops = p.warn(drops)
+		}
+		rows = append(rows, []string{class.Name, class.Priority, rate, fmt.Sprint(class.Packets), formatBytes(class.Bytes), drops, fmt.Sprint(class.Queued)})
+	}
+	printTable(p, 1, []string{"class", "priority", "rate", "packets", "bytes", "drops", "queued"}, rows)
+}
+
 func init() {
 	rootCmd.AddCommand(statusCmd)
 	statusCmd.Flags().StringP("interface", "i", "nylon", "Interface name")
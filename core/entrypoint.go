@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime/trace"
 
 	"github.com/encodeous/nylon/state"
@@ -125,6 +126,9 @@ func Bootstrap(centralPath, nodePath, logPath string, verbose bool, opts state.N
 	if logPath != "" {
 		nodeCfg.LogPath = logPath
 	}
+	if nodeCfg.StatePath == "" {
+		nodeCfg.StatePath = filepath.Join(filepath.Dir(nodePath), "nylon.state")
+	}
 
 	state.ExpandCentralConfig(centralCfg)
 	if err = state.CentralConfigValidator(centralCfg); err != nil {
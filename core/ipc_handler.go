@@ -70,6 +70,9 @@ func HandleNylonIPC(n *Nylon, rw *bufio.ReadWriter) error {
 	if _, ok := req.Request.(*protocol.IpcRequest_Trace); ok {
 		return handleTrace(n, rw)
 	}
+	if _, ok := req.Request.(*protocol.IpcRequest_Capture); ok {
+		return handleCapture(n, req.GetCapture(), rw)
+	}
 	if _, ok := req.Request.(*protocol.IpcRequest_Probe); ok {
 		resp := handleIPCProbe(n, req.GetProbe())
 		if err := writeResponse(rw, resp); err != nil {
@@ -86,6 +89,8 @@ func HandleNylonIPC(n *Nylon, rw *bufio.ReadWriter) error {
 			resp = handleStatus(n, req.GetStatus())
 		case *protocol.IpcRequest_Reload:
 			resp = handleIPCReload(n, req.GetReload())
+		case *protocol.IpcRequest_Drain:
+			resp = handleIPCDrain(n, req.GetDrain())
 		default:
 			resp = errResponse("unknown method")
 		}
@@ -144,17 +149,24 @@ func handleStatus(n *Nylon, req *protocol.StatusRequest) *protocol.IpcResponse {
 				Advertised:      buildAdvertisements(n),
 				Seqnos:          buildSeqnos(n),
 				Stats: &protocol.NodeStats{
-					NeighbourCount:        int32(len(n.RouterState.Neighbours)),
-					ActiveEndpointCount:   int32(activeEps),
-					SelectedRouteCount:    int32(len(n.RouterState.Routes)),
-					AdvertisedPrefixCount: int32(len(n.RouterState.Advertised)),
-					TxBytes:               txBytes,
-					RxBytes:               rxBytes,
+					NeighbourCount:         int32(len(n.RouterState.Neighbours)),
+					ActiveEndpointCount:    int32(activeEps),
+					SelectedRouteCount:     int32(len(n.RouterState.Routes)),
+					AdvertisedPrefixCount:  int32(len(n.RouterState.Advertised)),
+					TxBytes:                txBytes,
+					RxBytes:                rxBytes,
+					DuplicatesSent:         n.duplicate.sent.Load(),
+					DuplicatesDropped:      n.duplicate.dropped.Load(),
+					PortForwardConnections: uint64(n.portForwardConnections()),
 				},
+				Draining: n.RouterState.Draining,
 			},
 			Neighbours:           buildNeighbours(n, wgStats),
 			Routes:               buildRouteTables(n),
 			FeasibilityDistances: buildFeasibilityDistances(n),
+			DampenedSources:      buildDampenedSources(n),
+			AclRules:             buildACLRules(n),
+			QosClasses:           buildQoSClasses(n),
 		}},
 	}
 }
@@ -163,7 +175,8 @@ func buildAdvertisements(n *Nylon) []*protocol.Advertisement {
 	entries := make([]*protocol.Advertisement, 0, len(n.RouterState.Advertised))
 	for prefix, adv := range n.RouterState.Advertised {
 		entries = append(entries, &protocol.Advertisement{
-			Prefix:      prefix.String(),
+			Prefix:      prefix.Prefix.String(),
+			SrcPrefix:   srcPrefixText(prefix),
 			NodeId:      string(adv.NodeId),
 			Metric:      adv.MetricFn(),
 			ExpiryUnix:  adv.Expiry.Unix(),
@@ -178,12 +191,16 @@ func buildSeqnos(n *Nylon) []*protocol.SeqnoEntry {
 	entries := make([]*protocol.SeqnoEntry, 0, len(n.RouterState.SelfSeqno))
 	for prefix, seqno := range n.RouterState.SelfSeqno {
 		entries = append(entries, &protocol.SeqnoEntry{
-			Prefix: prefix.String(),
-			Seqno:  uint32(seqno),
+			Prefix:    prefix.Prefix.String(),
+			Seqno:     uint32(seqno),
+			SrcPrefix: srcPrefixText(prefix),
 		})
 	}
 	slices.SortFunc(entries, func(a, b *protocol.SeqnoEntry) int {
-		return cmp.Compare(a.Prefix, b.Prefix)
+		if c := cmp.Compare(a.Prefix, b.Prefix); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.SrcPrefix, b.SrcPrefix)
 	})
 	return entries
 }
@@ -197,19 +214,24 @@ func buildNeighbours(n *Nylon, wgStats map[state.NyPublicKey]device.PeerStatus)
 		neigh := n.RouterState.GetNeighbour(id)
 		eps := make([]*protocol.EndpointInfo, 0)
 		routes := make([]*protocol.NeighRoute, 0)
+		var bond state.BondMode
 		if neigh != nil {
 			eps = n.buildEndpoints(neigh)
 			routes = buildNeighRoutes(neigh)
+			bond = neigh.Link.Bond
 		}
 		stat := wgStats[cfg.PubKey]
 		neighbours = append(neighbours, &protocol.NeighbourInfo{
-			PeerId:        string(id),
-			PublicKey:     keyString(cfg.PubKey),
-			PassiveClient: n.IsClient(id),
-			Endpoints:     eps,
-			Routes:        routes,
-			Advertised:    advertisementsForNode(n, id),
-			Wireguard:     wireGuardPeerStatsProto(stat),
+			PeerId:              string(id),
+			PublicKey:           keyString(cfg.PubKey),
+			PassiveClient:       n.IsClient(id),
+			Endpoints:           eps,
+			Routes:              routes,
+			Advertised:          advertisementsForNode(n, id),
+			Wireguard:           wireGuardPeerStatsProto(stat),
+			UnauthorizedUpdates: n.router.UnauthorizedUpdates[id],
+			Bond:                string(bond),
+			SpoofedPackets:      n.spoofedPackets(id),
 		})
 	}
 	return neighbours
@@ -217,12 +239,15 @@ func buildNeighbours(n *Nylon, wgStats map[state.NyPublicKey]device.PeerStatus)
 
 func (n *Nylon) buildEndpoints(neigh *state.Neighbour) []*protocol.EndpointInfo {
 	eps := make([]*protocol.EndpointInfo, 0, len(neigh.Eps))
+	shares := n.bondShares(neigh.Id)
 	for _, ep := range neigh.Eps {
 		nep := ep.AsNylonEndpoint()
 		var resolved *string
 		if ap, err := n.EndpointResolver.Get(nep.Address); err == nil {
 			resolved = new(ap.String())
 		}
+		lost, late := nep.ProbeCounters()
+		attrs := nep.Attrs()
 		eps = append(eps, &protocol.EndpointInfo{
 			Address:         nep.Address,
 			Resolved:        resolved,
@@ -231,6 +256,17 @@ func (n *Nylon) buildEndpoints(neigh *state.Neighbour) []*protocol.EndpointInfo
 			Metric:          ep.Metric(),
 			FilteredRttNs:   int64(nep.FilteredPing()),
 			StabilizedRttNs: int64(nep.StabilizedPing()),
+			JitterNs:        int64(nep.Jitter()),
+			LossRatio:       nep.LossRatio(),
+			LostProbes:      lost,
+			LateProbes:      late,
+			Backup:          attrs.IsBackup(),
+			Metered:         attrs.Metered,
+			TxPackets:       nep.Traffic.TxPackets.Load(),
+			TxBytes:         nep.Traffic.TxBytes.Load(),
+			RxPackets:       nep.Traffic.RxPackets.Load(),
+			RxBytes:         nep.Traffic.RxBytes.Load(),
+			BondShare:       shares[nep],
 		})
 	}
 	slices.SortFunc(eps, func(a, b *protocol.EndpointInfo) int {
@@ -262,25 +298,31 @@ func buildRouteTables(n *Nylon) *protocol.RouteTables {
 	slices.SortFunc(tables.Selected, func(a, b *protocol.SelRoute) int {
 		return comparePubRoute(a.PubRoute, b.PubRoute)
 	})
-	for prefix, route := range forwarding.Forward.All() {
-		tables.Forward = append(tables.Forward, &protocol.RouteTableEntry{
-			Prefix:    prefix.String(),
-			Nh:        string(route.Nh),
-			Blackhole: route.Blackhole,
-		})
+	for prefix, route := range allRoutes(forwarding.Forward, forwarding.SourceForward) {
+		tables.Forward = append(tables.Forward, routeTableEntryProto(prefix, route))
 	}
 	sortRouteTableEntries(tables.Forward)
-	for prefix, route := range forwarding.Exit.All() {
-		tables.Exit = append(tables.Exit, &protocol.RouteTableEntry{
-			Prefix:    prefix.String(),
-			Nh:        string(route.Nh),
-			Blackhole: route.Blackhole,
-		})
+	for prefix, route := range allRoutes(forwarding.Exit, forwarding.SourceExit) {
+		tables.Exit = append(tables.Exit, routeTableEntryProto(prefix, route))
 	}
 	sortRouteTableEntries(tables.Exit)
 	return tables
 }
 
+func routeTableEntryProto(prefix state.RoutePrefix, route RouteTableEntry) *protocol.RouteTableEntry {
+	multipath := make([]string, 0, len(route.Multipath))
+	for _, hop := range route.Multipath {
+		multipath = append(multipath, string(hop.Nh))
+	}
+	return &protocol.RouteTableEntry{
+		Prefix:    prefix.Prefix.String(),
+		Nh:        string(route.Nh),
+		Blackhole: route.Blackhole,
+		Multipath: multipath,
+		SrcPrefix: srcPrefixText(prefix),
+	}
+}
+
 func buildFeasibilityDistances(n *Nylon) []*protocol.FeasibilityDistance {
 	entries := make([]*protocol.FeasibilityDistance, 0, len(n.RouterState.Sources))
 	for source, fd := range n.RouterState.Sources {
@@ -293,6 +335,88 @@ func buildFeasibilityDistances(n *Nylon) []*protocol.FeasibilityDistance {
 		if c := cmp.Compare(a.Source.Prefix, b.Source.Prefix); c != 0 {
 			return c
 		}
+		if c := cmp.Compare(a.Source.SrcPrefix, b.Source.SrcPrefix); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Source.NodeId, b.Source.NodeId)
+	})
+	return entries
+}
+
+func buildACLRules(n *Nylon) []*protocol.ACLRuleStats {
+	t := n.acl.Load()
+	if t == nil {
+		return nil
+	}
+	names := append(slices.Clone(t.acl.RuleNames()), "default")
+	rules := make([]*protocol.ACLRuleStats, 0, len(names))
+	for i, name := range names {
+		rule := i
+		if i == len(names)-1 {
+			rule = -1
+		}
+		rules = append(rules, &protocol.ACLRuleStats{
+			Name:   name,
+			Action: string(t.acl.RuleAction(rule)),
+			Hits:   t.hits[i].Load(),
+			Drops:  t.drops[i].Load(),
+		})
+	}
+	return rules
+}
+
+func buildQoSClasses(n *Nylon) []*protocol.QoSClassStats {
+	t := n.qos.Load()
+	if t == nil {
+		return nil
+	}
+	classes := make([]*protocol.QoSClassStats, 0, len(t.packets))
+	for i := range t.packets {
+		stats := &protocol.QoSClassStats{
+			Name:     "default",
+			Priority: string(state.QoSNormal),
+			Packets:  t.packets[i].Load(),
+			Bytes:    t.bytes[i].Load(),
+			Drops:    t.drops[i].Load(),
+			Queued:   t.queued[i].Load(),
+		}
+		if i < len(t.qos.Classes) {
+			class := t.qos.Classes[i]
+			stats.Name, stats.RateBps = class.Name, uint64(class.Rate)
+			if class.Priority != "" {
+				stats.Priority = string(class.Priority)
+			}
+		}
+		classes = append(classes, stats)
+	}
+	return classes
+}
+
+func buildDampenedSources(n *Nylon) []*protocol.DampenedSource {
+	d := n.RouterState.Dampening
+	if d == nil {
+		return nil
+	}
+	now := time.Now()
+	entries := make([]*protocol.DampenedSource, 0, len(d.Entries))
+	for source, entry := range d.Entries {
+		if entry.Penalty < 1 {
+			continue
+		}
+		entries = append(entries, &protocol.DampenedSource{
+			Source:     sourceProto(source),
+			Penalty:    entry.Penalty,
+			Suppressed: entry.Suppressed,
+			ReuseInMs:  d.ReuseIn(entry, now).Milliseconds(),
+		})
+	}
+	slices.SortFunc(entries, func(a, b *protocol.DampenedSource) int {
+		if c := cmp.Compare(a.Source.Prefix, b.Source.Prefix); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Source.SrcPrefix, b.Source.SrcPrefix); c != 0 {
+			return c
+		}
 		return cmp.Compare(a.Source.NodeId, b.Source.NodeId)
 	})
 	return entries
@@ -306,7 +430,8 @@ func advertisementsForNode(n *Nylon, id state.NodeId) []*protocol.Advertisement
 		}
 		ads = append(ads, &protocol.Advertisement{
 			NodeId:      string(adv.NodeId),
-			Prefix:      prefix.String(),
+			Prefix:      prefix.Prefix.String(),
+			SrcPrefix:   srcPrefixText(prefix),
 			Metric:      adv.MetricFn(),
 			ExpiryUnix:  adv.Expiry.Unix(),
 			PassiveHold: adv.IsPassiveHold,
@@ -340,9 +465,18 @@ func wireGuardPeerStatsProto(stat device.PeerStatus) *protocol.WireGuardPeerStat
 
 func sourceProto(source state.Source) *protocol.Source {
 	return &protocol.Source{
-		NodeId: string(source.NodeId),
-		Prefix: source.Prefix.String(),
+		NodeId:    string(source.NodeId),
+		Prefix:    source.Prefix.String(),
+		SrcPrefix: srcPrefixText(source.RoutePrefix),
+	}
+}
+
+// srcPrefixText returns the source prefix of a source-specific route, or an empty string.
+func srcPrefixText(prefix state.RoutePrefix) string {
+	if !prefix.IsSourceSpecific() {
+		return ""
 	}
+	return prefix.Src.String()
 }
 
 func fdProto(fd state.FD) *protocol.FD {
@@ -372,11 +506,16 @@ func selRouteProto(route state.SelRoute) *protocol.SelRoute {
 		retractedBy = append(retractedBy, string(id))
 	}
 	slices.Sort(retractedBy)
+	multipath := make([]string, 0, len(route.Multipath))
+	for _, id := range route.Multipath {
+		multipath = append(multipath, string(id))
+	}
 	return &protocol.SelRoute{
 		PubRoute:     pubRouteProto(route.PubRoute),
 		Nh:           string(route.Nh),
 		ExpireAtUnix: route.ExpireAt.Unix(),
 		RetractedBy:  retractedBy,
+		Multipath:    multipath,
 	}
 }
 
@@ -392,6 +531,9 @@ func comparePubRoute(a, b *protocol.PubRoute) int {
 	if c := cmp.Compare(a.Source.Prefix, b.Source.Prefix); c != 0 {
 		return c
 	}
+	if c := cmp.Compare(a.Source.SrcPrefix, b.Source.SrcPrefix); c != 0 {
+		return c
+	}
 	if c := cmp.Compare(a.Source.NodeId, b.Source.NodeId); c != 0 {
 		return c
 	}
@@ -406,6 +548,9 @@ func sortAdvertisements(entries []*protocol.Advertisement) {
 		if c := cmp.Compare(a.Prefix, b.Prefix); c != 0 {
 			return c
 		}
+		if c := cmp.Compare(a.SrcPrefix, b.SrcPrefix); c != 0 {
+			return c
+		}
 		return cmp.Compare(a.NodeId, b.NodeId)
 	})
 }
@@ -415,6 +560,9 @@ func sortRouteTableEntries(entries []*protocol.RouteTableEntry) {
 		if c := cmp.Compare(a.Prefix, b.Prefix); c != 0 {
 			return c
 		}
+		if c := cmp.Compare(a.SrcPrefix, b.SrcPrefix); c != 0 {
+			return c
+		}
 		return cmp.Compare(a.Nh, b.Nh)
 	})
 }
@@ -527,3 +675,38 @@ func handleTrace(n *Nylon, rw *bufio.ReadWriter) error {
 		}
 	}
 }
+
+func handleCapture(n *Nylon, req *protocol.CaptureRequest, rw *bufio.ReadWriter) error {
+	s, err := n.startCapture(req)
+	if err != nil {
+		if err := writeResponse(rw, errResponse(err.Error())); err != nil {
+			return err
+		}
+		return device.ErrIPCStatusHandled
+	}
+	defer n.stopCapture(s)
+	if err := writeResponse(rw, &protocol.IpcResponse{Ok: true}); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(n.Context)
+	defer cancel()
+	go func() {
+		_, _ = rw.ReadByte() // wait for EOF / disconnect
+		cancel()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return device.ErrIPCStatusHandled
+		case packet := <-s.packets:
+			packet.Skipped = s.skipped.Swap(0)
+			resp := &protocol.IpcResponse{
+				Ok:       true,
+				Response: &protocol.IpcResponse_Captured{Captured: packet},
+			}
+			if err := writeResponse(rw, resp); err != nil {
+				return device.ErrIPCStatusHandled
+			}
+		}
+	}
+}
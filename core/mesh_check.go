@@ -0,0 +1,465 @@
+package core
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/encodeous/nylon/protocol"
+	"github.com/encodeous/nylon/state"
+)
+
+const (
+	MeshLoop      = "loop"
+	MeshBlackhole = "blackhole"
+)
+
+const (
+	MeshNoRoute        = "no route"
+	MeshBlackholeRoute = "blackhole route"
+)
+
+// MeshReport describes how status snapshots of the mesh forward each prefix
+type MeshReport struct {
+	Nodes []state.NodeId `json:"nodes"`
+	// Missing lists next hops without a snapshot, forwarding past them is not checked
+	Missing    []state.NodeId       `json:"missing"`
+	Problems   []*MeshProblem       `json:"problems"`
+	Asymmetric []*MeshAsymmetry     `json:"asymmetric"`
+	Seqnos     []*MeshSeqnoConflict `json:"seqnos"`
+}
+
+// MeshProblem is a forwarding loop, or a router that drops packets for a prefix
+type MeshProblem struct {
+	Kind      string `json:"kind"`
+	Prefix    string `json:"prefix"`
+	SrcPrefix string `json:"src_prefix,omitempty"`
+	// Path is the cycle for loops, and the path to the router that drops packets for blackholes
+	Path   []state.NodeId `json:"path"`
+	Reason string         `json:"reason,omitempty"`
+	// From lists the routers whose packets are affected
+	From []state.NodeId `json:"from"`
+}
+
+// MeshAsymmetry is a pair of routers whose traffic takes different paths in each direction
+type MeshAsymmetry struct {
+	A       state.NodeId   `json:"a"`
+	B       state.NodeId   `json:"b"`
+	Forward []state.NodeId `json:"forward"`
+	Reverse []state.NodeId `json:"reverse"`
+}
+
+// MeshSeqnoConflict is a source whose seqno is not the same on every router
+type MeshSeqnoConflict struct {
+	NodeId    state.NodeId `json:"node_id"`
+	Prefix    string       `json:"prefix"`
+	SrcPrefix string       `json:"src_prefix,omitempty"`
+	// Seqnos holds the seqno of the selected route on each router, and the current seqno of the origin
+	Seqnos map[state.NodeId]uint32 `json:"seqnos"`
+}
+
+type meshEntry struct {
+	prefix netip.Prefix
+	entry  *protocol.RouteTableEntry
+}
+
+type meshDest struct {
+	prefix netip.Prefix
+	src    string
+}
+
+type meshChecker struct {
+	forward  map[state.NodeId][]meshEntry
+	report   *MeshReport
+	problems map[string]*MeshProblem
+	missing  map[state.NodeId]struct{}
+}
+
+// CheckMesh follows the forwarding tables of the snapshots for every prefix and reports loops, blackholes,
+// asymmetric paths and seqno disagreements.
+func CheckMesh(statuses []*protocol.StatusResponse) (*MeshReport, error) {
+	c := &meshChecker{
+		forward:  make(map[state.NodeId][]meshEntry),
+		report:   &MeshReport{},
+		problems: make(map[string]*MeshProblem),
+		missing:  make(map[state.NodeId]struct{}),
+	}
+	byNode := make(map[state.NodeId]*protocol.StatusResponse)
+	dests := make(map[meshDest]struct{})
+	for _, status := range statuses {
+		id := state.NodeId(status.GetNode().GetNodeId())
+		if id == "" {
+			return nil, fmt.Errorf("snapshot without a node id")
+		}
+		if _, ok := byNode[id]; ok {
+			return nil, fmt.Errorf("more than one snapshot of %s", id)
+		}
+		byNode[id] = status
+		entries := make([]meshEntry, 0, len(status.GetRoutes().GetForward()))
+		for _, entry := range status.GetRoutes().GetForward() {
+			prefix, err := netip.ParsePrefix(entry.Prefix)
+			if err != nil {
+				return nil, fmt.Errorf("route of %s: %w", id, err)
+			}
+			entries = append(entries, meshEntry{prefix: prefix, entry: entry})
+			dests[meshDest{prefix: prefix, src: entry.SrcPrefix}] = struct{}{}
+		}
+		c.forward[id] = entries
+	}
+	c.report.Nodes = slices.Sorted(maps.Keys(byNode))
+
+	sortedDests := slices.SortedFunc(maps.Keys(dests), func(a, b meshDest) int {
+		if c := a.prefix.Addr().Compare(b.prefix.Addr()); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.prefix.Bits(), b.prefix.Bits()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.src, b.src)
+	})
+	for _, dest := range sortedDests {
+		for _, id := range c.report.Nodes {
+			c.walk(dest, []state.NodeId{id})
+		}
+	}
+	for _, problem := range c.problems {
+		slices.Sort(problem.From)
+		problem.From = slices.Compact(problem.From)
+		c.report.Problems = append(c.report.Problems, problem)
+	}
+	slices.SortFunc(c.report.Problems, func(a, b *MeshProblem) int {
+		return cmp.Or(
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Prefix, b.Prefix),
+			cmp.Compare(a.SrcPrefix, b.SrcPrefix),
+			slices.Compare(a.Path, b.Path),
+		)
+	})
+	c.report.Missing = slices.Sorted(maps.Keys(c.missing))
+	c.checkSymmetry(byNode)
+	c.checkSeqnos(byNode)
+	return c.report, nil
+}
+
+// lookup finds the route a router uses for dest, preferring a source-specific route unless a plain route is more specific
+func (c *meshChecker) lookup(id state.NodeId, dest meshDest) *protocol.RouteTableEntry {
+	var plain, specific *meshEntry
+	for i, entry := range c.forward[id] {
+		if entry.prefix.Bits() > dest.prefix.Bits() || !entry.prefix.Contains(dest.prefix.Addr()) {
+			continue
+		}
+		switch entry.entry.SrcPrefix {
+		case "":
+			if plain == nil || entry.prefix.Bits() > plain.prefix.Bits() {
+				plain = &c.forward[id][i]
+			}
+		case dest.src:
+			if specific == nil || entry.prefix.Bits() > specific.prefix.Bits() {
+				specific = &c.forward[id][i]
+			}
+		}
+	}
+	if specific != nil && (plain == nil || specific.prefix.Bits() >= plain.prefix.Bits()) {
+		return specific.entry
+	}
+	if plain != nil {
+		return plain.entry
+	}
+	return nil
+}
+
+// walk follows every next hop for dest from the last router of path
+func (c *meshChecker) walk(dest meshDest, path []state.NodeId) {
+	id := path[len(path)-1]
+	if i := slices.Index(path[:len(path)-1], id); i != -1 {
+		c.addProblem(MeshLoop, dest, canonicalCycle(path[i:len(path)-1]), "", path[0])
+		return
+	}
+	if _, ok := c.forward[id]; !ok {
+		c.missing[id] = struct{}{}
+		return
+	}
+	entry := c.lookup(id, dest)
+	switch {
+	case entry == nil:
+		c.addProblem(MeshBlackhole, dest, path, MeshNoRoute, path[0])
+		return
+	case entry.Blackhole:
+		c.addProblem(MeshBlackhole, dest, path, MeshBlackholeRoute, path[0])
+		return
+	case state.NodeId(entry.Nh) == id:
+		return // delivered
+	}
+	hops := entry.Multipath
+	if len(hops) == 0 {
+		hops = []string{entry.Nh}
+	}
+	for _, hop := range hops {
+		c.walk(dest, append(slices.Clip(path), state.NodeId(hop)))
+	}
+}
+
+func (c *meshChecker) addProblem(kind string, dest meshDest, path []state.NodeId, reason string, from state.NodeId) {
+	key := kind + "|" + dest.prefix.String() + "|" + dest.src + "|" + reason + "|"
+	if kind == MeshLoop {
+		key += joinNodeIds(path)
+	} else {
+		key += string(path[len(path)-1])
+	}
+	if problem, ok := c.problems[key]; ok {
+		problem.From = append(problem.From, from)
+		if len(path) < len(problem.Path) {
+			problem.Path = slices.Clone(path)
+		}
+		return
+	}
+	c.problems[key] = &MeshProblem{
+		Kind:      kind,
+		Prefix:    dest.prefix.String(),
+		SrcPrefix: dest.src,
+		Path:      slices.Clone(path),
+		Reason:    reason,
+		From:      []state.NodeId{from},
+	}
+}
+
+// canonicalCycle rotates a cycle to start at its smallest node id, so every walk reports the loop the same way
+func canonicalCycle(cycle []state.NodeId) []state.NodeId {
+	start := 0
+	for i, id := range cycle {
+		if id < cycle[start] {
+			start = i
+		}
+	}
+	return append(slices.Clone(cycle[start:]), cycle[:start]...)
+}
+
+func joinNodeIds(ids []state.NodeId) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = string(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+// hostPrefixes finds a single-address prefix that only the router itself originates, to compare paths between routers
+func hostPrefixes(byNode map[state.NodeId]*protocol.StatusResponse) map[state.NodeId]netip.Prefix {
+	candidates := make(map[netip.Prefix][]state.NodeId)
+	for id, status := range byNode {
+		for _, adv := range status.GetNode().GetAdvertised() {
+			prefix, err := netip.ParsePrefix(adv.Prefix)
+			if err != nil || adv.SrcPrefix != "" || state.NodeId(adv.NodeId) != id || prefix.Bits() != prefix.Addr().BitLen() {
+				continue
+			}
+			candidates[prefix] = append(candidates[prefix], id)
+		}
+	}
+	hosts := make(map[state.NodeId]netip.Prefix)
+	for prefix, ids := range candidates {
+		if len(ids) != 1 {
+			continue // anycast
+		}
+		if cur, ok := hosts[ids[0]]; !ok || prefix.Addr().Less(cur.Addr()) {
+			hosts[ids[0]] = prefix
+		}
+	}
+	return hosts
+}
+
+// primaryPath follows the selected next hop for dest, returning false if packets are not delivered to dst
+func (c *meshChecker) primaryPath(dest meshDest, src, dst state.NodeId) ([]state.NodeId, bool) {
+	path := []state.NodeId{src}
+	for {
+		id := path[len(path)-1]
+		entry := c.lookup(id, dest)
+		if entry == nil || entry.Blackhole {
+			return nil, false
+		}
+		nh := state.NodeId(entry.Nh)
+		if nh == id {
+			return path, id == dst
+		}
+		if _, ok := c.forward[nh]; !ok || slices.Contains(path, nh) {
+			return nil, false
+		}
+		path = append(path, nh)
+	}
+}
+
+func (c *meshChecker) checkSymmetry(byNode map[state.NodeId]*protocol.StatusResponse) {
+	hosts := hostPrefixes(byNode)
+	for i, a := range c.report.Nodes {
+		for _, b := range c.report.Nodes[i+1:] {
+			aHost, aOk := hosts[a]
+			bHost, bOk := hosts[b]
+			if !aOk || !bOk {
+				continue
+			}
+			forward, ok := c.primaryPath(meshDest{prefix: bHost}, a, b)
+			if !ok {
+				continue
+			}
+			reverse, ok := c.primaryPath(meshDest{prefix: aHost}, b, a)
+			if !ok {
+				continue
+			}
+			backwards := slices.Clone(reverse)
+			slices.Reverse(backwards)
+			if !slices.Equal(forward, backwards) {
+				c.report.Asymmetric = append(c.report.Asymmetric, &MeshAsymmetry{A: a, B: b, Forward: forward, Reverse: reverse})
+			}
+		}
+	}
+}
+
+func (c *meshChecker) checkSeqnos(byNode map[state.NodeId]*protocol.StatusResponse) {
+	type sourceKey struct {
+		node        state.NodeId
+		prefix, src string
+	}
+	views := make(map[sourceKey]map[state.NodeId]uint32)
+	add := func(key sourceKey, id state.NodeId, seqno uint32) {
+		if views[key] == nil {
+			views[key] = make(map[state.NodeId]uint32)
+		}
+		views[key][id] = seqno
+	}
+	for id, status := range byNode {
+		for _, route := range status.GetRoutes().GetSelected() {
+			pub := route.GetPubRoute()
+			if pub.GetFd().GetMetric() == state.INF {
+				continue // retracted routes keep their last seqno
+			}
+			src := pub.GetSource()
+			add(sourceKey{state.NodeId(src.NodeId), src.Prefix, src.SrcPrefix}, id, pub.GetFd().GetSeqno())
+		}
+		for _, seqno := range status.GetNode().GetSeqnos() {
+			add(sourceKey{id, seqno.Prefix, seqno.SrcPrefix}, id, seqno.Seqno)
+		}
+	}
+	for key, seqnos := range views {
+		values := slices.Collect(maps.Values(seqnos))
+		slices.Sort(values)
+		if len(slices.Compact(values)) < 2 {
+			continue
+		}
+		c.report.Seqnos = append(c.report.Seqnos, &MeshSeqnoConflict{
+			NodeId:    key.node,
+			Prefix:    key.prefix,
+			SrcPrefix: key.src,
+			Seqnos:    seqnos,
+		})
+	}
+	slices.SortFunc(c.report.Seqnos, func(a, b *MeshSeqnoConflict) int {
+		return cmp.Or(
+			cmp.Compare(a.NodeId, b.NodeId),
+			cmp.Compare(a.Prefix, b.Prefix),
+			cmp.Compare(a.SrcPrefix, b.SrcPrefix),
+		)
+	})
+}
+
+// ParseStatusSnapshot reads a snapshot saved with `nylon status --json`, or a bare status response
+func ParseStatusSnapshot(data []byte) (*protocol.StatusResponse, error) {
+	resp := &protocol.IpcResponse{}
+	if err := pjUnmarshal.Unmarshal(data, resp); err == nil {
+		if status := resp.GetStatus(); status != nil {
+			return status, nil
+		}
+		if resp.Error != "" {
+			return nil, fmt.Errorf("snapshot is an error response: %s", resp.Error)
+		}
+	}
+	status := &protocol.StatusResponse{}
+	if err := pjUnmarshal.Unmarshal(data, status); err != nil {
+		return nil, err
+	}
+	if status.GetNode().GetNodeId() == "" {
+		return nil, fmt.Errorf("not a nylon status snapshot")
+	}
+	return status, nil
+}
+
+// FetchStatusSnapshot gets the status of a node from the /status endpoint of its observability server
+func FetchStatusSnapshot(ctx context.Context, client *http.Client, statusUrl string) (*protocol.StatusResponse, error) {
+	data, err := httpGet(ctx, client, statusUrl)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStatusSnapshot(data)
+}
+
+// DiscoverStatusSnapshots fetches the status of every node listed by the /discovery endpoint at discoveryUrl.
+// Nodes are reached through the first of their addresses that answers, failures are returned per node.
+func DiscoverStatusSnapshots(ctx context.Context, client *http.Client, discoveryUrl string) ([]*protocol.StatusResponse, map[state.NodeId]error, error) {
+	data, err := httpGet(ctx, client, discoveryUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+	var groups []discoveryGroup
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, nil, fmt.Errorf("parse discovery response: %w", err)
+	}
+	statuses := make([]*protocol.StatusResponse, 0, len(groups))
+	failed := make(map[state.NodeId]error)
+	for _, group := range groups {
+		id := state.NodeId(group.Labels["nylon_node"])
+		var errs []error
+		for _, target := range group.Targets {
+			status, err := FetchStatusSnapshot(ctx, client, (&url.URL{Scheme: "http", Host: target, Path: "/status"}).String())
+			if err == nil {
+				statuses = append(statuses, status)
+				errs = nil
+				break
+			}
+			errs = append(errs, err)
+		}
+		if len(errs) != 0 {
+			failed[id] = errs[len(errs)-1]
+		}
+	}
+	return statuses, failed, nil
+}
+
+// ObservabilityUrl turns an address or url of an observability server into the url of one of its endpoints
+func ObservabilityUrl(addr, path string) (string, error) {
+	if !strings.Contains(addr, "://") {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return "", fmt.Errorf("%q is neither a url nor host:port", addr)
+		}
+		addr = "http://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", err
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = path
+	}
+	return u.String(), nil
+}
+
+func httpGet(ctx context.Context, client *http.Client, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: %s", target, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,194 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/encodeous/nylon/protocol"
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// meshStatus builds a snapshot of a router that originates host and forwards by the given prefix -> next hop routes
+func meshStatus(id, host string, routes map[string]string) *protocol.StatusResponse {
+	status := &protocol.StatusResponse{
+		Node: &protocol.NodeStatus{
+			NodeId:     id,
+			Advertised: []*protocol.Advertisement{{NodeId: id, Prefix: host}},
+			Seqnos:     []*protocol.SeqnoEntry{{Prefix: host, Seqno: 1}},
+		},
+		Routes: &protocol.RouteTables{
+			Forward: []*protocol.RouteTableEntry{{Prefix: host, Nh: id}},
+		},
+	}
+	for prefix, nh := range routes {
+		entry := &protocol.RouteTableEntry{Prefix: prefix, Nh: nh}
+		if nh == "" {
+			entry.Blackhole = true
+		}
+		status.Routes.Forward = append(status.Routes.Forward, entry)
+	}
+	return status
+}
+
+func TestCheckMesh_Consistent(t *testing.T) {
+	report, err := CheckMesh([]*protocol.StatusResponse{
+		meshStatus("a", "10.0.0.1/32", map[string]string{"10.0.0.2/32": "b", "10.0.0.3/32": "b"}),
+		meshStatus("b", "10.0.0.2/32", map[string]string{"10.0.0.1/32": "a", "10.0.0.3/32": "c"}),
+		meshStatus("c", "10.0.0.3/32", map[string]string{"10.0.0.1/32": "b", "10.0.0.2/32": "b"}),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []state.NodeId{"a", "b", "c"}, report.Nodes)
+	assert.Empty(t, report.Problems)
+	assert.Empty(t, report.Asymmetric)
+	assert.Empty(t, report.Missing)
+	assert.Empty(t, report.Seqnos)
+}
+
+func TestCheckMesh_Blackholes(t *testing.T) {
+	report, err := CheckMesh([]*protocol.StatusResponse{
+		meshStatus("a", "10.0.0.1/32", map[string]string{"10.0.0.3/32": "b", "10.0.0.4/32": "b", "10.0.0.0/8": "d"}),
+		meshStatus("b", "10.0.0.2/32", map[string]string{"10.0.0.3/32": "c", "10.0.0.4/32": ""}),
+		meshStatus("c", "10.0.0.3/32", map[string]string{"10.0.0.1/32": "a"}),
+	})
+	require.NoError(t, err)
+	// a forwards 10.0.0.2 through its covering route to d, which has no snapshot
+	assert.Equal(t, []state.NodeId{"d"}, report.Missing)
+
+	var blackholes []string
+	for _, problem := range report.Problems {
+		require.Equal(t, MeshBlackhole, problem.Kind)
+		blackholes = append(blackholes, problem.Prefix+" "+joinNodeIds(problem.Path)+" "+problem.Reason+" from "+joinNodeIds(problem.From))
+	}
+	assert.Equal(t, []string{
+		"10.0.0.0/8 b no route from b",
+		"10.0.0.0/8 c no route from c",
+		"10.0.0.1/32 b no route from b",
+		"10.0.0.2/32 c no route from c",
+		"10.0.0.4/32 b blackhole route from a,b",
+		"10.0.0.4/32 c no route from c",
+	}, blackholes)
+}
+
+func TestCheckMesh_Loop(t *testing.T) {
+	report, err := CheckMesh([]*protocol.StatusResponse{
+		meshStatus("a", "10.0.0.1/32", map[string]string{"10.0.0.9/32": "c"}),
+		meshStatus("b", "10.0.0.2/32", map[string]string{"10.0.0.9/32": "a"}),
+		meshStatus("c", "10.0.0.3/32", map[string]string{"10.0.0.9/32": "b"}),
+	})
+	require.NoError(t, err)
+	var loops []*MeshProblem
+	for _, problem := range report.Problems {
+		if problem.Kind == MeshLoop {
+			loops = append(loops, problem)
+		}
+	}
+	require.Len(t, loops, 1)
+	assert.Equal(t, []state.NodeId{"a", "c", "b"}, loops[0].Path)
+	assert.Equal(t, []state.NodeId{"a", "b", "c"}, loops[0].From)
+}
+
+func TestCheckMesh_Asymmetric(t *testing.T) {
+	report, err := CheckMesh([]*protocol.StatusResponse{
+		meshStatus("a", "10.0.0.1/32", map[string]string{"10.0.0.2/32": "b", "10.0.0.3/32": "c"}),
+		meshStatus("b", "10.0.0.2/32", map[string]string{"10.0.0.1/32": "c", "10.0.0.3/32": "c"}),
+		meshStatus("c", "10.0.0.3/32", map[string]string{"10.0.0.1/32": "a", "10.0.0.2/32": "b"}),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, report.Problems)
+	require.Len(t, report.Asymmetric, 1)
+	assert.Equal(t, &MeshAsymmetry{
+		A:       "a",
+		B:       "b",
+		Forward: []state.NodeId{"a", "b"},
+		Reverse: []state.NodeId{"b", "c", "a"},
+	}, report.Asymmetric[0])
+}
+
+func TestCheckMesh_SeqnoDisagreement(t *testing.T) {
+	selected := func(origin, prefix string, seqno, metric uint32) *protocol.SelRoute {
+		return &protocol.SelRoute{PubRoute: &protocol.PubRoute{
+			Source: &protocol.Source{NodeId: origin, Prefix: prefix},
+			Fd:     &protocol.FD{Seqno: seqno, Metric: metric},
+		}}
+	}
+	a := meshStatus("a", "10.0.0.1/32", map[string]string{"10.0.0.2/32": "b"})
+	a.Node.Seqnos[0].Seqno = 5
+	b := meshStatus("b", "10.0.0.2/32", map[string]string{"10.0.0.1/32": "a"})
+	b.Routes.Selected = []*protocol.SelRoute{selected("a", "10.0.0.1/32", 4, 10)}
+	c := meshStatus("c", "10.0.0.3/32", nil)
+	c.Routes.Selected = []*protocol.SelRoute{
+		selected("a", "10.0.0.1/32", 3, state.INF), // retracted routes are ignored
+		selected("b", "10.0.0.2/32", 1, 10),
+	}
+
+	report, err := CheckMesh([]*protocol.StatusResponse{a, b, c})
+	require.NoError(t, err)
+	require.Len(t, report.Seqnos, 1)
+	assert.Equal(t, &MeshSeqnoConflict{
+		NodeId: "a",
+		Prefix: "10.0.0.1/32",
+		Seqnos: map[state.NodeId]uint32{"a": 5, "b": 4},
+	}, report.Seqnos[0])
+}
+
+func TestCheckMesh_DuplicateSnapshot(t *testing.T) {
+	_, err := CheckMesh([]*protocol.StatusResponse{
+		meshStatus("a", "10.0.0.1/32", nil),
+		meshStatus("a", "10.0.0.1/32", nil),
+	})
+	assert.ErrorContains(t, err, "more than one snapshot of a")
+}
+
+func TestParseStatusSnapshot(t *testing.T) {
+	status := meshStatus("a", "10.0.0.1/32", nil)
+	wrapped, err := pjMarshal.Marshal(&protocol.IpcResponse{Ok: true, Response: &protocol.IpcResponse_Status{Status: status}})
+	require.NoError(t, err)
+	bare, err := pjMarshal.Marshal(status)
+	require.NoError(t, err)
+
+	for _, data := range [][]byte{wrapped, bare} {
+		parsed, err := ParseStatusSnapshot(data)
+		require.NoError(t, err)
+		assert.Equal(t, "a", parsed.Node.NodeId)
+		assert.Len(t, parsed.Routes.Forward, 1)
+	}
+	_, err = ParseStatusSnapshot([]byte(`{"ok":false,"error":"nylon shutting down"}`))
+	assert.ErrorContains(t, err, "nylon shutting down")
+	_, err = ParseStatusSnapshot([]byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestDiscoverStatusSnapshots(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/discovery":
+			host := strings.TrimPrefix(server.URL, "http://")
+			_ = json.NewEncoder(w).Encode([]discoveryGroup{
+				{Targets: []string{"127.0.0.1:1", host}, Labels: map[string]string{"nylon_node": "alice"}},
+				{Targets: []string{"127.0.0.1:1"}, Labels: map[string]string{"nylon_node": "bob"}},
+			})
+		case "/status":
+			data, _ := pjMarshal.Marshal(meshStatus("alice", "10.0.0.1/32", nil))
+			_, _ = w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	discoveryUrl, err := ObservabilityUrl(strings.TrimPrefix(server.URL, "http://"), "/discovery")
+	require.NoError(t, err)
+	statuses, failed, err := DiscoverStatusSnapshots(context.Background(), server.Client(), discoveryUrl)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "alice", statuses[0].Node.NodeId)
+	assert.Contains(t, failed, state.NodeId("bob"))
+	assert.NotContains(t, failed, state.NodeId("alice"))
+}
@@ -40,11 +40,40 @@ type Nylon struct {
 	PeerMap          atomic.Pointer[map[state.NyPublicKey]state.NodeId]
 	DNSResolver      *state.DNSResolver
 	EndpointResolver *state.EndpointResolver
-	prefixHealth     map[netip.Prefix]advertisedPrefixHealth
+	prefixHealth     map[state.RoutePrefix]advertisedPrefixHealth
+	babel            *babelSpeaker
+	bgp              *bgpSpeaker
+	// transitPackets counts packets forwarded from a router neighbour to another peer
+	transitPackets atomic.Uint64
+	drain          drainState
+	detect         detectState
+	probeRate      probeRateState
+	duplicate      duplicateState
+	capture        captureState
+	// acl is the acl of the mesh as enforced by the data path, nil if the mesh has none
+	acl atomic.Pointer[aclTable]
+	// qos is the classifier of the mesh as applied by the data path, nil if the mesh has no classes
+	qos atomic.Pointer[qosTable]
+	// portForwards holds the port forwards of the node config, nil if it has none
+	portForwards *portForwardTable
+	// hostForwards are the port forwards that listen on the host, see startHostPortForwards
+	hostForwards []*hostForward
+	// sources holds the source addresses every peer may send from, see syncSources
+	sources atomic.Pointer[sourceTable]
+	// links is the data path view of the endpoints of every neighbour, see syncLinks
+	links atomic.Pointer[linkTable]
+	// routerPeers holds the public keys of the routers, whose packets may be transit traffic
+	routerPeers atomic.Pointer[map[state.NyPublicKey]struct{}]
 
 	router struct {
 		LastStarvationRequest time.Time
 		IO                    map[state.NodeId]*IOPending
+		// UnauthorizedUpdates counts the updates rejected from each neighbour due to an unauthorized origin or signature
+		UnauthorizedUpdates map[state.NodeId]uint64
+		// Signatures holds the verified origin signature of each route we may relay, see signed routes
+		Signatures map[routeVersion][]byte
+		// persisted is the router state file as it was last written
+		persisted []byte
 
 		// Tables is published atomically so forwarding and exit routes always
 		// belong to the same state generation.
@@ -97,6 +126,9 @@ func NewNylon(ccfg state.CentralCfg, ncfg state.LocalCfg, logLevel slog.Level, c
 	} else {
 		rt = state.DefaultRouterTunables()
 	}
+	if ncfg.MultipathBand != 0 {
+		rt.MultipathBand = ncfg.MultipathBand
+	}
 
 	handlers := make([]slog.Handler, 0)
 	if opts.DBG_log_json {
@@ -190,11 +222,18 @@ func (n *Nylon) Init() error {
 		ttlcache.WithTTL[uint64, EpPing](5*time.Second),
 		ttlcache.WithDisableTouchOnHit[uint64, EpPing](),
 	)
+	n.PingBuf.OnEviction(handleProbeEviction)
 	go n.PingBuf.Start()
 
 	n.RepeatTask(func() error {
 		return nylonGc(n)
 	}, n.GcDelay)
+	n.initDrain()
+
+	err = n.initPortForwards()
+	if err != nil {
+		return err
+	}
 
 	// wireguard configuration
 	err = n.initWireGuard()
@@ -203,9 +242,7 @@ func (n *Nylon) Init() error {
 	}
 
 	// endpoint probing
-	n.RepeatTask(func() error {
-		return n.probeLinks(true)
-	}, n.ProbeDelay)
+	n.initProbeRate()
 	n.RepeatTask(func() error {
 		// refresh dynamic endpoints
 		seen := make(map[string]struct{})
@@ -227,11 +264,22 @@ func (n *Nylon) Init() error {
 		return nil
 	}, n.EndpointResolveDelay)
 	n.RepeatTask(func() error {
-		return n.probeLinks(false)
+		return n.probeInactive()
 	}, n.ProbeRecoveryDelay)
 	n.RepeatTask(func() error {
 		return n.probeNew()
 	}, n.ProbeDiscoveryDelay)
+	n.initDetect()
+
+	err = n.initBabel()
+	if err != nil {
+		return err
+	}
+
+	err = n.initBGP()
+	if err != nil {
+		return err
+	}
 
 	n.reconcileAdvertisedPrefixes(&n.CentralCfg)
 
@@ -256,6 +304,9 @@ func (n *Nylon) Start() error {
 	if err := n.startObservability(); err != nil {
 		return err
 	}
+	if err := n.startHostPortForwards(); err != nil {
+		return err
+	}
 
 	n.Log.Info("Nylon has been initialized. To gracefully exit, send SIGINT or Ctrl+C.")
 
@@ -263,7 +314,11 @@ func (n *Nylon) Start() error {
 	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		select {
-		case _ = <-c:
+		case sig := <-c:
+			if sig == syscall.SIGTERM {
+				// move flows off this router before tearing it down, a second signal skips the wait
+				n.drainBeforeExit(c)
+			}
 			n.Cancel(errors.New("received shutdown signal"))
 		case <-n.Context.Done():
 			return
@@ -335,6 +390,8 @@ func (n *Nylon) Cleanup() error {
 		health.monitor.Stop()
 	}
 
+	n.cleanupBabel()
+	n.cleanupBGP()
 	n.CleanupRouter()
 	n.Trace.Cleanup()
 
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"hash/maphash"
+	"net/netip"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+)
+
+// aclFlowShards is the number of independently locked parts of the flow table, so the routines of the data path
+// rarely wait for each other
+const aclFlowShards = 64
+
+// aclTable is the acl of the mesh as enforced by the data path. It is rebuilt when the central config is applied, and
+// published atomically like the forwarding tables.
+type aclTable struct {
+	acl *state.ACL
+	// hits and drops count the packets matched and dropped by each rule, the default action is last
+	hits  []atomic.Uint64
+	drops []atomic.Uint64
+	// flows holds the reverse of every flow accepted by a rule, so replies are accepted even where the rules would
+	// deny them
+	flows *aclFlows
+}
+
+type aclFlow struct {
+	src, dst     netip.Addr
+	proto        uint8
+	sport, dport uint16
+}
+
+func (f aclFlow) reverse() aclFlow {
+	return aclFlow{src: f.dst, dst: f.src, proto: f.proto, sport: f.dport, dport: f.sport}
+}
+
+// aclFlows remembers flows until they are idle for longer than the timeout. Lookups only take the read lock of one
+// shard, and refresh the flow atomically.
+type aclFlows struct {
+	seed     maphash.Seed
+	timeout  time.Duration
+	capacity int // per shard
+	shards   [aclFlowShards]aclFlowShard
+}
+
+type aclFlowShard struct {
+	mu    sync.RWMutex
+	flows map[aclFlow]*atomic.Int64 // unix nanoseconds of the last packet of the flow
+}
+
+func newACLFlows(timeout time.Duration, capacity uint64) *aclFlows {
+	f := &aclFlows{seed: maphash.MakeSeed(), timeout: timeout, capacity: max(int(capacity/aclFlowShards), 1)}
+	for i := range f.shards {
+		f.shards[i].flows = make(map[aclFlow]*atomic.Int64)
+	}
+	return f
+}
+
+func (f *aclFlows) shard(flow aclFlow) *aclFlowShard {
+	return &f.shards[maphash.Comparable(f.seed, flow)%aclFlowShards]
+}
+
+// lookup returns true if a flow is remembered and not idle, and refreshes it
+func (f *aclFlows) lookup(flow aclFlow, now time.Time) bool {
+	s := f.shard(flow)
+	s.mu.RLock()
+	seen, ok := s.flows[flow]
+	s.mu.RUnlock()
+	if !ok || now.Sub(time.Unix(0, seen.Load())) > f.timeout {
+		return false
+	}
+	seen.Store(now.UnixNano())
+	return true
+}
+
+// record remembers a flow, or refreshes it if it is already remembered. A full shard forgets its idle flows, and the
+// least recently used one if none are idle.
+func (f *aclFlows) record(flow aclFlow, now time.Time) {
+	if f.lookup(flow, now) {
+		return
+	}
+	s := f.shard(flow)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seen, ok := s.flows[flow]; ok {
+		seen.Store(now.UnixNano())
+		return
+	}
+	if len(s.flows) >= f.capacity {
+		var oldest aclFlow
+		oldestSeen := int64(0)
+		for k, seen := range s.flows {
+			t := seen.Load()
+			if now.Sub(time.Unix(0, t)) > f.timeout {
+				delete(s.flows, k)
+			} else if oldestSeen == 0 || t < oldestSeen {
+				oldest, oldestSeen = k, t
+			}
+		}
+		if len(s.flows) >= f.capacity {
+			delete(s.flows, oldest)
+		}
+	}
+	seen := new(atomic.Int64)
+	seen.Store(now.UnixNano())
+	s.flows[flow] = seen
+}
+
+// counter returns the index of the counters of a rule returned by state.ACL.Match
+func (t *aclTable) counter(rule int) int {
+	if rule < 0 {
+		return len(t.hits) - 1
+	}
+	return rule
+}
+
+// deleteExpired forgets the flows that are idle for longer than the timeout
+func (f *aclFlows) deleteExpired(now time.Time) {
+	for i := range f.shards {
+		s := &f.shards[i]
+		s.mu.Lock()
+		for flow, seen := range s.flows {
+			if now.Sub(time.Unix(0, seen.Load())) > f.timeout {
+				delete(s.flows, flow)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// syncACL publishes the acl of the central config. Counters are kept while the rules keep their names, and accepted
+// flows are kept across changes, since the packets of the flow are checked against the new rules where they enter
+// the mesh.
+func (n *Nylon) syncACL(acl *state.ACL) {
+	old := n.acl.Load()
+	if acl == nil {
+		n.acl.Store(nil)
+		return
+	}
+	next := &aclTable{
+		acl:   acl,
+		hits:  make([]atomic.Uint64, len(acl.RuleNames())+1),
+		drops: make([]atomic.Uint64, len(acl.RuleNames())+1),
+	}
+	if old != nil {
+		next.flows = old.flows
+		if slices.Equal(old.acl.RuleNames(), acl.RuleNames()) {
+			for i := range next.hits {
+				next.hits[i].Store(old.hits[i].Load())
+				next.drops[i].Store(old.drops[i].Load())
+			}
+		}
+	} else {
+		next.flows = newACLFlows(n.ACLFlowTimeout, n.ACLFlowCapacity)
+	}
+	n.acl.Store(next)
+}
+
+// aclAccepts checks a packet against the acl at the router it enters the mesh at, and, if the acl is enforced at
+// exit, at the router it leaves the mesh at. Transit packets are not checked. The replies of a flow are accepted once
+// a rule has accepted it at this router.
+func (n *Nylon) aclAccepts(packet *device.TCElement) bool {
+	t := n.acl.Load()
+	if t == nil {
+		return true
+	}
+	if ver := packet.GetIPVersion(); ver != 4 && ver != 6 {
+		return true
+	}
+	flow := aclFlow{src: packet.GetSrc(), dst: packet.GetDst(), proto: packet.GetProtocol()}
+	sport, dport, hasPorts := packet.GetPorts()
+	flow.sport, flow.dport = sport, dport
+
+	ingress := !packet.Incoming()
+	if !ingress {
+		_, ingress = t.acl.Clients[state.NyPublicKey(packet.FromPeer.GetPublicKey())]
+	}
+	if !ingress {
+		if !n.exitsLocally(packet) {
+			return true
+		}
+	}
+	now := time.Now()
+	if t.flows.lookup(flow, now) {
+		return true
+	}
+	rule, accept := t.acl.Match(state.ACLPacket{
+		Src:     flow.src,
+		Dst:     flow.dst,
+		Proto:   flow.proto,
+		Port:    dport,
+		HasPort: hasPorts,
+	})
+	if accept {
+		t.flows.record(flow.reverse(), now)
+	}
+	if !ingress && !t.acl.EnforceAtExit {
+		// the packet was checked where it entered the mesh, the rules only decide whether its replies are accepted
+		return true
+	}
+	t.hits[t.counter(rule)].Add(1)
+	if !accept {
+		t.drops[t.counter(rule)].Add(1)
+		return false
+	}
+	return true
+}
+
+// exitsLocally returns true if an incoming packet leaves the mesh at this router
+func (n *Nylon) exitsLocally(packet *device.TCElement) bool {
+	if n.UseSystemRouting {
+		return true
+	}
+	entry, ok := n.router.Tables.Load().LookupExit(packet.GetSrc(), packet.GetDst())
+	return ok && entry.Nh == n.LocalCfg.Id
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+	"github.com/gaissmai/bart"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestACLAcceptsRepliesToAcceptedFlows(t *testing.T) {
+	cfg := &state.CentralCfg{
+		Routers: []state.RouterCfg{
+			{NodeCfg: state.NodeCfg{Id: "alice", Addresses: []netip.Addr{netip.MustParseAddr("10.0.0.1")}}},
+			{NodeCfg: state.NodeCfg{Id: "bob", Addresses: []netip.Addr{netip.MustParseAddr("10.0.0.2")}}},
+		},
+		ACL: &state.ACLCfg{
+			Default: state.ACLDeny,
+			Rules:   []state.ACLRule{{Name: "ssh", Action: state.ACLAccept, Src: []string{"alice"}, Dst: []string{"bob"}, Ports: []state.PortRange{{First: 22, Last: 22}}}},
+		},
+	}
+	acl, err := cfg.CompileACL()
+	require.NoError(t, err)
+
+	n := &Nylon{RouterTunables: state.DefaultRouterTunables()}
+	n.LocalCfg.Id = "alice"
+	tables := &ForwardingTables{Forward: new(bart.Table[RouteTableEntry]), Exit: new(bart.Table[RouteTableEntry])}
+	tables.Exit.Insert(netip.MustParsePrefix("10.0.0.1/32"), RouteTableEntry{Nh: "alice"})
+	n.router.Tables.Store(tables)
+	n.syncACL(acl)
+	bob := new(device.Peer)
+
+	// alice is the ingress of its own packets
+	assert.True(t, n.aclAccepts(testTCPPacket("10.0.0.1", "10.0.0.2", 40000, 22, nil)))
+	assert.False(t, n.aclAccepts(testTCPPacket("10.0.0.1", "10.0.0.2", 40000, 80, nil)))
+
+	// replies from bob leave the mesh at alice, and are accepted without a rule when the acl is enforced at exit
+	acl.EnforceAtExit = true
+	assert.True(t, n.aclAccepts(testTCPPacket("10.0.0.2", "10.0.0.1", 22, 40000, bob)))
+	assert.False(t, n.aclAccepts(testTCPPacket("10.0.0.2", "10.0.0.1", 22, 40001, bob)), "only the accepted flow is a reply")
+
+	// transit packets are not checked
+	assert.True(t, n.aclAccepts(testTCPPacket("10.0.0.2", "10.0.0.3", 22, 40001, bob)))
+
+	rules := buildACLRules(n)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "ssh", rules[0].Name)
+	assert.Equal(t, uint64(1), rules[0].Hits)
+	assert.Equal(t, "default", rules[1].Name)
+	assert.Equal(t, uint64(2), rules[1].Hits)
+	assert.Equal(t, uint64(2), rules[1].Drops)
+
+	// counters survive a config change that keeps the rules
+	n.syncACL(acl)
+	assert.Equal(t, uint64(1), n.acl.Load().hits[0].Load())
+}
+
+func TestACLOnlyAcceptsRepliesToFlowsARuleAccepted(t *testing.T) {
+	cfg := &state.CentralCfg{
+		Routers: []state.RouterCfg{
+			{NodeCfg: state.NodeCfg{Id: "alice", Addresses: []netip.Addr{netip.MustParseAddr("10.0.0.1")}}},
+			{NodeCfg: state.NodeCfg{Id: "bob", Addresses: []netip.Addr{netip.MustParseAddr("10.0.0.2")}}},
+		},
+		ACL: &state.ACLCfg{
+			Default: state.ACLDeny,
+			Rules:   []state.ACLRule{{Name: "web", Action: state.ACLAccept, Src: []string{"bob"}, Dst: []string{"alice"}, Ports: []state.PortRange{{First: 80, Last: 80}}}},
+		},
+	}
+	acl, err := cfg.CompileACL()
+	require.NoError(t, err)
+
+	n := &Nylon{RouterTunables: state.DefaultRouterTunables()}
+	n.LocalCfg.Id = "alice"
+	tables := &ForwardingTables{Forward: new(bart.Table[RouteTableEntry]), Exit: new(bart.Table[RouteTableEntry])}
+	tables.Exit.Insert(netip.MustParsePrefix("10.0.0.1/32"), RouteTableEntry{Nh: "alice"})
+	n.router.Tables.Store(tables)
+	n.syncACL(acl)
+	bob := new(device.Peer)
+
+	// without enforce_at_exit, packets leaving the mesh at alice were checked where they entered it
+	assert.True(t, n.aclAccepts(testTCPPacket("10.0.0.2", "10.0.0.1", 40000, 80, bob)))
+	assert.True(t, n.aclAccepts(testTCPPacket("10.0.0.2", "10.0.0.1", 40000, 81, bob)))
+
+	// but only the replies of the flow a rule accepted enter the mesh at alice
+	assert.True(t, n.aclAccepts(testTCPPacket("10.0.0.1", "10.0.0.2", 80, 40000, nil)))
+	assert.False(t, n.aclAccepts(testTCPPacket("10.0.0.1", "10.0.0.2", 81, 40000, nil)))
+
+	// the packets that were not enforced are not counted
+	rules := buildACLRules(n)
+	assert.Zero(t, rules[0].Hits)
+	assert.Equal(t, uint64(1), rules[1].Hits)
+}
+
+func TestACLFlows(t *testing.T) {
+	flows := newACLFlows(time.Minute, aclFlowShards)
+	now := time.Now()
+	flow := aclFlow{src: netip.MustParseAddr("10.0.0.1"), dst: netip.MustParseAddr("10.0.0.2"), proto: device.ProtoTCP, sport: 40000, dport: 22}
+	assert.False(t, flows.lookup(flow, now))
+	flows.record(flow, now)
+	assert.True(t, flows.lookup(flow, now.Add(50*time.Second)))
+	assert.True(t, flows.lookup(flow, now.Add(100*time.Second)), "a lookup keeps the flow alive")
+	assert.False(t, flows.lookup(flow, now.Add(200*time.Second)))
+
+	flows.deleteExpired(now.Add(200 * time.Second))
+	for i := range flows.shards {
+		assert.Empty(t, flows.shards[i].flows)
+	}
+
+	// a full shard forgets the least recently used flow
+	other := flow
+	for flows.shard(other) != flows.shard(flow) || other == flow {
+		other.sport++
+	}
+	flows.record(flow, now)
+	flows.record(other, now.Add(time.Second))
+	assert.False(t, flows.lookup(flow, now.Add(time.Second)))
+	assert.True(t, flows.lookup(other, now.Add(time.Second)))
+}
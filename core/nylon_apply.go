@@ -2,7 +2,6 @@ package core
 
 import (
 	"errors"
-	"net/netip"
 	"reflect"
 	"slices"
 
@@ -69,47 +68,33 @@ func normalizeCentralConfig(cfg *state.CentralCfg) (*state.CentralCfg, error) {
 }
 
 func (n *Nylon) reconcileRouterState(next *state.CentralCfg) error {
-	desired := make(map[state.NodeId]state.RouterCfg)
-	for _, peer := range next.GetPeers(n.LocalCfg.Id) {
-		if !next.IsRouter(peer) {
-			continue
-		}
-		desired[peer] = next.GetRouter(peer)
+	acl, err := next.CompileACL()
+	if err != nil {
+		return err
 	}
-
-	neighs := make([]*state.Neighbour, 0, len(desired))
-	for _, neigh := range n.RouterState.Neighbours {
-		cfg, ok := desired[neigh.Id]
-		if !ok {
-			// remove old neighbours
-			delete(n.router.IO, neigh.Id)
-			continue
-		}
-		// configure existing neighbours
-		reconcileConfiguredEndpoints(neigh, cfg.Endpoints, &n.RouterTunables)
-		neighs = append(neighs, neigh)
-		delete(desired, neigh.Id)
+	qos, err := next.CompileQoS()
+	if err != nil {
+		return err
 	}
-
-	// create new neighbours
-	ids := make([]state.NodeId, 0, len(desired))
-	for id := range desired {
-		ids = append(ids, id)
-	}
-	slices.Sort(ids)
-	for _, id := range ids {
-		cfg := desired[id]
-		stNeigh := &state.Neighbour{
-			Id:     id,
-			Routes: make(map[netip.Prefix]state.NeighRoute),
-			Eps:    make([]state.Endpoint, 0, len(cfg.Endpoints)),
-		}
-		for _, ep := range cfg.Endpoints {
-			stNeigh.Eps = append(stNeigh.Eps, state.NewEndpoint(ep, false, nil, &n.RouterTunables))
+	err = ReconcileRouterConfig(n.RouterState, next, func(neigh *state.Neighbour, router state.RouterCfg) {
+		reconcileConfiguredEndpoints(neigh, router.Endpoints, &n.RouterTunables)
+	})
+	if err != nil {
+		return err
+	}
+	neighs := n.RouterState.Neighbours
+	for id := range n.router.IO {
+		if n.RouterState.GetNeighbour(id) == nil {
+			delete(n.router.IO, id)
 		}
-		neighs = append(neighs, stNeigh)
 	}
-	n.RouterState.Neighbours = neighs
+	n.detect.enabled.Store(slices.ContainsFunc(neighs, func(neigh *state.Neighbour) bool {
+		return neigh.Link.Detect.Enabled()
+	}))
+	duplicate := slices.Clone(next.Duplicate)
+	n.duplicate.rules.Store(&duplicate)
+	n.syncACL(acl)
+	n.syncQoS(qos)
 	if n.EndpointResolver != nil {
 		addresses := make(map[string]struct{})
 		for _, neigh := range neighs {
@@ -129,13 +114,18 @@ func (n *Nylon) reconcileRouterState(next *state.CentralCfg) error {
 		pubkeyMap[x.PubKey] = x.Id
 	}
 	n.PeerMap.Store(new(pubkeyMap))
+	routerPeers := make(map[state.NyPublicKey]struct{}, len(next.Routers))
+	for _, x := range next.Routers {
+		routerPeers[x.PubKey] = struct{}{}
+	}
+	n.routerPeers.Store(&routerPeers)
 	return nil
 }
 
-func reconcileConfiguredEndpoints(neigh *state.Neighbour, desired []string, t *state.RouterTunables) {
-	desiredAddresses := make(map[string]struct{}, len(desired))
-	for _, address := range desired {
-		desiredAddresses[address] = struct{}{}
+func reconcileConfiguredEndpoints(neigh *state.Neighbour, desired []state.EndpointCfg, t *state.RouterTunables) {
+	desiredAttrs := make(map[string]state.EndpointAttrs, len(desired))
+	for _, ep := range desired {
+		desiredAttrs[ep.Address] = ep.EndpointAttrs
 	}
 
 	eps := make([]state.Endpoint, 0, len(neigh.Eps)+len(desired))
@@ -147,16 +137,19 @@ func reconcileConfiguredEndpoints(neigh *state.Neighbour, desired []string, t *s
 			continue
 		}
 		// only keep if desired
-		if _, ok := desiredAddresses[nep.Address]; ok {
+		if attrs, ok := desiredAttrs[nep.Address]; ok {
+			nep.SetAttrs(attrs)
 			eps = append(eps, ep)
 			seen[nep.Address] = struct{}{}
 		}
 	}
-	for _, address := range desired {
-		if _, ok := seen[address]; ok {
+	for _, ep := range desired {
+		if _, ok := seen[ep.Address]; ok {
 			continue
 		}
-		eps = append(eps, state.NewEndpoint(address, false, nil, t))
+		nep := state.NewEndpoint(ep.Address, false, nil, t)
+		nep.SetAttrs(ep.EndpointAttrs)
+		eps = append(eps, nep)
 	}
 	neigh.Eps = eps
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/protocol"
 	"github.com/encodeous/nylon/state"
 	"github.com/gaissmai/bart"
 	"github.com/stretchr/testify/assert"
@@ -19,7 +20,7 @@ func TestReconcileAdvertisedPrefixesStartsChangedPrefixHealth(t *testing.T) {
 		PrefixHealth: &state.StaticPrefixHealth{Prefix: prefix},
 	}
 	n := testNylonWithPrefixes(oldPrefix)
-	n.RouterState.Advertised[prefix] = state.Advertisement{
+	n.RouterState.Advertised[state.RoutePrefix{Prefix: prefix}] = state.Advertisement{
 		NodeId:   n.LocalCfg.Id,
 		Expiry:   maxConfigTime,
 		MetricFn: func() uint32 { return 0 },
@@ -35,9 +36,9 @@ func TestReconcileAdvertisedPrefixesStartsChangedPrefixHealth(t *testing.T) {
 	})
 
 	n.reconcileAdvertisedPrefixes(&next)
-	t.Cleanup(n.prefixHealth[prefix].monitor.Stop)
+	t.Cleanup(n.prefixHealth[state.RoutePrefix{Prefix: prefix}].monitor.Stop)
 
-	assert.Equal(t, state.INF, n.RouterState.Advertised[prefix].MetricFn())
+	assert.Equal(t, state.INF, n.RouterState.Advertised[state.RoutePrefix{Prefix: prefix}].MetricFn())
 }
 
 func TestReconcileAdvertisedPrefixesStartsChangedPingPrefixHealth(t *testing.T) {
@@ -46,7 +47,7 @@ func TestReconcileAdvertisedPrefixesStartsChangedPingPrefixHealth(t *testing.T)
 		PrefixHealth: &state.StaticPrefixHealth{Prefix: prefix},
 	}
 	n := testNylonWithPrefixes(oldPrefix)
-	n.RouterState.Advertised[prefix] = state.Advertisement{
+	n.RouterState.Advertised[state.RoutePrefix{Prefix: prefix}] = state.Advertisement{
 		NodeId:   n.LocalCfg.Id,
 		Expiry:   maxConfigTime,
 		MetricFn: func() uint32 { return 0 },
@@ -62,9 +63,9 @@ func TestReconcileAdvertisedPrefixesStartsChangedPingPrefixHealth(t *testing.T)
 	})
 
 	n.reconcileAdvertisedPrefixes(&next)
-	t.Cleanup(n.prefixHealth[prefix].monitor.Stop)
+	t.Cleanup(n.prefixHealth[state.RoutePrefix{Prefix: prefix}].monitor.Stop)
 
-	assert.Equal(t, state.INF, n.RouterState.Advertised[prefix].MetricFn())
+	assert.Equal(t, state.INF, n.RouterState.Advertised[state.RoutePrefix{Prefix: prefix}].MetricFn())
 }
 
 func TestReconcileAdvertisedPrefixesReusesUnchangedMonitor(t *testing.T) {
@@ -78,15 +79,15 @@ func TestReconcileAdvertisedPrefixesReusesUnchangedMonitor(t *testing.T) {
 		},
 	}
 	n := testNylonWithPrefixes(current)
-	monitor := current.NewMonitor(n.Log, &n.RouterTunables, n.DNSResolver)
+	monitor := current.NewMonitor(n.Log, &n.RouterTunables, n.DNSResolver, n)
 	t.Cleanup(monitor.Stop)
-	n.prefixHealth = map[netip.Prefix]advertisedPrefixHealth{
-		prefix: {
+	n.prefixHealth = map[state.RoutePrefix]advertisedPrefixHealth{
+		{Prefix: prefix}: {
 			config:  current,
 			monitor: monitor,
 		},
 	}
-	n.RouterState.Advertised[prefix] = state.Advertisement{
+	n.RouterState.Advertised[state.RoutePrefix{Prefix: prefix}] = state.Advertisement{
 		NodeId:   n.LocalCfg.Id,
 		Expiry:   maxConfigTime,
 		MetricFn: monitor.GetMetric,
@@ -103,8 +104,29 @@ func TestReconcileAdvertisedPrefixesReusesUnchangedMonitor(t *testing.T) {
 
 	n.reconcileAdvertisedPrefixes(&next)
 
-	assert.Equal(t, monitor, n.prefixHealth[prefix].monitor)
-	assert.Equal(t, state.INF, n.RouterState.Advertised[prefix].MetricFn())
+	assert.Equal(t, monitor, n.prefixHealth[state.RoutePrefix{Prefix: prefix}].monitor)
+	assert.Equal(t, state.INF, n.RouterState.Advertised[state.RoutePrefix{Prefix: prefix}].MetricFn())
+}
+
+func TestReconcileConfiguredEndpointsUpdatesAttrs(t *testing.T) {
+	tunables := state.DefaultRouterTunables()
+	kept := state.NewEndpoint("192.0.2.1:57175", false, nil, &tunables)
+	remote := state.NewEndpoint("198.51.100.1:40000", true, nil, &tunables)
+	neigh := &state.Neighbour{Id: "peer", Eps: []state.Endpoint{kept, remote, state.NewEndpoint("192.0.2.2:57175", false, nil, &tunables)}}
+
+	lte := state.EndpointAttrs{Class: state.EndpointBackup, Metered: true}
+	reconcileConfiguredEndpoints(neigh, []state.EndpointCfg{
+		{Address: "192.0.2.1:57175", EndpointAttrs: lte},
+		{Address: "192.0.2.3:57175"},
+	}, &tunables)
+
+	if assert.Len(t, neigh.Eps, 3) {
+		assert.Same(t, kept, neigh.Eps[0])
+		assert.Equal(t, lte, kept.Attrs())
+		assert.Same(t, remote, neigh.Eps[1])
+		assert.Equal(t, "192.0.2.3:57175", neigh.Eps[2].AsNylonEndpoint().Address)
+		assert.Equal(t, state.EndpointAttrs{}, neigh.Eps[2].AsNylonEndpoint().Attrs())
+	}
 }
 
 func TestRebindForwardingPeersUpdatesUnchangedNextHop(t *testing.T) {
@@ -146,6 +168,92 @@ func TestRebindForwardingPeersReusesUnchangedTable(t *testing.T) {
 	assert.Same(t, forward, rebound)
 }
 
+func TestRebindForwardingPeersUpdatesMultipathNextHops(t *testing.T) {
+	prefix := netip.MustParsePrefix("10.0.0.0/24")
+	oldPeer := new(device.Peer)
+	newPeer := new(device.Peer)
+	primary := new(device.Peer)
+	forward := new(bart.Table[RouteTableEntry])
+	forward.Insert(prefix, RouteTableEntry{
+		Nh:   "a",
+		Peer: primary,
+		Multipath: []NextHop{
+			{Nh: "a", Peer: primary},
+			{Nh: "b", Peer: oldPeer},
+		},
+	})
+
+	rebound, changed := rebindRouteTablePeers(forward, map[state.NodeId]*device.Peer{
+		"a": primary,
+		"b": newPeer,
+	})
+
+	assert.True(t, changed)
+	entry, ok := rebound.Lookup(prefix.Addr())
+	if assert.True(t, ok) {
+		assert.Same(t, primary, entry.Peer)
+		assert.Same(t, newPeer, entry.Multipath[1].Peer)
+	}
+}
+
+func TestForwardingLookupPrefersMostSpecificDestination(t *testing.T) {
+	tables := &ForwardingTables{
+		Forward: new(bart.Table[RouteTableEntry]),
+		Exit:    new(bart.Table[RouteTableEntry]),
+	}
+	route := func(dst, src string) state.RoutePrefix {
+		prefix := state.RoutePrefix{Prefix: netip.MustParsePrefix(dst)}
+		if src != "" {
+			prefix.Src = netip.MustParsePrefix(src)
+		}
+		return prefix
+	}
+	for prefix, nh := range map[state.RoutePrefix]state.NodeId{
+		route("0.0.0.0/0", ""):                 "default",
+		route("0.0.0.0/0", "10.1.0.0/16"):      "site",
+		route("0.0.0.0/0", "10.1.2.0/24"):      "subnet",
+		route("192.168.0.0/16", ""):            "plain",
+		route("192.168.1.0/24", "10.1.0.0/16"): "specific",
+	} {
+		tables.Forward, tables.SourceForward = setRoute(tables.Forward, tables.SourceForward, prefix, &RouteTableEntry{Nh: nh})
+	}
+
+	for _, tc := range []struct {
+		src, dst string
+		nh       state.NodeId
+	}{
+		{"10.2.0.1", "1.1.1.1", "default"},
+		{"10.1.0.1", "1.1.1.1", "site"},
+		{"10.1.2.1", "1.1.1.1", "subnet"},
+		// a plain route with a more specific destination wins over a matching source
+		{"10.1.0.1", "192.168.2.1", "plain"},
+		{"10.1.0.1", "192.168.1.1", "specific"},
+		{"10.2.0.1", "192.168.1.1", "plain"},
+	} {
+		entry, ok := tables.LookupForward(netip.MustParseAddr(tc.src), netip.MustParseAddr(tc.dst))
+		if assert.True(t, ok, "%s -> %s", tc.src, tc.dst) {
+			assert.Equal(t, tc.nh, entry.Nh, "%s -> %s", tc.src, tc.dst)
+		}
+	}
+
+	// removing the last source-specific route for a destination drops its source table
+	tables.Forward, tables.SourceForward = setRoute(tables.Forward, tables.SourceForward, route("192.168.1.0/24", "10.1.0.0/16"), nil)
+	_, ok := tables.SourceForward.Get(netip.MustParsePrefix("192.168.1.0/24"))
+	assert.False(t, ok)
+}
+
+func TestRoutePrefixWireRoundTrip(t *testing.T) {
+	for _, prefix := range []state.RoutePrefix{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/24")},
+		{Prefix: netip.MustParsePrefix("::/0"), Src: netip.MustParsePrefix("2001:db8::/48")},
+	} {
+		dst, src := marshalRoutePrefix(prefix)
+		decoded, err := unmarshalRoutePrefix(dst, src)
+		assert.NoError(t, err)
+		assert.Equal(t, prefix, decoded)
+	}
+}
+
 func TestHandleNylonPacketDropsUnknownRetiredPeer(t *testing.T) {
 	n := &Nylon{Log: slog.New(slog.NewTextHandler(io.Discard, nil))}
 	peerMap := make(map[state.NyPublicKey]state.NodeId)
@@ -170,10 +278,10 @@ func testNylonWithPrefixes(prefixes ...state.PrefixHealthWrapper) *Nylon {
 		RouterState: &state.RouterState{
 			RouterTunables: &tunables,
 			Id:             id,
-			SelfSeqno:      make(map[netip.Prefix]uint16),
-			Routes:         make(map[netip.Prefix]state.SelRoute),
+			SelfSeqno:      make(map[state.RoutePrefix]uint16),
+			Routes:         make(map[state.RoutePrefix]state.SelRoute),
 			Sources:        make(map[state.Source]state.FD),
-			Advertised:     make(map[netip.Prefix]state.Advertisement),
+			Advertised:     make(map[state.RoutePrefix]state.Advertisement),
 		},
 		Log: slog.New(slog.NewTextHandler(io.Discard, nil)),
 	}
@@ -191,3 +299,73 @@ func testCentralConfig(id state.NodeId, prefixes ...state.PrefixHealthWrapper) s
 		},
 	}
 }
+
+func TestRouteUpdateRejectsUnauthorizedOrigin(t *testing.T) {
+	dbPrefix := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.9.0.0/24")}
+	n := testNylonWithPrefixes()
+	n.CentralCfg.Routers = append(n.CentralCfg.Routers,
+		state.RouterCfg{NodeCfg: state.NodeCfg{Id: "leaf"}},
+		state.RouterCfg{NodeCfg: state.NodeCfg{Id: "db", Prefixes: []state.PrefixHealthWrapper{{
+			PrefixHealth: &state.StaticPrefixHealth{Prefix: dbPrefix.Prefix},
+		}}}},
+	)
+	n.CentralCfg.Graph = []string{"node, leaf, db"}
+	n.router.IO = make(map[state.NodeId]*IOPending)
+	n.router.UnauthorizedUpdates = make(map[state.NodeId]uint64)
+	n.router.log = n.Log
+	assert.NoError(t, n.reconcileRouterState(&n.CentralCfg))
+
+	prefix, _ := marshalRoutePrefix(dbPrefix)
+	// leaf claims to be forwarding a route originated by itself
+	assert.NoError(t, n.routerHandleRouteUpdate("leaf", &protocol.Ny_Update{RouterId: "leaf", Prefix: prefix, Metric: 0}))
+	assert.Empty(t, n.RouterState.GetNeighbour("leaf").Routes)
+	assert.Equal(t, uint64(1), n.router.UnauthorizedUpdates["leaf"])
+
+	// leaf may relay the route originated by db
+	assert.NoError(t, n.routerHandleRouteUpdate("leaf", &protocol.Ny_Update{RouterId: "db", Prefix: prefix, Metric: 0}))
+	assert.Contains(t, n.RouterState.GetNeighbour("leaf").Routes, dbPrefix)
+	assert.Equal(t, uint64(1), n.router.UnauthorizedUpdates["leaf"])
+}
+
+func TestRouteUpdateRequiresOriginSignature(t *testing.T) {
+	dbKey, leafKey := state.GenerateKey(), state.GenerateKey()
+	dbPrefix := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.9.0.0/24")}
+	n := testNylonWithPrefixes()
+	n.CentralCfg.Routers = append(n.CentralCfg.Routers,
+		state.RouterCfg{NodeCfg: state.NodeCfg{Id: "leaf", PubKey: leafKey.Pubkey()}},
+		state.RouterCfg{NodeCfg: state.NodeCfg{Id: "db", PubKey: dbKey.Pubkey(), Prefixes: []state.PrefixHealthWrapper{{
+			PrefixHealth: &state.StaticPrefixHealth{Prefix: dbPrefix.Prefix},
+		}}}},
+	)
+	n.CentralCfg.Graph = []string{"node, leaf, db"}
+	n.CentralCfg.SignedRoutes = true
+	n.router.IO = make(map[state.NodeId]*IOPending)
+	n.router.UnauthorizedUpdates = make(map[state.NodeId]uint64)
+	n.router.Signatures = make(map[routeVersion][]byte)
+	n.router.log = n.Log
+	assert.NoError(t, n.reconcileRouterState(&n.CentralCfg))
+
+	src := state.Source{NodeId: "db", RoutePrefix: dbPrefix}
+	prefix, _ := marshalRoutePrefix(dbPrefix)
+	forged, err := state.SignRoute(leafKey, src, 5)
+	assert.NoError(t, err)
+	signed, err := state.SignRoute(dbKey, src, 5)
+	assert.NoError(t, err)
+
+	// leaf cannot forge an origination by db
+	for _, sig := range [][]byte{nil, forged} {
+		assert.NoError(t, n.routerHandleRouteUpdate("leaf", &protocol.Ny_Update{RouterId: "db", Prefix: prefix, Seqno: 5, Signature: sig}))
+	}
+	assert.Empty(t, n.RouterState.GetNeighbour("leaf").Routes)
+	assert.Equal(t, uint64(2), n.router.UnauthorizedUpdates["leaf"])
+
+	// but it may relay db's signed route with its own metric, and we relay the same signature
+	assert.NoError(t, n.routerHandleRouteUpdate("leaf", &protocol.Ny_Update{RouterId: "db", Prefix: prefix, Seqno: 5, Metric: 40, Signature: signed}))
+	assert.Contains(t, n.RouterState.GetNeighbour("leaf").Routes, dbPrefix)
+	assert.Equal(t, signed, n.routeSignature(state.PubRoute{Source: src, FD: state.FD{Seqno: 5, Metric: 50}}))
+
+	// our own routes are signed with our key
+	self := state.Source{NodeId: n.LocalCfg.Id, RoutePrefix: dbPrefix}
+	n.LocalCfg.Key = state.GenerateKey()
+	assert.True(t, state.VerifyRoute(n.LocalCfg.Key.Pubkey(), self, 1, n.routeSignature(state.PubRoute{Source: self, FD: state.FD{Seqno: 1}})))
+}
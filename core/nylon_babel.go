@@ -0,0 +1,519 @@
+package core
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/encodeous/nylon/protocol/babel"
+	"github.com/encodeous/nylon/state"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	defaultBabelCost        = 96 // babeld's default cost for wired interfaces
+	defaultBabelMetricScale = 100
+	babelUpdatesPerPacket   = 32
+)
+
+// babelSpeaker exchanges routes with standard Babel routers on the configured babel interfaces.
+// Babel routers are not mesh neighbours, routes are redistributed between the two protocols instead:
+// selected mesh routes are announced on each interface, and routes learned on the interfaces back babel prefixes.
+// All state is owned by the dispatch goroutine.
+type babelSpeaker struct {
+	interfaces []*babelInterface
+	conn       *ipv6.PacketConn // shared by all interfaces, like babeld
+	routerId   babel.RouterId
+	installed  map[netip.Prefix]babelKernelRoute
+}
+
+type babelInterface struct {
+	cfg        state.BabelInterfaceCfg
+	itf        *net.Interface
+	conn       *ipv6.PacketConn
+	addrs      []netip.Addr
+	helloSeqno uint16
+	seqno      uint16 // seqno of the routes we announce
+	neighbours map[netip.Addr]*babelNeighbour
+	routes     map[babelRouteKey]babelRoute
+	announced  map[netip.Prefix]struct{} // prefixes announced in the last update, retracted once they are no longer selected
+}
+
+type babelNeighbour struct {
+	helloExpiry time.Time
+	txCost      uint16 // cost reported by the neighbour's IHU
+	ihuExpiry   time.Time
+}
+
+type babelRouteKey struct {
+	prefix    netip.Prefix
+	neighbour netip.Addr
+}
+
+type babelRoute struct {
+	metric   uint16 // metric advertised by the neighbour
+	routerId babel.RouterId
+	nextHop  netip.Addr
+	expiry   time.Time
+}
+
+type babelKernelRoute struct {
+	itf     string
+	nextHop netip.Addr
+}
+
+// babelRouterId derives the Babel router id of a node from its public key
+func babelRouterId(pub state.NyPublicKey) babel.RouterId {
+	sum := sha256.Sum256(pub[:])
+	return babel.RouterId(sum[:8])
+}
+
+func (n *Nylon) initBabel() error {
+	if len(n.LocalCfg.Babel) == 0 {
+		return nil
+	}
+	c, err := net.ListenPacket("udp6", fmt.Sprintf("[::]:%d", babel.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen for babel: %w", err)
+	}
+	conn := ipv6.NewPacketConn(c)
+	speaker := &babelSpeaker{
+		conn:      conn,
+		routerId:  babelRouterId(n.LocalCfg.Key.Pubkey()),
+		installed: make(map[netip.Prefix]babelKernelRoute),
+	}
+	n.babel = speaker
+	_ = conn.SetMulticastHopLimit(1)
+	_ = conn.SetMulticastLoopback(false)
+	// not supported on every platform, packets are then matched to interfaces by the zone of their source address
+	_ = conn.SetControlMessage(ipv6.FlagInterface, true)
+	group := &net.UDPAddr{IP: babel.Group.AsSlice()}
+	for _, cfg := range n.LocalCfg.Babel {
+		if cfg.Cost == 0 {
+			cfg.Cost = defaultBabelCost
+		}
+		if cfg.MetricScale == 0 {
+			cfg.MetricScale = defaultBabelMetricScale
+		}
+		itf, err := net.InterfaceByName(cfg.Interface)
+		if err != nil {
+			return fmt.Errorf("babel interface %s: %w", cfg.Interface, err)
+		}
+		if err := conn.JoinGroup(itf, group); err != nil {
+			return fmt.Errorf("babel interface %s: %w", cfg.Interface, err)
+		}
+		b := &babelInterface{
+			cfg:        cfg,
+			itf:        itf,
+			conn:       conn,
+			neighbours: make(map[netip.Addr]*babelNeighbour),
+			routes:     make(map[babelRouteKey]babelRoute),
+			announced:  make(map[netip.Prefix]struct{}),
+		}
+		b.refreshAddrs()
+		speaker.interfaces = append(speaker.interfaces, b)
+		n.Log.Info("babel interface started", "interface", cfg.Interface, "router_id", speaker.routerId.String())
+	}
+	go speaker.receive(n)
+
+	n.RepeatTask(func() error {
+		for _, b := range speaker.interfaces {
+			b.refreshAddrs()
+			b.sendHello(n)
+		}
+		speaker.expire(n)
+		return nil
+	}, n.BabelHelloInterval)
+	n.RepeatTask(func() error {
+		for _, b := range speaker.interfaces {
+			b.sendUpdates(n, speaker.routerId)
+		}
+		return nil
+	}, n.BabelUpdateInterval)
+	return nil
+}
+
+func (n *Nylon) cleanupBabel() {
+	if n.babel == nil {
+		return
+	}
+	_ = n.babel.conn.Close()
+	for prefix, route := range n.babel.installed {
+		if err := RemoveGatewayRoute(n.Log, route.itf, prefix); err != nil {
+			n.Log.Warn("failed to remove babel route", "prefix", prefix, "err", err)
+		}
+	}
+	n.babel.installed = nil
+}
+
+// routeMetric returns the nylon metric of the best route to prefix learned on any babel interface
+func (s *babelSpeaker) routeMetric(prefix netip.Prefix) uint32 {
+	if s == nil {
+		return state.INF
+	}
+	_, _, metric := s.bestRoute(prefix)
+	return metric
+}
+
+func (s *babelSpeaker) bestRoute(prefix netip.Prefix) (*babelInterface, babelRoute, uint32) {
+	var bestItf *babelInterface
+	var best babelRoute
+	bestMetric := state.INF
+	now := time.Now()
+	for _, b := range s.interfaces {
+		for key, route := range b.routes {
+			if key.prefix != prefix || now.After(route.expiry) {
+				continue
+			}
+			metric := b.toNylonMetric(babelAdd(route.metric, b.linkCost(key.neighbour, now)))
+			if metric < bestMetric {
+				bestItf, best, bestMetric = b, route, metric
+			}
+		}
+	}
+	return bestItf, best, bestMetric
+}
+
+// expire removes dead neighbours and routes, and keeps the kernel routes of babel prefixes in sync
+func (s *babelSpeaker) expire(n *Nylon) {
+	now := time.Now()
+	for _, b := range s.interfaces {
+		for addr, neigh := range b.neighbours {
+			if now.After(neigh.helloExpiry) && now.After(neigh.ihuExpiry) {
+				delete(b.neighbours, addr)
+			}
+		}
+		for key, route := range b.routes {
+			if now.After(route.expiry) {
+				delete(b.routes, key)
+			}
+		}
+	}
+	s.syncKernelRoutes(n)
+}
+
+func (s *babelSpeaker) syncKernelRoutes(n *Nylon) {
+	if n.NoNetConfigure {
+		return
+	}
+	desired := make(map[netip.Prefix]babelKernelRoute)
+	if node := n.CentralCfg.TryGetNode(n.LocalCfg.Id); node != nil {
+		for _, prefix := range node.Prefixes {
+			if _, ok := prefix.PrefixHealth.(*state.BabelPrefixHealth); !ok {
+				continue
+			}
+			b, route, metric := s.bestRoute(prefix.GetPrefix())
+			if metric != state.INF {
+				desired[prefix.GetPrefix()] = babelKernelRoute{itf: b.cfg.Interface, nextHop: route.nextHop}
+			}
+		}
+	}
+	for prefix, route := range s.installed {
+		if want, ok := desired[prefix]; ok && want == route {
+			continue
+		}
+		if err := RemoveGatewayRoute(n.Log, route.itf, prefix); err != nil {
+			n.Log.Warn("failed to remove babel route", "prefix", prefix, "err", err)
+		}
+		delete(s.installed, prefix)
+	}
+	for prefix, route := range desired {
+		if _, ok := s.installed[prefix]; ok {
+			continue
+		}
+		if err := ConfigureGatewayRoute(n.Log, route.itf, prefix, route.nextHop); err != nil {
+			n.Log.Warn("failed to install babel route", "prefix", prefix, "via", route.nextHop, "err", err)
+			continue
+		}
+		s.installed[prefix] = route
+	}
+}
+
+func (b *babelInterface) refreshAddrs() {
+	addrs, err := b.itf.Addrs()
+	if err != nil {
+		return
+	}
+	b.addrs = b.addrs[:0]
+	for _, addr := range addrs {
+		if prefix, err := netip.ParsePrefix(addr.String()); err == nil {
+			b.addrs = append(b.addrs, prefix.Addr())
+		}
+	}
+}
+
+func (b *babelInterface) localAddr(is4 bool) netip.Addr {
+	for _, addr := range b.addrs {
+		if addr.Is4() == is4 {
+			return addr
+		}
+	}
+	return netip.Addr{}
+}
+
+func (s *babelSpeaker) receive(n *Nylon) {
+	buf := make([]byte, 65535)
+	for n.Context.Err() == nil {
+		size, cm, src, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			n.Log.Debug("babel read failed", "err", err)
+			continue
+		}
+		addr, ok := src.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		b := s.interfaceOf(cm, addr)
+		if b == nil {
+			continue
+		}
+		pkt, err := babel.Decode(buf[:size])
+		if err != nil {
+			n.Log.Debug("invalid babel packet", "interface", b.cfg.Interface, "from", addr, "err", err)
+			continue
+		}
+		from := addr.AddrPort().Addr().Unmap().WithZone("")
+		n.Dispatch(func() error {
+			b.handlePacket(n, from, pkt)
+			return nil
+		})
+	}
+}
+
+func (s *babelSpeaker) interfaceOf(cm *ipv6.ControlMessage, src *net.UDPAddr) *babelInterface {
+	for _, b := range s.interfaces {
+		if (cm != nil && cm.IfIndex == b.itf.Index) || (cm == nil && src.Zone == b.itf.Name) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (b *babelInterface) handlePacket(n *Nylon, from netip.Addr, pkt *babel.Packet) {
+	if slices.Contains(b.addrs, from) {
+		return // our own multicast
+	}
+	now := time.Now()
+	neigh, ok := b.neighbours[from]
+	if !ok {
+		neigh = &babelNeighbour{txCost: babel.Infinity}
+		b.neighbours[from] = neigh
+	}
+	for _, hello := range pkt.Hellos {
+		if hello.Unicast {
+			continue
+		}
+		neigh.helloExpiry = now.Add(babelExpiry(hello.Interval, n.BabelHelloInterval))
+	}
+	for _, ihu := range pkt.IHUs {
+		if ihu.Addr.IsValid() && !slices.Contains(b.addrs, ihu.Addr) {
+			continue
+		}
+		neigh.txCost = ihu.RxCost
+		neigh.ihuExpiry = now.Add(babelExpiry(ihu.Interval, 3*n.BabelHelloInterval))
+	}
+
+	meshIds := make(map[babel.RouterId]struct{})
+	for _, router := range n.CentralCfg.Routers {
+		meshIds[babelRouterId(router.PubKey)] = struct{}{}
+	}
+	speaker := n.babel
+	for _, update := range pkt.Updates {
+		if !update.Prefix.IsValid() {
+			if update.Metric == babel.Infinity {
+				for key := range b.routes {
+					if key.neighbour == from {
+						delete(b.routes, key)
+					}
+				}
+			}
+			continue
+		}
+		// routes originated by the mesh are never imported back, which would create a loop through the LAN
+		if _, ok := meshIds[update.RouterId]; ok {
+			continue
+		}
+		key := babelRouteKey{prefix: update.Prefix, neighbour: from}
+		if update.Metric == babel.Infinity {
+			delete(b.routes, key)
+			continue
+		}
+		nextHop := update.NextHop
+		if !nextHop.IsValid() {
+			nextHop = from
+		}
+		b.routes[key] = babelRoute{
+			metric:   update.Metric,
+			routerId: update.RouterId,
+			nextHop:  nextHop,
+			expiry:   now.Add(babelExpiry(update.Interval, n.BabelUpdateInterval)),
+		}
+	}
+
+	for _, req := range pkt.RouteRequests {
+		if !req.Prefix.IsValid() {
+			b.sendUpdates(n, speaker.routerId)
+			break
+		}
+		b.sendUpdates(n, speaker.routerId, req.Prefix)
+	}
+	for _, req := range pkt.SeqnoRequests {
+		if req.RouterId != speaker.routerId {
+			continue
+		}
+		// only bump our seqno if the requester has not seen a newer one yet
+		if int16(req.Seqno-b.seqno) > 0 {
+			b.seqno++
+		}
+		b.sendUpdates(n, speaker.routerId, req.Prefix)
+	}
+	if len(pkt.Updates) != 0 {
+		speaker.syncKernelRoutes(n)
+	}
+}
+
+// linkCost returns the cost of the link to a neighbour, which is infinite unless the link is bidirectional
+func (b *babelInterface) linkCost(addr netip.Addr, now time.Time) uint16 {
+	neigh, ok := b.neighbours[addr]
+	if !ok || now.After(neigh.helloExpiry) || now.After(neigh.ihuExpiry) {
+		return babel.Infinity
+	}
+	return neigh.txCost
+}
+
+func (b *babelInterface) sendHello(n *Nylon) {
+	b.helloSeqno++
+	pkt := babel.Packet{
+		Hellos: []babel.Hello{{Seqno: b.helloSeqno, Interval: n.BabelHelloInterval}},
+	}
+	now := time.Now()
+	for addr, neigh := range b.neighbours {
+		if now.After(neigh.helloExpiry) {
+			continue
+		}
+		pkt.IHUs = append(pkt.IHUs, babel.IHU{
+			RxCost:   b.cfg.Cost,
+			Interval: 3 * n.BabelHelloInterval,
+			Addr:     addr,
+		})
+	}
+	b.send(n, &pkt)
+}
+
+// sendUpdates announces the selected mesh routes, optionally restricted to the given prefixes, and retracts routes
+// that were announced before but are no longer selected.
+func (b *babelInterface) sendUpdates(n *Nylon, routerId babel.RouterId, only ...netip.Prefix) {
+	nh4 := b.localAddr(true)
+	current := b.exportedRoutes(n)
+	updates := make([]babel.Update, 0)
+	add := func(prefix netip.Prefix, metric uint16) {
+		u := babel.Update{
+			Prefix:   prefix,
+			Interval: n.BabelUpdateInterval,
+			Seqno:    b.seqno,
+			Metric:   metric,
+			RouterId: routerId,
+		}
+		if prefix.Addr().Is4() {
+			u.NextHop = nh4
+		}
+		updates = append(updates, u)
+	}
+	if len(only) != 0 {
+		for _, prefix := range only {
+			if metric, ok := current[prefix]; ok {
+				add(prefix, metric)
+			} else {
+				add(prefix, babel.Infinity)
+			}
+		}
+	} else {
+		for prefix, metric := range current {
+			add(prefix, metric)
+		}
+		for prefix := range b.announced {
+			if _, ok := current[prefix]; !ok {
+				add(prefix, babel.Infinity)
+			}
+		}
+		b.announced = make(map[netip.Prefix]struct{}, len(current))
+		for prefix := range current {
+			b.announced[prefix] = struct{}{}
+		}
+	}
+	for chunk := range slices.Chunk(updates, babelUpdatesPerPacket) {
+		b.send(n, &babel.Packet{Updates: chunk})
+	}
+}
+
+// exportedRoutes returns the babel metric of every selected mesh route announced on the interface
+func (b *babelInterface) exportedRoutes(n *Nylon) map[netip.Prefix]uint16 {
+	local := make(map[netip.Prefix]struct{})
+	if node := n.CentralCfg.TryGetNode(n.LocalCfg.Id); node != nil {
+		for _, prefix := range node.Prefixes {
+			if _, ok := prefix.PrefixHealth.(*state.BabelPrefixHealth); ok {
+				local[prefix.GetPrefix()] = struct{}{}
+			}
+		}
+	}
+	hasAddr4 := b.localAddr(true).IsValid()
+	routes := make(map[netip.Prefix]uint16)
+	for prefix, route := range n.RouterState.Routes {
+		// babel has no source-specific routes without extensions
+		if prefix.Src.IsValid() || route.Metric == state.INF {
+			continue
+		}
+		if _, ok := local[prefix.Prefix]; ok && route.NodeId == n.LocalCfg.Id {
+			continue // learned from babel in the first place
+		}
+		// IPv4 routes need an IPv4 next hop
+		if prefix.Prefix.Addr().Is4() && !hasAddr4 {
+			continue
+		}
+		routes[prefix.Prefix] = b.toBabelMetric(route.Metric)
+	}
+	return routes
+}
+
+func (b *babelInterface) send(n *Nylon, pkt *babel.Packet) {
+	buf, err := pkt.Encode()
+	if err != nil {
+		n.Log.Warn("failed to encode babel packet", "interface", b.cfg.Interface, "err", err)
+		return
+	}
+	dst := &net.UDPAddr{IP: babel.Group.AsSlice(), Port: babel.Port, Zone: b.itf.Name}
+	if _, err := b.conn.WriteTo(buf, &ipv6.ControlMessage{IfIndex: b.itf.Index}, dst); err != nil {
+		n.Log.Debug("babel send failed", "interface", b.cfg.Interface, "err", err)
+	}
+}
+
+func (b *babelInterface) toNylonMetric(metric uint16) uint32 {
+	if metric == babel.Infinity {
+		return state.INF
+	}
+	return uint32(min(uint64(state.INFM), uint64(metric)*uint64(b.cfg.MetricScale)))
+}
+
+func (b *babelInterface) toBabelMetric(metric uint32) uint16 {
+	scale := uint64(b.cfg.MetricScale)
+	return uint16(min(babel.Infinity-1, (uint64(metric)+scale-1)/scale))
+}
+
+func babelAdd(a, b uint16) uint16 {
+	return uint16(min(uint32(babel.Infinity), uint32(a)+uint32(b)))
+}
+
+// babelExpiry returns how long state announced with the given interval is kept, following RFC 8966 Appendix B
+func babelExpiry(interval, fallback time.Duration) time.Duration {
+	if interval == 0 {
+		interval = fallback
+	}
+	return interval * 7 / 2
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/encodeous/nylon/protocol/babel"
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func testBabelNylon(prefixes ...state.PrefixHealthWrapper) (*Nylon, *babelInterface) {
+	n := testNylonWithPrefixes(prefixes...)
+	n.NoNetConfigure = true
+	b := &babelInterface{
+		cfg:        state.BabelInterfaceCfg{Interface: "lan0", Cost: defaultBabelCost, MetricScale: defaultBabelMetricScale},
+		addrs:      []netip.Addr{netip.MustParseAddr("fe80::1"), netip.MustParseAddr("192.168.1.1")},
+		neighbours: make(map[netip.Addr]*babelNeighbour),
+		routes:     make(map[babelRouteKey]babelRoute),
+		announced:  make(map[netip.Prefix]struct{}),
+	}
+	n.babel = &babelSpeaker{
+		interfaces: []*babelInterface{b},
+		routerId:   babel.RouterId{1},
+		installed:  make(map[netip.Prefix]babelKernelRoute),
+	}
+	return n, b
+}
+
+func TestBabelImportsRoutesOverBidirectionalLinks(t *testing.T) {
+	lan := netip.MustParsePrefix("10.20.0.0/16")
+	n, b := testBabelNylon(state.PrefixHealthWrapper{PrefixHealth: &state.BabelPrefixHealth{Prefix: lan}})
+	monitor := n.CentralCfg.Routers[0].Prefixes[0].NewMonitor(n.Log, &n.RouterTunables, n.DNSResolver, n)
+	peer := netip.MustParseAddr("fe80::2")
+	update := babel.Update{Prefix: lan, Interval: 16 * time.Second, Seqno: 1, Metric: 100, RouterId: babel.RouterId{2}}
+
+	// no IHU yet, so the link is not known to be bidirectional
+	b.handlePacket(n, peer, &babel.Packet{
+		Hellos:  []babel.Hello{{Seqno: 1, Interval: 4 * time.Second}},
+		Updates: []babel.Update{update},
+	})
+	assert.Equal(t, state.INF, monitor.GetMetric())
+
+	b.handlePacket(n, peer, &babel.Packet{
+		IHUs: []babel.IHU{{RxCost: 96, Interval: 12 * time.Second, Addr: netip.MustParseAddr("fe80::1")}},
+	})
+	assert.Equal(t, uint32((100+96)*defaultBabelMetricScale), monitor.GetMetric())
+
+	// the route is retracted
+	update.Metric = babel.Infinity
+	b.handlePacket(n, peer, &babel.Packet{Updates: []babel.Update{update}})
+	assert.Equal(t, state.INF, monitor.GetMetric())
+}
+
+func TestBabelIgnoresRoutesOriginatedByTheMesh(t *testing.T) {
+	lan := netip.MustParsePrefix("10.20.0.0/16")
+	n, b := testBabelNylon(state.PrefixHealthWrapper{PrefixHealth: &state.BabelPrefixHealth{Prefix: lan}})
+	other := state.GenerateKey().Pubkey()
+	n.CentralCfg.Routers = append(n.CentralCfg.Routers, state.RouterCfg{NodeCfg: state.NodeCfg{Id: "other", PubKey: other}})
+
+	peer := netip.MustParseAddr("fe80::2")
+	b.handlePacket(n, peer, &babel.Packet{
+		Hellos:  []babel.Hello{{Seqno: 1, Interval: 4 * time.Second}},
+		IHUs:    []babel.IHU{{RxCost: 96, Interval: 12 * time.Second}},
+		Updates: []babel.Update{{Prefix: lan, Interval: 16 * time.Second, Metric: 100, RouterId: babelRouterId(other)}},
+	})
+	assert.Equal(t, state.INF, n.ExternalMetric(state.ExternalBabel, lan))
+}
+
+func TestBabelExportsSelectedMeshRoutes(t *testing.T) {
+	lan := netip.MustParsePrefix("10.20.0.0/16")
+	n, b := testBabelNylon(state.PrefixHealthWrapper{PrefixHealth: &state.BabelPrefixHealth{Prefix: lan}})
+	route := func(node state.NodeId, prefix state.RoutePrefix, metric uint32) state.SelRoute {
+		return state.SelRoute{PubRoute: state.PubRoute{
+			Source: state.Source{NodeId: node, RoutePrefix: prefix},
+			FD:     state.FD{Metric: metric},
+		}}
+	}
+	remote := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.30.0.0/16")}
+	remote6 := state.RoutePrefix{Prefix: netip.MustParsePrefix("fd00:30::/48")}
+	sourceSpecific := state.RoutePrefix{Prefix: netip.MustParsePrefix("0.0.0.0/0"), Src: netip.MustParsePrefix("10.30.0.0/16")}
+	retracted := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.40.0.0/16")}
+	n.RouterState.Routes[remote] = route("remote", remote, 20_050)
+	n.RouterState.Routes[remote6] = route("remote", remote6, state.INFM)
+	n.RouterState.Routes[sourceSpecific] = route("remote", sourceSpecific, 100)
+	n.RouterState.Routes[retracted] = route("remote", retracted, state.INF)
+	n.RouterState.Routes[state.RoutePrefix{Prefix: lan}] = route(n.LocalCfg.Id, state.RoutePrefix{Prefix: lan}, 200)
+
+	assert.Equal(t, map[netip.Prefix]uint16{
+		remote.Prefix:  201,
+		remote6.Prefix: babel.Infinity - 1,
+	}, b.exportedRoutes(n))
+}
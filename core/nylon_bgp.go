@@ -0,0 +1,466 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/encodeous/nylon/protocol/bgp"
+	"github.com/encodeous/nylon/state"
+)
+
+const (
+	bgpPrefixesPerUpdate = 100
+	bgpSessionQueue      = 256
+	bgpWriteTimeout      = 10 * time.Second
+	bgpLocalPref         = 100
+)
+
+// bgpSpeaker announces selected mesh routes to BGP peers, and imports the routes they announce for bgp prefixes.
+// Each peer has a goroutine maintaining its session, received updates and exports are handled on the dispatch goroutine.
+type bgpSpeaker struct {
+	cfg      state.BGPCfg
+	routerId netip.Addr
+	peers    []*bgpPeer
+	listener net.Listener
+}
+
+type bgpPeer struct {
+	cfg     state.BGPPeerCfg
+	inbound chan net.Conn // connections accepted for a passive peer
+	session *bgpSession   // the established session, owned by the dispatch goroutine
+}
+
+type bgpSession struct {
+	peer   *bgpPeer
+	conn   net.Conn
+	open   *bgp.Open // the peer's open message
+	as4    bool
+	local  netip.Addr // local address of the connection
+	out    chan []byte
+	ribIn  map[netip.Prefix]netip.Addr // prefix -> next hop announced by the peer
+	adjOut map[netip.Prefix]netip.Addr // prefix -> next hop we announced
+}
+
+// bgpRouterId derives a BGP identifier from the node's public key
+func bgpRouterId(pub state.NyPublicKey) netip.Addr {
+	sum := sha256.Sum256(pub[:])
+	return netip.AddrFrom4([4]byte(sum[:4]))
+}
+
+func (n *Nylon) initBGP() error {
+	cfg := n.LocalCfg.BGP
+	if cfg == nil {
+		return nil
+	}
+	s := &bgpSpeaker{
+		cfg:      *cfg,
+		routerId: cfg.RouterId,
+	}
+	if !s.routerId.IsValid() {
+		s.routerId = bgpRouterId(n.LocalCfg.Key.Pubkey())
+	}
+	for _, peerCfg := range cfg.Peers {
+		if peerCfg.Port == 0 {
+			peerCfg.Port = bgp.Port
+		}
+		s.peers = append(s.peers, &bgpPeer{cfg: peerCfg, inbound: make(chan net.Conn, 1)})
+	}
+	if cfg.Listen != "" {
+		listener, err := net.Listen("tcp", cfg.Listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen for bgp: %w", err)
+		}
+		s.listener = listener
+		go s.accept(n)
+	}
+	n.bgp = s
+	for _, peer := range s.peers {
+		go peer.run(n, s)
+	}
+	n.RepeatTask(func() error {
+		s.syncExports(n)
+		return nil
+	}, n.BGPUpdateDelay)
+	n.Log.Info("bgp speaker started", "asn", cfg.ASN, "router_id", s.routerId, "peers", len(s.peers))
+	return nil
+}
+
+func (n *Nylon) cleanupBGP() {
+	if n.bgp == nil {
+		return
+	}
+	if n.bgp.listener != nil {
+		_ = n.bgp.listener.Close()
+	}
+}
+
+// routeMetric returns 0 if any established peer announces prefix, or INF otherwise
+func (s *bgpSpeaker) routeMetric(prefix netip.Prefix) uint32 {
+	if s == nil {
+		return state.INF
+	}
+	for _, peer := range s.peers {
+		if peer.session == nil {
+			continue
+		}
+		if _, ok := peer.session.ribIn[prefix]; ok {
+			return 0
+		}
+	}
+	return state.INF
+}
+
+func (s *bgpSpeaker) accept(n *Nylon) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			n.Log.Debug("bgp accept failed", "err", err)
+			continue
+		}
+		remote := conn.RemoteAddr().(*net.TCPAddr).AddrPort().Addr().Unmap()
+		idx := slices.IndexFunc(s.peers, func(p *bgpPeer) bool {
+			return p.cfg.Passive && p.cfg.Addr == remote
+		})
+		if idx == -1 {
+			n.Log.Debug("rejected bgp connection from unknown peer", "addr", remote)
+			_ = conn.Close()
+			continue
+		}
+		select {
+		case s.peers[idx].inbound <- conn:
+		default:
+			// the peer already has a pending connection
+			_ = conn.Close()
+		}
+	}
+}
+
+func (p *bgpPeer) connect(n *Nylon) (net.Conn, error) {
+	if p.cfg.Passive {
+		select {
+		case conn := <-p.inbound:
+			return conn, nil
+		case <-n.Context.Done():
+			return nil, n.Context.Err()
+		}
+	}
+	dialer := net.Dialer{Timeout: n.BGPConnectRetry}
+	return dialer.DialContext(n.Context, "tcp", net.JoinHostPort(p.cfg.Addr.String(), strconv.Itoa(int(p.cfg.Port))))
+}
+
+func (p *bgpPeer) run(n *Nylon, s *bgpSpeaker) {
+	for n.Context.Err() == nil {
+		conn, err := p.connect(n)
+		if err == nil {
+			err = p.runSession(n, s, conn)
+			_ = conn.Close()
+		}
+		if n.Context.Err() != nil {
+			return
+		}
+		n.Log.Warn("bgp session down", "peer", p.cfg.Addr, "err", err)
+		select {
+		case <-time.After(n.BGPConnectRetry):
+		case <-n.Context.Done():
+			return
+		}
+	}
+}
+
+// runSession performs the open handshake and handles messages until the session fails
+func (p *bgpPeer) runSession(n *Nylon, s *bgpSpeaker, conn net.Conn) error {
+	stop := context.AfterFunc(n.Context, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+	holdTime := uint16(n.BGPHoldTime / time.Second)
+	open := bgp.Open{
+		ASN:      s.cfg.ASN,
+		HoldTime: holdTime,
+		RouterId: s.routerId,
+		Families: []bgp.Family{bgp.IPv4Unicast, bgp.IPv6Unicast},
+		AS4:      true,
+	}
+	buf, err := open.Encode()
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(n.BGPHoldTime))
+	if _, err := conn.Write(buf); err != nil {
+		return err
+	}
+	msg, err := bgp.ReadMessage(conn)
+	if err != nil {
+		return err
+	}
+	if msg.Type != bgp.MsgOpen {
+		return unexpectedBGPMessage(msg)
+	}
+	peerOpen, err := bgp.DecodeOpen(msg.Body)
+	if err != nil {
+		return err
+	}
+	if peerOpen.ASN != p.cfg.ASN {
+		_, _ = conn.Write((&bgp.Notification{Code: bgp.ErrOpen, Subcode: bgp.ErrOpenBadPeer}).Encode())
+		return fmt.Errorf("peer has asn %d, expected %d", peerOpen.ASN, p.cfg.ASN)
+	}
+	if peerOpen.HoldTime == 1 || peerOpen.HoldTime == 2 {
+		_, _ = conn.Write((&bgp.Notification{Code: bgp.ErrOpen, Subcode: bgp.ErrOpenHoldTime}).Encode())
+		return fmt.Errorf("peer has an unacceptable hold time of %ds", peerOpen.HoldTime)
+	}
+	hold := time.Duration(min(holdTime, peerOpen.HoldTime)) * time.Second
+	if _, err := conn.Write(bgp.EncodeKeepalive()); err != nil {
+		return err
+	}
+	msg, err = bgp.ReadMessage(conn)
+	if err != nil {
+		return err
+	}
+	if msg.Type != bgp.MsgKeepalive {
+		return unexpectedBGPMessage(msg)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	session := &bgpSession{
+		peer:   p,
+		conn:   conn,
+		open:   peerOpen,
+		as4:    peerOpen.AS4,
+		local:  conn.LocalAddr().(*net.TCPAddr).AddrPort().Addr().Unmap(),
+		out:    make(chan []byte, bgpSessionQueue),
+		ribIn:  make(map[netip.Prefix]netip.Addr),
+		adjOut: make(map[netip.Prefix]netip.Addr),
+	}
+	n.Log.Info("bgp session established", "peer", p.cfg.Addr, "asn", peerOpen.ASN, "router_id", peerOpen.RouterId)
+	done := make(chan struct{})
+	defer close(done)
+	go session.write(hold, done)
+	n.Dispatch(func() error {
+		p.session = session
+		s.syncSession(n, session)
+		return nil
+	})
+	defer n.Dispatch(func() error {
+		if p.session == session {
+			p.session = nil
+		}
+		return nil
+	})
+
+	for {
+		if hold != 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(hold))
+		}
+		msg, err := bgp.ReadMessage(conn)
+		if err != nil {
+			return err
+		}
+		switch msg.Type {
+		case bgp.MsgKeepalive:
+		case bgp.MsgUpdate:
+			update, err := bgp.DecodeUpdate(msg.Body, session.as4)
+			if err != nil {
+				_, _ = conn.Write((&bgp.Notification{Code: bgp.ErrUpdate}).Encode())
+				return err
+			}
+			n.Dispatch(func() error {
+				s.handleUpdate(n, session, update)
+				return nil
+			})
+		case bgp.MsgNotification:
+			notification, err := bgp.DecodeNotification(msg.Body)
+			if err != nil {
+				return err
+			}
+			return notification
+		default:
+			_, _ = conn.Write((&bgp.Notification{Code: bgp.ErrFSM}).Encode())
+			return unexpectedBGPMessage(msg)
+		}
+	}
+}
+
+func unexpectedBGPMessage(msg bgp.Message) error {
+	if msg.Type == bgp.MsgNotification {
+		if notification, err := bgp.DecodeNotification(msg.Body); err == nil {
+			return notification
+		}
+	}
+	return fmt.Errorf("unexpected bgp message type %d", msg.Type)
+}
+
+// write sends queued messages and keepalives until the session ends
+func (b *bgpSession) write(hold time.Duration, done chan struct{}) {
+	var keepalive <-chan time.Time
+	if hold != 0 {
+		ticker := time.NewTicker(hold / 3)
+		defer ticker.Stop()
+		keepalive = ticker.C
+	}
+	for {
+		var msg []byte
+		select {
+		case msg = <-b.out:
+		case <-keepalive:
+			msg = bgp.EncodeKeepalive()
+		case <-done:
+			return
+		}
+		_ = b.conn.SetWriteDeadline(time.Now().Add(bgpWriteTimeout))
+		if _, err := b.conn.Write(msg); err != nil {
+			_ = b.conn.Close()
+			return
+		}
+	}
+}
+
+// send queues a message, resetting the session if the peer cannot keep up
+func (b *bgpSession) send(n *Nylon, msg []byte) {
+	select {
+	case b.out <- msg:
+	default:
+		n.Log.Warn("bgp send queue is full, resetting session", "peer", b.peer.cfg.Addr)
+		_ = b.conn.Close()
+	}
+}
+
+func (s *bgpSpeaker) handleUpdate(n *Nylon, session *bgpSession, update *bgp.Update) {
+	if session.peer.session != session {
+		return
+	}
+	for _, prefix := range update.Withdrawn {
+		delete(session.ribIn, prefix)
+	}
+	for _, prefix := range update.NLRI {
+		// a route that has passed through our AS is a loop
+		if slices.Contains(update.ASPath, s.cfg.ASN) {
+			delete(session.ribIn, prefix)
+			continue
+		}
+		session.ribIn[prefix] = update.NextHopFor(prefix)
+	}
+}
+
+func (s *bgpSpeaker) syncExports(n *Nylon) {
+	for _, peer := range s.peers {
+		if peer.session != nil {
+			s.syncSession(n, peer.session)
+		}
+	}
+}
+
+// exportedRoutes returns the next hop of every selected mesh route announced to the session's peer
+func (s *bgpSpeaker) exportedRoutes(n *Nylon, session *bgpSession) map[netip.Prefix]netip.Addr {
+	local := make(map[netip.Prefix]struct{})
+	var addrs []netip.Addr
+	if node := n.CentralCfg.TryGetNode(n.LocalCfg.Id); node != nil {
+		addrs = node.Addresses
+		for _, prefix := range node.Prefixes {
+			if _, ok := prefix.PrefixHealth.(*state.BGPPrefixHealth); ok {
+				local[prefix.GetPrefix()] = struct{}{}
+			}
+		}
+	}
+	nextHop := func(is4 bool) netip.Addr {
+		family := bgp.IPv6Unicast
+		if is4 {
+			family = bgp.IPv4Unicast
+		}
+		if !session.open.HasFamily(family) {
+			return netip.Addr{}
+		}
+		for _, nh := range session.peer.cfg.NextHops {
+			if nh.Is4() == is4 {
+				return nh
+			}
+		}
+		if session.local.Is4() == is4 {
+			return session.local
+		}
+		for _, addr := range addrs {
+			if addr.Is4() == is4 {
+				return addr
+			}
+		}
+		return netip.Addr{}
+	}
+	nh4, nh6 := nextHop(true), nextHop(false)
+
+	routes := make(map[netip.Prefix]netip.Addr)
+	for prefix, route := range n.RouterState.Routes {
+		if prefix.Src.IsValid() || route.Metric == state.INF {
+			continue
+		}
+		if _, ok := local[prefix.Prefix]; ok && route.NodeId == n.LocalCfg.Id {
+			continue // learned from bgp in the first place
+		}
+		nh := nh6
+		if prefix.Prefix.Addr().Is4() {
+			nh = nh4
+		}
+		if nh.IsValid() {
+			routes[prefix.Prefix] = nh
+		}
+	}
+	return routes
+}
+
+// syncSession sends the changes between the routes announced to a peer and the current mesh routes
+func (s *bgpSpeaker) syncSession(n *Nylon, session *bgpSession) {
+	desired := s.exportedRoutes(n, session)
+	withdrawn := make([]netip.Prefix, 0)
+	for prefix := range session.adjOut {
+		if _, ok := desired[prefix]; !ok {
+			withdrawn = append(withdrawn, prefix)
+			delete(session.adjOut, prefix)
+		}
+	}
+	announced := make(map[netip.Addr][]netip.Prefix)
+	for prefix, nh := range desired {
+		if session.adjOut[prefix] == nh {
+			continue
+		}
+		announced[nh] = append(announced[nh], prefix)
+		session.adjOut[prefix] = nh
+	}
+
+	ibgp := session.peer.cfg.ASN == s.cfg.ASN
+	updates := make([]bgp.Update, 0)
+	for chunk := range slices.Chunk(withdrawn, bgpPrefixesPerUpdate) {
+		updates = append(updates, bgp.Update{Withdrawn: chunk})
+	}
+	for nh, prefixes := range announced {
+		for chunk := range slices.Chunk(prefixes, bgpPrefixesPerUpdate) {
+			u := bgp.Update{NLRI: chunk, Origin: bgp.OriginIGP}
+			if nh.Is4() {
+				u.NextHop = nh
+			} else {
+				u.MPNextHop = nh
+			}
+			if ibgp {
+				u.LocalPref = new(uint32(bgpLocalPref))
+			} else {
+				u.ASPath = []uint32{s.cfg.ASN}
+			}
+			updates = append(updates, u)
+		}
+	}
+	for _, u := range updates {
+		buf, err := u.Encode(session.as4)
+		if err != nil {
+			n.Log.Warn("failed to encode bgp update", "peer", session.peer.cfg.Addr, "err", err)
+			continue
+		}
+		session.send(n, buf)
+	}
+}
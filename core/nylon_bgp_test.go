@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"maps"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBGPNylon returns a node that runs its dispatch loop until the test ends
+func testBGPNylon(t *testing.T, id state.NodeId, cfg *state.BGPCfg, prefixes ...state.PrefixHealthWrapper) *Nylon {
+	n := testNylonWithPrefixes(prefixes...)
+	n.LocalCfg.Id = id
+	n.RouterState.Id = id
+	n.CentralCfg.Routers[0].Id = id
+	n.LocalCfg.Key = state.GenerateKey()
+	n.LocalCfg.BGP = cfg
+	n.BGPUpdateDelay = 20 * time.Millisecond
+	n.BGPConnectRetry = 50 * time.Millisecond
+	n.DispatchChannel = make(chan func() error, 128)
+	n.Context, n.Cancel = context.WithCancelCause(context.Background())
+	t.Cleanup(func() {
+		n.Cancel(context.Canceled)
+		n.cleanupBGP()
+	})
+	go func() {
+		for {
+			select {
+			case fun := <-n.DispatchChannel:
+				_ = fun()
+			case <-n.Context.Done():
+				return
+			}
+		}
+	}()
+	require.NoError(t, n.initBGP())
+	return n
+}
+
+func dispatchValue[T any](t *testing.T, n *Nylon, fun func() T) T {
+	value, err := NewDispatchFuture(n, func() (T, error) {
+		return fun(), nil
+	}).Await(t.Context())
+	require.NoError(t, err)
+	return value
+}
+
+func TestBGPSpeakersExchangeRoutes(t *testing.T) {
+	meshPrefix := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.30.0.0/16")}
+	meshPrefix6 := state.RoutePrefix{Prefix: netip.MustParsePrefix("fd00:30::/48")}
+	loopback := netip.MustParseAddr("127.0.0.1")
+
+	// a listens for b, which imports the routes a exports as a bgp prefix
+	a := testBGPNylon(t, "a", &state.BGPCfg{
+		ASN:    65001,
+		Listen: "127.0.0.1:0",
+		Peers: []state.BGPPeerCfg{{
+			Addr:     loopback,
+			ASN:      65002,
+			Passive:  true,
+			NextHops: []netip.Addr{netip.MustParseAddr("fd00::a")},
+		}},
+	})
+	port := a.bgp.listener.Addr().(*net.TCPAddr).Port
+	b := testBGPNylon(t, "b", &state.BGPCfg{
+		ASN:   65002,
+		Peers: []state.BGPPeerCfg{{Addr: loopback, Port: uint16(port), ASN: 65001}},
+	},
+		state.PrefixHealthWrapper{PrefixHealth: &state.BGPPrefixHealth{Prefix: meshPrefix.Prefix, Metric: 40}},
+		state.PrefixHealthWrapper{PrefixHealth: &state.BGPPrefixHealth{Prefix: meshPrefix6.Prefix}},
+	)
+	monitor := b.CentralCfg.Routers[0].Prefixes[0].NewMonitor(b.Log, &b.RouterTunables, b.DNSResolver, b)
+	route := func(node state.NodeId, prefix state.RoutePrefix) state.SelRoute {
+		return state.SelRoute{PubRoute: state.PubRoute{
+			Source: state.Source{NodeId: node, RoutePrefix: prefix},
+			FD:     state.FD{Metric: 100},
+		}}
+	}
+
+	dispatchValue(t, a, func() any {
+		a.RouterState.Routes[meshPrefix] = route("remote", meshPrefix)
+		a.RouterState.Routes[meshPrefix6] = route("remote", meshPrefix6)
+		return nil
+	})
+	otherPrefix := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.40.0.0/16")}
+	dispatchValue(t, b, func() any {
+		// b originates the imported prefix into the mesh, and has a route to another prefix
+		b.RouterState.Routes[meshPrefix] = route("b", meshPrefix)
+		b.RouterState.Routes[otherPrefix] = route("remote", otherPrefix)
+		return nil
+	})
+	require.Eventually(t, func() bool {
+		return dispatchValue(t, b, func() bool {
+			return b.ExternalMetric(state.ExternalBGP, meshPrefix6.Prefix) == 0 && monitor.GetMetric() == 40
+		})
+	}, 5*time.Second, 10*time.Millisecond)
+	nextHops := dispatchValue(t, b, func() map[netip.Prefix]netip.Addr {
+		return maps.Clone(b.bgp.peers[0].session.ribIn)
+	})
+	assert.Equal(t, map[netip.Prefix]netip.Addr{
+		meshPrefix.Prefix:  loopback,
+		meshPrefix6.Prefix: netip.MustParseAddr("fd00::a"),
+	}, nextHops)
+
+	// the prefix b imported from bgp is not announced back to a
+	require.Eventually(t, func() bool {
+		return dispatchValue(t, a, func() bool {
+			return a.ExternalMetric(state.ExternalBGP, otherPrefix.Prefix) == 0
+		})
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, state.INF, dispatchValue(t, a, func() uint32 {
+		return a.ExternalMetric(state.ExternalBGP, meshPrefix.Prefix)
+	}))
+
+	// the route is withdrawn once a no longer selects it
+	dispatchValue(t, a, func() any {
+		delete(a.RouterState.Routes, meshPrefix)
+		return nil
+	})
+	require.Eventually(t, func() bool {
+		return dispatchValue(t, b, monitor.GetMetric) == state.INF
+	}, 5*time.Second, 10*time.Millisecond)
+}
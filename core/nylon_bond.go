@@ -0,0 +1,209 @@
+package core
+
+import (
+	"cmp"
+	"net/netip"
+	"slices"
+	"sync/atomic"
+
+	"github.com/encodeous/nylon/polyamide/conn"
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+)
+
+// bondSlots is the length of the schedule traffic of a bonded link is spread with, an endpoint with a smaller share
+// than one slot carries no traffic
+const bondSlots = 64
+
+// peerLinks is the data path view of the link to a neighbour. It is rebuilt on the dispatch thread and published
+// atomically, like the forwarding tables.
+type peerLinks struct {
+	bond state.BondMode
+	// ranked lists the active endpoints of the link with distinct addresses, from the most to the least preferred
+	ranked []bondSlot
+	// schedule lists the endpoints of a bonded link, each in proportion to its weight and interleaved
+	schedule []bondSlot
+	stripe   atomic.Uint32 // position in the schedule when striping packets
+	// fallback is the endpoint wireguard sends through when the packet does not choose one
+	fallback *state.NylonEndpoint
+	byAddr   map[netip.AddrPort]*state.NylonEndpoint
+}
+
+type bondSlot struct {
+	wg     conn.Endpoint
+	ep     *state.NylonEndpoint
+	backup bool
+	metric uint32
+}
+
+type linkTable map[*device.Peer]*peerLinks
+
+// syncLinks publishes the endpoints of every neighbour to the data path
+func (n *Nylon) syncLinks() {
+	if n.Device == nil {
+		return
+	}
+	links := make(linkTable, len(n.RouterState.Neighbours))
+	for _, neigh := range n.RouterState.Neighbours {
+		peer := n.Device.LookupPeer(device.NoisePublicKey(n.GetNode(neigh.Id).PubKey))
+		if peer == nil {
+			continue
+		}
+		pl := &peerLinks{
+			bond:   neigh.Link.Bond,
+			byAddr: make(map[netip.AddrPort]*state.NylonEndpoint, len(neigh.Eps)),
+		}
+		for _, ep := range neigh.Eps {
+			nep := ep.AsNylonEndpoint()
+			if nep.WgEndpoint != nil {
+				pl.byAddr[nep.WgEndpoint.DstIPPort()] = nep
+			}
+		}
+		if eps := peer.GetEndpoints(); len(eps) > 0 {
+			pl.fallback = pl.byAddr[eps[0].DstIPPort()]
+		}
+		pl.ranked = n.rankLinks(neigh)
+		if pl.bond != state.BondNone {
+			pl.schedule = bondSchedule(pl.ranked)
+		}
+		links[peer] = pl
+	}
+	n.links.Store(&links)
+}
+
+// rankLinks returns the active endpoints of a neighbour with distinct addresses. Primary endpoints come before backup
+// endpoints, and endpoints of the same class are ordered by their metric.
+func (n *Nylon) rankLinks(neigh *state.Neighbour) []bondSlot {
+	ranked := make([]bondSlot, 0, len(neigh.Eps))
+	for _, ep := range neigh.Eps {
+		if !ep.IsActive() {
+			continue
+		}
+		nep := ep.AsNylonEndpoint()
+		wg, err := nep.GetWgEndpoint(n.Device, n.EndpointResolver)
+		if err != nil {
+			continue
+		}
+		ranked = append(ranked, bondSlot{wg: wg, ep: nep, backup: state.IsBackupEndpoint(ep), metric: ep.Metric()})
+	}
+	slices.SortStableFunc(ranked, func(a, b bondSlot) int {
+		if a.backup != b.backup {
+			if a.backup {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(a.metric, b.metric)
+	})
+	seen := make(map[netip.AddrPort]struct{}, len(ranked))
+	return slices.DeleteFunc(ranked, func(slot bondSlot) bool {
+		if _, ok := seen[slot.wg.DstIPPort()]; ok {
+			return true
+		}
+		seen[slot.wg.DstIPPort()] = struct{}{}
+		return false
+	})
+}
+
+// bondSchedule spreads the slots of a bonded link across its ranked endpoints, weighted by the inverse of their
+// metrics. Backup endpoints are only bonded while no primary endpoint is active.
+func bondSchedule(ranked []bondSlot) []bondSlot {
+	if len(ranked) == 0 {
+		return nil
+	}
+	members := make([]bondSlot, 0, len(ranked))
+	metrics := make([]uint32, 0, len(ranked))
+	for _, slot := range ranked {
+		if slot.backup == ranked[0].backup {
+			members = append(members, slot)
+			metrics = append(metrics, slot.metric)
+		}
+	}
+	schedule := make([]bondSlot, bondSlots)
+	for i, member := range bondWeights(metrics, bondSlots) {
+		schedule[i] = members[member]
+	}
+	return schedule
+}
+
+// bondWeights returns a schedule of the given length that picks each member in proportion to the inverse of its
+// metric. Picks are interleaved with smooth weighted round-robin, so striped packets alternate between endpoints.
+func bondWeights(metrics []uint32, length int) []int {
+	weights := make([]float64, len(metrics))
+	total := 0.0
+	for i, metric := range metrics {
+		weights[i] = 1 / float64(max(metric, 1))
+		total += weights[i]
+	}
+	current := make([]float64, len(metrics))
+	schedule := make([]int, length)
+	for slot := range schedule {
+		best := 0
+		for i := range current {
+			current[i] += weights[i]
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		schedule[slot] = best
+	}
+	return schedule
+}
+
+// bondShares returns the fraction of the traffic of a bonded link that each of its endpoints carries
+func (n *Nylon) bondShares(id state.NodeId) map[*state.NylonEndpoint]float64 {
+	links := n.links.Load()
+	if links == nil || n.Device == nil {
+		return nil
+	}
+	pl := (*links)[n.Device.LookupPeer(device.NoisePublicKey(n.GetNode(id).PubKey))]
+	if pl == nil || len(pl.schedule) == 0 {
+		return nil
+	}
+	shares := make(map[*state.NylonEndpoint]float64)
+	for _, slot := range pl.schedule {
+		shares[slot.ep] += 1 / float64(len(pl.schedule))
+	}
+	return shares
+}
+
+// sendThrough chooses the endpoint of a bonded link for a packet to a neighbour, and counts it on that endpoint
+func (n *Nylon) sendThrough(packet *device.TCElement) {
+	links := n.links.Load()
+	if links == nil || packet.ToPeer == nil {
+		return
+	}
+	pl := (*links)[packet.ToPeer]
+	if pl == nil {
+		return
+	}
+	ep := pl.fallback
+	if len(pl.schedule) != 0 {
+		var slot bondSlot
+		if pl.bond == state.BondPacket {
+			slot = pl.schedule[pl.stripe.Add(1)%uint32(len(pl.schedule))]
+		} else {
+			// rehash, so flows spread across multipath next hops by the same hash still use every endpoint
+			slot = pl.schedule[(packet.FlowHash()*0x9e3779b1)>>26%uint32(len(pl.schedule))]
+		}
+		packet.ToEp = slot.wg
+		ep = slot.ep
+	}
+	if ep != nil {
+		ep.Traffic.CountTx(len(packet.Packet))
+	}
+}
+
+// countReceived counts a data packet from a neighbour on the endpoint it arrived from
+func (n *Nylon) countReceived(packet *device.TCElement) {
+	links := n.links.Load()
+	if links == nil || packet.FromPeer == nil || packet.FromEp == nil {
+		return
+	}
+	if pl := (*links)[packet.FromPeer]; pl != nil {
+		if ep := pl.byAddr[packet.FromEp.DstIPPort()]; ep != nil {
+			ep.Traffic.CountRx(len(packet.Packet))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/encodeous/nylon/polyamide/conn"
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBondWeights(t *testing.T) {
+	// equal metrics alternate
+	assert.Equal(t, []int{0, 1, 0, 1, 0, 1}, bondWeights([]uint32{10_000, 10_000}, 6))
+
+	// an endpoint with a third of the metric carries three quarters of the traffic, interleaved with the other
+	schedule := bondWeights([]uint32{10_000, 30_000}, bondSlots)
+	counts := make([]int, 2)
+	for _, member := range schedule {
+		counts[member]++
+	}
+	assert.Equal(t, []int{48, 16}, counts)
+	assert.Equal(t, []int{0, 0, 1, 0}, schedule[:4])
+
+	assert.Equal(t, []int{0, 0, 0}, bondWeights([]uint32{0}, 3))
+}
+
+func TestSendThroughStripesBondedLink(t *testing.T) {
+	tunables := state.DefaultRouterTunables()
+	bind := conn.NewStdNetBind()
+	slot := func(address string) bondSlot {
+		wg, err := bind.ParseEndpoint(address)
+		require.NoError(t, err)
+		return bondSlot{wg: wg, ep: state.NewEndpoint(address, false, wg, &tunables)}
+	}
+	a, b := slot("192.0.2.1:57175"), slot("198.51.100.1:57175")
+	peer := new(device.Peer)
+	links := linkTable{peer: {
+		bond:     state.BondPacket,
+		schedule: []bondSlot{a, b},
+		byAddr:   map[netip.AddrPort]*state.NylonEndpoint{a.wg.DstIPPort(): a.ep, b.wg.DstIPPort(): b.ep},
+	}}
+	n := &Nylon{}
+	n.links.Store(&links)
+
+	sent := make(map[string]int)
+	for range 4 {
+		packet := &device.TCElement{Packet: make([]byte, 100), ToPeer: peer}
+		n.sendThrough(packet)
+		require.NotNil(t, packet.ToEp)
+		sent[packet.ToEp.DstToString()]++
+	}
+	assert.Equal(t, map[string]int{"192.0.2.1:57175": 2, "198.51.100.1:57175": 2}, sent)
+	assert.Equal(t, uint64(200), a.ep.Traffic.TxBytes.Load())
+	assert.Equal(t, uint64(2), b.ep.Traffic.TxPackets.Load())
+
+	// received packets are counted on the endpoint they arrived from
+	n.countReceived(&device.TCElement{Packet: make([]byte, 60), FromPeer: peer, FromEp: b.wg})
+	assert.Equal(t, uint64(60), b.ep.Traffic.RxBytes.Load())
+	assert.Zero(t, a.ep.Traffic.RxPackets.Load())
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/protocol"
+	"github.com/encodeous/nylon/state"
+)
+
+// bounds of packet captures, so a capture cannot slow down the data path
+const (
+	captureMaxSessions    = 4
+	captureQueue          = 256 // packets buffered for a slow client, later packets are skipped
+	captureDefaultRate    = 1000
+	captureMaxRate        = 10000
+	captureDefaultSnaplen = 65535
+)
+
+// captureState holds the packet captures of IPC clients. The data path only observes packets while a capture runs.
+type captureState struct {
+	mu       sync.Mutex // serialises changes to sessions
+	sessions atomic.Pointer[[]*captureSession]
+}
+
+type captureSession struct {
+	filter  state.CaptureFilter
+	snaplen int
+	rate    float64
+	bucket  tokenBucket
+	packets chan *protocol.CapturedPacket
+	// skipped counts the matching packets that were not captured since the last packet sent to the client
+	skipped atomic.Uint64
+}
+
+var captureActions = map[device.TCAction]string{
+	device.TcForward: "forward",
+	device.TcBounce:  "bounce",
+	device.TcDrop:    "drop",
+}
+
+// startCapture starts a packet capture, and observes the data path if it is the first
+func (n *Nylon) startCapture(req *protocol.CaptureRequest) (*captureSession, error) {
+	filter, err := state.ParseCaptureFilter(req.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	s := &captureSession{
+		filter:  filter,
+		snaplen: captureDefaultSnaplen,
+		rate:    captureDefaultRate,
+		packets: make(chan *protocol.CapturedPacket, captureQueue),
+	}
+	if req.GetSnaplen() != 0 {
+		s.snaplen = int(min(req.GetSnaplen(), captureDefaultSnaplen))
+	}
+	if req.GetRate() != 0 {
+		s.rate = float64(min(req.GetRate(), captureMaxRate))
+	}
+
+	n.capture.mu.Lock()
+	defer n.capture.mu.Unlock()
+	var sessions []*captureSession
+	if cur := n.capture.sessions.Load(); cur != nil {
+		sessions = slices.Clone(*cur)
+	}
+	if len(sessions) >= captureMaxSessions {
+		return nil, fmt.Errorf("too many packet captures running, at most %d are allowed", captureMaxSessions)
+	}
+	sessions = append(sessions, s)
+	n.capture.sessions.Store(&sessions)
+	if len(sessions) == 1 && n.Device != nil {
+		n.Device.SetTCObserver(n.observeCapture)
+	}
+	return s, nil
+}
+
+// stopCapture stops a packet capture, and stops observing the data path if it was the last
+func (n *Nylon) stopCapture(s *captureSession) {
+	n.capture.mu.Lock()
+	defer n.capture.mu.Unlock()
+	cur := n.capture.sessions.Load()
+	if cur == nil {
+		return
+	}
+	sessions := slices.DeleteFunc(slices.Clone(*cur), func(other *captureSession) bool {
+		return other == s
+	})
+	if len(sessions) != 0 {
+		n.capture.sessions.Store(&sessions)
+		return
+	}
+	n.capture.sessions.Store(nil)
+	if n.Device != nil {
+		n.Device.SetTCObserver(nil)
+	}
+}
+
+// observeCapture hands a packet that passed the traffic control filters to every capture it matches. It runs on the
+// data path, so packets are skipped rather than waited for.
+func (n *Nylon) observeCapture(packet *device.TCElement, action device.TCAction) {
+	sessions := n.capture.sessions.Load()
+	if sessions == nil {
+		return
+	}
+	if ver := packet.GetIPVersion(); ver != 4 && ver != 6 {
+		return
+	}
+	cp := state.CapturePacket{
+		Src:      packet.GetSrc(),
+		Dst:      packet.GetDst(),
+		Proto:    packet.GetProtocol(),
+		Incoming: packet.Incoming(),
+		FromPeer: n.peerId(packet.FromPeer),
+		ToPeer:   n.peerId(packet.ToPeer),
+		Action:   captureActions[action],
+	}
+	cp.SrcPort, cp.DstPort, cp.HasPorts = packet.GetPorts()
+	if action != device.TcForward {
+		cp.ToPeer = ""
+	}
+	now := time.Now()
+	for _, s := range *sessions {
+		if !s.filter(&cp) {
+			continue
+		}
+		// the bucket holds packets rather than bytes, and bursts up to a second of packets
+		if !s.bucket.take(1, now, s.rate, s.rate) {
+			s.skipped.Add(1)
+			continue
+		}
+		captured := &protocol.CapturedPacket{
+			TimestampNs: now.UnixNano(),
+			Incoming:    cp.Incoming,
+			FromPeer:    string(cp.FromPeer),
+			ToPeer:      string(cp.ToPeer),
+			Action:      cp.Action,
+			Length:      uint32(len(packet.Packet)),
+			Data:        slices.Clone(packet.Packet[:min(len(packet.Packet), s.snaplen)]),
+		}
+		select {
+		case s.packets <- captured:
+		default:
+			s.skipped.Add(1)
+		}
+	}
+}
+
+// peerId returns the node of a peer, or an empty id if the peer is nil or unknown
+func (n *Nylon) peerId(peer *device.Peer) state.NodeId {
+	nt := n.PeerMap.Load()
+	if peer == nil || nt == nil {
+		return ""
+	}
+	return (*nt)[state.NyPublicKey(peer.GetPublicKey())]
+}
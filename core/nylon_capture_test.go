@@ -0,0 +1,61 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/encodeous/nylon/polyamide/conn"
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/polyamide/tun/tuntest"
+	"github.com/encodeous/nylon/protocol"
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapture(t *testing.T) {
+	n := &Nylon{}
+	bobKey := state.GenerateKey().Pubkey()
+	peers := map[state.NyPublicKey]state.NodeId{bobKey: "bob"}
+	n.PeerMap.Store(&peers)
+	dev := device.NewDevice(tuntest.NewChannelTUN().TUN(), conn.NewDefaultBind(), device.NewLogger(device.LogLevelError, ""))
+	defer dev.Close()
+	bob, err := dev.NewPeer(device.NoisePublicKey(bobKey))
+	require.NoError(t, err)
+
+	_, err = n.startCapture(&protocol.CaptureRequest{Filter: "tcp and"})
+	assert.Error(t, err)
+
+	ssh, err := n.startCapture(&protocol.CaptureRequest{Filter: "peer bob and port 22", Snaplen: 20, Rate: 2})
+	require.NoError(t, err)
+	all, err := n.startCapture(&protocol.CaptureRequest{})
+	require.NoError(t, err)
+
+	packet := testTCPPacket("10.0.0.2", "10.0.0.1", 40000, 22, bob)
+	for range 3 {
+		n.observeCapture(packet, device.TcBounce)
+	}
+	n.observeCapture(testTCPPacket("10.0.0.1", "10.0.0.2", 22, 40000, nil), device.TcDrop)
+
+	// the rate limit bursts up to a second of packets
+	require.Len(t, ssh.packets, 2)
+	captured := <-ssh.packets
+	assert.True(t, captured.Incoming)
+	assert.Equal(t, "bob", captured.FromPeer)
+	assert.Equal(t, "bounce", captured.Action)
+	assert.Len(t, captured.Data, 20, "packets are truncated to the snaplen")
+	assert.Equal(t, uint32(len(packet.Packet)), captured.Length)
+	assert.Equal(t, uint64(1), ssh.skipped.Load())
+
+	require.Len(t, all.packets, 4)
+	n.stopCapture(all)
+	n.stopCapture(ssh)
+	assert.Nil(t, n.capture.sessions.Load())
+	n.observeCapture(packet, device.TcBounce)
+
+	for range captureMaxSessions {
+		_, err = n.startCapture(&protocol.CaptureRequest{})
+		require.NoError(t, err)
+	}
+	_, err = n.startCapture(&protocol.CaptureRequest{})
+	assert.ErrorContains(t, err, "too many packet captures")
+}
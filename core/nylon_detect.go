@@ -0,0 +1,83 @@
+package core
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/encodeous/nylon/state"
+)
+
+// detectState tracks the endpoints of links with fast failure detection
+type detectState struct {
+	enabled   atomic.Bool // some link to a neighbour has fast failure detection
+	endpoints map[*state.NylonEndpoint]detectEndpoint
+}
+
+type detectEndpoint struct {
+	probedAt time.Time // when the last liveness probe was sent
+	active   bool      // whether the endpoint was active at the last check
+}
+
+func (n *Nylon) initDetect() {
+	n.detect.endpoints = make(map[*state.NylonEndpoint]detectEndpoint)
+	go func() {
+		ticker := time.NewTicker(n.DetectTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-n.Context.Done():
+				return
+			case <-ticker.C:
+				// links without fast detection keep the regular probe rate, so the tick is idle unless one is configured
+				if n.detect.enabled.Load() {
+					n.Dispatch(func() error {
+						return n.probeDetect(time.Now())
+					})
+				}
+			}
+		}
+	}()
+}
+
+// probeDetect sends liveness probes to the active endpoints of links with fast failure detection, and reroutes as soon
+// as one of them is declared dead, instead of waiting for the next garbage collection.
+func (n *Nylon) probeDetect(now time.Time) error {
+	seen := make(map[*state.NylonEndpoint]struct{})
+	failed := false
+	for _, neigh := range n.RouterState.Neighbours {
+		detect := neigh.Link.Detect
+		if !detect.Enabled() {
+			continue
+		}
+		for _, ep := range neigh.Eps {
+			nep := ep.AsNylonEndpoint()
+			seen[nep] = struct{}{}
+			st := n.detect.endpoints[nep]
+			active := nep.IsActive()
+			if st.active && !active {
+				n.Log.Debug("endpoint declared dead by fast failure detection", "peer", neigh.Id, "ep", nep.Address, "threshold", nep.DeadThreshold())
+				failed = true
+			}
+			st.active = active
+			// probes are due up to half a tick early, so an interval that is a multiple of the tick is kept exactly
+			if active && (st.probedAt.IsZero() || now.Sub(st.probedAt)+n.DetectTick/2 >= detect.Interval) {
+				st.probedAt = now
+				if err := n.ProbeLiveness(neigh.Id, nep); err != nil && n.DBG_log_probe {
+					n.Log.Debug("liveness probe failed", "err", err.Error())
+				}
+			}
+			n.detect.endpoints[nep] = st
+		}
+	}
+	for nep := range n.detect.endpoints {
+		if _, ok := seen[nep]; !ok {
+			delete(n.detect.endpoints, nep)
+		}
+	}
+	if failed {
+		ComputeRoutes(n.RouterState, n)
+		// send the resulting updates now rather than at the next flush
+		return n.flushIO()
+	}
+	return nil
+}
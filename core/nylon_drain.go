@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/protocol"
+	"github.com/encodeous/nylon/state"
+)
+
+// drainState tracks when this router last forwarded traffic between two peers
+type drainState struct {
+	since       time.Time // when the router started draining
+	transit     uint64    // transitPackets at the last check
+	lastTransit time.Time // zero if no transit traffic was ever seen
+}
+
+func (n *Nylon) initDrain() {
+	n.RepeatTask(func() error {
+		n.checkTransit(time.Now())
+		return nil
+	}, time.Second)
+}
+
+func (n *Nylon) checkTransit(now time.Time) {
+	if count := n.transitPackets.Load(); count != n.drain.transit {
+		n.drain.transit = count
+		n.drain.lastTransit = now
+	}
+}
+
+// fromRouter returns true if a packet was received from a router neighbour. Packets from our passive clients are not
+// transit traffic, as they can only ever reach the mesh through us.
+func (n *Nylon) fromRouter(peer *device.Peer) bool {
+	routers := n.routerPeers.Load()
+	if routers == nil || peer == nil {
+		return false
+	}
+	_, ok := (*routers)[state.NyPublicKey(peer.GetPublicKey())]
+	return ok
+}
+
+// isDrained reports whether a draining router has stopped carrying transit traffic. With system routing, transit
+// traffic is forwarded by the host and cannot be observed, so the router is drained once neighbours had the quiet
+// period to switch away.
+func (n *Nylon) isDrained(now time.Time) bool {
+	if !n.RouterState.Draining {
+		return false
+	}
+	if n.UseSystemRouting {
+		return now.Sub(n.drain.since) >= n.DrainQuietPeriod
+	}
+	return n.drain.lastTransit.IsZero() || now.Sub(n.drain.lastTransit) >= n.DrainQuietPeriod
+}
+
+func (n *Nylon) Drain(draining bool) {
+	if draining != n.RouterState.Draining {
+		if draining {
+			n.drain.since = time.Now()
+			n.Log.Info("draining, moving traffic away from this router")
+		} else {
+			n.Log.Info("undraining, this router carries traffic again")
+		}
+	}
+	SetDraining(n.RouterState, n, draining)
+}
+
+func handleIPCDrain(n *Nylon, req *protocol.DrainRequest) *protocol.IpcResponse {
+	switch req.GetAction() {
+	case protocol.DrainAction_DRAIN:
+		n.Drain(true)
+	case protocol.DrainAction_UNDRAIN:
+		n.Drain(false)
+	}
+	now := time.Now()
+	n.checkTransit(now)
+	idle := int64(-1)
+	if !n.drain.lastTransit.IsZero() {
+		idle = now.Sub(n.drain.lastTransit).Milliseconds()
+	}
+	return &protocol.IpcResponse{
+		Ok: true,
+		Response: &protocol.IpcResponse_Drain{Drain: &protocol.DrainResponse{
+			Draining:       n.RouterState.Draining,
+			Drained:        n.isDrained(now),
+			IdleMs:         idle,
+			TransitPackets: n.drain.transit,
+			Unobservable:   n.UseSystemRouting,
+		}},
+	}
+}
+
+// drainBeforeExit drains the router and waits until it no longer carries transit traffic, so flows through this node
+// move to other routers before it shuts down. It returns early on the next signal.
+func (n *Nylon) drainBeforeExit(signals <-chan os.Signal) {
+	ctx, cancel := context.WithTimeout(n.Context, n.DrainTimeout)
+	defer cancel()
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	n.Dispatch(func() error {
+		n.Drain(true)
+		return nil
+	})
+	for {
+		select {
+		case <-signals:
+			return
+		case <-ctx.Done():
+			n.Log.Warn("stopped waiting for the router to drain", "timeout", n.DrainTimeout)
+			return
+		case <-ticker.C:
+		}
+		drained, err := NewDispatchFuture(n, func() (bool, error) {
+			now := time.Now()
+			n.checkTransit(now)
+			return n.isDrained(now), nil
+		}).Await(ctx)
+		if err == nil && drained {
+			if n.UseSystemRouting {
+				n.Log.Info("drained, transit traffic forwarded by the system routing table cannot be observed")
+			} else {
+				n.Log.Info("drained, no transit traffic is forwarded through this router")
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/conn"
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/polyamide/tun/tuntest"
+	"github.com/encodeous/nylon/protocol"
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainWaitsForTransitTrafficToStop(t *testing.T) {
+	n := testNylonWithPrefixes()
+	status := func(action protocol.DrainAction) *protocol.DrainResponse {
+		resp := handleIPCDrain(n, &protocol.DrainRequest{Action: action})
+		assert.True(t, resp.Ok)
+		return resp.GetDrain()
+	}
+
+	assert.False(t, status(protocol.DrainAction_DRAIN_STATUS).Draining)
+
+	// transit traffic is still flowing when the drain starts
+	n.transitPackets.Add(10)
+	d := status(protocol.DrainAction_DRAIN)
+	assert.True(t, d.Draining)
+	assert.False(t, d.Drained)
+	assert.Equal(t, uint64(10), d.TransitPackets)
+	assert.True(t, n.RouterState.Draining)
+
+	// the neighbours switched away, and nothing was forwarded for the quiet period
+	n.drain.lastTransit = time.Now().Add(-n.DrainQuietPeriod)
+	d = status(protocol.DrainAction_DRAIN_STATUS)
+	assert.True(t, d.Drained)
+	assert.GreaterOrEqual(t, d.IdleMs, n.DrainQuietPeriod.Milliseconds())
+
+	d = status(protocol.DrainAction_UNDRAIN)
+	assert.False(t, d.Draining)
+	assert.False(t, d.Drained)
+	assert.False(t, n.RouterState.Draining)
+}
+
+func TestDrainCountsTransitFromRoutersOnly(t *testing.T) {
+	dev := device.NewDevice(tuntest.NewChannelTUN().TUN(), conn.NewDefaultBind(), device.NewLogger(device.LogLevelError, ""))
+	defer dev.Close()
+	bobKey, phoneKey := state.GenerateKey().Pubkey(), state.GenerateKey().Pubkey()
+	bob, err := dev.NewPeer(device.NoisePublicKey(bobKey))
+	require.NoError(t, err)
+	phone, err := dev.NewPeer(device.NoisePublicKey(phoneKey))
+	require.NoError(t, err)
+
+	n := testNylonWithPrefixes()
+	n.routerPeers.Store(&map[state.NyPublicKey]struct{}{bobKey: {}})
+	assert.True(t, n.fromRouter(bob))
+	// our passive clients can never move to another router
+	assert.False(t, n.fromRouter(phone))
+	assert.False(t, n.fromRouter(nil))
+}
+
+func TestDrainWithSystemRouting(t *testing.T) {
+	n := testNylonWithPrefixes()
+	n.UseSystemRouting = true
+	d := handleIPCDrain(n, &protocol.DrainRequest{Action: protocol.DrainAction_DRAIN}).GetDrain()
+	assert.True(t, d.Unobservable)
+	assert.False(t, d.Drained)
+
+	// transit traffic cannot be seen, so the router is drained once neighbours had the quiet period to switch away
+	n.drain.since = time.Now().Add(-n.DrainQuietPeriod)
+	d = handleIPCDrain(n, &protocol.DrainRequest{Action: protocol.DrainAction_DRAIN_STATUS}).GetDrain()
+	assert.True(t, d.Drained)
+}
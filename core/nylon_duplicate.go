@@ -0,0 +1,169 @@
+package core
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+)
+
+// duplicateState sends the packets selected by the duplicate rules over two paths where they enter the mesh, and drops
+// the copies that arrive after the first where they leave it
+type duplicateState struct {
+	rules   atomic.Pointer[[]state.DuplicateRule]
+	sent    atomic.Uint64 // copies sent over a second path
+	dropped atomic.Uint64 // copies dropped because another copy arrived first
+
+	mu     sync.Mutex
+	window dedupWindow
+}
+
+// dedupWindow remembers the keys of recently received packets, the oldest are forgotten first
+type dedupWindow struct {
+	seen map[uint64]time.Time
+	ring []dedupEntry
+	next int
+}
+
+type dedupEntry struct {
+	hash uint64
+	at   time.Time
+}
+
+// observe records a packet, and returns true if the packet was already seen within age
+func (w *dedupWindow) observe(hash uint64, now time.Time, age time.Duration, size int) bool {
+	if at, ok := w.seen[hash]; ok && now.Sub(at) < age {
+		return true
+	}
+	if len(w.ring) != size {
+		w.seen = make(map[uint64]time.Time, size)
+		w.ring = make([]dedupEntry, size)
+		w.next = 0
+	}
+	old := w.ring[w.next]
+	if at, ok := w.seen[old.hash]; ok && at.Equal(old.at) {
+		delete(w.seen, old.hash)
+	}
+	w.ring[w.next] = dedupEntry{hash: hash, at: now}
+	w.seen[hash] = now
+	w.next = (w.next + 1) % size
+	return false
+}
+
+// duplicated returns true if a packet is selected by the duplicate rules
+func (n *Nylon) duplicated(packet *device.TCElement) bool {
+	rules := n.duplicate.rules.Load()
+	if rules == nil || len(*rules) == 0 {
+		return false
+	}
+	if ver := packet.GetIPVersion(); ver != 4 && ver != 6 {
+		return false
+	}
+	return state.MatchDuplicate(*rules, packet.GetDst(), packet.GetDSCP())
+}
+
+// leavesMesh returns true if an incoming packet is delivered by this node rather than forwarded to another router.
+// With system routing, every incoming packet leaves the data path of nylon.
+func (n *Nylon) leavesMesh(packet *device.TCElement) bool {
+	if n.UseSystemRouting {
+		return true
+	}
+	tables := n.router.Tables.Load()
+	if entry, ok := tables.LookupExit(packet.GetSrc(), packet.GetDst()); ok && entry.Nh == n.LocalCfg.Id {
+		return true
+	}
+	entry, ok := tables.LookupForward(packet.GetSrc(), packet.GetDst())
+	if !ok || entry.Blackhole {
+		return false
+	}
+	_, peer := entry.Select(packet)
+	return !n.fromRouter(peer)
+}
+
+// dedupIncoming returns true if an incoming packet is a copy of a duplicated packet that already arrived, and should be
+// dropped where it leaves the mesh. Only routers tag the packets they duplicate, so a tag from any other peer is
+// ignored, and it is not carried further.
+func (n *Nylon) dedupIncoming(packet *device.TCElement) bool {
+	if !packet.Incoming() || packet.DuplicateTag == 0 {
+		return false
+	}
+	if !n.fromRouter(packet.FromPeer) {
+		packet.DuplicateTag = 0
+		return false
+	}
+	if !n.leavesMesh(packet) {
+		return false
+	}
+	if n.dropDuplicate(packet) {
+		return true
+	}
+	packet.DuplicateTag = 0
+	return false
+}
+
+// dropDuplicate returns true if the packet is a copy of a duplicated packet that was already received. Only the copies
+// made by sendDuplicate carry a DuplicateTag, and every transmission gets its own, so a resend is never dropped.
+func (n *Nylon) dropDuplicate(packet *device.TCElement) bool {
+	if packet.DuplicateTag == 0 {
+		return false
+	}
+	key := packet.PacketHash() ^ packet.DuplicateTag
+	n.duplicate.mu.Lock()
+	seen := n.duplicate.window.observe(key, time.Now(), n.DuplicateWindow, n.DuplicateWindowPackets)
+	n.duplicate.mu.Unlock()
+	if seen {
+		n.duplicate.dropped.Add(1)
+	}
+	return seen
+}
+
+// sendDuplicate attaches a copy of a selected packet that is forwarded over a second path. The copy goes to another
+// multipath next hop of the route if there is one, or otherwise through the next best endpoint of the same neighbour.
+// It is only called where packets enter the mesh, and tags both copies so the node where they leave can tell them
+// from a resend. Packets without room for the tag within the MTU are sent once.
+func (n *Nylon) sendDuplicate(packet *device.TCElement, entry RouteTableEntry) {
+	if !n.duplicated(packet) || !n.Device.CanTagDuplicate(packet) {
+		return
+	}
+	packet.DuplicateTag = rand.Uint64() | 1
+	for _, hop := range entry.Multipath {
+		if hop.Peer == nil || hop.Peer == packet.ToPeer {
+			continue
+		}
+		dup := n.Device.DuplicateTCElement(packet)
+		dup.ToPeer = hop.Peer
+		n.sendThrough(dup)
+		n.duplicate.sent.Add(1)
+		return
+	}
+	links := n.links.Load()
+	if links == nil || packet.ToPeer == nil {
+		return
+	}
+	pl := (*links)[packet.ToPeer]
+	if pl == nil || len(pl.ranked) < 2 {
+		return
+	}
+	if packet.ToEp == nil {
+		// pin the packet to an endpoint, so the copy is sure to take another
+		if pl.fallback != nil && pl.fallback.WgEndpoint != nil {
+			packet.ToEp = pl.fallback.WgEndpoint
+		} else {
+			packet.ToEp = pl.ranked[0].wg
+		}
+	}
+	for _, slot := range pl.ranked {
+		if slot.wg.DstIPPort() == packet.ToEp.DstIPPort() {
+			continue
+		}
+		dup := n.Device.DuplicateTCElement(packet)
+		dup.ToPeer = packet.ToPeer
+		dup.ToEp = slot.wg
+		slot.ep.Traffic.CountTx(len(dup.Packet))
+		n.duplicate.sent.Add(1)
+		return
+	}
+}
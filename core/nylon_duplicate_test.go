@@ -0,0 +1,157 @@
+package core
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/conn"
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/polyamide/tun/tuntest"
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupWindow(t *testing.T) {
+	var w dedupWindow
+	now := time.Now()
+	assert.False(t, w.observe(1, now, time.Second, 2))
+	assert.True(t, w.observe(1, now.Add(time.Millisecond), time.Second, 2), "the second copy is a duplicate")
+	assert.False(t, w.observe(1, now.Add(2*time.Second), time.Second, 2), "copies outside the window are kept")
+
+	// the oldest packets are forgotten once the window is full
+	assert.False(t, w.observe(2, now, time.Second, 2))
+	assert.False(t, w.observe(3, now, time.Second, 2))
+	assert.False(t, w.observe(1, now, time.Second, 2))
+	assert.True(t, w.observe(3, now, time.Second, 2))
+}
+
+func TestSendDuplicate(t *testing.T) {
+	dev := device.NewDevice(tuntest.NewChannelTUN().TUN(), conn.NewDefaultBind(), device.NewLogger(device.LogLevelError, ""))
+	defer dev.Close()
+	tunables := state.DefaultRouterTunables()
+	bind := conn.NewStdNetBind()
+	slot := func(address string) bondSlot {
+		wg, err := bind.ParseEndpoint(address)
+		require.NoError(t, err)
+		return bondSlot{wg: wg, ep: state.NewEndpoint(address, false, wg, &tunables)}
+	}
+	a, b := slot("192.0.2.1:57175"), slot("198.51.100.1:57175")
+	peer, other := new(device.Peer), new(device.Peer)
+	links := linkTable{peer: {ranked: []bondSlot{a, b}}}
+	n := &Nylon{Device: dev}
+	n.links.Store(&links)
+	rules := []state.DuplicateRule{{Prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}}}
+	n.duplicate.rules.Store(&rules)
+
+	packet := func(dst string) *device.TCElement {
+		tce := dev.NewTCElement()
+		tce.InitPacket(4, 28)
+		tce.SetDst(netip.MustParseAddr(dst))
+		tce.ToPeer = peer
+		return tce
+	}
+
+	// packets not selected by a rule are sent once
+	other1 := packet("10.0.1.1")
+	n.sendDuplicate(other1, RouteTableEntry{Peer: peer})
+	assert.Nil(t, other1.Duplicate)
+
+	// without a second next hop, the copy takes the next best endpoint
+	voice := packet("10.0.0.1")
+	n.sendDuplicate(voice, RouteTableEntry{Peer: peer})
+	require.NotNil(t, voice.Duplicate)
+	assert.Equal(t, a.wg, voice.ToEp)
+	assert.Equal(t, peer, voice.Duplicate.ToPeer)
+	assert.Equal(t, b.wg, voice.Duplicate.ToEp)
+	assert.Equal(t, voice.Packet, voice.Duplicate.Packet)
+	assert.NotZero(t, voice.DuplicateTag)
+	assert.Equal(t, voice.DuplicateTag, voice.Duplicate.DuplicateTag)
+	assert.Equal(t, uint64(1), b.ep.Traffic.TxPackets.Load())
+
+	// a multipath route sends the copy to another next hop
+	voice = packet("10.0.0.1")
+	n.sendDuplicate(voice, RouteTableEntry{Peer: peer, Multipath: []NextHop{{Nh: "a", Peer: peer}, {Nh: "b", Peer: other}}})
+	require.NotNil(t, voice.Duplicate)
+	assert.Equal(t, other, voice.Duplicate.ToPeer)
+	assert.Equal(t, uint64(2), n.duplicate.sent.Load())
+
+	// packets without room for the tag within the mtu are sent once
+	large := dev.NewTCElement()
+	large.InitPacket(4, tuntest.DefaultMTU)
+	large.SetDst(netip.MustParseAddr("10.0.0.1"))
+	large.ToPeer = peer
+	n.sendDuplicate(large, RouteTableEntry{Peer: peer, Multipath: []NextHop{{Nh: "a", Peer: peer}, {Nh: "b", Peer: other}}})
+	assert.Nil(t, large.Duplicate)
+	assert.Zero(t, large.DuplicateTag)
+}
+
+func TestDropDuplicateLetsResendsThrough(t *testing.T) {
+	dev := device.NewDevice(tuntest.NewChannelTUN().TUN(), conn.NewDefaultBind(), device.NewLogger(device.LogLevelError, ""))
+	defer dev.Close()
+	peer := new(device.Peer)
+	links := linkTable{}
+	n := &Nylon{Device: dev, RouterTunables: state.DefaultRouterTunables()}
+	n.links.Store(&links)
+	rules := []state.DuplicateRule{{Prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}}}
+	n.duplicate.rules.Store(&rules)
+
+	send := func() *device.TCElement {
+		tce := dev.NewTCElement()
+		tce.InitPacket(4, 28)
+		tce.SetDst(netip.MustParseAddr("10.0.0.1"))
+		tce.ToPeer = peer
+		n.sendDuplicate(tce, RouteTableEntry{Peer: peer, Multipath: []NextHop{{Nh: "a", Peer: peer}, {Nh: "b", Peer: new(device.Peer)}}})
+		require.NotNil(t, tce.Duplicate)
+		return tce
+	}
+
+	// the copy that arrives second is dropped
+	first := send()
+	assert.False(t, n.dropDuplicate(first))
+	assert.True(t, n.dropDuplicate(first.Duplicate))
+
+	// an identical resend within the window is a new transmission, and gets through
+	resend := send()
+	assert.Equal(t, first.Packet, resend.Packet)
+	assert.False(t, n.dropDuplicate(resend))
+	assert.True(t, n.dropDuplicate(resend.Duplicate))
+
+	// packets that were not duplicated are never dropped
+	plain := dev.NewTCElement()
+	plain.InitPacket(4, 28)
+	plain.SetDst(netip.MustParseAddr("10.0.0.1"))
+	assert.False(t, n.dropDuplicate(plain))
+	assert.False(t, n.dropDuplicate(plain))
+	assert.Equal(t, uint64(2), n.duplicate.dropped.Load())
+}
+
+func TestDedupIgnoresTagsFromNonRouters(t *testing.T) {
+	dev := device.NewDevice(tuntest.NewChannelTUN().TUN(), conn.NewDefaultBind(), device.NewLogger(device.LogLevelError, ""))
+	defer dev.Close()
+	bobKey, phoneKey := state.GenerateKey().Pubkey(), state.GenerateKey().Pubkey()
+	bob, err := dev.NewPeer(device.NoisePublicKey(bobKey))
+	require.NoError(t, err)
+	phone, err := dev.NewPeer(device.NoisePublicKey(phoneKey))
+	require.NoError(t, err)
+	n := &Nylon{Device: dev, RouterTunables: state.DefaultRouterTunables()}
+	n.UseSystemRouting = true
+	n.routerPeers.Store(&map[state.NyPublicKey]struct{}{bobKey: {}})
+
+	receive := func(from *device.Peer) *device.TCElement {
+		packet := testTCPPacket("10.0.0.2", "10.0.0.1", 1000, 2000, from)
+		packet.DuplicateTag = 0x1234
+		return packet
+	}
+
+	// a passive client cannot get our packets dropped, and its tag is not carried further
+	fromPhone := receive(phone)
+	assert.False(t, n.dedupIncoming(fromPhone))
+	assert.Zero(t, fromPhone.DuplicateTag)
+	assert.False(t, n.dedupIncoming(receive(phone)))
+
+	// the second copy from a router is dropped
+	assert.False(t, n.dedupIncoming(receive(bob)))
+	assert.True(t, n.dedupIncoming(receive(bob)))
+}
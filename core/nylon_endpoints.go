@@ -2,6 +2,7 @@ package core
 
 import (
 	"cmp"
+	"context"
 	"fmt"
 	"math/rand/v2"
 	"slices"
@@ -17,7 +18,10 @@ import (
 type EpPing struct {
 	TimeSent time.Time
 	Peer     state.NodeId
+	Endpoint *state.NylonEndpoint
 	Complete func(protocol.EndpointProbeStatus, time.Duration)
+	// Liveness probes are sent by fast failure detection, they only keep the endpoint active
+	Liveness bool
 }
 
 func (n *Nylon) sendEndpointProbes(peer state.NodeId, timeout time.Duration) ([]Future[*protocol.EndpointProbeResult], error) {
@@ -31,18 +35,24 @@ func (n *Nylon) sendEndpointProbes(peer state.NodeId, timeout time.Duration) ([]
 	})
 	probes := make([]Future[*protocol.EndpointProbeResult], 0, len(eps))
 	for _, ep := range eps {
-		result, _ := n.sendEndpointProbe(neigh.Id, ep.AsNylonEndpoint(), timeout)
+		result, _ := n.sendEndpointProbe(neigh.Id, ep.AsNylonEndpoint(), timeout, false)
 		probes = append(probes, result)
 	}
 	return probes, nil
 }
 
 func (n *Nylon) Probe(node state.NodeId, ep *state.NylonEndpoint) error {
-	_, err := n.sendEndpointProbe(node, ep, 0)
+	_, err := n.sendEndpointProbe(node, ep, 0, false)
 	return err
 }
 
-func (n *Nylon) sendEndpointProbe(node state.NodeId, ep *state.NylonEndpoint, timeout time.Duration) (Future[*protocol.EndpointProbeResult], error) {
+// ProbeLiveness sends a liveness probe for fast failure detection, the reply renews the endpoint without measuring it
+func (n *Nylon) ProbeLiveness(node state.NodeId, ep *state.NylonEndpoint) error {
+	_, err := n.sendEndpointProbe(node, ep, 0, true)
+	return err
+}
+
+func (n *Nylon) sendEndpointProbe(node state.NodeId, ep *state.NylonEndpoint, timeout time.Duration, liveness bool) (Future[*protocol.EndpointProbeResult], error) {
 	address := ep.Address
 	resolved := ""
 	resultFuture, completeResult := NewFuture[*protocol.EndpointProbeResult]()
@@ -81,6 +91,9 @@ func (n *Nylon) sendEndpointProbe(node state.NodeId, ep *state.NylonEndpoint, ti
 			},
 		},
 	}
+	if !ep.IsRemote() {
+		ping.GetProbeOp().Endpoint = &address
+	}
 	var timeoutTimer *time.Timer
 	if timeout > 0 {
 		timeoutTimer = time.AfterFunc(timeout, func() {
@@ -89,16 +102,22 @@ func (n *Nylon) sendEndpointProbe(node state.NodeId, ep *state.NylonEndpoint, ti
 		})
 	}
 
+	ttl := ttlcache.DefaultTTL
+	if liveness {
+		ttl = ep.DeadThreshold()
+	}
 	n.PingBuf.Set(token, EpPing{
 		TimeSent: sentAt,
 		Peer:     node,
+		Endpoint: ep,
 		Complete: func(status protocol.EndpointProbeStatus, latency time.Duration) {
 			if timeoutTimer != nil {
 				timeoutTimer.Stop()
 			}
 			completeEndpoint(status, latency, nil)
 		},
-	}, ttlcache.DefaultTTL)
+		Liveness: liveness,
+	}, ttl)
 
 	go func() {
 		err := n.SendNylon(ping, nep, peer)
@@ -114,6 +133,16 @@ func (n *Nylon) sendEndpointProbe(node state.NodeId, ep *state.NylonEndpoint, ti
 	return resultFuture, nil
 }
 
+// handleProbeEviction counts probes that expired from the ping buffer without a reply as lost
+func handleProbeEviction(_ context.Context, reason ttlcache.EvictionReason, item *ttlcache.Item[uint64, EpPing]) {
+	if reason != ttlcache.EvictionReasonExpired || item.Value().Liveness {
+		return
+	}
+	if ep := item.Value().Endpoint; ep != nil {
+		ep.RecordProbeLoss()
+	}
+}
+
 func handleProbe(n *Nylon, pkt *protocol.Ny_Probe, endpoint conn.Endpoint, peer *device.Peer, node state.NodeId) {
 	if pkt.ResponseToken == nil {
 		// ping
@@ -132,7 +161,7 @@ func handleProbe(n *Nylon, pkt *protocol.Ny_Probe, endpoint conn.Endpoint, peer
 		}
 
 		n.Dispatch(func() error {
-			handleProbePing(n, node, endpoint)
+			handleProbePing(n, node, pkt.GetEndpoint(), endpoint)
 			return nil
 		})
 	} else {
@@ -144,10 +173,12 @@ func handleProbe(n *Nylon, pkt *protocol.Ny_Probe, endpoint conn.Endpoint, peer
 	}
 }
 
-func handleProbePing(n *Nylon, node state.NodeId, wgEndpoint conn.Endpoint) {
+func handleProbePing(n *Nylon, node state.NodeId, localAddress string, wgEndpoint conn.Endpoint) {
 	if node == n.LocalCfg.Id {
 		return
 	}
+	// remote endpoints take the attributes of the local endpoint the ping was sent to
+	local, hasLocal := n.GetRouter(n.LocalCfg.Id).Endpoint(localAddress)
 	// check if link exists
 	for _, neigh := range n.RouterState.Neighbours {
 		for _, dep := range neigh.Eps {
@@ -158,6 +189,9 @@ func handleProbePing(n *Nylon, node state.NodeId, wgEndpoint conn.Endpoint) {
 
 				// refresh wireguard ep
 				dep.WgEndpoint = wgEndpoint
+				if hasLocal && dep.IsRemote() {
+					dep.ReachedLocal(local.EndpointAttrs)
+				}
 
 				wasInactive := !dep.IsActive()
 				dep.Renew()
@@ -177,8 +211,11 @@ func handleProbePing(n *Nylon, node state.NodeId, wgEndpoint conn.Endpoint) {
 	for _, neigh := range n.RouterState.Neighbours {
 		if neigh.Id == node {
 			newEp := state.NewEndpoint(wgEndpoint.DstIPPort().String(), true, wgEndpoint, &n.RouterTunables)
+			if hasLocal {
+				newEp.ReachedLocal(local.EndpointAttrs)
+			}
 			newEp.Renew()
-			neigh.Eps = append(neigh.Eps, newEp)
+			neigh.AddEndpoint(newEp)
 			// push route update to improve convergence time
 			ComputeRoutes(n.RouterState, n)
 			n.UpdateNeighbour(node)
@@ -208,6 +245,16 @@ func handleProbePong(n *Nylon, node state.NodeId, token uint64, ep conn.Endpoint
 			ap, err := n.EndpointResolver.Get(dpLink.Address)
 			if err == nil && ap == ep.DstIPPort() && neigh.Id == node {
 				// we have a link
+				if health.Liveness {
+					// rtt is measured by the regular probes
+					wasInactive := !dpLink.IsActive()
+					dpLink.Renew()
+					dpLink.WgEndpoint = ep
+					if wasInactive {
+						ComputeRoutes(n.RouterState, n)
+					}
+					return
+				}
 				if n.DBG_log_probe {
 					n.Log.Debug("probe back", "peer", node, "ping", latency)
 				}
@@ -225,11 +272,12 @@ func handleProbePong(n *Nylon, node state.NodeId, token uint64, ep conn.Endpoint
 	n.Log.Warn("probe came back and couldn't find link", "from", ep.DstToString(), "node", node)
 }
 
-func (n *Nylon) probeLinks(active bool) error {
-	// probe links
+// probeInactive probes the endpoints that are down, active endpoints are probed at an adaptive rate by probeActive
+func (n *Nylon) probeInactive() error {
 	for _, neigh := range n.RouterState.Neighbours {
 		for _, ep := range neigh.Eps {
-			if ep.IsActive() == active {
+			// metered endpoints are only probed again by discovery
+			if !ep.IsActive() && !ep.AsNylonEndpoint().Attrs().Metered {
 				err := n.Probe(neigh.Id, ep.AsNylonEndpoint())
 				if err != nil {
 					n.Log.Debug("probe failed", "err", err.Error())
@@ -251,14 +299,15 @@ func (n *Nylon) probeNew() error {
 			continue
 		}
 		cfg := n.GetRouter(peer)
-		for _, address := range cfg.Endpoints {
+		for _, ep := range cfg.Endpoints {
 			idx := slices.IndexFunc(neigh.Eps, func(link state.Endpoint) bool {
-				return !link.IsRemote() && link.AsNylonEndpoint().Address == address
+				return !link.IsRemote() && link.AsNylonEndpoint().Address == ep.Address
 			})
 			if idx == -1 {
 				// add the link to the neighbour
-				dpl := state.NewEndpoint(address, false, nil, &n.RouterTunables)
-				neigh.Eps = append(neigh.Eps, dpl)
+				nep := state.NewEndpoint(ep.Address, false, nil, &n.RouterTunables)
+				nep.SetAttrs(ep.EndpointAttrs)
+				neigh.AddEndpoint(nep)
 				idx = len(neigh.Eps) - 1
 			}
 			dpl := neigh.Eps[idx].AsNylonEndpoint()
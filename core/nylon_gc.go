@@ -1,5 +1,7 @@
 package core
 
+import "time"
+
 func nylonGc(n *Nylon) error {
 	activeAddresses := make(map[string]struct{})
 	for _, neigh := range n.RouterState.Neighbours {
@@ -32,6 +34,14 @@ func nylonGc(n *Nylon) error {
 		neigh.Eps = neigh.Eps[:count]
 	}
 
+	n.syncLinks()
+	n.syncSources()
+	if acl := n.acl.Load(); acl != nil {
+		acl.flows.deleteExpired(time.Now())
+	}
+	n.pruneQoS()
+	n.prunePortForwards()
+
 	err := n.GcRouter()
 	if err != nil {
 		return err
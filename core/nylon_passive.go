@@ -28,7 +28,7 @@ func scanPassivePeers(n *Nylon) error {
 			for _, prefix := range ncfg.Prefixes {
 				for _, neigh := range n.RouterState.Neighbours {
 					for _, route := range neigh.Routes {
-						if route.Prefix == prefix.GetPrefix() && route.NodeId != n.LocalCfg.Id && route.FD.Metric != state.INF {
+						if route.RoutePrefix == prefix.GetRoutePrefix() && route.NodeId != n.LocalCfg.Id && route.FD.Metric != state.INF {
 							hasOtherAdvertisers = true
 							goto foundAdvertiser
 						}
@@ -43,7 +43,7 @@ func scanPassivePeers(n *Nylon) error {
 			if n.IsClient(*nid) {
 				// we have a passive client
 				for _, newPrefix := range ncfg.Prefixes {
-					recentlyAdvertised := n.hasRecentlyAdvertised(newPrefix.GetPrefix())
+					recentlyAdvertised := n.hasRecentlyAdvertised(newPrefix.GetRoutePrefix())
 					if recentlyUpdated || !hasOtherAdvertisers && recentlyAdvertised {
 						n.updatePassiveClient(newPrefix, *nid, !recentlyUpdated)
 					}
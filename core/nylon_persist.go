@@ -0,0 +1,61 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+
+	"github.com/encodeous/nylon/state"
+	"github.com/goccy/go-yaml"
+)
+
+// restoreSeqnoMargin is how far each restored seqno is advanced. Seqnos are written every StatePersistDelay, so
+// the ones we advertised after the last write are lost on a crash. The margin keeps our first advertisements newer
+// than those, and if a seqno moved further, the seqno requests of neighbours raise it again.
+const restoreSeqnoMargin = 64
+
+// restoreRouterState loads the seqnos we originated before the last restart. Each seqno is advanced by
+// restoreSeqnoMargin, so our first advertisements are newer than anything neighbours remember and are feasible
+// immediately.
+func (n *Nylon) restoreRouterState() {
+	if n.StatePath == "" {
+		return
+	}
+	snapshot, err := state.ReadRouterSnapshot(n.StatePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		n.router.log.Warn("failed to read router state, starting from seqno 0", "path", n.StatePath, "err", err)
+		return
+	}
+	for _, entry := range snapshot.Seqnos {
+		n.RouterState.SetSeqno(entry.RoutePrefix(), entry.Seqno+restoreSeqnoMargin)
+	}
+	if snapshot.Generation > n.CentralCfg.Timestamp {
+		n.router.log.Warn("central config is older than the config applied before the restart", "config", n.CentralCfg.Timestamp, "applied", snapshot.Generation)
+	}
+	n.router.log.Info("restored router state", "path", n.StatePath, "seqnos", len(snapshot.Seqnos))
+}
+
+// persistRouterState writes the router state file if it changed since the last write. It runs every
+// StatePersistDelay and on shutdown, never in reaction to a neighbour, so peers cannot make the routing loop wait on
+// the disk.
+func (n *Nylon) persistRouterState() error {
+	if n.StatePath == "" {
+		return nil
+	}
+	data, err := yaml.Marshal(n.RouterState.Snapshot(n.CentralCfg.Timestamp))
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, n.router.persisted) {
+		return nil
+	}
+	if err := state.WriteRouterSnapshot(n.StatePath, data); err != nil {
+		n.router.log.Warn("failed to write router state", "path", n.StatePath, "err", err)
+		return nil
+	}
+	n.router.persisted = data
+	return nil
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterStateSurvivesRestart(t *testing.T) {
+	lan := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.1.0.0/16")}
+	path := filepath.Join(t.TempDir(), "nylon.state")
+
+	n := testNylonWithPrefixes()
+	n.StatePath = path
+	n.router.log = n.Log
+	n.RouterState.SetSeqno(lan, 41)
+	require.NoError(t, n.persistRouterState())
+	written, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// nothing changed, so the file is left alone
+	require.NoError(t, n.persistRouterState())
+	unchanged, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(written, unchanged))
+
+	restarted := testNylonWithPrefixes()
+	restarted.StatePath = path
+	restarted.router.log = restarted.Log
+	restarted.restoreRouterState()
+	assert.Equal(t, uint16(41+restoreSeqnoMargin), restarted.RouterState.GetSeqno(lan))
+
+	// a missing state file is a fresh start
+	fresh := testNylonWithPrefixes()
+	fresh.StatePath = filepath.Join(t.TempDir(), "nylon.state")
+	fresh.router.log = fresh.Log
+	fresh.restoreRouterState()
+	assert.Empty(t, fresh.RouterState.SelfSeqno)
+}
+
+func TestSeqnoIsPersistedPeriodically(t *testing.T) {
+	lan := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.1.0.0/16")}
+	path := filepath.Join(t.TempDir(), "nylon.state")
+
+	n := testNylonWithPrefixes()
+	n.StatePath = path
+	n.router.log = n.Log
+	n.RouterState.SetSeqno(lan, 41)
+	require.NoError(t, n.persistRouterState())
+
+	// a passive client going into hold bumps the seqno, which is only written by the next periodic persist
+	n.updatePassiveClient(state.PrefixHealthWrapper{PrefixHealth: &state.StaticPrefixHealth{Prefix: lan.Prefix}}, "client", true)
+	snapshot, err := state.ReadRouterSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, []state.SnapshotSeqno{{Prefix: lan.Prefix, Seqno: 41}}, snapshot.Seqnos)
+
+	// after a crash, the restored seqno is still newer than the one that was advertised but not written
+	restarted := testNylonWithPrefixes()
+	restarted.StatePath = path
+	restarted.router.log = restarted.Log
+	restarted.restoreRouterState()
+	assert.True(t, SeqnoGt(restarted.RouterState.GetSeqno(lan), n.RouterState.GetSeqno(lan)))
+
+	require.NoError(t, n.persistRouterState())
+	snapshot, err = state.ReadRouterSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, []state.SnapshotSeqno{{Prefix: lan.Prefix, Seqno: 42}}, snapshot.Seqnos)
+}
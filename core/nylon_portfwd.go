@@ -0,0 +1,239 @@
+package core
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"sync"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// ports handed out to connections that are forwarded into the mesh, when their own source port is taken
+const (
+	natPortFirst    = 49152
+	natPortLast     = 65535
+	natPortAttempts = 32
+)
+
+// portForwardTable holds the port forwards of this node and the connections through them
+type portForwardTable struct {
+	targets map[natListener]netip.AddrPort
+	// mu serialises the creation of connections, so the packets of a new connection get the same translation
+	mu sync.Mutex
+	// flows maps the tuple of the packets of a connection, in either direction, to the connection
+	flows *ttlcache.Cache[natTuple, *natFlow]
+}
+
+type natListener struct {
+	proto  uint8
+	listen netip.AddrPort
+}
+
+type natTuple struct {
+	src, dst netip.AddrPort
+	proto    uint8
+}
+
+// natFlow is a connection through a port forward. Packets matching orig are translated to the reverse of reply, and
+// packets matching reply to the reverse of orig.
+type natFlow struct {
+	orig, reply natTuple
+	// local is set if the target is reached through the host's routing table rather than the mesh
+	local bool
+}
+
+// initPortForwards builds the port forwards of the node config that listen on an address of this node, which do not
+// change while nylon is running
+func (n *Nylon) initPortForwards() error {
+	node := n.TryGetNode(n.LocalCfg.Id)
+	var forwards []state.PortForward
+	for _, fwd := range n.PortForwards {
+		if node == nil || !fwd.ListensOnHost(*node) {
+			forwards = append(forwards, fwd)
+		}
+	}
+	if len(forwards) == 0 {
+		return nil
+	}
+	t := &portForwardTable{
+		targets: make(map[natListener]netip.AddrPort),
+		flows: ttlcache.New[natTuple, *natFlow](
+			ttlcache.WithCapacity[natTuple, *natFlow](n.PortForwardCapacity),
+		),
+	}
+	for _, fwd := range forwards {
+		proto, err := state.PortForwardProto(fwd.Proto)
+		if err != nil {
+			return err
+		}
+		t.targets[natListener{proto: proto, listen: fwd.Listen}] = fwd.To
+	}
+	n.portForwards = t
+	return nil
+}
+
+func packetTuple(packet *device.TCElement) (natTuple, bool) {
+	if ver := packet.GetIPVersion(); ver != 4 && ver != 6 {
+		return natTuple{}, false
+	}
+	proto := packet.GetProtocol()
+	if proto != device.ProtoTCP && proto != device.ProtoUDP {
+		return natTuple{}, false
+	}
+	sport, dport, ok := packet.GetPorts()
+	if !ok {
+		return natTuple{}, false
+	}
+	return natTuple{
+		src:   netip.AddrPortFrom(packet.GetSrc(), sport),
+		dst:   netip.AddrPortFrom(packet.GetDst(), dport),
+		proto: proto,
+	}, true
+}
+
+// forwardPort translates the packets that reach a forwarded port, and returns TcBounce if the target is reached
+// through the host. It runs after the acl, so the acl applies to the listen address. IPv4 fragments to a listen
+// address are dropped, as only the first one carries the ports to translate it by.
+func (n *Nylon) forwardPort(packet *device.TCElement) device.TCAction {
+	t := n.portForwards
+	if t == nil {
+		return device.TcPass
+	}
+	tuple, ok := packetTuple(packet)
+	if !ok {
+		if packet.IsFragment() && t.listensOn(packet.GetProtocol(), packet.GetDst()) {
+			return device.TcDrop
+		}
+		return device.TcPass
+	}
+	var flow *natFlow
+	if item := t.flows.Get(tuple); item != nil {
+		flow = item.Value()
+		if tuple != flow.orig {
+			return device.TcPass
+		}
+		t.flows.Touch(flow.reply)
+	} else {
+		target, ok := t.targets[natListener{proto: tuple.proto, listen: tuple.dst}]
+		if !ok || !packet.Incoming() {
+			return device.TcPass
+		}
+		flow = n.openPortForward(t, tuple, target)
+		if flow == nil {
+			return device.TcDrop
+		}
+	}
+	packet.TranslateSrc(flow.reply.dst)
+	packet.TranslateDst(flow.reply.src)
+	if flow.local {
+		return device.TcBounce
+	}
+	return device.TcPass
+}
+
+// reversePortForward translates the replies of connections through a port forward. It runs before the acl, so the
+// acl sees the replies as coming from the listen address.
+func (n *Nylon) reversePortForward(packet *device.TCElement) {
+	t := n.portForwards
+	if t == nil {
+		return
+	}
+	tuple, ok := packetTuple(packet)
+	if !ok {
+		return
+	}
+	item := t.flows.Get(tuple)
+	if item == nil || tuple != item.Value().reply {
+		return
+	}
+	flow := item.Value()
+	t.flows.Touch(flow.orig)
+	packet.TranslateSrc(flow.orig.dst)
+	packet.TranslateDst(flow.orig.src)
+}
+
+// openPortForward tracks a new connection to a forwarded port. Connections to targets in the mesh are given the
+// listen address as their source, keeping the source port if it is free. It returns nil if no port is free.
+func (n *Nylon) openPortForward(t *portForwardTable, orig natTuple, target netip.AddrPort) *natFlow {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if item := t.flows.Get(orig); item != nil {
+		return item.Value()
+	}
+	flow := &natFlow{orig: orig, local: !n.routedByMesh(orig.dst.Addr(), target.Addr())}
+	flow.reply = natTuple{src: target, dst: orig.src, proto: orig.proto}
+	if flow.local {
+		if t.inUse(flow.reply) {
+			// the same source is connected to the target through another forward, its replies would be ambiguous
+			return nil
+		}
+	} else {
+		flow.reply.dst = netip.AddrPortFrom(orig.dst.Addr(), orig.src.Port())
+		for i := 0; t.inUse(flow.reply); i++ {
+			if i == natPortAttempts {
+				n.Log.Debug("no free port to forward a connection", "listen", orig.dst, "to", target)
+				return nil
+			}
+			flow.reply.dst = netip.AddrPortFrom(orig.dst.Addr(), uint16(natPortFirst+rand.IntN(natPortLast-natPortFirst+1)))
+		}
+	}
+	ttl := n.PortForwardUDPTimeout
+	if orig.proto == device.ProtoTCP {
+		ttl = n.PortForwardTCPTimeout
+	}
+	t.flows.Set(flow.orig, flow, ttl)
+	t.flows.Set(flow.reply, flow, ttl)
+	return flow
+}
+
+// listensOn returns true if a port forward of a protocol listens on an address
+func (t *portForwardTable) listensOn(proto uint8, addr netip.Addr) bool {
+	for l := range t.targets {
+		if l.proto == proto && l.listen.Addr() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// inUse returns true if a tuple belongs to a tracked connection. Either tuple of a connection may be evicted first
+// when the table is full, the other one is then stale.
+func (t *portForwardTable) inUse(tuple natTuple) bool {
+	item := t.flows.Get(tuple, ttlcache.WithDisableTouchOnHit[natTuple, *natFlow]())
+	return item != nil && t.flows.Has(item.Value().orig) && t.flows.Has(item.Value().reply)
+}
+
+// routedByMesh returns true if the mesh forwards packets to an address to another node
+func (n *Nylon) routedByMesh(src, dst netip.Addr) bool {
+	tables := n.router.Tables.Load()
+	if tables == nil {
+		return false
+	}
+	if exit, ok := tables.LookupExit(src, dst); ok && exit.Nh == n.LocalCfg.Id {
+		return false
+	}
+	_, ok := tables.LookupForward(src, dst)
+	return ok
+}
+
+// portForwardConnections returns the number of connections tracked through the port forwards
+func (n *Nylon) portForwardConnections() int {
+	count := 0
+	if n.portForwards != nil {
+		// every connection is tracked under its tuple in both directions
+		count = n.portForwards.flows.Len() / 2
+	}
+	for _, h := range n.hostForwards {
+		count += h.connections()
+	}
+	return count
+}
+
+// prunePortForwards forgets the connections that were idle for longer than their timeout
+func (n *Nylon) prunePortForwards() {
+	if n.portForwards != nil {
+		n.portForwards.flows.DeleteExpired()
+	}
+}
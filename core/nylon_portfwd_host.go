@@ -0,0 +1,251 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/encodeous/nylon/state"
+)
+
+const hostForwardDialTimeout = 10 * time.Second
+
+// hostForward is a port forward that listens on the host, e.g. on a public address, and relays the connections it
+// accepts to a target in the mesh. The relayed connections leave from the address of this node through the nylon
+// interface, so they pass the traffic control filters and the acl like any other traffic of this node.
+//
+// Unlike the port forwards to addresses in the mesh, which translate packets in forwardPort, it is a proxy: packets to
+// an address of the host arrive on its other interfaces and never reach the traffic control filters, and translating
+// them would take netfilter, which port forwards do without. The target therefore sees this node rather than the
+// client, and tcp connections are terminated on the host.
+type hostForward struct {
+	fwd state.PortForward
+	// src is the address of this node the relayed connections leave from, the host chooses one if it is invalid
+	src   netip.Addr
+	tcp   net.Listener
+	udp   *net.UDPConn
+	conns atomic.Int64 // tcp connections currently relayed
+
+	mu       sync.Mutex
+	sessions map[netip.AddrPort]*hostSession
+}
+
+// hostSession relays the datagrams of one udp client
+type hostSession struct {
+	conn *net.UDPConn
+	seen atomic.Int64 // unix nanoseconds of the last datagram from the client
+}
+
+// startHostPortForwards starts listening on the port forwards that listen on the host, they are closed with nylon
+func (n *Nylon) startHostPortForwards() error {
+	node := n.TryGetNode(n.LocalCfg.Id)
+	if node == nil {
+		return nil
+	}
+	for _, fwd := range n.PortForwards {
+		if !fwd.ListensOnHost(*node) {
+			continue
+		}
+		h := &hostForward{fwd: fwd, sessions: make(map[netip.AddrPort]*hostSession)}
+		for _, addr := range node.Addresses {
+			if addr.Is4() == fwd.To.Addr().Is4() {
+				h.src = addr
+				break
+			}
+		}
+		if err := n.listenHostForward(h); err != nil {
+			return err
+		}
+		n.hostForwards = append(n.hostForwards, h)
+		n.Log.Info("forwarding host port into the mesh", "proto", fwd.Proto, "listen", fwd.Listen, "to", fwd.To)
+	}
+	if len(n.hostForwards) > 0 {
+		go func() {
+			<-n.Context.Done()
+			for _, h := range n.hostForwards {
+				h.close()
+			}
+		}()
+	}
+	return nil
+}
+
+// listenHostForward opens the listener of a port forward on the host, and relays what it accepts in the background
+func (n *Nylon) listenHostForward(h *hostForward) error {
+	switch h.fwd.Proto {
+	case "tcp":
+		listener, err := net.Listen("tcp", h.fwd.Listen.String())
+		if err != nil {
+			return fmt.Errorf("listen on port forward %s: %w", h.fwd.Listen, err)
+		}
+		h.tcp = listener
+		go n.serveHostTCP(h)
+	case "udp":
+		conn, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(h.fwd.Listen))
+		if err != nil {
+			return fmt.Errorf("listen on port forward %s: %w", h.fwd.Listen, err)
+		}
+		h.udp = conn
+		go n.serveHostUDP(h)
+	default:
+		return fmt.Errorf("unknown port forward protocol %q, expected tcp or udp", h.fwd.Proto)
+	}
+	return nil
+}
+
+func (h *hostForward) dialer() *net.Dialer {
+	d := &net.Dialer{Timeout: hostForwardDialTimeout}
+	if h.src.IsValid() {
+		if h.fwd.Proto == "tcp" {
+			d.LocalAddr = &net.TCPAddr{IP: h.src.AsSlice()}
+		} else {
+			d.LocalAddr = &net.UDPAddr{IP: h.src.AsSlice()}
+		}
+	}
+	return d
+}
+
+func (n *Nylon) serveHostTCP(h *hostForward) {
+	for {
+		conn, err := h.tcp.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			n.Log.Debug("failed to accept a forwarded connection", "listen", h.fwd.Listen, "err", err)
+			continue
+		}
+		if uint64(h.conns.Load()+h.udpSessions()) >= n.PortForwardCapacity {
+			conn.Close()
+			continue
+		}
+		h.conns.Add(1)
+		go func() {
+			defer h.conns.Add(-1)
+			n.relayHostTCP(h, conn)
+		}()
+	}
+}
+
+// relayHostTCP relays a connection to the target until both sides have closed it
+func (n *Nylon) relayHostTCP(h *hostForward, conn net.Conn) {
+	defer conn.Close()
+	target, err := h.dialer().Dial("tcp", h.fwd.To.String())
+	if err != nil {
+		n.Log.Debug("failed to reach the target of a forwarded connection", "listen", h.fwd.Listen, "to", h.fwd.To, "err", err)
+		return
+	}
+	defer target.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(target, conn)
+		_ = target.(*net.TCPConn).CloseWrite()
+	}()
+	_, _ = io.Copy(conn, target)
+	_ = conn.(*net.TCPConn).CloseWrite()
+	<-done
+}
+
+func (n *Nylon) serveHostUDP(h *hostForward) {
+	buf := make([]byte, 65535)
+	for {
+		size, client, err := h.udp.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			n.Log.Debug("failed to receive a forwarded datagram", "listen", h.fwd.Listen, "err", err)
+			continue
+		}
+		client = netip.AddrPortFrom(client.Addr().Unmap(), client.Port())
+		session := n.hostSession(h, client)
+		if session == nil {
+			continue
+		}
+		session.seen.Store(time.Now().UnixNano())
+		_, _ = session.conn.Write(buf[:size])
+	}
+}
+
+// hostSession returns the session of a udp client, and opens one if it is new. It returns nil if the session cannot
+// be opened.
+func (n *Nylon) hostSession(h *hostForward, client netip.AddrPort) *hostSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if session, ok := h.sessions[client]; ok {
+		return session
+	}
+	if uint64(h.conns.Load()+int64(len(h.sessions))) >= n.PortForwardCapacity {
+		return nil
+	}
+	conn, err := h.dialer().Dial("udp", h.fwd.To.String())
+	if err != nil {
+		n.Log.Debug("failed to reach the target of a forwarded datagram", "listen", h.fwd.Listen, "to", h.fwd.To, "err", err)
+		return nil
+	}
+	session := &hostSession{conn: conn.(*net.UDPConn)}
+	h.sessions[client] = session
+	go n.relayHostReplies(h, client, session)
+	return session
+}
+
+// relayHostReplies relays the replies of the target to a udp client, until the client is idle for longer than the
+// udp timeout
+func (n *Nylon) relayHostReplies(h *hostForward, client netip.AddrPort, session *hostSession) {
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, client)
+		h.mu.Unlock()
+		session.conn.Close()
+	}()
+	buf := make([]byte, 65535)
+	for {
+		idle := time.Until(time.Unix(0, session.seen.Load()).Add(n.PortForwardUDPTimeout))
+		if idle <= 0 {
+			return
+		}
+		_ = session.conn.SetReadDeadline(time.Now().Add(idle))
+		size, err := session.conn.Read(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			// the deadline passed, or the target is unreachable for now
+			continue
+		}
+		if _, err := h.udp.WriteToUDPAddrPort(buf[:size], client); errors.Is(err, net.ErrClosed) {
+			return
+		}
+	}
+}
+
+func (h *hostForward) udpSessions() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return int64(len(h.sessions))
+}
+
+// connections returns the number of tcp connections and udp sessions currently relayed
+func (h *hostForward) connections() int {
+	return int(h.conns.Load() + h.udpSessions())
+}
+
+func (h *hostForward) close() {
+	if h.tcp != nil {
+		_ = h.tcp.Close()
+	}
+	if h.udp != nil {
+		_ = h.udp.Close()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, session := range h.sessions {
+		_ = session.conn.Close()
+	}
+}
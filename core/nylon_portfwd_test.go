@@ -0,0 +1,174 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+	"github.com/gaissmai/bart"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortForward(t *testing.T) {
+	n := &Nylon{RouterTunables: state.DefaultRouterTunables()}
+	n.LocalCfg.Id = "alice"
+	n.PortForwards = []state.PortForward{
+		{Proto: "tcp", Listen: netip.MustParseAddrPort("10.0.0.1:443"), To: netip.MustParseAddrPort("10.0.0.5:8443")},
+		{Proto: "tcp", Listen: netip.MustParseAddrPort("10.0.0.1:8080"), To: netip.MustParseAddrPort("192.168.1.10:80")},
+	}
+	tables := &ForwardingTables{Forward: new(bart.Table[RouteTableEntry]), Exit: new(bart.Table[RouteTableEntry])}
+	tables.Exit.Insert(netip.MustParsePrefix("10.0.0.1/32"), RouteTableEntry{Nh: "alice"})
+	tables.Forward.Insert(netip.MustParsePrefix("10.0.0.5/32"), RouteTableEntry{Nh: "bob"})
+	n.router.Tables.Store(tables)
+	require.NoError(t, n.initPortForwards())
+	peer := new(device.Peer)
+	addrs := func(packet *device.TCElement) (netip.AddrPort, netip.AddrPort) {
+		tuple, ok := packetTuple(packet)
+		require.True(t, ok)
+		assert.True(t, testChecksumsValid(packet.Packet), "the checksums stay valid after translation")
+		return tuple.src, tuple.dst
+	}
+
+	// targets in the mesh are reached from the listen address, so their replies return through us
+	packet := testTCPPacket("10.0.0.9", "10.0.0.1", 40000, 443, peer)
+	assert.Equal(t, device.TcPass, n.forwardPort(packet))
+	src, dst := addrs(packet)
+	assert.Equal(t, netip.MustParseAddrPort("10.0.0.1:40000"), src)
+	assert.Equal(t, netip.MustParseAddrPort("10.0.0.5:8443"), dst)
+
+	reply := testTCPPacket("10.0.0.5", "10.0.0.1", 8443, 40000, peer)
+	n.reversePortForward(reply)
+	src, dst = addrs(reply)
+	assert.Equal(t, netip.MustParseAddrPort("10.0.0.1:443"), src)
+	assert.Equal(t, netip.MustParseAddrPort("10.0.0.9:40000"), dst)
+
+	// another connection with the same source port is given a free port
+	packet = testTCPPacket("10.0.0.8", "10.0.0.1", 40000, 443, peer)
+	assert.Equal(t, device.TcPass, n.forwardPort(packet))
+	src, _ = addrs(packet)
+	assert.GreaterOrEqual(t, src.Port(), uint16(natPortFirst))
+
+	// other targets are reached through the host, and reply through the nylon interface
+	packet = testTCPPacket("10.0.0.9", "10.0.0.1", 40000, 8080, peer)
+	assert.Equal(t, device.TcBounce, n.forwardPort(packet))
+	src, dst = addrs(packet)
+	assert.Equal(t, netip.MustParseAddrPort("10.0.0.9:40000"), src)
+	assert.Equal(t, netip.MustParseAddrPort("192.168.1.10:80"), dst)
+
+	reply = testTCPPacket("192.168.1.10", "10.0.0.9", 80, 40000, nil)
+	n.reversePortForward(reply)
+	src, dst = addrs(reply)
+	assert.Equal(t, netip.MustParseAddrPort("10.0.0.1:8080"), src)
+	assert.Equal(t, netip.MustParseAddrPort("10.0.0.9:40000"), dst)
+
+	// only connections from the mesh are forwarded
+	packet = testTCPPacket("10.0.0.1", "10.0.0.1", 40001, 8080, nil)
+	assert.Equal(t, device.TcPass, n.forwardPort(packet))
+	_, dst = addrs(packet)
+	assert.Equal(t, netip.MustParseAddrPort("10.0.0.1:8080"), dst)
+
+	// fragments cannot be translated without their ports, so those to a listen address are dropped
+	fragment := func(dst string, flags byte) *device.TCElement {
+		packet := testTCPPacket("10.0.0.9", dst, 40000, 443, peer)
+		packet.Packet[device.IPv4offsetFlags] |= flags
+		return packet
+	}
+	assert.Equal(t, device.TcDrop, n.forwardPort(fragment("10.0.0.1", 0x20)), "the first fragment")
+	assert.Equal(t, device.TcDrop, n.forwardPort(fragment("10.0.0.1", 0x01)), "a later fragment")
+	assert.Equal(t, device.TcPass, n.forwardPort(fragment("10.0.0.2", 0x20)))
+
+	assert.Equal(t, 3, n.portForwardConnections())
+}
+
+func TestHostPortForward(t *testing.T) {
+	// the targets echo what they receive
+	service, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer service.Close()
+	go func() {
+		for {
+			conn, err := service.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	dns, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(netip.MustParseAddrPort("127.0.0.1:0")))
+	require.NoError(t, err)
+	defer dns.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			size, addr, err := dns.ReadFromUDPAddrPort(buf)
+			if err != nil {
+				return
+			}
+			_, _ = dns.WriteToUDPAddrPort(buf[:size], addr)
+		}
+	}()
+	// ports that are free to listen on
+	free := func(network string) netip.AddrPort {
+		if network == "tcp" {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer ln.Close()
+			return ln.Addr().(*net.TCPAddr).AddrPort()
+		}
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+		return conn.LocalAddr().(*net.UDPAddr).AddrPort()
+	}
+
+	n := testNylonWithPrefixes()
+	n.Context, n.Cancel = context.WithCancelCause(context.Background())
+	defer n.Cancel(nil)
+	n.PortForwards = []state.PortForward{
+		{Proto: "tcp", Listen: free("tcp"), To: service.Addr().(*net.TCPAddr).AddrPort()},
+		{Proto: "udp", Listen: free("udp"), To: dns.LocalAddr().(*net.UDPAddr).AddrPort()},
+	}
+	// forwards that listen on the host are not translated in the data path
+	require.NoError(t, n.initPortForwards())
+	assert.Nil(t, n.portForwards)
+	require.NoError(t, n.startHostPortForwards())
+	require.Len(t, n.hostForwards, 2)
+
+	conn, err := net.Dial("tcp", n.PortForwards[0].Listen.String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(reply))
+
+	client, err := net.Dial("udp", n.PortForwards[1].Listen.String())
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("query"))
+	require.NoError(t, err)
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reply = make([]byte, 16)
+	size, err := client.Read(reply)
+	require.NoError(t, err)
+	assert.Equal(t, "query", string(reply[:size]))
+	assert.Equal(t, 2, n.portForwardConnections())
+
+	// the listeners are closed with nylon
+	n.Cancel(nil)
+	assert.Eventually(t, func() bool {
+		_, err := net.Dial("tcp", n.PortForwards[0].Listen.String())
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -16,48 +16,28 @@ type advertisedPrefixHealth struct {
 
 func (n *Nylon) reconcileAdvertisedPrefixes(next *state.CentralCfg) {
 	if n.prefixHealth == nil {
-		n.prefixHealth = make(map[netip.Prefix]advertisedPrefixHealth)
+		n.prefixHealth = make(map[state.RoutePrefix]advertisedPrefixHealth)
 	}
 	nextNode := next.TryGetNode(n.LocalCfg.Id)
 	if nextNode == nil {
 		return
 	}
 
-	desiredLocal := make(map[netip.Prefix]int)
-	for i, prefix := range nextNode.Prefixes {
-		desiredLocal[prefix.GetPrefix()] = i
-	}
-
-	for prefix, adv := range n.RouterState.Advertised {
-		if adv.NodeId != n.LocalCfg.Id {
-			continue
-		}
-		if _, ok := desiredLocal[prefix]; !ok {
-			if old, ok := n.prefixHealth[prefix]; ok {
-				old.monitor.Stop()
-				delete(n.prefixHealth, prefix)
-			}
-			delete(n.RouterState.Advertised, prefix)
-		}
-	}
-
-	for prefix, index := range desiredLocal {
-		config := nextNode.Prefixes[index]
-		var health advertisedPrefixHealth
-		if current, ok := n.prefixHealth[prefix]; ok && current.config.SameConfig(config, &n.RouterTunables) {
-			health = current
-		} else {
-			if current, ok := n.prefixHealth[prefix]; ok {
-				current.monitor.Stop()
+	ReconcileAdvertised(n.RouterState, nextNode.Prefixes, func(config state.PrefixHealthWrapper) state.Advertisement {
+		prefix := config.GetRoutePrefix()
+		health, ok := n.prefixHealth[prefix]
+		if !ok || !health.config.SameConfig(config, &n.RouterTunables) {
+			if ok {
+				health.monitor.Stop()
 			}
 			n.Log.Debug("starting prefix healthcheck", "prefix", prefix)
 			health = advertisedPrefixHealth{
 				config:  config,
-				monitor: config.NewMonitor(n.Log, &n.RouterTunables, n.DNSResolver),
+				monitor: config.NewMonitor(n.Log, &n.RouterTunables, n.DNSResolver, n),
 			}
 			n.prefixHealth[prefix] = health
 		}
-		n.RouterState.Advertised[prefix] = state.Advertisement{
+		return state.Advertisement{
 			NodeId:   n.LocalCfg.Id,
 			Expiry:   maxConfigTime,
 			MetricFn: health.monitor.GetMetric,
@@ -65,7 +45,23 @@ func (n *Nylon) reconcileAdvertisedPrefixes(next *state.CentralCfg) {
 				health.monitor.Stop()
 			},
 		}
+	}, func(prefix state.RoutePrefix) {
+		if old, ok := n.prefixHealth[prefix]; ok {
+			old.monitor.Stop()
+			delete(n.prefixHealth, prefix)
+		}
+	})
+}
+
+// ExternalMetric implements state.ExternalRoutes for the prefixes imported from babel and bgp
+func (n *Nylon) ExternalMetric(proto state.ExternalProtocol, prefix netip.Prefix) uint32 {
+	switch proto {
+	case state.ExternalBabel:
+		return n.babel.routeMetric(prefix)
+	case state.ExternalBGP:
+		return n.bgp.routeMetric(prefix)
 	}
+	return state.INF
 }
 
 var maxConfigTime = time.Unix(1<<63-62135596801, 999999999)
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+)
+
+// probeRateState adapts how often active endpoints are probed. An endpoint on an idle link with a stable rtt is probed
+// exponentially less often, up to ProbeBackoffMax, and returns to its shortest interval on traffic, rtt variance,
+// probe loss or a route change. The shortest interval is ProbeDelay, or MeteredProbeDelay for metered endpoints.
+type probeRateState struct {
+	traffic      sync.Map // *device.Peer -> *atomic.Uint64, data packets to and from each neighbour
+	routeChanged bool     // the forwarding tables changed since the last probe round
+	endpoints    map[*state.NylonEndpoint]*probeRate
+}
+
+type probeRate struct {
+	interval time.Duration // current probe interval
+	probedAt time.Time     // when the last probe was sent
+	quiet    bool          // nothing reset the interval since the last probe
+	traffic  uint64        // data packets of the neighbour at the last round
+	lost     uint64        // lost probes of the endpoint at the last round
+}
+
+func (n *Nylon) initProbeRate() {
+	n.probeRate.endpoints = make(map[*state.NylonEndpoint]*probeRate)
+	n.RepeatTask(func() error {
+		return n.probeActive(time.Now())
+	}, n.ProbeDelay)
+}
+
+// countTraffic counts a data packet sent to or received from a neighbour, packets of other peers are ignored
+func (n *Nylon) countTraffic(peer *device.Peer) {
+	if peer == nil {
+		return
+	}
+	if count, ok := n.probeRate.traffic.Load(peer); ok {
+		count.(*atomic.Uint64).Add(1)
+	}
+}
+
+// next updates the interval with the state of the link seen at this round, and reports whether a probe is due
+func (r *probeRate) next(now time.Time, busy bool, base time.Duration, t *state.RouterTunables) bool {
+	if busy || r.interval < base {
+		r.interval = base
+		r.quiet = false
+	}
+	// probes are due up to half a round early, so the interval stays a multiple of ProbeDelay
+	if !r.probedAt.IsZero() && now.Sub(r.probedAt)+t.ProbeDelay/2 < r.interval {
+		return false
+	}
+	if r.quiet {
+		// the link stayed idle and stable for a whole interval
+		r.interval = min(r.interval*2, max(t.ProbeBackoffMax, base))
+	}
+	r.quiet = true
+	r.probedAt = now
+	return true
+}
+
+// probeActive probes the active endpoints whose adaptive probe interval has elapsed
+func (n *Nylon) probeActive(now time.Time) error {
+	routeChanged := n.probeRate.routeChanged
+	n.probeRate.routeChanged = false
+	seen := make(map[*state.NylonEndpoint]struct{})
+	peers := make(map[*device.Peer]struct{})
+	for _, neigh := range n.RouterState.Neighbours {
+		var traffic uint64
+		if peer := n.Device.LookupPeer(device.NoisePublicKey(n.GetNode(neigh.Id).PubKey)); peer != nil {
+			peers[peer] = struct{}{}
+			count, _ := n.probeRate.traffic.LoadOrStore(peer, new(atomic.Uint64))
+			traffic = count.(*atomic.Uint64).Load()
+		}
+		for _, ep := range neigh.Eps {
+			if !ep.IsActive() {
+				continue
+			}
+			nep := ep.AsNylonEndpoint()
+			seen[nep] = struct{}{}
+			base := n.ProbeDelay
+			if nep.Attrs().Metered {
+				base = n.MeteredProbeDelay
+			}
+			rate, ok := n.probeRate.endpoints[nep]
+			if !ok {
+				rate = &probeRate{interval: base}
+				n.probeRate.endpoints[nep] = rate
+			}
+			lost, _ := nep.ProbeCounters()
+			busy := routeChanged || traffic != rate.traffic || lost != rate.lost || !nep.IsStable(n.ProbeBackoffVariance)
+			rate.traffic, rate.lost = traffic, lost
+			if !rate.next(now, busy, base, &n.RouterTunables) {
+				continue
+			}
+			nep.SetProbeInterval(rate.interval)
+			if err := n.Probe(neigh.Id, nep); err != nil {
+				n.Log.Debug("probe failed", "err", err.Error())
+			}
+		}
+	}
+	for nep := range n.probeRate.endpoints {
+		if _, ok := seen[nep]; !ok {
+			// inactive endpoints start again at full rate
+			nep.SetProbeInterval(n.ProbeDelay)
+			delete(n.probeRate.endpoints, nep)
+		}
+	}
+	n.probeRate.traffic.Range(func(peer, _ any) bool {
+		if _, ok := peers[peer.(*device.Peer)]; !ok {
+			n.probeRate.traffic.Delete(peer)
+		}
+		return true
+	})
+	return nil
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeRateBacksOffWhileIdle(t *testing.T) {
+	tunables := state.DefaultRouterTunables()
+	tunables.ProbeBackoffMax = 8 * tunables.ProbeDelay
+	base := tunables.ProbeDelay
+	rate := &probeRate{interval: base}
+	start := time.Now()
+
+	// rounds run every ProbeDelay, record the ones that send a probe
+	run := func(from, to int, busy func(round int) bool) []int {
+		var probed []int
+		for round := from; round < to; round++ {
+			if rate.next(start.Add(time.Duration(round)*tunables.ProbeDelay), busy(round), base, &tunables) {
+				probed = append(probed, round)
+			}
+		}
+		return probed
+	}
+	idle := func(int) bool { return false }
+
+	assert.Equal(t, []int{0, 1, 3, 7, 15, 23, 31}, run(0, 32, idle))
+	assert.Equal(t, tunables.ProbeBackoffMax, rate.interval)
+
+	// traffic returns to full rate at once, and backs off again once the link is idle
+	assert.Equal(t, []int{32, 33, 34, 35, 37, 41}, run(32, 44, func(round int) bool { return round < 35 }))
+
+	// without a backoff limit above ProbeDelay every round probes
+	tunables.ProbeBackoffMax = 0
+	rate = &probeRate{interval: base}
+	assert.Equal(t, []int{0, 1, 2, 3}, run(0, 4, idle))
+
+	// metered endpoints are probed no faster than MeteredProbeDelay, even while busy
+	base = 3 * tunables.ProbeDelay
+	rate = &probeRate{interval: tunables.ProbeDelay}
+	assert.Equal(t, []int{4, 7, 10}, run(4, 12, func(int) bool { return true }))
+}
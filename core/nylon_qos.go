@@ -0,0 +1,154 @@
+package core
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+)
+
+// qosMinBurst is the smallest burst of a rate limited class in bytes, so a slow class still passes full-size packets
+const qosMinBurst = 3 * 1500
+
+// qosTable is the classifier of the mesh as applied by the data path. It is rebuilt when the central config is
+// applied, and published atomically like the forwarding tables.
+type qosTable struct {
+	qos      *state.QoS
+	priority []device.TCPriority
+	// packets, bytes and drops count the data packets of each class, packets that match no class are last. queued
+	// counts the packets staged to peers, with the copies of duplicated packets.
+	packets []atomic.Uint64
+	bytes   []atomic.Uint64
+	drops   []atomic.Uint64
+	queued  []atomic.Uint64
+	buckets sync.Map // qosBucketKey -> *tokenBucket
+}
+
+type qosBucketKey struct {
+	peer  *device.Peer
+	class int
+}
+
+// tokenBucket polices the traffic of a class to a peer
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// take removes size tokens from the bucket if it holds enough, after refilling it at rate up to burst
+func (b *tokenBucket) take(size int, now time.Time, rate, burst float64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+	if b.tokens < float64(size) {
+		return false
+	}
+	b.tokens -= float64(size)
+	return true
+}
+
+// qosPriorities maps the priorities of classes to the data path. Data packets stay below TcHighPriority, which is
+// left to the control traffic of nylon, so no class can delay route updates and probes.
+var qosPriorities = map[state.QoSPriority]device.TCPriority{
+	"":              device.TcNormalPriority,
+	state.QoSNormal: device.TcNormalPriority,
+	state.QoSMedium: device.TcMediumPriority,
+	state.QoSHigh:   device.TcDataHighPriority,
+}
+
+// counter returns the index of the counters of a class returned by state.QoS.Classify
+func (t *qosTable) counter(class int) int {
+	if class < 0 {
+		return len(t.packets) - 1
+	}
+	return class
+}
+
+// syncQoS publishes the classifier of the central config. Counters are kept while the classes keep their names.
+func (n *Nylon) syncQoS(qos *state.QoS) {
+	old := n.qos.Load()
+	if qos == nil {
+		n.qos.Store(nil)
+		return
+	}
+	next := &qosTable{
+		qos:      qos,
+		priority: make([]device.TCPriority, len(qos.Classes)),
+		packets:  make([]atomic.Uint64, len(qos.Classes)+1),
+		bytes:    make([]atomic.Uint64, len(qos.Classes)+1),
+		drops:    make([]atomic.Uint64, len(qos.Classes)+1),
+		queued:   make([]atomic.Uint64, len(qos.Classes)+1),
+	}
+	for i, class := range qos.Classes {
+		next.priority[i] = qosPriorities[class.Priority]
+	}
+	if old != nil && slices.EqualFunc(old.qos.Classes, qos.Classes, func(a, b state.QoSClass) bool {
+		return a.Name == b.Name
+	}) {
+		for i := range next.packets {
+			next.packets[i].Store(old.packets[i].Load())
+			next.bytes[i].Store(old.bytes[i].Load())
+			next.drops[i].Store(old.drops[i].Load())
+			next.queued[i].Store(old.queued[i].Load())
+		}
+	}
+	n.qos.Store(next)
+}
+
+// classify assigns a data packet forwarded to a peer to its class, and returns false if the packet is above the rate
+// of its class and must be dropped
+func (n *Nylon) classify(packet *device.TCElement) bool {
+	t := n.qos.Load()
+	if t == nil {
+		return true
+	}
+	sport, dport, hasPorts := packet.GetPorts()
+	class := t.qos.Classify(state.QoSPacket{
+		Dst:      packet.GetDst(),
+		DSCP:     packet.GetDSCP(),
+		Proto:    packet.GetProtocol(),
+		SrcPort:  sport,
+		DstPort:  dport,
+		HasPorts: hasPorts,
+	})
+	idx := t.counter(class)
+	if class >= 0 {
+		packet.Priority = t.priority[class]
+		if rate := t.qos.Classes[class].Rate; rate != 0 {
+			bucket, _ := t.buckets.LoadOrStore(qosBucketKey{peer: packet.ToPeer, class: class}, new(tokenBucket))
+			burst := max(rate.BytesPerSecond()*n.QoSBurst.Seconds(), qosMinBurst)
+			if !bucket.(*tokenBucket).take(len(packet.Packet), time.Now(), rate.BytesPerSecond(), burst) {
+				t.drops[idx].Add(1)
+				return false
+			}
+		}
+	}
+	t.packets[idx].Add(1)
+	t.bytes[idx].Add(uint64(len(packet.Packet)))
+	packet.Queued = &t.queued[idx]
+	return true
+}
+
+// pruneQoS forgets the rate limits of peers that were removed
+func (n *Nylon) pruneQoS() {
+	t := n.qos.Load()
+	if t == nil || n.Device == nil {
+		return
+	}
+	t.buckets.Range(func(key, _ any) bool {
+		peer := key.(qosBucketKey).peer
+		if n.Device.LookupPeer(peer.GetPublicKey()) != peer {
+			t.buckets.Delete(key)
+		}
+		return true
+	})
+}
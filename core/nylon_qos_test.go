@@ -0,0 +1,70 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQoSClassifyAndPolice(t *testing.T) {
+	cfg := &state.CentralCfg{QoS: &state.QoSCfg{Classes: []state.QoSClass{
+		{Name: "ssh", Priority: state.QoSHigh, Match: []state.QoSMatch{{Proto: "tcp", Ports: []state.PortRange{{First: 22, Last: 22}}}}},
+		{Name: "bulk", Rate: 8, Match: []state.QoSMatch{{Proto: "tcp", Ports: []state.PortRange{{First: 873, Last: 873}}}}},
+	}}}
+	qos, err := cfg.CompileQoS()
+	require.NoError(t, err)
+
+	n := &Nylon{RouterTunables: state.DefaultRouterTunables()}
+	n.syncQoS(qos)
+	bob := new(device.Peer)
+	packet := func(dport uint16) *device.TCElement {
+		tce := testTCPPacket("10.0.0.1", "10.0.0.2", 40000, dport, nil)
+		tce.ToPeer = bob
+		return tce
+	}
+
+	ssh := packet(22)
+	assert.True(t, n.classify(ssh))
+	assert.Equal(t, device.TcDataHighPriority, ssh.Priority, "data packets stay below the control traffic")
+	require.NotNil(t, ssh.Queued)
+	ssh.Queued.Add(1) // as the data path does when it stages the packet
+	assert.True(t, n.classify(packet(443)))
+
+	// the bulk class passes its minimum burst, and drops the packets above it
+	size := len(packet(873).Packet)
+	sent := 0
+	for range qosMinBurst/size + 10 {
+		if n.classify(packet(873)) {
+			sent++
+		}
+	}
+	assert.Equal(t, qosMinBurst/size, sent)
+
+	// the bucket refills at the rate of the class
+	bucket := &tokenBucket{}
+	now := time.Now()
+	assert.True(t, bucket.take(1000, now, 1000, 1000))
+	assert.False(t, bucket.take(1000, now.Add(500*time.Millisecond), 1000, 1000))
+	assert.True(t, bucket.take(1000, now.Add(time.Second), 1000, 1000))
+
+	classes := buildQoSClasses(n)
+	require.Len(t, classes, 3)
+	assert.Equal(t, uint64(1), classes[0].Packets)
+	assert.Equal(t, uint64(1), classes[0].Queued)
+	assert.Equal(t, "high", classes[0].Priority)
+	assert.Equal(t, uint64(sent), classes[1].Packets)
+	assert.Equal(t, uint64(10), classes[1].Drops)
+	assert.Equal(t, uint64(8), classes[1].RateBps)
+	assert.Equal(t, "default", classes[2].Name)
+	assert.Equal(t, uint64(1), classes[2].Packets)
+
+	// counters survive a config change that keeps the classes
+	n.syncQoS(qos)
+	assert.Equal(t, uint64(10), n.qos.Load().drops[1].Load())
+	n.syncQoS(nil)
+	assert.True(t, n.classify(packet(873)))
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/state"
+	"go4.org/netipx"
+)
+
+// sourceFilter holds the source addresses a peer may send data packets from. Like feasible-path reverse path
+// filtering (RFC 3704), a router neighbour may send from its own addresses and prefixes, and from every prefix it
+// advertises to us, since it can carry the traffic of those prefixes. A passive client may only send from its own
+// addresses and prefixes.
+type sourceFilter struct {
+	allowed *netipx.IPSet
+	spoofed *atomic.Uint64 // packets dropped for their source address, kept across rebuilds
+}
+
+type sourceTable map[*device.Peer]sourceFilter
+
+// syncSources publishes the source addresses every peer may send from to the data path. It is rebuilt on the
+// dispatch thread when a neighbour advertises a prefix it may send from for the first time, and on gc as the routes
+// of neighbours expire.
+func (n *Nylon) syncSources() {
+	if n.Device == nil {
+		return
+	}
+	old := n.sources.Load()
+	sources := make(sourceTable)
+	add := func(id state.NodeId, build func(*netipx.IPSetBuilder)) {
+		cfg := n.GetNode(id)
+		peer := n.Device.LookupPeer(device.NoisePublicKey(cfg.PubKey))
+		if peer == nil {
+			return
+		}
+		var b netipx.IPSetBuilder
+		for _, addr := range cfg.Addresses {
+			b.Add(addr)
+		}
+		for _, prefix := range cfg.Prefixes {
+			b.AddPrefix(prefix.GetPrefix())
+		}
+		if build != nil {
+			build(&b)
+		}
+		allowed, err := b.IPSet()
+		if err != nil {
+			n.Log.Error("failed to build the source addresses of a peer", "peer", id, "err", err)
+			return
+		}
+		filter := sourceFilter{allowed: allowed, spoofed: new(atomic.Uint64)}
+		if old != nil {
+			if prev, ok := (*old)[peer]; ok {
+				filter.spoofed = prev.spoofed
+			}
+		}
+		sources[peer] = filter
+	}
+	for _, neigh := range n.RouterState.Neighbours {
+		add(neigh.Id, func(b *netipx.IPSetBuilder) {
+			for prefix := range neigh.Routes {
+				if n.mayUseSource(neigh, prefix) {
+					b.AddPrefix(prefix.Prefix)
+				}
+			}
+		})
+	}
+	for _, id := range n.GetPeers(n.LocalCfg.Id) {
+		if n.IsClient(id) {
+			add(id, nil)
+		}
+	}
+	n.sources.Store(&sources)
+}
+
+// mayUseSource returns true if a neighbour may send from a prefix, because it advertises a reachable route to it.
+// Retracted routes and our own prefixes never count.
+func (n *Nylon) mayUseSource(neigh *state.Neighbour, prefix state.RoutePrefix) bool {
+	if neigh == nil {
+		return false
+	}
+	route, ok := neigh.Routes[prefix]
+	return ok && route.Metric != state.INF && route.NodeId != n.LocalCfg.Id
+}
+
+// spoofed returns true if a data packet from a peer has a source address the peer may not send from, and counts it
+func (n *Nylon) spoofed(packet *device.TCElement) bool {
+	sources := n.sources.Load()
+	if sources == nil || packet.FromPeer == nil {
+		return false
+	}
+	if ver := packet.GetIPVersion(); ver != 4 && ver != 6 {
+		return false
+	}
+	filter, ok := (*sources)[packet.FromPeer]
+	if !ok {
+		// the peer is being added or retired, it will be known at the next sync
+		return false
+	}
+	if filter.allowed.Contains(packet.GetSrc()) {
+		return false
+	}
+	filter.spoofed.Add(1)
+	return true
+}
+
+// spoofedPackets returns the packets dropped from a peer for their source address
+func (n *Nylon) spoofedPackets(id state.NodeId) uint64 {
+	sources := n.sources.Load()
+	if sources == nil || n.Device == nil {
+		return 0
+	}
+	peer := n.Device.LookupPeer(device.NoisePublicKey(n.GetNode(id).PubKey))
+	if filter, ok := (*sources)[peer]; ok {
+		return filter.spoofed.Load()
+	}
+	return 0
+}
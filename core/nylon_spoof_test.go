@@ -0,0 +1,120 @@
+package core
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/encodeous/nylon/polyamide/conn"
+	"github.com/encodeous/nylon/polyamide/device"
+	"github.com/encodeous/nylon/polyamide/tun/tuntest"
+	"github.com/encodeous/nylon/protocol"
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceValidation(t *testing.T) {
+	dev := device.NewDevice(tuntest.NewChannelTUN().TUN(), conn.NewDefaultBind(), device.NewLogger(device.LogLevelError, ""))
+	defer dev.Close()
+	bobKey, phoneKey := state.GenerateKey().Pubkey(), state.GenerateKey().Pubkey()
+	bob, err := dev.NewPeer(device.NoisePublicKey(bobKey))
+	require.NoError(t, err)
+	phone, err := dev.NewPeer(device.NoisePublicKey(phoneKey))
+	require.NoError(t, err)
+
+	n := &Nylon{Device: dev}
+	n.LocalCfg.Id = "alice"
+	n.CentralCfg = state.CentralCfg{
+		Routers: []state.RouterCfg{
+			{NodeCfg: state.NodeCfg{Id: "alice", PubKey: state.GenerateKey().Pubkey()}},
+			{NodeCfg: state.NodeCfg{Id: "bob", PubKey: bobKey, Addresses: []netip.Addr{netip.MustParseAddr("10.0.0.2")}}},
+		},
+		Clients: []state.ClientCfg{
+			{NodeCfg: state.NodeCfg{Id: "phone", PubKey: phoneKey, Addresses: []netip.Addr{netip.MustParseAddr("10.0.5.2")}}},
+		},
+		Graph: []string{"alice, bob", "alice, phone"},
+	}
+	route := func(prefix string, origin state.NodeId) (state.RoutePrefix, state.NeighRoute) {
+		rp := state.RoutePrefix{Prefix: netip.MustParsePrefix(prefix)}
+		return rp, state.NeighRoute{PubRoute: state.PubRoute{Source: state.Source{NodeId: origin, RoutePrefix: rp}}}
+	}
+	neigh := &state.Neighbour{Id: "bob", Routes: make(map[state.RoutePrefix]state.NeighRoute)}
+	for _, r := range [][2]string{{"10.0.3.0/24", "carol"}, {"10.0.1.1/32", "alice"}} {
+		prefix, nr := route(r[0], state.NodeId(r[1]))
+		neigh.Routes[prefix] = nr
+	}
+	retracted, nr := route("10.0.4.0/24", "carol")
+	nr.Metric = state.INF
+	neigh.Routes[retracted] = nr
+	n.RouterState = &state.RouterState{Neighbours: []*state.Neighbour{neigh}}
+	n.syncSources()
+
+	packet := func(src string, from *device.Peer) *device.TCElement {
+		return testTCPPacket(src, "10.0.0.1", 1000, 2000, from)
+	}
+	// a router may send from its own addresses, and from the prefixes it advertises to us
+	assert.False(t, n.spoofed(packet("10.0.0.2", bob)))
+	assert.False(t, n.spoofed(packet("10.0.3.7", bob)))
+	assert.True(t, n.spoofed(packet("10.0.1.1", bob)), "our own prefixes never come back from a neighbour")
+	assert.True(t, n.spoofed(packet("192.0.2.1", bob)))
+	assert.True(t, n.spoofed(packet("10.0.4.1", bob)), "retracted prefixes may not be sent from")
+
+	// a passive client may only send from its own addresses
+	assert.False(t, n.spoofed(packet("10.0.5.2", phone)))
+	assert.True(t, n.spoofed(packet("10.0.1.1", phone)))
+
+	// locally sent packets are not checked
+	assert.False(t, n.spoofed(packet("192.0.2.1", nil)))
+
+	// counters are kept when the table is rebuilt
+	n.syncSources()
+	assert.Equal(t, uint64(3), n.spoofedPackets("bob"))
+	assert.Equal(t, uint64(1), n.spoofedPackets("phone"))
+}
+
+func TestSourcesFollowNeighbourUpdates(t *testing.T) {
+	dev := device.NewDevice(tuntest.NewChannelTUN().TUN(), conn.NewDefaultBind(), device.NewLogger(device.LogLevelError, ""))
+	defer dev.Close()
+	bobKey := state.GenerateKey().Pubkey()
+	bob, err := dev.NewPeer(device.NoisePublicKey(bobKey))
+	require.NoError(t, err)
+
+	lan := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.0.3.0/24")}
+	n := &Nylon{Device: dev, RouterTunables: state.DefaultRouterTunables()}
+	n.LocalCfg.Id = "alice"
+	n.CentralCfg = state.CentralCfg{
+		Routers: []state.RouterCfg{
+			{NodeCfg: state.NodeCfg{Id: "alice", PubKey: state.GenerateKey().Pubkey()}},
+			{NodeCfg: state.NodeCfg{Id: "bob", PubKey: bobKey}},
+			{NodeCfg: state.NodeCfg{Id: "carol", PubKey: state.GenerateKey().Pubkey(), Prefixes: []state.PrefixHealthWrapper{
+				{PrefixHealth: &state.StaticPrefixHealth{Prefix: lan.Prefix}},
+			}}},
+		},
+		Graph: []string{"alice, bob", "bob, carol"},
+	}
+	n.RouterState = &state.RouterState{
+		RouterTunables: &n.RouterTunables,
+		Id:             "alice",
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
+		Sources:        make(map[state.Source]state.FD),
+		Neighbours:     []*state.Neighbour{{Id: "bob", Routes: make(map[state.RoutePrefix]state.NeighRoute)}},
+		Advertised:     make(map[state.RoutePrefix]state.Advertisement),
+		Origins:        map[state.RoutePrefix][]state.NodeId{lan: {"carol"}},
+	}
+	n.router.log = n.Log
+	n.router.IO = make(map[state.NodeId]*IOPending)
+	n.router.Tables.Store(&ForwardingTables{})
+	n.syncSources()
+	packet := testTCPPacket("10.0.3.7", "10.0.0.1", 1000, 2000, bob)
+	assert.True(t, n.spoofed(packet))
+
+	// bob may send from the prefix as soon as it advertises it, without waiting for gc
+	dst, _ := marshalRoutePrefix(lan)
+	require.NoError(t, n.routerHandleRouteUpdate("bob", &protocol.Ny_Update{RouterId: "carol", Prefix: dst, Seqno: 1, Metric: 100}))
+	assert.False(t, n.spoofed(packet))
+
+	// and may not once it retracts it
+	require.NoError(t, n.routerHandleRouteUpdate("bob", &protocol.Ny_Update{RouterId: "carol", Prefix: dst, Seqno: 1, Metric: state.INF}))
+	assert.True(t, n.spoofed(packet))
+}
@@ -53,14 +53,21 @@ func (n *Nylon) InstallTC() {
 		})
 		// forward only outgoing packets based on the routing table
 		n.Device.InstallFilter(func(dev *device.Device, packet *device.TCElement) (device.TCAction, error) {
-			entry, ok := n.router.Tables.Load().Forward.Lookup(packet.GetDst())
+			entry, ok := n.router.Tables.Load().LookupForward(packet.GetSrc(), packet.GetDst())
 			if ok && !packet.Incoming() {
 				if entry.Blackhole {
 					return device.TcDrop, nil
 				}
-				packet.ToPeer = entry.Peer
+				nh, peer := entry.Select(packet)
+				packet.ToPeer = peer
+				if !n.classify(packet) {
+					return device.TcDrop, nil
+				}
+				n.countTraffic(peer)
+				n.sendThrough(packet)
+				n.sendDuplicate(packet, entry)
 				if n.DBG_trace_tc {
-					t.Submit(fmt.Sprintf("Fwd packet: %v -> %v, via %s\n", packet.GetSrc(), packet.GetDst(), entry.Nh))
+					t.Submit(fmt.Sprintf("Fwd packet: %v -> %v, via %s\n", packet.GetSrc(), packet.GetDst(), nh))
 				}
 				return device.TcForward, nil
 			}
@@ -69,14 +76,27 @@ func (n *Nylon) InstallTC() {
 	} else {
 		// forward packets based on the routing table
 		n.Device.InstallFilter(func(dev *device.Device, packet *device.TCElement) (device.TCAction, error) {
-			entry, ok := n.router.Tables.Load().Forward.Lookup(packet.GetDst())
+			entry, ok := n.router.Tables.Load().LookupForward(packet.GetSrc(), packet.GetDst())
 			if ok {
 				if entry.Blackhole {
 					return device.TcDrop, nil
 				}
-				packet.ToPeer = entry.Peer
+				if packet.Incoming() && n.fromRouter(packet.FromPeer) {
+					n.transitPackets.Add(1)
+				}
+				nh, peer := entry.Select(packet)
+				packet.ToPeer = peer
+				if !n.classify(packet) {
+					return device.TcDrop, nil
+				}
+				n.countTraffic(peer)
+				n.sendThrough(packet)
+				// duplicate packets where they enter the mesh, transit packets already carry their copies
+				if !packet.Incoming() || !n.fromRouter(packet.FromPeer) {
+					n.sendDuplicate(packet, entry)
+				}
 				if n.DBG_trace_tc {
-					t.Submit(fmt.Sprintf("Fwd packet: %v -> %v, via %s\n", packet.GetSrc(), packet.GetDst(), entry.Nh))
+					t.Submit(fmt.Sprintf("Fwd packet: %v -> %v, via %s\n", packet.GetSrc(), packet.GetDst(), nh))
 				}
 				return device.TcForward, nil
 			}
@@ -107,7 +127,7 @@ func (n *Nylon) InstallTC() {
 
 	// bounce back packets destined for the current node
 	n.Device.InstallFilter(func(dev *device.Device, packet *device.TCElement) (device.TCAction, error) {
-		entry, ok := n.router.Tables.Load().Exit.Lookup(packet.GetDst())
+		entry, ok := n.router.Tables.Load().LookupExit(packet.GetSrc(), packet.GetDst())
 		// we should only accept packets destined to us, but not our passive clients
 		if ok && entry.Nh == n.LocalCfg.Id {
 			if n.DBG_trace_tc {
@@ -120,6 +140,51 @@ func (n *Nylon) InstallTC() {
 		return device.TcPass, nil
 	})
 
+	// forward the connections to forwarded ports of this node, after the acl has checked them against the listen address
+	n.Device.InstallFilter(func(dev *device.Device, packet *device.TCElement) (device.TCAction, error) {
+		action := n.forwardPort(packet)
+		if n.DBG_trace_tc && action == device.TcBounce {
+			t.Submit(fmt.Sprintf("Port forward: %v -> %v\n", packet.GetSrc(), packet.GetDst()))
+		}
+		return action, nil
+	})
+
+	// enforce the acl where packets enter and leave the mesh
+	n.Device.InstallFilter(func(dev *device.Device, packet *device.TCElement) (device.TCAction, error) {
+		if !n.aclAccepts(packet) {
+			if n.DBG_trace_tc {
+				t.Submit(fmt.Sprintf("ACL Drop: %v -> %v\n", packet.GetSrc(), packet.GetDst()))
+			}
+			return device.TcDrop, nil
+		}
+		return device.TcPass, nil
+	})
+
+	// translate the replies of forwarded connections back to the listen address before the acl sees them
+	n.Device.InstallFilter(func(dev *device.Device, packet *device.TCElement) (device.TCAction, error) {
+		n.reversePortForward(packet)
+		return device.TcPass, nil
+	})
+
+	// drop copies of duplicated packets that already arrived over another path, where they leave the mesh
+	n.Device.InstallFilter(func(dev *device.Device, packet *device.TCElement) (device.TCAction, error) {
+		if n.dedupIncoming(packet) {
+			return device.TcDrop, nil
+		}
+		return device.TcPass, nil
+	})
+
+	// drop data packets whose source address the sending peer may not use, before they reach any other filter
+	n.Device.InstallFilter(func(dev *device.Device, packet *device.TCElement) (device.TCAction, error) {
+		if packet.Incoming() && n.spoofed(packet) {
+			if n.DBG_trace_tc {
+				t.Submit(fmt.Sprintf("Spoofed: %v -> %v, peer %s\n", packet.GetSrc(), packet.GetDst(), packet.FromPeer))
+			}
+			return device.TcDrop, nil
+		}
+		return device.TcPass, nil
+	})
+
 	// handle incoming nylon packets
 	n.Device.InstallFilter(func(dev *device.Device, packet *device.TCElement) (device.TCAction, error) {
 		if packet.Incoming() && packet.GetIPVersion() == NyProtoId {
@@ -128,6 +193,16 @@ func (n *Nylon) InstallTC() {
 		}
 		return device.TcPass, nil
 	})
+
+	// count data packets received from neighbours, so probing backs off only while a link is idle. Filters run in
+	// reverse order of installation, so this one sees every packet before it is forwarded or delivered
+	n.Device.InstallFilter(func(dev *device.Device, packet *device.TCElement) (device.TCAction, error) {
+		if packet.Incoming() && (packet.GetIPVersion() == 4 || packet.GetIPVersion() == 6) {
+			n.countTraffic(packet.FromPeer)
+			n.countReceived(packet)
+		}
+		return device.TcPass, nil
+	})
 }
 
 func (n *Nylon) SendNylon(pkt *protocol.Ny, endpoint conn.Endpoint, peer *device.Peer) error {
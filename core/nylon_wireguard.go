@@ -174,6 +174,8 @@ func (n *Nylon) SyncWireGuard() error {
 	if err := n.syncWireGuardEndpoints(); err != nil {
 		return err
 	}
+	n.syncLinks()
+	n.syncSources()
 
 	// The forwarding table caches concrete peers for the one-lookup hot path.
 	// Rebind every entry before stopping peers from the previous generation.
@@ -213,8 +215,23 @@ func (n *Nylon) syncWireGuardEndpoints() error {
 
 		if nhNeigh != nil {
 			links := slices.Clone(nhNeigh.Eps)
+			// active primary endpoints go before active backups, and an active preferred endpoint goes first within
+			// its class, like in BestEndpoint
+			rank := func(ep state.Endpoint) int {
+				if !ep.IsActive() {
+					return 4
+				}
+				r := 0
+				if state.IsBackupEndpoint(ep) {
+					r = 2
+				}
+				if !nhNeigh.IsPreferred(ep) {
+					r++
+				}
+				return r
+			}
 			slices.SortStableFunc(links, func(a, b state.Endpoint) int {
-				return cmp.Compare(a.Metric(), b.Metric())
+				return cmp.Or(cmp.Compare(rank(a), rank(b)), cmp.Compare(a.Metric(), b.Metric()))
 			})
 			for _, ep := range links {
 				nep, err := ep.AsNylonEndpoint().GetWgEndpoint(n.Device, n.EndpointResolver)
@@ -39,16 +39,10 @@ func (n *Nylon) startObservability() error {
 		return fmt.Errorf("listen on observability address %q: %w", n.LocalCfg.ObservabilityAddr, err)
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/healthz", n.handleHealth)
-	mux.HandleFunc("/readyz", n.handleReady)
-	mux.HandleFunc("/metrics", n.handleMetrics)
-	mux.HandleFunc("/discovery", n.handleDiscovery)
-
 	obs := &observabilityServer{
 		listener: listener,
 		server: &http.Server{
-			Handler:           mux,
+			Handler:           n.observabilityMux(),
 			ReadHeaderTimeout: 5 * time.Second,
 		},
 	}
@@ -68,6 +62,19 @@ func (n *Nylon) startObservability() error {
 	return nil
 }
 
+func (n *Nylon) observabilityMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", n.handleHealth)
+	mux.HandleFunc("/readyz", n.handleReady)
+	mux.HandleFunc("/metrics", n.handleMetrics)
+	mux.HandleFunc("/discovery", n.handleDiscovery)
+	if n.LocalCfg.ServeStatus {
+		// the snapshot holds peers, endpoints, keys and routes, and the listener has no authentication
+		mux.HandleFunc("/status", n.handleStatusSnapshot)
+	}
+	return mux
+}
+
 func (o *observabilityServer) close() {
 	o.closeOnce.Do(func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -131,6 +138,23 @@ func (n *Nylon) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	writePrometheusMetrics(w, status)
 }
 
+func (n *Nylon) handleStatusSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), observabilityTimeout)
+	defer cancel()
+	status, err := n.statusSnapshot(ctx)
+	if err != nil {
+		http.Error(w, "status unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	data, err := pjMarshal.Marshal(status)
+	if err != nil {
+		http.Error(w, "status unavailable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
 func (n *Nylon) handleDiscovery(w http.ResponseWriter, _ *http.Request) {
 	_, port, err := net.SplitHostPort(n.LocalCfg.ObservabilityAddr)
 	if err != nil {
@@ -164,8 +188,16 @@ func writePrometheusMetrics(w io.Writer, status *protocol.StatusResponse) {
 	metrics.metric("nylon_active_endpoints", "Number of active peer endpoints.", "gauge", nil, float64(stats.ActiveEndpointCount))
 	metrics.metric("nylon_selected_routes", "Number of selected Babel routes.", "gauge", nil, float64(stats.SelectedRouteCount))
 	metrics.metric("nylon_advertised_prefixes", "Number of locally advertised prefixes.", "gauge", nil, float64(stats.AdvertisedPrefixCount))
+	draining := 0.0
+	if node.Draining {
+		draining = 1
+	}
+	metrics.metric("nylon_draining", "Whether the router is draining traffic away from itself.", "gauge", nil, draining)
 	metrics.metric("nylon_wireguard_transmit_bytes_total", "WireGuard bytes transmitted by this node.", "counter", nil, float64(stats.TxBytes))
 	metrics.metric("nylon_wireguard_receive_bytes_total", "WireGuard bytes received by this node.", "counter", nil, float64(stats.RxBytes))
+	metrics.metric("nylon_duplicate_packets_sent_total", "Copies of duplicated packets sent over a second path.", "counter", nil, float64(stats.DuplicatesSent))
+	metrics.metric("nylon_duplicate_packets_dropped_total", "Copies of duplicated packets dropped because another copy arrived first.", "counter", nil, float64(stats.DuplicatesDropped))
+	metrics.metric("nylon_port_forward_connections", "Connections tracked through the port forwards of this node.", "gauge", nil, float64(stats.PortForwardConnections))
 
 	for _, neigh := range status.Neighbours {
 		labels := map[string]string{"peer": neigh.PeerId}
@@ -177,6 +209,8 @@ func writePrometheusMetrics(w io.Writer, status *protocol.StatusResponse) {
 			handshake = float64(wg.LatestHandshakeUnix) / float64(time.Second)
 		}
 		metrics.metric("nylon_wireguard_peer_latest_handshake_seconds", "Unix time of the latest WireGuard handshake.", "gauge", labels, handshake)
+		metrics.metric("nylon_spoofed_packets_dropped_total", "Data packets from a peer dropped because the peer may not send from their source address.", "counter", labels, float64(neigh.SpoofedPackets))
+		metrics.metric("nylon_route_updates_unauthorized_total", "Route updates rejected because the originating router may not advertise the prefix, or its signature is invalid.", "counter", labels, float64(neigh.UnauthorizedUpdates))
 		for _, endpoint := range neigh.Endpoints {
 			epLabels := map[string]string{"peer": neigh.PeerId, "endpoint": endpoint.Address}
 			active := float64(0)
@@ -186,6 +220,15 @@ func writePrometheusMetrics(w io.Writer, status *protocol.StatusResponse) {
 			metrics.metric("nylon_endpoint_active", "Whether a peer endpoint is active.", "gauge", epLabels, active)
 			metrics.metric("nylon_endpoint_metric", "Current Babel endpoint metric.", "gauge", epLabels, float64(endpoint.Metric))
 			metrics.metric("nylon_endpoint_rtt_seconds", "Filtered endpoint round-trip time.", "gauge", epLabels, float64(endpoint.FilteredRttNs)/float64(time.Second))
+			metrics.metric("nylon_endpoint_jitter_seconds", "Smoothed endpoint round-trip time jitter.", "gauge", epLabels, float64(endpoint.JitterNs)/float64(time.Second))
+			metrics.metric("nylon_endpoint_loss_ratio", "Fraction of recent probes that were lost or late.", "gauge", epLabels, endpoint.LossRatio)
+			metrics.metric("nylon_endpoint_lost_probes_total", "Probes that were never answered.", "counter", epLabels, float64(endpoint.LostProbes))
+			metrics.metric("nylon_endpoint_late_probes_total", "Probes that were answered late.", "counter", epLabels, float64(endpoint.LateProbes))
+			metrics.metric("nylon_endpoint_transmit_packets_total", "Data packets sent through a peer endpoint.", "counter", epLabels, float64(endpoint.TxPackets))
+			metrics.metric("nylon_endpoint_transmit_bytes_total", "Data bytes sent through a peer endpoint.", "counter", epLabels, float64(endpoint.TxBytes))
+			metrics.metric("nylon_endpoint_receive_packets_total", "Data packets received through a peer endpoint.", "counter", epLabels, float64(endpoint.RxPackets))
+			metrics.metric("nylon_endpoint_receive_bytes_total", "Data bytes received through a peer endpoint.", "counter", epLabels, float64(endpoint.RxBytes))
+			metrics.metric("nylon_endpoint_bond_share", "Fraction of the traffic of a bonded link sent through a peer endpoint.", "gauge", epLabels, endpoint.BondShare)
 		}
 	}
 	for _, route := range status.GetRoutes().GetSelected() {
@@ -195,8 +238,38 @@ func writePrometheusMetrics(w io.Writer, status *protocol.StatusResponse) {
 			"router":   pub.GetSource().GetNodeId(),
 			"next_hop": route.GetNh(),
 		}
+		if src := pub.GetSource().GetSrcPrefix(); src != "" {
+			labels["src_prefix"] = src
+		}
 		metrics.metric("nylon_route_metric", "Metric of a selected Babel route.", "gauge", labels, float64(pub.GetFd().GetMetric()))
 	}
+	for _, rule := range status.GetAclRules() {
+		labels := map[string]string{"rule": rule.GetName(), "action": rule.GetAction()}
+		metrics.metric("nylon_acl_rule_hits_total", "Packets matched by an acl rule.", "counter", labels, float64(rule.GetHits()))
+		metrics.metric("nylon_acl_rule_drops_total", "Packets dropped by an acl rule.", "counter", labels, float64(rule.GetDrops()))
+	}
+	for _, class := range status.GetQosClasses() {
+		labels := map[string]string{"class": class.GetName(), "priority": class.GetPriority()}
+		metrics.metric("nylon_qos_class_packets_total", "Data packets sent in a qos class.", "counter", labels, float64(class.GetPackets()))
+		metrics.metric("nylon_qos_class_bytes_total", "Data bytes sent in a qos class.", "counter", labels, float64(class.GetBytes()))
+		metrics.metric("nylon_qos_class_drops_total", "Data packets of a qos class dropped above its rate limit.", "counter", labels, float64(class.GetDrops()))
+		metrics.metric("nylon_qos_class_queued_total", "Data packets of a qos class staged to peers, including duplicated copies.", "counter", labels, float64(class.GetQueued()))
+	}
+	for _, dampened := range status.GetDampenedSources() {
+		labels := map[string]string{
+			"prefix": dampened.GetSource().GetPrefix(),
+			"router": dampened.GetSource().GetNodeId(),
+		}
+		if src := dampened.GetSource().GetSrcPrefix(); src != "" {
+			labels["src_prefix"] = src
+		}
+		suppressed := 0.0
+		if dampened.GetSuppressed() {
+			suppressed = 1
+		}
+		metrics.metric("nylon_route_flap_penalty", "Flap dampening penalty of a route source.", "gauge", labels, dampened.GetPenalty())
+		metrics.metric("nylon_route_suppressed", "Whether a route source is suppressed by flap dampening.", "gauge", labels, suppressed)
+	}
 }
 
 type metricWriter struct {
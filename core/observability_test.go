@@ -8,6 +8,7 @@ import (
 	"net/netip"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/encodeous/nylon/protocol"
 	"github.com/encodeous/nylon/state"
@@ -60,3 +61,32 @@ func TestPrometheusMetrics(t *testing.T) {
 	require.Contains(t, output, `nylon_wireguard_peer_transmit_bytes_total{peer="bob"} 7`)
 	require.Equal(t, 1, strings.Count(output, "# HELP nylon_wireguard_peer_transmit_bytes_total "))
 }
+
+func TestPrometheusEndpointQualityMetrics(t *testing.T) {
+	status := &protocol.StatusResponse{
+		Node: &protocol.NodeStatus{Stats: &protocol.NodeStats{}},
+		Neighbours: []*protocol.NeighbourInfo{
+			{PeerId: "bob", Wireguard: &protocol.WireGuardPeerStats{}, Endpoints: []*protocol.EndpointInfo{
+				{Address: "10.0.0.2:57175", Active: true, JitterNs: int64(5 * time.Millisecond), LossRatio: 0.25, LostProbes: 3, LateProbes: 2},
+			}},
+		},
+	}
+	var buf bytes.Buffer
+	writePrometheusMetrics(&buf, status)
+	output := buf.String()
+	require.Contains(t, output, `nylon_endpoint_jitter_seconds{endpoint="10.0.0.2:57175",peer="bob"} 0.005`)
+	require.Contains(t, output, `nylon_endpoint_loss_ratio{endpoint="10.0.0.2:57175",peer="bob"} 0.25`)
+	require.Contains(t, output, `nylon_endpoint_lost_probes_total{endpoint="10.0.0.2:57175",peer="bob"} 3`)
+	require.Contains(t, output, `nylon_endpoint_late_probes_total{endpoint="10.0.0.2:57175",peer="bob"} 2`)
+}
+
+func TestObservabilityStatusIsOptIn(t *testing.T) {
+	n := &Nylon{}
+	rec := httptest.NewRecorder()
+	n.observabilityMux().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	n.LocalCfg.ServeStatus = true
+	_, pattern := n.observabilityMux().Handler(httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, "/status", pattern)
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"net/netip"
+
+	"github.com/encodeous/nylon/polyamide/device"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+// testTCPPacket builds an IPv4 TCP segment with a full TCP header and valid checksums
+func testTCPPacket(src, dst string, sport, dport uint16, from *device.Peer) *device.TCElement {
+	var buf [device.MaxMessageSize]byte
+	tce := &device.TCElement{Buffer: &buf, FromPeer: from}
+	tce.InitPacket(4, header.IPv4MinimumSize+header.TCPMinimumSize)
+	srcAs4, dstAs4 := netip.MustParseAddr(src).As4(), netip.MustParseAddr(dst).As4()
+	ipv4H := header.IPv4(tce.Packet)
+	ipv4H.Encode(&header.IPv4Fields{
+		SrcAddr:     tcpip.AddrFrom4(srcAs4),
+		DstAddr:     tcpip.AddrFrom4(dstAs4),
+		Protocol:    uint8(header.TCPProtocolNumber),
+		TTL:         64,
+		TotalLength: uint16(len(tce.Packet)),
+	})
+	tcpH := header.TCP(tce.Packet[header.IPv4MinimumSize:])
+	tcpH.Encode(&header.TCPFields{
+		SrcPort:    sport,
+		DstPort:    dport,
+		SeqNum:     1000,
+		DataOffset: header.TCPMinimumSize,
+		Flags:      header.TCPFlagSyn,
+		WindowSize: 65535,
+	})
+	ipv4H.SetChecksum(^ipv4H.CalculateChecksum())
+	pseudo := header.PseudoHeaderChecksum(header.TCPProtocolNumber, ipv4H.SourceAddress(), ipv4H.DestinationAddress(), header.TCPMinimumSize)
+	tcpH.SetChecksum(^tcpH.CalculateChecksum(pseudo))
+	return tce
+}
+
+// testChecksumsValid returns true if the IPv4 header and TCP checksums of a packet built by testTCPPacket are valid
+func testChecksumsValid(packet []byte) bool {
+	ipv4H := header.IPv4(packet)
+	tcpH := header.TCP(ipv4H.Payload())
+	return ipv4H.IsChecksumValid() && tcpH.IsChecksumValid(ipv4H.SourceAddress(), ipv4H.DestinationAddress(), 0, 0)
+}
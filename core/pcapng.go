@@ -0,0 +1,126 @@
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/encodeous/nylon/protocol"
+)
+
+// pcapng block types and options, see https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-02.html
+const (
+	pcapngSectionHeader     = 0x0A0D0D0A
+	pcapngInterface         = 0x00000001
+	pcapngEnhancedPacket    = 0x00000006
+	pcapngByteOrderMagic    = 0x1A2B3C4D
+	pcapngLinkTypeRaw       = 101 // raw IPv4 or IPv6 packets
+	pcapngOptEnd            = 0
+	pcapngOptComment        = 1
+	pcapngOptShbUserAppl    = 4
+	pcapngOptIfName         = 2
+	pcapngOptIfTsresol      = 9
+	pcapngOptEpbFlags       = 2
+	pcapngFlagInbound       = 1
+	pcapngFlagOutbound      = 2
+	pcapngTsresolNanosecond = 9
+)
+
+// PcapngWriter writes captured packets in the pcapng format, as a single interface of raw IP packets
+type PcapngWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewPcapngWriter writes the section and interface headers of a capture of an interface
+func NewPcapngWriter(w io.Writer, itf string, snaplen uint32) (*PcapngWriter, error) {
+	p := &PcapngWriter{w: w}
+
+	var shb []byte
+	shb = binary.LittleEndian.AppendUint32(shb, pcapngByteOrderMagic)
+	shb = binary.LittleEndian.AppendUint16(shb, 1) // major version
+	shb = binary.LittleEndian.AppendUint16(shb, 0) // minor version
+	shb = binary.LittleEndian.AppendUint64(shb, ^uint64(0))
+	shb = appendPcapngOption(shb, pcapngOptShbUserAppl, []byte("nylon"))
+	shb = appendPcapngOption(shb, pcapngOptEnd, nil)
+	if err := p.writeBlock(pcapngSectionHeader, shb); err != nil {
+		return nil, err
+	}
+
+	var idb []byte
+	idb = binary.LittleEndian.AppendUint16(idb, pcapngLinkTypeRaw)
+	idb = binary.LittleEndian.AppendUint16(idb, 0) // reserved
+	idb = binary.LittleEndian.AppendUint32(idb, snaplen)
+	idb = appendPcapngOption(idb, pcapngOptIfName, []byte(itf))
+	idb = appendPcapngOption(idb, pcapngOptIfTsresol, []byte{pcapngTsresolNanosecond})
+	idb = appendPcapngOption(idb, pcapngOptEnd, nil)
+	if err := p.writeBlock(pcapngInterface, idb); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// WritePacket writes a captured packet, with its direction as the packet flags, and its peers and action as a comment
+func (p *PcapngWriter) WritePacket(packet *protocol.CapturedPacket) error {
+	ts := uint64(packet.TimestampNs)
+	epb := p.buf[:0]
+	epb = binary.LittleEndian.AppendUint32(epb, 0) // interface id
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(ts>>32))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(ts))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(packet.Data)))
+	epb = binary.LittleEndian.AppendUint32(epb, packet.Length)
+	epb = append(epb, packet.Data...)
+	epb = append(epb, make([]byte, pcapngPadding(len(packet.Data)))...)
+
+	flags := uint32(pcapngFlagOutbound)
+	if packet.Incoming {
+		flags = pcapngFlagInbound
+	}
+	epb = appendPcapngOption(epb, pcapngOptEpbFlags, binary.LittleEndian.AppendUint32(nil, flags))
+	epb = appendPcapngOption(epb, pcapngOptComment, []byte(FormatCapturedPacket(packet)))
+	epb = appendPcapngOption(epb, pcapngOptEnd, nil)
+	p.buf = epb
+	return p.writeBlock(pcapngEnhancedPacket, epb)
+}
+
+// FormatCapturedPacket describes where a captured packet came from and what happened to it
+func FormatCapturedPacket(packet *protocol.CapturedPacket) string {
+	from := packet.FromPeer
+	if !packet.Incoming {
+		from = "local"
+	} else if from == "" {
+		from = "unknown peer"
+	}
+	desc := fmt.Sprintf("from %s, %s", from, packet.Action)
+	if packet.ToPeer != "" {
+		desc += " to " + packet.ToPeer
+	}
+	if packet.Skipped != 0 {
+		desc += fmt.Sprintf(" (%d packets skipped before)", packet.Skipped)
+	}
+	return desc
+}
+
+func (p *PcapngWriter) writeBlock(blockType uint32, body []byte) error {
+	// the block type and length precede the body, and the length is repeated after it
+	length := uint32(12 + len(body))
+	block := make([]byte, 0, length)
+	block = binary.LittleEndian.AppendUint32(block, blockType)
+	block = binary.LittleEndian.AppendUint32(block, length)
+	block = append(block, body...)
+	block = binary.LittleEndian.AppendUint32(block, length)
+	_, err := p.w.Write(block)
+	return err
+}
+
+func appendPcapngOption(b []byte, code uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value)))
+	b = append(b, value...)
+	return append(b, make([]byte, pcapngPadding(len(value)))...)
+}
+
+// pcapngPadding returns the padding after n bytes, as blocks and options are aligned to 32 bits
+func pcapngPadding(n int) int {
+	return (4 - n%4) % 4
+}
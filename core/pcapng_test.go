@@ -0,0 +1,48 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/encodeous/nylon/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPcapngWriter(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewPcapngWriter(&out, "nylon", 96)
+	require.NoError(t, err)
+	data := testTCPPacket("10.0.0.1", "10.0.0.2", 40000, 22, nil).Packet
+	require.NoError(t, w.WritePacket(&protocol.CapturedPacket{
+		TimestampNs: 1_700_000_000_123_456_789,
+		Incoming:    true,
+		FromPeer:    "bob",
+		Action:      "bounce",
+		Length:      uint32(len(data)) + 3,
+		Data:        data[:len(data)-1], // truncated, and not 32 bit aligned
+	}))
+
+	var blocks []uint32
+	b := out.Bytes()
+	for len(b) > 0 {
+		require.GreaterOrEqual(t, len(b), 12)
+		blockType, length := binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[4:])
+		require.Zero(t, length%4, "blocks are 32 bit aligned")
+		require.LessOrEqual(t, int(length), len(b))
+		assert.Equal(t, length, binary.LittleEndian.Uint32(b[length-4:]), "the length is repeated at the end of the block")
+		blocks = append(blocks, blockType)
+		if blockType == pcapngEnhancedPacket {
+			body := b[8 : length-4]
+			ts := uint64(binary.LittleEndian.Uint32(body[4:]))<<32 | uint64(binary.LittleEndian.Uint32(body[8:]))
+			assert.Equal(t, uint64(1_700_000_000_123_456_789), ts)
+			assert.Equal(t, uint32(len(data)-1), binary.LittleEndian.Uint32(body[12:]))
+			assert.Equal(t, uint32(len(data)+3), binary.LittleEndian.Uint32(body[16:]))
+			assert.Equal(t, data[:len(data)-1], body[20:20+len(data)-1])
+			assert.Contains(t, string(body), "from bob, bounce")
+		}
+		b = b[length:]
+	}
+	assert.Equal(t, []uint32{pcapngSectionHeader, pcapngInterface, pcapngEnhancedPacket}, blocks)
+}
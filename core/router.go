@@ -1,6 +1,8 @@
 package core
 
 import (
+	"bytes"
+	"iter"
 	"net/netip"
 	"time"
 
@@ -19,6 +21,26 @@ type RouteTableEntry struct {
 	Nh        state.NodeId
 	Peer      *device.Peer
 	Blackhole bool
+	// Multipath contains every next hop that flows are spread across, empty if the route has a single next hop
+	Multipath []NextHop
+}
+
+type NextHop struct {
+	Nh   state.NodeId
+	Peer *device.Peer
+}
+
+// Select picks the next hop for a packet. Flows are spread across multipath next hops by their 5-tuple hash,
+// so every packet of a flow takes the same path.
+func (e RouteTableEntry) Select(packet *device.TCElement) (state.NodeId, *device.Peer) {
+	if len(e.Multipath) == 0 {
+		return e.Nh, e.Peer
+	}
+	hop := e.Multipath[packet.FlowHash()%uint32(len(e.Multipath))]
+	if hop.Peer == nil {
+		return e.Nh, e.Peer
+	}
+	return hop.Nh, hop.Peer
 }
 
 type ForwardingTables struct {
@@ -26,6 +48,98 @@ type ForwardingTables struct {
 	Forward *bart.Table[RouteTableEntry]
 	// Exit contains only routes to services hosted on this node.
 	Exit *bart.Table[RouteTableEntry]
+	// SourceForward and SourceExit contain source-specific routes (RFC 9079), indexed by destination and then source prefix.
+	SourceForward *bart.Table[*bart.Table[RouteTableEntry]]
+	SourceExit    *bart.Table[*bart.Table[RouteTableEntry]]
+}
+
+// LookupForward finds the route for a packet from src to dst in the forwarding table.
+func (t *ForwardingTables) LookupForward(src, dst netip.Addr) (RouteTableEntry, bool) {
+	return lookupRoute(t.Forward, t.SourceForward, src, dst)
+}
+
+// LookupExit finds the route for a packet from src to dst in the exit table.
+func (t *ForwardingTables) LookupExit(src, dst netip.Addr) (RouteTableEntry, bool) {
+	return lookupRoute(t.Exit, t.SourceExit, src, dst)
+}
+
+// lookupRoute implements the disambiguation rule of RFC 9079 Section 5.1: the most specific destination prefix wins,
+// and among routes with the same destination, the most specific source prefix wins.
+func lookupRoute(table *bart.Table[RouteTableEntry], specific *bart.Table[*bart.Table[RouteTableEntry]], src, dst netip.Addr) (RouteTableEntry, bool) {
+	if specific == nil || specific.Size() == 0 {
+		return table.Lookup(dst)
+	}
+	host := netip.PrefixFrom(dst, dst.BitLen())
+	bits := -1
+	pfx, entry, ok := table.LookupPrefixLPM(host)
+	if ok {
+		bits = pfx.Bits()
+	}
+	for dstPfx, srcTable := range specific.Supernets(host) {
+		if dstPfx.Bits() < bits {
+			break // a plain route with a more specific destination takes precedence
+		}
+		if srcEntry, ok := srcTable.Lookup(src); ok {
+			return srcEntry, true
+		}
+	}
+	return entry, ok
+}
+
+// setRoute returns copies of the tables with the route for prefix replaced by entry, or removed if entry is nil.
+func setRoute(table *bart.Table[RouteTableEntry], specific *bart.Table[*bart.Table[RouteTableEntry]], prefix state.RoutePrefix, entry *RouteTableEntry) (*bart.Table[RouteTableEntry], *bart.Table[*bart.Table[RouteTableEntry]]) {
+	if !prefix.IsSourceSpecific() {
+		table = table.Clone()
+		if entry == nil {
+			table.Delete(prefix.Prefix)
+		} else {
+			table.Insert(prefix.Prefix, *entry)
+		}
+		return table, specific
+	}
+	if specific == nil {
+		specific = new(bart.Table[*bart.Table[RouteTableEntry]])
+	} else {
+		specific = specific.Clone()
+	}
+	srcTable, ok := specific.Get(prefix.Prefix)
+	if ok {
+		srcTable = srcTable.Clone()
+	} else {
+		srcTable = new(bart.Table[RouteTableEntry])
+	}
+	if entry == nil {
+		srcTable.Delete(prefix.Src)
+	} else {
+		srcTable.Insert(prefix.Src, *entry)
+	}
+	if srcTable.Size() == 0 {
+		specific.Delete(prefix.Prefix)
+	} else {
+		specific.Insert(prefix.Prefix, srcTable)
+	}
+	return table, specific
+}
+
+// allRoutes iterates over every route in the tables, including source-specific routes.
+func allRoutes(table *bart.Table[RouteTableEntry], specific *bart.Table[*bart.Table[RouteTableEntry]]) iter.Seq2[state.RoutePrefix, RouteTableEntry] {
+	return func(yield func(state.RoutePrefix, RouteTableEntry) bool) {
+		for prefix, entry := range table.All() {
+			if !yield(state.RoutePrefix{Prefix: prefix}, entry) {
+				return
+			}
+		}
+		if specific == nil {
+			return
+		}
+		for dst, srcTable := range specific.All() {
+			for src, entry := range srcTable.All() {
+				if !yield(state.RoutePrefix{Prefix: dst, Src: src}, entry) {
+					return
+				}
+			}
+		}
+	}
 }
 
 func (n *Nylon) GetNeighIO(neigh state.NodeId) *IOPending {
@@ -34,8 +148,8 @@ func (n *Nylon) GetNeighIO(neigh state.NodeId) *IOPending {
 		nio = &IOPending{
 			SeqnoReq:   make(map[state.Source]state.Pair[uint16, uint8]),
 			SeqnoDedup: ttlcache.New[state.Source, uint16](ttlcache.WithTTL[state.Source, uint16](n.SeqnoDedupTTL), ttlcache.WithDisableTouchOnHit[state.Source, uint16]()),
-			Acks:       make(map[netip.Prefix]struct{}),
-			Updates:    make(map[netip.Prefix]*protocol.Ny_Update),
+			Acks:       make(map[state.RoutePrefix]struct{}),
+			Updates:    make(map[state.RoutePrefix]*protocol.Ny_Update),
 		}
 		n.router.IO[neigh] = nio
 	}
@@ -44,17 +158,69 @@ func (n *Nylon) GetNeighIO(neigh state.NodeId) *IOPending {
 }
 
 func (n *Nylon) SendRouteUpdate(neigh state.NodeId, advRoute state.PubRoute) {
+	advRoute = exportRoute(n.RouterState, neigh, advRoute)
 	nio := n.GetNeighIO(neigh)
-	prefix, _ := advRoute.Prefix.MarshalBinary()
-	nio.Updates[advRoute.Prefix] = &protocol.Ny_Update{
-		RouterId: string(advRoute.NodeId),
-		Prefix:   prefix,
-		Seqno:    uint32(advRoute.Seqno),
-		Metric:   advRoute.Metric,
+	prefix, src := marshalRoutePrefix(advRoute.RoutePrefix)
+	update := &protocol.Ny_Update{
+		RouterId:  string(advRoute.NodeId),
+		Prefix:    prefix,
+		Seqno:     uint32(advRoute.Seqno),
+		Metric:    advRoute.Metric,
+		SrcPrefix: src,
+	}
+	if n.SignedRoutes && advRoute.Metric != state.INF {
+		update.Signature = n.routeSignature(advRoute)
+	}
+	nio.Updates[advRoute.RoutePrefix] = update
+}
+
+// routeVersion identifies a single origination of a route, which is what signed routes sign
+type routeVersion struct {
+	state.Source
+	Seqno uint16
+}
+
+// routeSignature returns the origin signature to send with route. Our own routes are signed on first use,
+// other routes carry the signature we verified when they were received.
+func (n *Nylon) routeSignature(route state.PubRoute) []byte {
+	version := routeVersion{route.Source, route.Seqno}
+	if sig, ok := n.router.Signatures[version]; ok {
+		return sig
+	}
+	if route.NodeId != n.LocalCfg.Id {
+		n.router.log.Debug("no signature for relayed route", "route", route)
+		return nil
+	}
+	sig, err := state.SignRoute(n.LocalCfg.Key, route.Source, route.Seqno)
+	if err != nil {
+		n.router.log.Error("failed to sign route", "route", route, "err", err)
+		return nil
+	}
+	n.router.Signatures[version] = sig
+	return sig
+}
+
+// checkSignature verifies the origin signature of an update when signed routes are enabled.
+// Retractions are not signed, as they only withdraw the sending neighbour's own entry. A signature only covers the
+// seqno, so an old update can be replayed once the seqno of its origin wraps around, see signed_routes in the docs.
+func (n *Nylon) checkSignature(neigh state.NodeId, src state.Source, update *protocol.Ny_Update) bool {
+	if !n.SignedRoutes || update.Metric == state.INF {
+		return true
+	}
+	version := routeVersion{src, uint16(update.Seqno)}
+	if sig, ok := n.router.Signatures[version]; ok && bytes.Equal(sig, update.Signature) {
+		return true
+	}
+	if !state.VerifyRoute(n.GetNode(src.NodeId).PubKey, src, version.Seqno, update.Signature) {
+		n.router.UnauthorizedUpdates[neigh]++
+		n.router.log.Warn("received update with invalid origin signature", "from", neigh, "source", src, "seqno", version.Seqno)
+		return false
 	}
+	n.router.Signatures[version] = update.Signature
+	return true
 }
 
-func (n *Nylon) SendAckRetract(neigh state.NodeId, prefix netip.Prefix) {
+func (n *Nylon) SendAckRetract(neigh state.NodeId, prefix state.RoutePrefix) {
 	nio := n.GetNeighIO(neigh)
 	nio.Acks[prefix] = struct{}{}
 }
@@ -104,43 +270,49 @@ func (n *Nylon) UpdateNeighbour(neigh state.NodeId) {
 	PushFullTable(n.RouterState, n, neigh)
 }
 
-func (n *Nylon) TableInsertRoute(prefix netip.Prefix, route state.SelRoute) {
+func (n *Nylon) TableInsertRoute(prefix state.RoutePrefix, route state.SelRoute) {
+	n.probeRate.routeChanged = true
 	nh := route.Nh
-	tables := n.router.Tables.Load()
-	nf := tables.Forward.Clone()
-	ne := tables.Exit.Clone()
+	next := *n.router.Tables.Load()
 	if route.Metric == state.INF {
-		nf.Insert(prefix, RouteTableEntry{
+		next.Forward, next.SourceForward = setRoute(next.Forward, next.SourceForward, prefix, &RouteTableEntry{
 			Nh:        nh,
 			Blackhole: true,
 		})
-		ne.Delete(prefix)
-		n.router.Tables.Store(&ForwardingTables{Forward: nf, Exit: ne})
+		next.Exit, next.SourceExit = setRoute(next.Exit, next.SourceExit, prefix, nil)
+		n.router.Tables.Store(&next)
 		return
 	}
 	peer := n.Device.LookupPeer(device.NoisePublicKey(n.GetNode(nh).PubKey))
-	nf.Insert(prefix, RouteTableEntry{
-		Nh:   nh,
-		Peer: peer,
+	var multipath []NextHop
+	for _, id := range route.Multipath {
+		multipath = append(multipath, NextHop{
+			Nh:   id,
+			Peer: n.Device.LookupPeer(device.NoisePublicKey(n.GetNode(id).PubKey)),
+		})
+	}
+	next.Forward, next.SourceForward = setRoute(next.Forward, next.SourceForward, prefix, &RouteTableEntry{
+		Nh:        nh,
+		Peer:      peer,
+		Multipath: multipath,
 	})
 	if route.Nh == n.LocalCfg.Id {
-		ne.Insert(prefix, RouteTableEntry{
+		next.Exit, next.SourceExit = setRoute(next.Exit, next.SourceExit, prefix, &RouteTableEntry{
 			Nh:   nh,
 			Peer: peer,
 		})
 	} else {
-		ne.Delete(prefix)
+		next.Exit, next.SourceExit = setRoute(next.Exit, next.SourceExit, prefix, nil)
 	}
-	n.router.Tables.Store(&ForwardingTables{Forward: nf, Exit: ne})
+	n.router.Tables.Store(&next)
 }
 
-func (n *Nylon) TableDeleteRoute(prefix netip.Prefix) {
-	tables := n.router.Tables.Load()
-	nf := tables.Forward.Clone()
-	ne := tables.Exit.Clone()
-	nf.Delete(prefix)
-	ne.Delete(prefix)
-	n.router.Tables.Store(&ForwardingTables{Forward: nf, Exit: ne})
+func (n *Nylon) TableDeleteRoute(prefix state.RoutePrefix) {
+	n.probeRate.routeChanged = true
+	next := *n.router.Tables.Load()
+	next.Forward, next.SourceForward = setRoute(next.Forward, next.SourceForward, prefix, nil)
+	next.Exit, next.SourceExit = setRoute(next.Exit, next.SourceExit, prefix, nil)
+	n.router.Tables.Store(&next)
 }
 
 func (n *Nylon) rebindForwardingPeers() {
@@ -160,9 +332,36 @@ func (n *Nylon) rebindForwardingPeers() {
 
 	forward, forwardChanged := rebindRouteTablePeers(tables.Forward, peers)
 	exit, exitChanged := rebindRouteTablePeers(tables.Exit, peers)
-	if forwardChanged || exitChanged {
-		n.router.Tables.Store(&ForwardingTables{Forward: forward, Exit: exit})
+	sourceForward, sourceForwardChanged := rebindSourceTablePeers(tables.SourceForward, peers)
+	sourceExit, sourceExitChanged := rebindSourceTablePeers(tables.SourceExit, peers)
+	if forwardChanged || exitChanged || sourceForwardChanged || sourceExitChanged {
+		n.router.Tables.Store(&ForwardingTables{
+			Forward:       forward,
+			Exit:          exit,
+			SourceForward: sourceForward,
+			SourceExit:    sourceExit,
+		})
+	}
+}
+
+func rebindSourceTablePeers(specific *bart.Table[*bart.Table[RouteTableEntry]], peers map[state.NodeId]*device.Peer) (*bart.Table[*bart.Table[RouteTableEntry]], bool) {
+	if specific == nil {
+		return nil, false
 	}
+	next := specific
+	changed := false
+	for dst, srcTable := range specific.All() {
+		rebound, ok := rebindRouteTablePeers(srcTable, peers)
+		if !ok {
+			continue
+		}
+		if !changed {
+			next = specific.Clone()
+			changed = true
+		}
+		next.Insert(dst, rebound)
+	}
+	return next, changed
 }
 
 func rebindRouteTablePeers(table *bart.Table[RouteTableEntry], peers map[state.NodeId]*device.Peer) (*bart.Table[RouteTableEntry], bool) {
@@ -173,7 +372,13 @@ func rebindRouteTablePeers(table *bart.Table[RouteTableEntry], peers map[state.N
 			continue
 		}
 		peer := peers[entry.Nh]
-		if entry.Peer == peer {
+		stale := entry.Peer != peer
+		for _, hop := range entry.Multipath {
+			if hop.Peer != peers[hop.Nh] {
+				stale = true
+			}
+		}
+		if !stale {
 			continue
 		}
 		if !changed {
@@ -181,6 +386,13 @@ func rebindRouteTablePeers(table *bart.Table[RouteTableEntry], peers map[state.N
 			changed = true
 		}
 		entry.Peer = peer
+		if len(entry.Multipath) != 0 {
+			multipath := make([]NextHop, 0, len(entry.Multipath))
+			for _, hop := range entry.Multipath {
+				multipath = append(multipath, NextHop{Nh: hop.Nh, Peer: peers[hop.Nh]})
+			}
+			entry.Multipath = multipath
+		}
 		next.Insert(prefix, entry)
 	}
 	return next, changed
@@ -190,11 +402,12 @@ type IOPending struct {
 	// SeqnoReq values represent a pair of (seqno, hop count)
 	SeqnoReq   map[state.Source]state.Pair[uint16, uint8]
 	SeqnoDedup *ttlcache.Cache[state.Source, uint16]
-	Acks       map[netip.Prefix]struct{}
-	Updates    map[netip.Prefix]*protocol.Ny_Update
+	Acks       map[state.RoutePrefix]struct{}
+	Updates    map[state.RoutePrefix]*protocol.Ny_Update
 }
 
 func (n *Nylon) CleanupRouter() error {
+	_ = n.persistRouterState()
 	n.router.log = nil
 	n.router.IO = nil
 	return nil
@@ -211,6 +424,21 @@ func (n *Nylon) GcRouter() error {
 	for _, nio := range n.router.IO {
 		nio.SeqnoDedup.DeleteExpired()
 	}
+	// keep only the signatures of routes we still hold
+	inUse := make(map[routeVersion]struct{})
+	for _, route := range n.RouterState.Routes {
+		inUse[routeVersion{route.Source, route.Seqno}] = struct{}{}
+	}
+	for _, neigh := range n.RouterState.Neighbours {
+		for _, route := range neigh.Routes {
+			inUse[routeVersion{route.Source, route.Seqno}] = struct{}{}
+		}
+	}
+	for version := range n.router.Signatures {
+		if _, ok := inUse[version]; !ok {
+			delete(n.router.Signatures, version)
+		}
+	}
 	return nil
 }
 
@@ -218,19 +446,24 @@ func (n *Nylon) InitRouter() error {
 	n.router.log = n.Log.With("module", log.ScopeRouter)
 	n.router.log.Debug("init router")
 	n.router.IO = make(map[state.NodeId]*IOPending)
+	n.router.UnauthorizedUpdates = make(map[state.NodeId]uint64)
+	n.router.Signatures = make(map[routeVersion][]byte)
 	n.router.Tables.Store(&ForwardingTables{
-		Forward: new(bart.Table[RouteTableEntry]),
-		Exit:    new(bart.Table[RouteTableEntry]),
+		Forward:       new(bart.Table[RouteTableEntry]),
+		Exit:          new(bart.Table[RouteTableEntry]),
+		SourceForward: new(bart.Table[*bart.Table[RouteTableEntry]]),
+		SourceExit:    new(bart.Table[*bart.Table[RouteTableEntry]]),
 	})
 	n.RouterState = &state.RouterState{
 		RouterTunables: &n.RouterTunables,
 		Id:             n.LocalCfg.Id,
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     make([]*state.Neighbour, 0),
-		Advertised:     make(map[netip.Prefix]state.Advertisement),
+		Advertised:     make(map[state.RoutePrefix]state.Advertisement),
 	}
+	n.restoreRouterState()
 	n.router.log.Debug("schedule router tasks")
 
 	n.RepeatTask(func() error {
@@ -245,6 +478,7 @@ func (n *Nylon) InitRouter() error {
 	n.RepeatTask(func() error {
 		return n.flushIO()
 	}, n.NeighbourIOFlushDelay)
+	n.RepeatTask(n.persistRouterState, n.StatePersistDelay)
 	return nil
 }
 
@@ -253,9 +487,14 @@ func (n *Nylon) ComputeSysRouteTable() []netip.Prefix {
 	prefixes := make([]netip.Prefix, 0)
 	selectedSelf := make([]netip.Prefix, 0)
 	for entry, v := range n.RouterState.Routes {
-		prefixes = append(prefixes, entry)
+		// a source-specific route installed by destination alone would pull the traffic of every source into the
+		// interface, where the packets that do not match its source are blackholed. They are left to the host to steer.
+		if entry.IsSourceSpecific() {
+			continue
+		}
+		prefixes = append(prefixes, entry.Prefix)
 		if v.Nh == n.LocalCfg.Id {
-			selectedSelf = append(selectedSelf, entry)
+			selectedSelf = append(selectedSelf, entry.Prefix)
 		}
 	}
 
@@ -278,7 +517,7 @@ func (n *Nylon) updatePassiveClient(prefix state.PrefixHealthWrapper, node state
 	// inserts an artificial route into the table
 
 	hasPassiveHold := false
-	old, ok := n.RouterState.Advertised[prefix.GetPrefix()]
+	old, ok := n.RouterState.Advertised[prefix.GetRoutePrefix()]
 	if ok && old.NodeId == node {
 		hasPassiveHold = old.IsPassiveHold
 	}
@@ -286,14 +525,14 @@ func (n *Nylon) updatePassiveClient(prefix state.PrefixHealthWrapper, node state
 	if passiveHold && !hasPassiveHold {
 		// the first time we enter passive hold, we should increment the seqno to prevent other nodes from switching away from the route
 		// this reduces a lot of route flapping when the client wakes up, sends some traffic and then goes back to sleep
-		n.RouterState.SetSeqno(prefix.GetPrefix(), n.RouterState.GetSeqno(prefix.GetPrefix())+1)
+		n.RouterState.SetSeqno(prefix.GetRoutePrefix(), n.RouterState.GetSeqno(prefix.GetRoutePrefix())+1)
 	}
 
 	metric, ok := prefix.StaticMetric()
 	if !ok {
 		return
 	}
-	n.RouterState.Advertised[prefix.GetPrefix()] = state.Advertisement{
+	n.RouterState.Advertised[prefix.GetRoutePrefix()] = state.Advertisement{
 		NodeId:        node,
 		Expiry:        time.Now().Add(n.ClientKeepaliveInterval),
 		IsPassiveHold: passiveHold,
@@ -306,7 +545,7 @@ func (n *Nylon) updatePassiveClient(prefix state.PrefixHealthWrapper, node state
 	}
 }
 
-func (n *Nylon) hasRecentlyAdvertised(prefix netip.Prefix) bool {
+func (n *Nylon) hasRecentlyAdvertised(prefix state.RoutePrefix) bool {
 	adv, ok := n.RouterState.Advertised[prefix]
 	if !ok {
 		return false
@@ -324,7 +563,7 @@ func (n *Nylon) checkNeigh(id state.NodeId) bool {
 	return false
 }
 
-func (n *Nylon) checkPrefix(prefix netip.Prefix) bool {
+func (n *Nylon) checkPrefix(prefix state.RoutePrefix) bool {
 	for _, p := range n.GetPrefixes() {
 		if p == prefix {
 			return true
@@ -334,6 +573,16 @@ func (n *Nylon) checkPrefix(prefix netip.Prefix) bool {
 	return false
 }
 
+// checkOrigin ensures that origin is configured to advertise prefix, and counts the updates rejected from neigh
+func (n *Nylon) checkOrigin(neigh state.NodeId, origin state.NodeId, prefix state.RoutePrefix) bool {
+	if MayOriginate(n.RouterState, origin, prefix) {
+		return true
+	}
+	n.router.UnauthorizedUpdates[neigh]++
+	n.router.log.Warn("received update from unauthorized origin", "from", neigh, "router", origin, "prefix", prefix)
+	return false
+}
+
 func (n *Nylon) checkNode(id state.NodeId) bool {
 	ncfg := n.TryGetNode(id)
 	if ncfg == nil {
@@ -344,34 +593,40 @@ func (n *Nylon) checkNode(id state.NodeId) bool {
 
 // packet handlers
 func (n *Nylon) routerHandleRouteUpdate(node state.NodeId, update *protocol.Ny_Update) error {
-	prefix := netip.Prefix{}
-	err := prefix.UnmarshalBinary(update.Prefix)
+	prefix, err := unmarshalRoutePrefix(update.Prefix, update.SrcPrefix)
 	if err != nil {
 		n.router.log.Warn("received update with invalid prefix", "prefix", update.Prefix, "err", err)
 		return nil
 	}
+	src := state.Source{
+		NodeId:      state.NodeId(update.RouterId),
+		RoutePrefix: prefix,
+	}
 	if !n.checkNeigh(node) ||
 		!n.checkPrefix(prefix) ||
-		!n.checkNode(state.NodeId(update.RouterId)) {
+		!n.checkNode(src.NodeId) ||
+		!n.checkOrigin(node, src.NodeId, prefix) ||
+		!n.checkSignature(node, src, update) {
 		return nil
 	}
+	neigh := n.RouterState.GetNeighbour(node)
+	allowed := n.mayUseSource(neigh, prefix)
 	HandleNeighbourUpdate(n.RouterState, n, node, state.PubRoute{
-		Source: state.Source{
-			NodeId: state.NodeId(update.RouterId),
-			Prefix: prefix,
-		},
+		Source: src,
 		FD: state.FD{
 			Seqno:  uint16(update.Seqno),
 			Metric: update.Metric,
 		},
 	})
 	ComputeRoutes(n.RouterState, n)
+	if n.mayUseSource(neigh, prefix) != allowed {
+		n.syncSources()
+	}
 	return nil
 }
 
 func (n *Nylon) routerHandleAckRetract(neigh state.NodeId, update *protocol.Ny_AckRetract) error {
-	prefix := netip.Prefix{}
-	err := prefix.UnmarshalBinary(update.Prefix)
+	prefix, err := unmarshalRoutePrefix(update.Prefix, update.SrcPrefix)
 	if err != nil {
 		n.router.log.Warn("received ack retract with invalid prefix", "prefix", update.Prefix, "err", err)
 		return nil
@@ -385,8 +640,7 @@ func (n *Nylon) routerHandleAckRetract(neigh state.NodeId, update *protocol.Ny_A
 }
 
 func (n *Nylon) routerHandleSeqnoRequest(neigh state.NodeId, pkt *protocol.Ny_SeqnoRequest) error {
-	prefix := netip.Prefix{}
-	err := prefix.UnmarshalBinary(pkt.Prefix)
+	prefix, err := unmarshalRoutePrefix(pkt.Prefix, pkt.SrcPrefix)
 	if err != nil {
 		n.router.log.Warn("received seqno request with invalid prefix", "prefix", pkt.Prefix, "err", err)
 		return nil
@@ -397,12 +651,36 @@ func (n *Nylon) routerHandleSeqnoRequest(neigh state.NodeId, pkt *protocol.Ny_Se
 		return nil
 	}
 	HandleSeqnoRequest(n.RouterState, n, neigh, state.Source{
-		NodeId: state.NodeId(pkt.RouterId),
-		Prefix: prefix,
+		NodeId:      state.NodeId(pkt.RouterId),
+		RoutePrefix: prefix,
 	}, uint16(pkt.Seqno), uint8(pkt.HopCount))
 	return nil
 }
 
+// marshalRoutePrefix encodes the destination and source prefix of a route, the source is empty for routes that are not source-specific.
+func marshalRoutePrefix(prefix state.RoutePrefix) ([]byte, []byte) {
+	dst, _ := prefix.Prefix.MarshalBinary()
+	if !prefix.IsSourceSpecific() {
+		return dst, nil
+	}
+	src, _ := prefix.Src.MarshalBinary()
+	return dst, src
+}
+
+func unmarshalRoutePrefix(dst []byte, src []byte) (state.RoutePrefix, error) {
+	prefix := state.RoutePrefix{}
+	if err := prefix.Prefix.UnmarshalBinary(dst); err != nil {
+		return prefix, err
+	}
+	if len(src) == 0 {
+		return prefix, nil
+	}
+	if err := prefix.Src.UnmarshalBinary(src); err != nil {
+		return prefix, err
+	}
+	return prefix, nil
+}
+
 func (n *Nylon) flushIO() error {
 	for _, neigh := range n.RouterState.Neighbours {
 		// TODO, investigate effect of packet loss on control messages
@@ -421,13 +699,14 @@ func (n *Nylon) flushIO() error {
 				// if a single proto message is somehow larger than SafeMTU, we still send it, but it will get fragmented
 
 				for seqR, _ := range nio.SeqnoReq {
-					prefixBytes, _ := seqR.Prefix.MarshalBinary()
+					prefixBytes, srcBytes := marshalRoutePrefix(seqR.RoutePrefix)
 					req := &protocol.Ny{Type: &protocol.Ny_SeqnoRequestOp{
 						SeqnoRequestOp: &protocol.Ny_SeqnoRequest{
-							RouterId: string(seqR.NodeId),
-							Prefix:   prefixBytes,
-							Seqno:    uint32(nio.SeqnoReq[seqR].V1),
-							HopCount: uint32(nio.SeqnoReq[seqR].V2),
+							RouterId:  string(seqR.NodeId),
+							Prefix:    prefixBytes,
+							Seqno:     uint32(nio.SeqnoReq[seqR].V1),
+							HopCount:  uint32(nio.SeqnoReq[seqR].V2),
+							SrcPrefix: srcBytes,
 						},
 					}}
 					if tLength != 0 && tLength+proto.Size(req) >= n.SafeMTU {
@@ -451,10 +730,11 @@ func (n *Nylon) flushIO() error {
 				}
 
 				for prefix := range nio.Acks {
-					prefixBytes, _ := prefix.MarshalBinary()
+					prefixBytes, srcBytes := marshalRoutePrefix(prefix)
 					req := &protocol.Ny{Type: &protocol.Ny_AckRetractOp{
 						AckRetractOp: &protocol.Ny_AckRetract{
-							Prefix: prefixBytes,
+							Prefix:    prefixBytes,
+							SrcPrefix: srcBytes,
 						},
 					}}
 					if tLength != 0 && tLength+proto.Size(req) >= n.SafeMTU {
@@ -4,7 +4,6 @@ package core
 // https://datatracker.ietf.org/doc/html/rfc8966
 
 import (
-	"net/netip"
 	"slices"
 	"time"
 
@@ -12,15 +11,18 @@ import (
 	"github.com/encodeous/nylon/state"
 )
 
+// DrainMetric is added to every route a draining router advertises
+const DrainMetric = state.INFM / 2
+
 // Router is an interface that defines the underlying router operations
 type Router interface {
 	SendRouteUpdate(neigh state.NodeId, advRoute state.PubRoute)
-	SendAckRetract(neigh state.NodeId, prefix netip.Prefix)
+	SendAckRetract(neigh state.NodeId, prefix state.RoutePrefix)
 	BroadcastSendRouteUpdate(advRoute state.PubRoute)
 	RequestSeqno(neigh state.NodeId, src state.Source, seqno uint16, hopCnt uint8)
 	BroadcastRequestSeqno(src state.Source, seqno uint16, hopCnt uint8)
-	TableInsertRoute(prefix netip.Prefix, route state.SelRoute)
-	TableDeleteRoute(prefix netip.Prefix)
+	TableInsertRoute(prefix state.RoutePrefix, route state.SelRoute)
+	TableDeleteRoute(prefix state.RoutePrefix)
 	RouterEvent(event string, desc string, args ...any)
 }
 
@@ -99,8 +101,57 @@ func PushFullTable(s *state.RouterState, r Router, neigh state.NodeId) {
 	}
 }
 
+// exportRoute applies the export policy to an update about to be sent to neigh.
+// Rejected routes are sent as retractions, so that the neighbour drops any copy it already holds.
+// A draining router still advertises its routes, with the metric raised so that neighbours use any alternative.
+func exportRoute(s *state.RouterState, neigh state.NodeId, route state.PubRoute) state.PubRoute {
+	if route.Metric == state.INF {
+		return route
+	}
+	if s.Policy != nil {
+		nh := s.Id
+		if selRoute, ok := s.Routes[route.RoutePrefix]; ok {
+			nh = selRoute.Nh
+		}
+		metric, accepted := s.Policy.Export(state.PolicyRoute{
+			RoutePrefix: route.RoutePrefix,
+			Origin:      route.NodeId,
+			Neighbour:   neigh,
+			NextHop:     nh,
+			Metric:      route.Metric,
+		})
+		if !accepted {
+			metric = state.INF
+		}
+		route.Metric = metric
+	}
+	if s.Draining {
+		route.Metric = AddMetric(route.Metric, DrainMetric)
+	}
+	return route
+}
+
+// SetDraining starts or stops moving traffic away from this router. When draining starts, the sources of our transit
+// routes are asked for a new seqno, so neighbours can switch to alternatives that are unfeasible with their current
+// feasibility distance instead of staying on us.
+func SetDraining(s *state.RouterState, r Router, draining bool) {
+	if s.Draining == draining {
+		return
+	}
+	s.Draining = draining
+	if draining {
+		for _, route := range s.Routes {
+			if route.Metric == state.INF || route.NodeId == s.Id || s.GetNeighbour(route.Nh) == nil {
+				continue
+			}
+			r.RequestSeqno(route.Nh, route.Source, route.Seqno+1, s.SeqnoRequestHopCount)
+		}
+	}
+	FullTableUpdate(s, r)
+}
+
 func RunGC(s *state.RouterState, r Router) {
-	now := time.Now()
+	now := s.Now()
 
 	//   When a route's expiry timer triggers, the behaviour depends on
 	//   whether the route's metric is finite.  If the metric is finite, it is
@@ -118,8 +169,8 @@ func RunGC(s *state.RouterState, r Router) {
 				} else {
 					// route expired, set metric to INF
 					route.Metric = state.INF
-					route.ExpireAt = time.Now().Add(s.RouteExpiryTime) // reset expiry time
-					neigh.Routes[prefix] = route                       // update the route
+					route.ExpireAt = s.Now().Add(s.RouteExpiryTime) // reset expiry time
+					neigh.Routes[prefix] = route                    // update the route
 					r.RouterEvent(log.EventRouteExpired, "expired and marked", "neigh", neigh.Id, "prefix", prefix)
 				}
 			}
@@ -143,18 +194,18 @@ func RunGC(s *state.RouterState, r Router) {
 	for src := range s.Sources {
 		found := false
 		for _, neigh := range s.Neighbours {
-			if nSrc, ok := neigh.Routes[src.Prefix]; ok && nSrc.Source == src {
+			if nSrc, ok := neigh.Routes[src.RoutePrefix]; ok && nSrc.Source == src {
 				found = true
 				break
 			}
 		}
 		if !found {
-			if selRoute, ok := s.Routes[src.Prefix]; ok && selRoute.Source == src {
+			if selRoute, ok := s.Routes[src.RoutePrefix]; ok && selRoute.Source == src {
 				found = true
 			}
 		}
 		if !found {
-			if adv, ok := s.Advertised[src.Prefix]; ok && adv.NodeId == src.NodeId {
+			if adv, ok := s.Advertised[src.RoutePrefix]; ok && adv.NodeId == src.NodeId {
 				found = true
 			}
 		}
@@ -164,7 +215,7 @@ func RunGC(s *state.RouterState, r Router) {
 	}
 }
 
-func retract(s *state.RouterState, r Router, prefix netip.Prefix) {
+func retract(s *state.RouterState, r Router, prefix state.RoutePrefix) {
 	tblEntry, ok := s.Routes[prefix]
 	if !ok {
 		r.RouterEvent(log.EventInconsistentState, "attempted to retract non-existent route", "prefix", prefix)
@@ -181,7 +232,7 @@ func HandleSeqnoRequest(s *state.RouterState, r Router, fromNeigh state.NodeId,
 	//   sequence number, it checks whether its route table contains a
 	//   selected entry for that prefix.
 
-	if selRoute, ok := s.Routes[src.Prefix]; ok {
+	if selRoute, ok := s.Routes[src.RoutePrefix]; ok {
 		//   If a selected route for the given prefix exists and has finite metric,
 		//   and either the router-ids are different or the router-ids are equal
 		//   and the entry's sequence number is no smaller (modulo 2^(16)) than
@@ -205,7 +256,7 @@ func HandleSeqnoRequest(s *state.RouterState, r Router, fromNeigh state.NodeId,
 				//   Nylon note: We increase seqno by more than one, as we do not persist our seqno
 				//   state, so we cannot guarantee that increasing by one is enough.
 
-				s.SetSeqno(selRoute.Prefix, reqSeqno)
+				s.SetSeqno(selRoute.RoutePrefix, reqSeqno)
 				ComputeRoutes(s, r) // should generate an update
 			} else {
 				//   Otherwise, if the requested router-id is not its own, the received
@@ -214,7 +265,7 @@ func HandleSeqnoRequest(s *state.RouterState, r Router, fromNeigh state.NodeId,
 				//   neighbours, the node selects a neighbour to forward the request to as
 				//   follows:
 
-				_, isAdv := s.Routes[src.Prefix]
+				_, isAdv := s.Routes[src.RoutePrefix]
 				if hopCnt >= 2 && isAdv {
 					var nh *state.NodeId
 					if NeighContainsFunc(s, func(neigh state.NodeId, route state.NeighRoute) bool {
@@ -225,7 +276,7 @@ func HandleSeqnoRequest(s *state.RouterState, r Router, fromNeigh state.NodeId,
 						if n == nil || n.BestEndpoint() == nil {
 							return false
 						}
-						if src.Prefix == route.Prefix && neigh != fromNeigh && route.Metric != state.INF && checkFeasibility(s, route.PubRoute) {
+						if src.RoutePrefix == route.RoutePrefix && neigh != fromNeigh && route.Metric != state.INF && checkFeasibility(s, route.PubRoute) {
 							nh = &neigh
 							return true // found a feasible route
 						}
@@ -239,7 +290,7 @@ func HandleSeqnoRequest(s *state.RouterState, r Router, fromNeigh state.NodeId,
 						if n == nil || n.BestEndpoint() == nil {
 							return false
 						}
-						if src.Prefix == route.Prefix && neigh != fromNeigh && route.Metric != state.INF {
+						if src.RoutePrefix == route.RoutePrefix && neigh != fromNeigh && route.Metric != state.INF {
 							nh = &neigh
 							return true // found a route
 						}
@@ -260,7 +311,7 @@ func HandleSeqnoRequest(s *state.RouterState, r Router, fromNeigh state.NodeId,
 
 }
 
-func HandleAckRetract(s *state.RouterState, r Router, neighId state.NodeId, prefix netip.Prefix) {
+func HandleAckRetract(s *state.RouterState, r Router, neighId state.NodeId, prefix state.RoutePrefix) {
 	rt, ok := s.Routes[prefix]
 	if !ok {
 		r.RouterEvent(log.EventInconsistentState, "attempted to ack the retraction of a non-existent route", "prefix", prefix)
@@ -287,10 +338,22 @@ func HandleNeighbourUpdate(s *state.RouterState, r Router, neighId state.NodeId,
 
 	n := s.GetNeighbour(neighId)
 
-	_, ok := n.Routes[adv.Prefix]
+	_, ok := n.Routes[adv.RoutePrefix]
 
 	if adv.Metric == state.INF {
-		r.SendAckRetract(neighId, adv.Source.Prefix)
+		r.SendAckRetract(neighId, adv.RoutePrefix)
+	} else if metric, accepted := s.Policy.Import(state.PolicyRoute{
+		RoutePrefix: adv.RoutePrefix,
+		Origin:      adv.NodeId,
+		Neighbour:   neighId,
+		NextHop:     neighId,
+		Metric:      adv.Metric,
+	}); accepted {
+		adv.Metric = metric
+	} else {
+		// routes rejected by the import policy are treated as retractions
+		r.RouterEvent(log.EventRouteFiltered, "rejected by import policy", "neigh", neighId, "route", adv)
+		adv.Metric = state.INF
 	}
 
 	if !ok {
@@ -315,9 +378,9 @@ func HandleNeighbourUpdate(s *state.RouterState, r Router, neighId state.NodeId,
 		//      metric carried by the update.
 
 		// create the route
-		n.Routes[adv.Prefix] = state.NeighRoute{
+		n.Routes[adv.RoutePrefix] = state.NeighRoute{
 			PubRoute: adv,
-			ExpireAt: time.Now().Add(s.RouteExpiryTime),
+			ExpireAt: s.Now().Add(s.RouteExpiryTime),
 		}
 	} else {
 		// 		If such an entry exists:
@@ -326,7 +389,7 @@ func HandleNeighbourUpdate(s *state.RouterState, r Router, neighId state.NodeId,
 		//      the router-id of the update is equal to the router-id of the
 		//      entry, then the update MAY be ignored;
 
-		selRoute, hasSelected := s.Routes[adv.Source.Prefix]
+		selRoute, hasSelected := s.Routes[adv.RoutePrefix]
 		isSelected := hasSelected && selRoute.Nh == neighId && selRoute.Source == adv.Source
 		if !checkFeasibility(s, adv) {
 			dummy := state.SelRoute{
@@ -334,12 +397,7 @@ func HandleNeighbourUpdate(s *state.RouterState, r Router, neighId state.NodeId,
 				Nh:       neighId,
 				ExpireAt: time.Time{},
 			}
-			bestEp := n.BestEndpoint()
-			if bestEp != nil {
-				dummy.Metric = AddMetric(dummy.Metric, AddMetric(bestEp.Metric(), s.HopCost))
-			} else {
-				dummy.Metric = state.INF
-			}
+			dummy.Metric = AddMetric(dummy.Metric, linkMetric(s, n))
 			isMoreOptimal := hasSelected && IsStrictlyBetter(selRoute, dummy)
 			if isSelected {
 				// 3.8.2.2.  Dealing with Unfeasible Updates
@@ -371,14 +429,29 @@ func HandleNeighbourUpdate(s *state.RouterState, r Router, neighId state.NodeId,
 		//      update (possibly a retraction) MUST be sent in a timely manner as
 		//      described in Section 3.7.2.
 
-		nr := n.Routes[adv.Prefix]
+		nr := n.Routes[adv.RoutePrefix]
 		nr.PubRoute = adv
 
 		if adv.Metric != state.INF {
-			nr.ExpireAt = time.Now().Add(s.RouteExpiryTime)
+			nr.ExpireAt = s.Now().Add(s.RouteExpiryTime)
 		}
-		n.Routes[adv.Prefix] = nr
+		n.Routes[adv.RoutePrefix] = nr
+	}
+}
+
+// linkMetric is the cost of the link to neigh: the metric of its best endpoint, plus the hop cost
+// and the static cost of the link.
+func linkMetric(s *state.RouterState, neigh *state.Neighbour) uint32 {
+	bestEp := neigh.BestEndpoint()
+	if bestEp == nil {
+		return state.INF
 	}
+	return AddMetric(AddMetric(bestEp.Metric(), s.HopCost), neigh.Link.Cost) // hop cost prevents 0 cost metric
+}
+
+func isBackupRoute(s *state.RouterState, route state.SelRoute) bool {
+	neigh := s.GetNeighbour(route.Nh)
+	return neigh != nil && neigh.Link.Backup
 }
 
 func isHeldRoute(s *state.RouterState, route state.SelRoute) bool {
@@ -395,11 +468,52 @@ func isHeldRoute(s *state.RouterState, route state.SelRoute) bool {
 	hasLink := neigh != nil && neigh.BestEndpoint() != nil
 	return (route.Metric == state.INF || !hasLink) &&
 		len(route.RetractedBy) < connectedNeighs &&
-		route.ExpireAt.After(time.Now())
+		route.ExpireAt.After(s.Now())
+}
+
+// dampenSources feeds the metrics advertised for every source into the dampener, and returns the sources that are
+// suppressed. Only neighbours with a link are heard, so that losing a link does not count as a flap of the sources
+// behind it.
+func dampenSources(s *state.RouterState, advMetrics map[state.RoutePrefix]uint32) map[state.Source]bool {
+	if s.Dampening == nil {
+		return nil
+	}
+	heard := []state.NodeId{s.Id}
+	advertised := make(map[state.Source]map[state.NodeId]uint32)
+	advertise := func(src state.Source, id state.NodeId, metric uint32) {
+		if advertised[src] == nil {
+			advertised[src] = make(map[state.NodeId]uint32)
+		}
+		advertised[src][id] = metric
+	}
+	for prefix, metric := range advMetrics {
+		advertise(state.Source{NodeId: s.Id, RoutePrefix: prefix}, s.Id, metric)
+	}
+	for _, neigh := range s.Neighbours {
+		if neigh.BestEndpoint() == nil {
+			continue
+		}
+		heard = append(heard, neigh.Id)
+		for _, adv := range neigh.Routes {
+			advertise(adv.Source, neigh.Id, adv.Metric)
+		}
+	}
+	return s.Dampening.ObserveAdvertised(heard, advertised, s.LargeChangeThreshold, s.Now())
+}
+
+// markAnycastRoute remembers route if it is a usable route to the origin previously selected for an anycast prefix
+func markAnycastRoute(s *state.RouterState, sticky map[state.RoutePrefix]state.SelRoute, route state.SelRoute) {
+	if _, ok := s.Anycast[route.RoutePrefix]; !ok || route.Metric == state.INF {
+		return
+	}
+	prev, ok := s.Routes[route.RoutePrefix]
+	if ok && prev.Metric != state.INF && sameRoute(prev, route) {
+		sticky[route.RoutePrefix] = route
+	}
 }
 
 func ComputeRoutes(s *state.RouterState, r Router) {
-	newTable := make(map[netip.Prefix]state.SelRoute)
+	newTable := make(map[state.RoutePrefix]state.SelRoute)
 
 	// 3.5.4.  Hold Time
 	//
@@ -445,6 +559,7 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 	}
 
 	// add our own routes to the route table, so that we can advertise them
+	advMetrics := make(map[state.RoutePrefix]uint32, len(s.Advertised))
 	for prefix, adv := range s.Advertised {
 		advMetric := uint32(0)
 		if adv.IsPassiveHold {
@@ -453,11 +568,21 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 		} else if adv.MetricFn != nil {
 			advMetric = adv.MetricFn()
 		}
+		advMetrics[prefix] = advMetric
+	}
+	suppressed := dampenSources(s, advMetrics)
+	// routes for anycast prefixes that still reach the previously selected origin
+	sticky := make(map[state.RoutePrefix]state.SelRoute)
+	for prefix, adv := range s.Advertised {
+		advMetric := advMetrics[prefix]
+		if suppressed[state.Source{NodeId: s.Id, RoutePrefix: prefix}] {
+			advMetric = state.INF
+		}
 		newTable[prefix] = state.SelRoute{
 			PubRoute: state.PubRoute{
 				Source: state.Source{
-					NodeId: s.Id,
-					Prefix: prefix,
+					NodeId:      s.Id,
+					RoutePrefix: prefix,
 				},
 				FD: state.FD{
 					Seqno:  s.GetSeqno(prefix),
@@ -465,8 +590,9 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 				},
 			},
 			Nh:       adv.NodeId, // next hop is self or directly connected client
-			ExpireAt: slices.MinFunc([]time.Time{time.Now().Add(s.RouteExpiryTime), adv.Expiry}, time.Time.Compare),
+			ExpireAt: slices.MinFunc([]time.Time{s.Now().Add(s.RouteExpiryTime), adv.Expiry}, time.Time.Compare),
 		}
+		markAnycastRoute(s, sticky, newTable[prefix])
 	}
 
 	// 3.6.  Route Selection
@@ -500,12 +626,7 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 		// We refer to our current node as A, our neighbour as B, and S as our source.
 
 		// Cost(A, B)
-		CAB := state.INF
-
-		if bestEp != nil {
-			CAB = bestEp.Metric()
-			CAB = AddMetric(CAB, s.HopCost) // to prevent 0 cost metric
-		}
+		CAB := linkMetric(s, neigh)
 
 		// enumerate through neighbour advertisements
 		for prefix, adv := range neigh.Routes {
@@ -520,6 +641,11 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 				continue // ignored
 			}
 
+			// routes from a flapping source are not selected until it has been stable for a while
+			if suppressed[adv.Source] {
+				continue
+			}
+
 			fd := state.FD{
 				Seqno:  adv.Seqno,
 				Metric: totalCost,
@@ -538,6 +664,7 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 			if !checkFeasibility(s, adv.PubRoute) {
 				continue // ignored
 			}
+			markAnycastRoute(s, sticky, newRoute)
 
 			// Refresh the current winner for this recomputation. This keeps a
 			// selected next hop selected even when its metric worsens.
@@ -550,6 +677,13 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 				// create new route
 				newTable[prefix] = newRoute
 			} else {
+				// routes over backup links are only selected when nothing else is available
+				if oldBackup, newBackup := isBackupRoute(s, oldRoute), isBackupRoute(s, newRoute); oldRoute.Metric != state.INF && oldBackup != newBackup {
+					if oldBackup {
+						newTable[prefix] = newRoute
+					}
+					continue
+				}
 				// check if we should switch to this route
 				if oldRoute.Metric == newRoute.Metric {
 					if prevRoute, ok := s.Routes[prefix]; ok && sameRoute(oldRoute, prevRoute) {
@@ -563,6 +697,17 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 		}
 	}
 
+	// keep flows on the anycast origin they are using unless another origin is significantly better
+	for prefix, route := range sticky {
+		best := newTable[prefix]
+		if best.Source == route.Source || isBackupRoute(s, route) && !isBackupRoute(s, best) {
+			continue
+		}
+		if AddMetric(best.Metric, s.AnycastSwitchThreshold) > route.Metric {
+			newTable[prefix] = route
+		}
+	}
+
 	// compare our new route table with the old one
 
 	//   A change of router-id for the selected route to a given prefix may be
@@ -573,11 +718,13 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 	// Here, we also want to send updates for new seqno, and routes that changed drastically in metric
 
 	for prefix, newRoute := range newTable {
+		newRoute.Multipath = computeMultipath(s, newRoute)
+		newTable[prefix] = newRoute
 		oldRoute, exists := s.Routes[prefix]
 		if !exists || oldRoute.Metric == state.INF && newRoute.Metric != state.INF {
 			r.TableInsertRoute(prefix, newRoute)
 			r.RouterEvent(log.EventRouteInserted, "inserted", "prefix", prefix, "new", newRoute)
-		} else if oldRoute.Nh != newRoute.Nh {
+		} else if oldRoute.Nh != newRoute.Nh || !slices.Equal(oldRoute.Multipath, newRoute.Multipath) {
 			r.TableInsertRoute(prefix, newRoute)
 			r.RouterEvent(log.EventRouteUpdated, "updated", "prefix", prefix, "old", oldRoute, "new", newRoute)
 		}
@@ -603,6 +750,7 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 				// Add the retracted route back as INF so it can be held
 				oldRoute.Metric = state.INF
 				oldRoute.RetractedBy = nil
+				oldRoute.Multipath = nil
 				newTable[prefix] = oldRoute
 				// insert blackhole
 				r.TableInsertRoute(prefix, oldRoute)
@@ -618,6 +766,41 @@ func ComputeRoutes(s *state.RouterState, r Router) {
 	s.Routes = newTable // update the route table
 }
 
+// computeMultipath returns the set of next hops that traffic for the selected route may be spread across.
+// Every feasible route is loop-free (Section 2.4), so any neighbour advertising a feasible route from the same
+// source within MultipathBand of the selected metric can carry a share of the flows.
+func computeMultipath(s *state.RouterState, route state.SelRoute) []state.NodeId {
+	if s.MultipathBand < 1 || route.Metric == state.INF || route.Source.NodeId == s.Id {
+		return nil
+	}
+	limit := float64(route.Metric) * s.MultipathBand
+	backup := isBackupRoute(s, route)
+	nhs := make([]state.NodeId, 0)
+	for _, neigh := range s.Neighbours {
+		if neigh.Id == route.Nh {
+			nhs = append(nhs, neigh.Id)
+			continue
+		}
+		adv, ok := neigh.Routes[route.RoutePrefix]
+		if !ok || adv.Source != route.Source || adv.Metric == state.INF || neigh.Link.Backup != backup {
+			continue
+		}
+		totalCost := AddMetric(linkMetric(s, neigh), adv.Metric)
+		if totalCost == state.INF || float64(totalCost) > limit {
+			continue
+		}
+		if !checkFeasibility(s, adv.PubRoute) {
+			continue
+		}
+		nhs = append(nhs, neigh.Id)
+	}
+	if len(nhs) < 2 {
+		return nil
+	}
+	slices.Sort(nhs)
+	return nhs
+}
+
 func SolveStarvation(router *state.RouterState, r Router) {
 	// 3.8.2.1.  Avoiding Starvation
 
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/encodeous/nylon/state"
+)
+
+// ReconcileRouterConfig applies the central config to the state of a router: its neighbours and the links to them, the
+// route policy, anycast prefixes, flap dampening and the origins it accepts routes from. The daemon and the simulator
+// both configure their routers through it. endpoints sets the endpoints of a neighbour from the config of that router.
+// If the config cannot be compiled, the state is left untouched.
+func ReconcileRouterConfig(s *state.RouterState, cfg *state.CentralCfg, endpoints func(neigh *state.Neighbour, router state.RouterCfg)) error {
+	policy, err := cfg.CompileRoutePolicy(s.Id)
+	if err != nil {
+		return err
+	}
+	edges := cfg.GetEdges()
+	desired := make(map[state.NodeId]state.RouterCfg)
+	for _, peer := range cfg.GetPeers(s.Id) {
+		if cfg.IsRouter(peer) {
+			desired[peer] = cfg.GetRouter(peer)
+		}
+	}
+
+	// neighbours that are no longer peers are dropped, along with the routes they advertised
+	neighs := make([]*state.Neighbour, 0, len(desired))
+	for _, id := range slices.Sorted(maps.Keys(desired)) {
+		neigh := s.GetNeighbour(id)
+		if neigh == nil {
+			neigh = &state.Neighbour{
+				Id:     id,
+				Routes: make(map[state.RoutePrefix]state.NeighRoute),
+			}
+		}
+		endpoints(neigh, desired[id])
+		neigh.SetLink(cfg.GetLink(edges, s.Id, id))
+		neighs = append(neighs, neigh)
+	}
+	s.Neighbours = neighs
+	s.Policy = policy
+	s.Anycast = cfg.GetAnycastPrefixes()
+	s.Origins = cfg.GetOrigins()
+	if cfg.Dampening == nil {
+		s.Dampening = nil
+	} else if !s.Dampening.SameConfig(*cfg.Dampening) {
+		s.Dampening = state.NewDampener(*cfg.Dampening)
+	}
+	return nil
+}
+
+// ReconcileAdvertised advertises the prefixes configured on a router, and stops advertising the prefixes it no longer
+// has. advertise returns the advertisement of a configured prefix, and withdraw is called for every prefix removed.
+func ReconcileAdvertised(s *state.RouterState, prefixes []state.PrefixHealthWrapper, advertise func(prefix state.PrefixHealthWrapper) state.Advertisement, withdraw func(prefix state.RoutePrefix)) {
+	desired := make(map[state.RoutePrefix]state.PrefixHealthWrapper, len(prefixes))
+	for _, prefix := range prefixes {
+		desired[prefix.GetRoutePrefix()] = prefix
+	}
+	for prefix, adv := range s.Advertised {
+		if _, ok := desired[prefix]; !ok && adv.NodeId == s.Id {
+			withdraw(prefix)
+			delete(s.Advertised, prefix)
+		}
+	}
+	for prefix, config := range desired {
+		s.Advertised[prefix] = advertise(config)
+	}
+}
+
+// MayOriginate returns true if origin is configured to advertise prefix, so a neighbour cannot hijack a prefix by
+// claiming to forward it on behalf of another router.
+func MayOriginate(s *state.RouterState, origin state.NodeId, prefix state.RoutePrefix) bool {
+	return slices.Contains(s.Origins[prefix], origin)
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileRouterConfig(t *testing.T) {
+	lan := state.PrefixHealthWrapper{PrefixHealth: &state.StaticPrefixHealth{Prefix: netip.MustParsePrefix("10.1.0.0/16")}}
+	cfg := &state.CentralCfg{
+		Routers: []state.RouterCfg{
+			{NodeCfg: state.NodeCfg{Id: "a"}},
+			{NodeCfg: state.NodeCfg{Id: "b", Prefixes: []state.PrefixHealthWrapper{lan}}},
+			{NodeCfg: state.NodeCfg{Id: "c"}},
+		},
+		Clients:   []state.ClientCfg{{NodeCfg: state.NodeCfg{Id: "phone"}}},
+		Graph:     []string{"a, c [cost=7]", "a, b", "a, phone"},
+		Dampening: &state.DampeningCfg{},
+	}
+	s := &state.RouterState{Id: "a", Advertised: make(map[state.RoutePrefix]state.Advertisement)}
+	var configured []state.NodeId
+	endpoints := func(neigh *state.Neighbour, router state.RouterCfg) {
+		assert.Equal(t, neigh.Id, router.Id)
+		configured = append(configured, neigh.Id)
+	}
+	require.NoError(t, ReconcileRouterConfig(s, cfg, endpoints))
+
+	// passive clients are not router neighbours
+	assert.Equal(t, []state.NodeId{"b", "c"}, configured)
+	require.Len(t, s.Neighbours, 2)
+	assert.Equal(t, uint32(7), s.GetNeighbour("c").Link.Cost)
+	assert.True(t, MayOriginate(s, "b", lan.GetRoutePrefix()))
+	assert.False(t, MayOriginate(s, "c", lan.GetRoutePrefix()))
+	assert.NotNil(t, s.Dampening)
+
+	// neighbours keep their routes across reconciles, and are dropped once they are no longer peers
+	route := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.2.0.0/16")}
+	s.GetNeighbour("b").Routes[route] = state.NeighRoute{}
+	cfg.Graph = []string{"a, b"}
+	cfg.Dampening = nil
+	require.NoError(t, ReconcileRouterConfig(s, cfg, endpoints))
+	require.Len(t, s.Neighbours, 1)
+	assert.Contains(t, s.GetNeighbour("b").Routes, route)
+	assert.Nil(t, s.Dampening)
+}
+
+func TestReconcileAdvertised(t *testing.T) {
+	lan := state.PrefixHealthWrapper{PrefixHealth: &state.StaticPrefixHealth{Prefix: netip.MustParsePrefix("10.1.0.0/16")}}
+	client := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.5.0.2/32")}
+	s := &state.RouterState{Id: "a", Advertised: map[state.RoutePrefix]state.Advertisement{
+		{Prefix: netip.MustParsePrefix("10.9.0.0/16")}: {NodeId: "a"},
+		client: {NodeId: "phone"},
+	}}
+	var withdrawn []state.RoutePrefix
+	ReconcileAdvertised(s, []state.PrefixHealthWrapper{lan}, func(prefix state.PrefixHealthWrapper) state.Advertisement {
+		return state.Advertisement{NodeId: "a"}
+	}, func(prefix state.RoutePrefix) {
+		withdrawn = append(withdrawn, prefix)
+	})
+	// the prefixes of passive clients are advertised separately
+	assert.Equal(t, []state.RoutePrefix{{Prefix: netip.MustParsePrefix("10.9.0.0/16")}}, withdrawn)
+	assert.Len(t, s.Advertised, 2)
+	assert.Contains(t, s.Advertised, lan.GetRoutePrefix())
+	assert.Contains(t, s.Advertised, client)
+}
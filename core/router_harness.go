@@ -88,7 +88,7 @@ func NewRouterEvent(eventType string, args ...any) RouterEvent {
 	return RouterEvent{Type: eventType, Args: args}
 }
 
-func AckRetract(neigh state.NodeId, prefix netip.Prefix) RouterEvent {
+func AckRetract(neigh state.NodeId, prefix state.RoutePrefix) RouterEvent {
 	return NewRouterEvent(eventSendAckRetract, neigh, prefix)
 }
 
@@ -108,11 +108,11 @@ func BroadcastRequestSeqno(src state.Source, seqno uint16, hopCnt uint8) RouterE
 	return NewRouterEvent(eventBroadcastSeqnoRequest, src, seqno, hopCnt)
 }
 
-func TableInsert(prefix netip.Prefix, route state.SelRoute) RouterEvent {
+func TableInsert(prefix state.RoutePrefix, route state.SelRoute) RouterEvent {
 	return NewRouterEvent(eventTableInsertRoute, prefix, route)
 }
 
-func TableDelete(prefix netip.Prefix) RouterEvent {
+func TableDelete(prefix state.RoutePrefix) RouterEvent {
 	return NewRouterEvent(eventTableDeleteRoute, prefix)
 }
 
@@ -122,15 +122,15 @@ func RouterLog(event string, desc string, args ...any) RouterEvent {
 	return NewRouterEvent(eventRouterLog, eventArgs...)
 }
 
-func (h *RouterHarness) TableInsertRoute(prefix netip.Prefix, route state.SelRoute) {
+func (h *RouterHarness) TableInsertRoute(prefix state.RoutePrefix, route state.SelRoute) {
 	h.tableActions = append(h.tableActions, TableInsert(prefix, route))
 }
 
-func (h *RouterHarness) TableDeleteRoute(prefix netip.Prefix) {
+func (h *RouterHarness) TableDeleteRoute(prefix state.RoutePrefix) {
 	h.tableActions = append(h.tableActions, TableDelete(prefix))
 }
 
-func (h *RouterHarness) SendAckRetract(neigh state.NodeId, prefix netip.Prefix) {
+func (h *RouterHarness) SendAckRetract(neigh state.NodeId, prefix state.RoutePrefix) {
 	h.actions = append(h.actions, AckRetract(neigh, prefix))
 }
 
@@ -247,17 +247,17 @@ func MakeNeighbours(ids ...state.NodeId) []*state.Neighbour {
 	for _, id := range ids {
 		neighs = append(neighs, &state.Neighbour{
 			Id:     id,
-			Routes: make(map[netip.Prefix]state.NeighRoute),
+			Routes: make(map[state.RoutePrefix]state.NeighRoute),
 		})
 	}
 	return neighs
 }
 
-func MakePubRoute(nodeId state.NodeId, prefix netip.Prefix, seqno uint16, metric uint32) state.PubRoute {
+func MakePubRoute(nodeId state.NodeId, prefix state.RoutePrefix, seqno uint16, metric uint32) state.PubRoute {
 	return state.PubRoute{
 		Source: state.Source{
-			NodeId: nodeId,
-			Prefix: prefix,
+			NodeId:      nodeId,
+			RoutePrefix: prefix,
 		},
 		FD: state.FD{
 			Seqno:  seqno,
@@ -289,10 +289,10 @@ func RemoveLink(r *state.RouterState, ep *MockEndpoint) {
 	}
 }
 
-func (h *RouterHarness) NeighUpdate(rs *state.RouterState, neighId state.NodeId, nodeId state.NodeId, prefix netip.Prefix, seqno uint16, metric uint32) {
+func (h *RouterHarness) NeighUpdate(rs *state.RouterState, neighId state.NodeId, nodeId state.NodeId, prefix state.RoutePrefix, seqno uint16, metric uint32) {
 	HandleNeighbourUpdate(rs, h, neighId, MakePubRoute(nodeId, prefix, seqno, metric))
 }
 
-func (h *RouterHarness) NeighUpdateSvc(rs *state.RouterState, neighId state.NodeId, nodeId state.NodeId, prefix netip.Prefix, seqno uint16, metric uint32) {
+func (h *RouterHarness) NeighUpdateSvc(rs *state.RouterState, neighId state.NodeId, nodeId state.NodeId, prefix state.RoutePrefix, seqno uint16, metric uint32) {
 	HandleNeighbourUpdate(rs, h, neighId, MakePubRoute(nodeId, prefix, seqno, metric))
 }
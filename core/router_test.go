@@ -19,12 +19,12 @@ var (
 
 // Helper function to convert test node IDs to prefixes
 // Maps single letter IDs to IP addresses in 10.0.0.x/32 range
-func nodeToPrefix(nodeId string) netip.Prefix {
+func nodeToPrefix(nodeId string) state.RoutePrefix {
 	var ipByte byte
 	if len(nodeId) > 0 {
 		ipByte = strings.ToLower(nodeId)[0] - 'a' + 1
 	}
-	return netip.MustParsePrefix(fmt.Sprintf("10.0.0.%d/32", ipByte))
+	return state.RoutePrefix{Prefix: netip.MustParsePrefix(fmt.Sprintf("10.0.0.%d/32", ipByte))}
 }
 
 func TestRouterBasicComputeRoutes(t *testing.T) {
@@ -34,11 +34,11 @@ func TestRouterBasicComputeRoutes(t *testing.T) {
 	rs := state.RouterState{
 		RouterTunables: tunables,
 		Id:             "a",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("b", "c", "d"),
-		Advertised:     map[netip.Prefix]state.Advertisement{aPrefix: {NodeId: state.NodeId("a"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{aPrefix: {NodeId: state.NodeId("a"), Expiry: maxTime}},
 	}
 	ComputeRoutes(&rs, h)
 	// we should have only routes to ourselves
@@ -68,11 +68,11 @@ func TestRouterNet1A_BasicRetraction(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("S", "B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{aPrefix: {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{aPrefix: {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	sr := AddLink(rs, NewMockEndpoint("S", 1))
@@ -123,7 +123,7 @@ func TestRouterNet1A_BasicRetraction(t *testing.T) {
 	// B advertises S to A
 	h.NeighUpdate(rs, "B", "S", nodeToPrefix("S"), 0, 2)
 	a = h.GetActions()
-	a.AssertEqual(t, RequestSeqno("B", state.Source{NodeId: "S", Prefix: nodeToPrefix("S")}, 1, 64))
+	a.AssertEqual(t, RequestSeqno("B", state.Source{NodeId: "S", RoutePrefix: nodeToPrefix("S")}, 1, 64))
 
 	// Suppose now the link to S goes down
 	//          B
@@ -139,8 +139,8 @@ func TestRouterNet1A_BasicRetraction(t *testing.T) {
 	// We should retract our route to S
 	a.AssertContains(t, BroadcastUpdateRoute(state.PubRoute{
 		Source: state.Source{
-			NodeId: "S",
-			Prefix: nodeToPrefix("S"),
+			NodeId:      "S",
+			RoutePrefix: nodeToPrefix("S"),
 		},
 		FD: state.FD{
 			Seqno:  0,
@@ -164,11 +164,11 @@ func TestRouterNet2S_SolveStarvation(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "S",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("A", "B"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("S"): {NodeId: state.NodeId("S"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("S"): {NodeId: state.NodeId("S"), Expiry: maxTime}},
 	}
 
 	AS := AddLink(rs, NewMockEndpoint("A", 1))
@@ -196,9 +196,9 @@ func TestRouterNet2S_SolveStarvation(t *testing.T) {
 10.0.0.2/32 via (nh: A, router: B, prefix: 10.0.0.2/32, seqno: 0, metric: 2)`, rs.StringRoutes())
 
 	// check feasibility distances
-	assert.Equal(t, state.FD{Seqno: 0, Metric: 1}, rs.Sources[state.Source{NodeId: "A", Prefix: nodeToPrefix("A")}])
-	assert.Equal(t, state.FD{Seqno: 0, Metric: 2}, rs.Sources[state.Source{NodeId: "B", Prefix: nodeToPrefix("B")}])
-	assert.Equal(t, state.FD{Seqno: 0, Metric: 0}, rs.Sources[state.Source{NodeId: "S", Prefix: nodeToPrefix("S")}])
+	assert.Equal(t, state.FD{Seqno: 0, Metric: 1}, rs.Sources[state.Source{NodeId: "A", RoutePrefix: nodeToPrefix("A")}])
+	assert.Equal(t, state.FD{Seqno: 0, Metric: 2}, rs.Sources[state.Source{NodeId: "B", RoutePrefix: nodeToPrefix("B")}])
+	assert.Equal(t, state.FD{Seqno: 0, Metric: 0}, rs.Sources[state.Source{NodeId: "S", RoutePrefix: nodeToPrefix("S")}])
 
 	// Suppose now that the link to A goes down
 	//    A
@@ -215,8 +215,8 @@ func TestRouterNet2S_SolveStarvation(t *testing.T) {
 	// We should retract our route to A
 	a.AssertContains(t, BroadcastUpdateRoute(state.PubRoute{
 		Source: state.Source{
-			NodeId: "A",
-			Prefix: nodeToPrefix("A"),
+			NodeId:      "A",
+			RoutePrefix: nodeToPrefix("A"),
 		},
 		FD: state.FD{
 			Seqno:  0,
@@ -231,7 +231,7 @@ func TestRouterNet2S_SolveStarvation(t *testing.T) {
 	a.AssertNotContains(t, BroadcastUpdateRoute(state.PubRoute{}))
 	SolveStarvation(rs, h)
 	a = h.GetActions()
-	a.AssertContains(t, BroadcastRequestSeqno(state.Source{NodeId: "A", Prefix: nodeToPrefix("A")}, uint16(1), uint8(64)))
+	a.AssertContains(t, BroadcastRequestSeqno(state.Source{NodeId: "A", RoutePrefix: nodeToPrefix("A")}, uint16(1), uint8(64)))
 
 	// suppose now that A receives the seqno request, sends an update to B, and B sends it to S
 	h.NeighUpdate(rs, "B", "A", nodeToPrefix("A"), 1, 1)
@@ -239,8 +239,8 @@ func TestRouterNet2S_SolveStarvation(t *testing.T) {
 	a = h.GetActions()
 	pr := state.PubRoute{
 		Source: state.Source{
-			NodeId: "A",
-			Prefix: nodeToPrefix("A"),
+			NodeId:      "A",
+			RoutePrefix: nodeToPrefix("A"),
 		},
 		FD: state.FD{
 			Seqno:  1,
@@ -267,11 +267,11 @@ func TestRouterNet3A_HandleRetraction(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -344,11 +344,11 @@ func TestRouterNet4A_OverlappingServiceHoldLoop(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("S", "B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	SA := AddLink(rs, NewMockEndpoint("S", 1))
@@ -429,11 +429,11 @@ func TestRouterNet4A_OverlappingServiceMetricIncrease(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("S", "B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	SA := AddLink(rs, NewMockEndpoint("S", 1))
@@ -476,10 +476,10 @@ func TestRouterNet4A_OverlappingServiceMetricIncrease(t *testing.T) {
 	assert.Empty(t, a, "We should not change routes as S is still feasible")
 	// However, for C, Cost(A, S) = 3 > 2, meaning S is no longer feasible via A
 	// C should send a seqno request to A
-	HandleSeqnoRequest(rs, h, "C", state.Source{NodeId: "S", Prefix: nodeToPrefix("X")}, 1, 64)
+	HandleSeqnoRequest(rs, h, "C", state.Source{NodeId: "S", RoutePrefix: nodeToPrefix("X")}, 1, 64)
 	a = h.GetActions()
 	// A should forward the request to S, decrementing the TTL by 1
-	a.AssertEqual(t, RequestSeqno("S", state.Source{NodeId: "S", Prefix: nodeToPrefix("X")}, 1, 63))
+	a.AssertEqual(t, RequestSeqno("S", state.Source{NodeId: "S", RoutePrefix: nodeToPrefix("X")}, 1, 63))
 
 	// Now, S replies with an update with a higher seqno
 	h.NeighUpdateSvc(rs, "S", "S", nodeToPrefix("X"), 1, 0)
@@ -488,7 +488,7 @@ func TestRouterNet4A_OverlappingServiceMetricIncrease(t *testing.T) {
 	a.AssertEqual(t, BroadcastUpdateRoute(MakePubRoute("S", nodeToPrefix("X"), 1, 3)))
 
 	// Suppose, some other node also requests the seqno for S,X
-	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "S", Prefix: nodeToPrefix("X")}, 1, 64)
+	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "S", RoutePrefix: nodeToPrefix("X")}, 1, 64)
 	// A should not forward the request as we already have a route to S with an equivalent or higher seqno
 	a = h.GetActions()
 	// Instead, A should just reply with its current route to S,X
@@ -497,17 +497,17 @@ func TestRouterNet4A_OverlappingServiceMetricIncrease(t *testing.T) {
 	// Now, suppose some node requests the seqno for A
 
 	// Req 1: A should not increase its seqno
-	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "A", Prefix: nodeToPrefix("A")}, 0, 64)
+	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "A", RoutePrefix: nodeToPrefix("A")}, 0, 64)
 	a = h.GetActions()
 	a.AssertEqual(t, UpdateRoute("B", MakePubRoute("A", nodeToPrefix("A"), 0, 0)))
 
 	// Req 2: A should increase its seqno by 1
-	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "A", Prefix: nodeToPrefix("A")}, 1, 64)
+	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "A", RoutePrefix: nodeToPrefix("A")}, 1, 64)
 	a = h.GetActions()
 	a.AssertEqual(t, BroadcastUpdateRoute(MakePubRoute("A", nodeToPrefix("A"), 1, 0)))
 
 	// Req 3: A should increase its seqno to 5
-	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "A", Prefix: nodeToPrefix("A")}, 5, 64)
+	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "A", RoutePrefix: nodeToPrefix("A")}, 5, 64)
 	a = h.GetActions()
 	a.AssertEqual(t, BroadcastUpdateRoute(MakePubRoute("A", nodeToPrefix("A"), 5, 0)))
 }
@@ -536,11 +536,11 @@ func TestRouter_SeqnoRequestNotForwardedBackToRequester(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -550,13 +550,13 @@ func TestRouter_SeqnoRequestNotForwardedBackToRequester(t *testing.T) {
 	h.NeighUpdate(rs, "B", "S", sPrefix, 0, 1)
 	ComputeRoutes(rs, h)
 	assert.Equal(t, "B", string(rs.Routes[sPrefix].Nh))
-	assert.Equal(t, state.FD{Seqno: 0, Metric: 2}, rs.Sources[state.Source{NodeId: "S", Prefix: sPrefix}])
+	assert.Equal(t, state.FD{Seqno: 0, Metric: 2}, rs.Sources[state.Source{NodeId: "S", RoutePrefix: sPrefix}])
 	h.GetActions()
 
-	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "S", Prefix: sPrefix}, 1, 64)
+	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "S", RoutePrefix: sPrefix}, 1, 64)
 	a := h.GetActions()
-	a.AssertNotContains(t, RequestSeqno("B", state.Source{NodeId: "S", Prefix: sPrefix}, 1, 63))
-	a.AssertContains(t, RequestSeqno("C", state.Source{NodeId: "S", Prefix: sPrefix}, 1, 63))
+	a.AssertNotContains(t, RequestSeqno("B", state.Source{NodeId: "S", RoutePrefix: sPrefix}, 1, 63))
+	a.AssertContains(t, RequestSeqno("C", state.Source{NodeId: "S", RoutePrefix: sPrefix}, 1, 63))
 }
 
 func TestRouterNet5A_SelectedUnfeasibleUpdate(t *testing.T) {
@@ -575,11 +575,11 @@ func TestRouterNet5A_SelectedUnfeasibleUpdate(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -627,8 +627,8 @@ func TestRouterNet5A_SelectedUnfeasibleUpdate(t *testing.T) {
 	ComputeRoutes(rs, h)
 	a = h.GetActions()
 	a.AssertEqual(t,
-		RequestSeqno("B", state.Source{NodeId: "C", Prefix: nodeToPrefix("C")}, 1, 64),
-		RequestSeqno("B", state.Source{NodeId: "D", Prefix: nodeToPrefix("D")}, 1, 64),
+		RequestSeqno("B", state.Source{NodeId: "C", RoutePrefix: nodeToPrefix("C")}, 1, 64),
+		RequestSeqno("B", state.Source{NodeId: "D", RoutePrefix: nodeToPrefix("D")}, 1, 64),
 	)
 
 	// Now, we get the seqno updates from B
@@ -655,11 +655,11 @@ func TestRouter_BackupRouteOverridesHeldRoute(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	AC := AddLink(rs, NewMockEndpoint("C", 1))
@@ -685,7 +685,7 @@ func TestRouter_BackupRouteOverridesHeldRoute(t *testing.T) {
 
 	// A should realize it's starved and request a higher seqno
 	SolveStarvation(rs, h)
-	h.GetActions().AssertContains(t, BroadcastRequestSeqno(state.Source{NodeId: "C", Prefix: cPrefix}, uint16(1), uint8(64)))
+	h.GetActions().AssertContains(t, BroadcastRequestSeqno(state.Source{NodeId: "C", RoutePrefix: cPrefix}, uint16(1), uint8(64)))
 
 	// Now B advertises C with the higher seqno (1). This is now FEASIBLE.
 	h.NeighUpdate(rs, "B", "C", cPrefix, 1, 10)
@@ -709,11 +709,11 @@ func TestRouter_RetractedByClearedWhenHeldRouteRecovers(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C", "D"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	AC := AddLink(rs, NewMockEndpoint("C", 1))
@@ -750,11 +750,11 @@ func TestRouter_AckRetractIgnoredForFiniteRoute(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("C", 1))
@@ -785,11 +785,11 @@ func TestRouter_UnfeasibleUpdatePreferenceUsesTotalMetric(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	AC := AddLink(rs, NewMockEndpoint("C", 5))
@@ -810,7 +810,7 @@ func TestRouter_UnfeasibleUpdatePreferenceUsesTotalMetric(t *testing.T) {
 
 	h.NeighUpdate(rs, "B", "C", cPrefix, 0, 20)
 	a := h.GetActions()
-	a.AssertNotContains(t, RequestSeqno("B", state.Source{NodeId: "C", Prefix: cPrefix}, 1, 64))
+	a.AssertNotContains(t, RequestSeqno("B", state.Source{NodeId: "C", RoutePrefix: cPrefix}, 1, 64))
 }
 
 func TestRouter_KeepsSelectedRouteOnEqualMetric(t *testing.T) {
@@ -831,11 +831,11 @@ func TestRouter_KeepsSelectedRouteOnEqualMetric(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -868,16 +868,16 @@ func TestRouter_HeldRouteInstallsBlackhole(t *testing.T) {
 	//       C advertises 10.0.0.3/32
 
 	h := &RouterHarness{}
-	aggregate := netip.MustParsePrefix("10.0.0.0/24")
+	aggregate := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.0.0.0/24")}
 	specific := nodeToPrefix("C")
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -901,17 +901,17 @@ func TestRouter_HeldRouteInstallsBlackhole(t *testing.T) {
 func TestRouter_HeldRouteReleaseDeletesBlackhole(t *testing.T) {
 	releaseCases := []struct {
 		name    string
-		release func(*state.RouterState, *RouterHarness, netip.Prefix)
+		release func(*state.RouterState, *RouterHarness, state.RoutePrefix)
 	}{
 		{
 			name: "all acknowledgements received",
-			release: func(rs *state.RouterState, h *RouterHarness, prefix netip.Prefix) {
+			release: func(rs *state.RouterState, h *RouterHarness, prefix state.RoutePrefix) {
 				HandleAckRetract(rs, h, "B", prefix)
 			},
 		},
 		{
 			name: "hold expires",
-			release: func(rs *state.RouterState, h *RouterHarness, prefix netip.Prefix) {
+			release: func(rs *state.RouterState, h *RouterHarness, prefix state.RoutePrefix) {
 				route := rs.Routes[prefix]
 				route.ExpireAt = time.Now().Add(-time.Second)
 				rs.Routes[prefix] = route
@@ -924,16 +924,16 @@ func TestRouter_HeldRouteReleaseDeletesBlackhole(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tunables := ConfigureConstants()
 			h := &RouterHarness{}
-			aggregate := netip.MustParsePrefix("10.0.0.0/24")
+			aggregate := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.0.0.0/24")}
 			specific := nodeToPrefix("C")
 			rs := &state.RouterState{
 				RouterTunables: tunables,
 				Id:             "A",
-				SelfSeqno:      make(map[netip.Prefix]uint16),
-				Routes:         make(map[netip.Prefix]state.SelRoute),
+				SelfSeqno:      make(map[state.RoutePrefix]uint16),
+				Routes:         make(map[state.RoutePrefix]state.SelRoute),
 				Sources:        make(map[state.Source]state.FD),
 				Neighbours:     MakeNeighbours("B", "C"),
-				Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+				Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 			}
 
 			_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -985,16 +985,16 @@ func TestRouter_SelectedNeighbourUnfeasibleSourceChangeIsUnselected(t *testing.T
 
 	h := &RouterHarness{}
 	prefix := nodeToPrefix("P")
-	oldSrc := state.Source{NodeId: "S", Prefix: prefix}
-	newSrc := state.Source{NodeId: "T", Prefix: prefix}
+	oldSrc := state.Source{NodeId: "S", RoutePrefix: prefix}
+	newSrc := state.Source{NodeId: "T", RoutePrefix: prefix}
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -1030,11 +1030,11 @@ func TestRouter_DoesNotSelectInactiveEndpointRoute(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	ep := NewMockEndpoint("B", 1)
@@ -1067,11 +1067,11 @@ func TestRouter_SeqnoRequestSkipsInactiveForwardingNeighbour(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C", "D"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -1086,10 +1086,10 @@ func TestRouter_SeqnoRequestSkipsInactiveForwardingNeighbour(t *testing.T) {
 	h.NeighUpdate(rs, "D", "S", sPrefix, 0, 1)
 	h.GetActions()
 
-	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "S", Prefix: sPrefix}, 1, 64)
+	HandleSeqnoRequest(rs, h, "B", state.Source{NodeId: "S", RoutePrefix: sPrefix}, 1, 64)
 	a := h.GetActions()
-	a.AssertNotContains(t, RequestSeqno("C", state.Source{NodeId: "S", Prefix: sPrefix}, 1, 63))
-	a.AssertContains(t, RequestSeqno("D", state.Source{NodeId: "S", Prefix: sPrefix}, 1, 63))
+	a.AssertNotContains(t, RequestSeqno("C", state.Source{NodeId: "S", RoutePrefix: sPrefix}, 1, 63))
+	a.AssertContains(t, RequestSeqno("D", state.Source{NodeId: "S", RoutePrefix: sPrefix}, 1, 63))
 }
 
 func TestRouter_FullTableUpdateDoesNotUpdateFeasibilityForRetraction(t *testing.T) {
@@ -1110,12 +1110,12 @@ func TestRouter_FullTableUpdateDoesNotUpdateFeasibilityForRetraction(t *testing.
 
 	h := &RouterHarness{}
 	prefix := nodeToPrefix("S")
-	src := state.Source{NodeId: "S", Prefix: prefix}
+	src := state.Source{NodeId: "S", RoutePrefix: prefix}
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes: map[netip.Prefix]state.SelRoute{
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes: map[state.RoutePrefix]state.SelRoute{
 			prefix: {
 				PubRoute: MakePubRoute("S", prefix, 1, state.INF),
 				Nh:       "B",
@@ -1126,7 +1126,7 @@ func TestRouter_FullTableUpdateDoesNotUpdateFeasibilityForRetraction(t *testing.
 			src: {Seqno: 0, Metric: 1},
 		},
 		Neighbours: MakeNeighbours("B"),
-		Advertised: make(map[netip.Prefix]state.Advertisement),
+		Advertised: make(map[state.RoutePrefix]state.Advertisement),
 	}
 
 	FullTableUpdate(rs, h)
@@ -1149,15 +1149,15 @@ func TestRouter_SolveStarvationIgnoresRetractedNeighbourRoute(t *testing.T) {
 
 	h := &RouterHarness{}
 	prefix := nodeToPrefix("S")
-	src := state.Source{NodeId: "S", Prefix: prefix}
+	src := state.Source{NodeId: "S", RoutePrefix: prefix}
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        map[state.Source]state.FD{src: {Seqno: 0, Metric: 1}},
 		Neighbours:     MakeNeighbours("B"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -1188,12 +1188,12 @@ func TestRouter_SeqnoRequestDoesNotForwardToRetractedRoute(t *testing.T) {
 
 	h := &RouterHarness{}
 	prefix := nodeToPrefix("S")
-	src := state.Source{NodeId: "S", Prefix: prefix}
+	src := state.Source{NodeId: "S", RoutePrefix: prefix}
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes: map[netip.Prefix]state.SelRoute{
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes: map[state.RoutePrefix]state.SelRoute{
 			prefix: {
 				PubRoute: MakePubRoute("S", prefix, 0, state.INF),
 				Nh:       "B",
@@ -1202,7 +1202,7 @@ func TestRouter_SeqnoRequestDoesNotForwardToRetractedRoute(t *testing.T) {
 		},
 		Sources:    map[state.Source]state.FD{src: {Seqno: 0, Metric: 1}},
 		Neighbours: MakeNeighbours("B", "C", "D"),
-		Advertised: map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised: map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -1236,15 +1236,15 @@ func TestRouter_UnfeasibleEqualMetricUpdateDoesNotRequestSeqno(t *testing.T) {
 
 	h := &RouterHarness{}
 	prefix := nodeToPrefix("S")
-	src := state.Source{NodeId: "S", Prefix: prefix}
+	src := state.Source{NodeId: "S", RoutePrefix: prefix}
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	AC := AddLink(rs, NewMockEndpoint("C", 5))
@@ -1275,17 +1275,17 @@ func TestRouter_BroadcastUsesConfiguredNeighbours(t *testing.T) {
 	// silently drops the update/request.
 
 	prefix := nodeToPrefix("S")
-	src := state.Source{NodeId: "S", Prefix: prefix}
+	src := state.Source{NodeId: "S", RoutePrefix: prefix}
 	n := &Nylon{
 		RouterTunables: *tunables,
 		RouterState: &state.RouterState{
 			RouterTunables: tunables,
 			Id:             "A",
-			SelfSeqno:      make(map[netip.Prefix]uint16),
-			Routes:         make(map[netip.Prefix]state.SelRoute),
+			SelfSeqno:      make(map[state.RoutePrefix]uint16),
+			Routes:         make(map[state.RoutePrefix]state.SelRoute),
 			Sources:        make(map[state.Source]state.FD),
 			Neighbours:     MakeNeighbours("B", "C"),
-			Advertised:     make(map[netip.Prefix]state.Advertisement),
+			Advertised:     make(map[state.RoutePrefix]state.Advertisement),
 		},
 	}
 	n.router.IO = make(map[state.NodeId]*IOPending)
@@ -1325,11 +1325,11 @@ func TestRouter_HeldRouteDoesNotReinstallBlackholeOnNoopRecompute(t *testing.T)
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -1367,11 +1367,11 @@ func TestRouter5A_GCRoutes(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	_ = AddLink(rs, NewMockEndpoint("B", 1))
@@ -1433,11 +1433,11 @@ func TestRouterNet6A_ConvergeOptimal(t *testing.T) {
 	rs := &state.RouterState{
 		RouterTunables: tunables,
 		Id:             "A",
-		SelfSeqno:      make(map[netip.Prefix]uint16),
-		Routes:         make(map[netip.Prefix]state.SelRoute),
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
 		Sources:        make(map[state.Source]state.FD),
 		Neighbours:     MakeNeighbours("B", "C"),
-		Advertised:     map[netip.Prefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
+		Advertised:     map[state.RoutePrefix]state.Advertisement{nodeToPrefix("A"): {NodeId: state.NodeId("A"), Expiry: maxTime}},
 	}
 
 	AB := AddLink(rs, NewMockEndpoint("B", 1))
@@ -1524,3 +1524,379 @@ func TestRouterNet6A_ConvergeOptimal(t *testing.T) {
 10.0.0.3/32 via (nh: C, router: C, prefix: 10.0.0.3/32, seqno: 0, metric: 10000000)
 10.0.0.4/32 via (nh: C, router: D, prefix: 10.0.0.4/32, seqno: 0, metric: 10000001)`, rs.StringRoutes())
 }
+
+func TestRouter_MultipathIncludesFeasibleRoutesWithinBand(t *testing.T) {
+	tunables := ConfigureConstants()
+	tunables.MultipathBand = 1.5
+	// A reaches S through B, C and D. B and C are within the multipath band, D is not.
+	//
+	//       B
+	//     1 | 1
+	//   D - A - S (via B, C, D)
+	//     2 | 1
+	//       C
+
+	h := &RouterHarness{}
+	sPrefix := nodeToPrefix("S")
+	rs := &state.RouterState{
+		RouterTunables: tunables,
+		Id:             "A",
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
+		Sources:        make(map[state.Source]state.FD),
+		Neighbours:     MakeNeighbours("B", "C", "D"),
+		Advertised:     map[state.RoutePrefix]state.Advertisement{},
+	}
+
+	_ = AddLink(rs, NewMockEndpoint("B", 1))
+	AC := AddLink(rs, NewMockEndpoint("C", 2))
+	_ = AddLink(rs, NewMockEndpoint("D", 5))
+
+	h.NeighUpdate(rs, "B", "S", sPrefix, 0, 1)
+	h.NeighUpdate(rs, "C", "S", sPrefix, 0, 1)
+	h.NeighUpdate(rs, "D", "S", sPrefix, 0, 1)
+	ComputeRoutes(rs, h)
+	assert.Equal(t, "B", string(rs.Routes[sPrefix].Nh))
+	assert.Equal(t, []state.NodeId{"B", "C"}, rs.Routes[sPrefix].Multipath)
+	h.GetTableActions().AssertContains(t, TableInsert(sPrefix, rs.Routes[sPrefix]))
+
+	// C falls outside the band, so the route reverts to a single next hop
+	AC.metric = 4
+	ComputeRoutes(rs, h)
+	assert.Equal(t, "B", string(rs.Routes[sPrefix].Nh))
+	assert.Empty(t, rs.Routes[sPrefix].Multipath)
+	h.GetTableActions().AssertContains(t, TableInsert(sPrefix, rs.Routes[sPrefix]))
+}
+
+func TestRouter_MultipathDisabledByDefault(t *testing.T) {
+	tunables := ConfigureConstants()
+	h := &RouterHarness{}
+	sPrefix := nodeToPrefix("S")
+	rs := &state.RouterState{
+		RouterTunables: tunables,
+		Id:             "A",
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
+		Sources:        make(map[state.Source]state.FD),
+		Neighbours:     MakeNeighbours("B", "C"),
+		Advertised:     map[state.RoutePrefix]state.Advertisement{},
+	}
+
+	_ = AddLink(rs, NewMockEndpoint("B", 1))
+	_ = AddLink(rs, NewMockEndpoint("C", 1))
+
+	h.NeighUpdate(rs, "B", "S", sPrefix, 0, 1)
+	h.NeighUpdate(rs, "C", "S", sPrefix, 0, 1)
+	ComputeRoutes(rs, h)
+	assert.Empty(t, rs.Routes[sPrefix].Multipath)
+}
+
+func TestRouter_SourceSpecificRoutesAreSelectedIndependently(t *testing.T) {
+	tunables := ConfigureConstants()
+	h := &RouterHarness{}
+	defaultRoute := state.RoutePrefix{Prefix: netip.MustParsePrefix("0.0.0.0/0")}
+	fromSite := state.RoutePrefix{
+		Prefix: netip.MustParsePrefix("0.0.0.0/0"),
+		Src:    netip.MustParsePrefix("10.1.0.0/16"),
+	}
+	rs := &state.RouterState{
+		RouterTunables: tunables,
+		Id:             "S",
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
+		Sources:        make(map[state.Source]state.FD),
+		Neighbours:     MakeNeighbours("A", "B"),
+		Advertised:     map[state.RoutePrefix]state.Advertisement{},
+	}
+
+	_ = AddLink(rs, NewMockEndpoint("A", 10))
+	_ = AddLink(rs, NewMockEndpoint("B", 1))
+
+	h.NeighUpdate(rs, "A", "A", fromSite, 0, 0)
+	h.NeighUpdate(rs, "B", "B", defaultRoute, 0, 0)
+	ComputeRoutes(rs, h)
+
+	// the source-specific route is not replaced by the cheaper default route
+	assert.Equal(t, state.NodeId("A"), rs.Routes[fromSite].Nh)
+	assert.Equal(t, state.NodeId("B"), rs.Routes[defaultRoute].Nh)
+
+	a := h.GetTableActions()
+	a.AssertContains(t, TableInsert(fromSite, rs.Routes[fromSite]))
+	a.AssertContains(t, TableInsert(defaultRoute, rs.Routes[defaultRoute]))
+
+	// retracting the source-specific route leaves the default route alone
+	h.NeighUpdate(rs, "A", "A", fromSite, 0, state.INF)
+	h.GetActions().AssertContains(t, AckRetract("A", fromSite))
+	ComputeRoutes(rs, h)
+	assert.Equal(t, uint32(state.INF), rs.Routes[fromSite].Metric)
+	assert.Equal(t, state.NodeId("B"), rs.Routes[defaultRoute].Nh)
+}
+
+func TestRouter_PolicyFiltersImportsAndExports(t *testing.T) {
+	tunables := ConfigureConstants()
+	h := &RouterHarness{}
+	branch := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.20.1.0/24")}
+	partner := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.30.0.0/16")}
+	cfg := &state.CentralCfg{
+		Routers: []state.RouterCfg{
+			{NodeCfg: state.NodeCfg{Id: "s"}},
+			{NodeCfg: state.NodeCfg{Id: "a"}},
+			{NodeCfg: state.NodeCfg{Id: "b"}},
+		},
+		Graph: []string{"sites = A", "S, A, B"},
+		Policies: []state.PolicyCfg{{
+			Nodes: []string{"s"},
+			Import: []state.PolicyRule{
+				{Neighbours: []string{"b"}, Action: state.PolicyOffset, Offset: 100},
+				{Prefixes: []netip.Prefix{netip.MustParsePrefix("10.30.0.0/16")}, Neighbours: []string{"sites"}, Action: state.PolicyReject},
+			},
+			Export: []state.PolicyRule{
+				{Prefixes: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")}, Neighbours: []string{"b"}, Action: state.PolicyReject},
+			},
+		}},
+	}
+	policy, err := cfg.CompileRoutePolicy("s")
+	assert.NoError(t, err)
+	rs := &state.RouterState{
+		RouterTunables: tunables,
+		Id:             "s",
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
+		Sources:        make(map[state.Source]state.FD),
+		Neighbours:     MakeNeighbours("a", "b"),
+		Advertised:     map[state.RoutePrefix]state.Advertisement{},
+		Policy:         policy,
+	}
+
+	_ = AddLink(rs, NewMockEndpoint("a", 10))
+	_ = AddLink(rs, NewMockEndpoint("b", 10))
+
+	h.NeighUpdate(rs, "a", "a", branch, 0, 5)
+	h.NeighUpdate(rs, "a", "a", partner, 0, 5)
+	h.NeighUpdate(rs, "b", "b", partner, 0, 5)
+	ComputeRoutes(rs, h)
+
+	// the partner prefix is only accepted from B, with the offset applied
+	assert.Equal(t, state.NodeId("a"), rs.Routes[branch].Nh)
+	assert.Equal(t, state.NodeId("b"), rs.Routes[partner].Nh)
+	assert.Equal(t, uint32(115), rs.Routes[partner].Metric)
+	_, hasFiltered := rs.GetNeighbour("a").Routes[partner]
+	assert.False(t, hasFiltered)
+
+	// the branch prefix is exported to A but retracted towards B
+	assert.Equal(t, rs.Routes[branch].PubRoute, exportRoute(rs, "a", rs.Routes[branch].PubRoute))
+	assert.Equal(t, uint32(state.INF), exportRoute(rs, "b", rs.Routes[branch].PubRoute).Metric)
+	assert.Equal(t, rs.Routes[partner].PubRoute, exportRoute(rs, "a", rs.Routes[partner].PubRoute))
+}
+
+func TestRouter_LinkCostAndBackupLinks(t *testing.T) {
+	tunables := ConfigureConstants()
+	h := &RouterHarness{}
+	prefix := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.9.0.0/16")}
+	rs := &state.RouterState{
+		RouterTunables: tunables,
+		Id:             "S",
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
+		Sources:        make(map[state.Source]state.FD),
+		Neighbours:     MakeNeighbours("A", "B", "C"),
+		Advertised:     map[state.RoutePrefix]state.Advertisement{},
+	}
+	rs.GetNeighbour("A").Link = state.LinkAttrs{Cost: 100}
+	rs.GetNeighbour("B").Link = state.LinkAttrs{Backup: true}
+
+	_ = AddLink(rs, NewMockEndpoint("A", 10))
+	_ = AddLink(rs, NewMockEndpoint("B", 1))
+	linkC := AddLink(rs, NewMockEndpoint("C", 50))
+
+	h.NeighUpdate(rs, "A", "X", prefix, 0, 0)
+	h.NeighUpdate(rs, "B", "X", prefix, 0, 0)
+	h.NeighUpdate(rs, "C", "X", prefix, 0, 0)
+	ComputeRoutes(rs, h)
+
+	// C wins since the static cost of A outweighs its lower latency, and B is a backup link
+	assert.Equal(t, state.NodeId("C"), rs.Routes[prefix].Nh)
+	assert.Equal(t, uint32(50), rs.Routes[prefix].Metric)
+
+	// the backup link is used once the others are gone
+	RemoveLink(rs, linkC)
+	h.NeighUpdate(rs, "A", "X", prefix, 0, state.INF)
+	ComputeRoutes(rs, h)
+	assert.Equal(t, state.NodeId("B"), rs.Routes[prefix].Nh)
+}
+
+func TestRouter_FlapDampeningSuppressesUnstableSource(t *testing.T) {
+	tunables := ConfigureConstants()
+	h := &RouterHarness{}
+	prefix := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.9.0.0/16")}
+	rs := &state.RouterState{
+		RouterTunables: tunables,
+		Id:             "S",
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
+		Sources:        make(map[state.Source]state.FD),
+		Neighbours:     MakeNeighbours("A"),
+		Advertised:     map[state.RoutePrefix]state.Advertisement{},
+		Dampening:      state.NewDampener(state.DampeningCfg{}),
+	}
+	_ = AddLink(rs, NewMockEndpoint("A", 10))
+	src := state.Source{NodeId: "X", RoutePrefix: prefix}
+
+	// each retraction adds a penalty, the third one crosses the suppress threshold
+	seqno := uint16(0)
+	for range 3 {
+		seqno++
+		h.NeighUpdate(rs, "A", "X", prefix, seqno, 5)
+		ComputeRoutes(rs, h)
+		assert.Equal(t, state.NodeId("A"), rs.Routes[prefix].Nh)
+		h.NeighUpdate(rs, "A", "X", prefix, seqno, state.INF)
+		ComputeRoutes(rs, h)
+	}
+	assert.True(t, rs.Dampening.Entries[src].Suppressed)
+
+	// the source is not selected while it is suppressed, even though it is reachable again
+	seqno++
+	h.NeighUpdate(rs, "A", "X", prefix, seqno, 5)
+	ComputeRoutes(rs, h)
+	assert.NotEqual(t, uint32(5+10), rs.Routes[prefix].Metric)
+
+	// once the penalty decays below the reuse threshold, the route is selected again
+	rs.Dampening.Entries[src].Updated = time.Now().Add(-time.Hour)
+	ComputeRoutes(rs, h)
+	assert.False(t, rs.Dampening.Entries[src].Suppressed)
+	assert.Equal(t, state.NodeId("A"), rs.Routes[prefix].Nh)
+	assert.Equal(t, uint32(5+10), rs.Routes[prefix].Metric)
+}
+
+func TestRouter_FlapDampeningIgnoresLinkFlaps(t *testing.T) {
+	tunables := ConfigureConstants()
+	h := &RouterHarness{}
+	prefix := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.9.0.0/16")}
+	rs := &state.RouterState{
+		RouterTunables: tunables,
+		Id:             "S",
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
+		Sources:        make(map[state.Source]state.FD),
+		Neighbours:     MakeNeighbours("A", "B"),
+		Advertised:     map[state.RoutePrefix]state.Advertisement{},
+		Dampening:      state.NewDampener(state.DampeningCfg{}),
+	}
+	linkA := AddLink(rs, NewMockEndpoint("A", 10))
+	_ = AddLink(rs, NewMockEndpoint("B", 200_000))
+	src := state.Source{NodeId: "X", RoutePrefix: prefix}
+
+	h.NeighUpdate(rs, "A", "X", prefix, 1, 5)
+	h.NeighUpdate(rs, "B", "X", prefix, 1, 5)
+	ComputeRoutes(rs, h)
+	assert.Equal(t, state.NodeId("A"), rs.Routes[prefix].Nh)
+
+	for range 5 {
+		// the link to A fails, and its routes expire while it is down
+		RemoveLink(rs, linkA)
+		ComputeRoutes(rs, h)
+		h.NeighUpdate(rs, "A", "X", prefix, 1, state.INF)
+		ComputeRoutes(rs, h)
+		assert.Equal(t, state.NodeId("B"), rs.Routes[prefix].Nh)
+
+		// the link comes back with a much worse metric, then recovers
+		linkA.metric = 300_000
+		AddLink(rs, linkA)
+		ComputeRoutes(rs, h)
+		h.NeighUpdate(rs, "A", "X", prefix, 1, 5)
+		ComputeRoutes(rs, h)
+		linkA.metric = 10
+		ComputeRoutes(rs, h)
+	}
+	assert.Zero(t, rs.Dampening.Entries[src].Penalty)
+	assert.False(t, rs.Dampening.Entries[src].Suppressed)
+	assert.Equal(t, state.NodeId("A"), rs.Routes[prefix].Nh)
+	assert.Equal(t, uint32(5+10), rs.Routes[prefix].Metric)
+
+	// a retraction carried by a neighbour that stayed reachable is still penalised
+	h.NeighUpdate(rs, "A", "X", prefix, 2, state.INF)
+	h.NeighUpdate(rs, "B", "X", prefix, 2, state.INF)
+	ComputeRoutes(rs, h)
+	assert.Equal(t, float64(state.DampeningRetractPenalty), rs.Dampening.Entries[src].Penalty)
+}
+
+func TestRouter_AnycastRoutesStayOnSelectedOrigin(t *testing.T) {
+	tunables := ConfigureConstants()
+	h := &RouterHarness{}
+	vip := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.53.0.53/32")}
+	rs := &state.RouterState{
+		RouterTunables: tunables,
+		Id:             "S",
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
+		Sources:        make(map[state.Source]state.FD),
+		Neighbours:     MakeNeighbours("A", "B"),
+		Advertised:     map[state.RoutePrefix]state.Advertisement{},
+		Anycast:        map[state.RoutePrefix]struct{}{vip: {}},
+	}
+	_ = AddLink(rs, NewMockEndpoint("A", 10))
+	_ = AddLink(rs, NewMockEndpoint("B", 10))
+
+	h.NeighUpdate(rs, "A", "A", vip, 1, 1_000)
+	h.NeighUpdate(rs, "B", "B", vip, 1, 5_000)
+	ComputeRoutes(rs, h)
+	assert.Equal(t, state.NodeId("A"), rs.Routes[vip].Nh)
+
+	// A's health check slows down, but not by enough to move existing flows to B
+	h.NeighUpdate(rs, "A", "A", vip, 2, 15_000)
+	ComputeRoutes(rs, h)
+	assert.Equal(t, state.NodeId("A"), rs.Routes[vip].Nh)
+	assert.Equal(t, uint32(15_010), rs.Routes[vip].Metric)
+
+	// once B is better by more than the switch threshold, the route moves
+	h.NeighUpdate(rs, "A", "A", vip, 3, 40_000)
+	ComputeRoutes(rs, h)
+	assert.Equal(t, state.NodeId("B"), rs.Routes[vip].Nh)
+
+	// A recovering is not enough to move the flows back, but B failing its health check is
+	h.NeighUpdate(rs, "A", "A", vip, 4, 1_000)
+	ComputeRoutes(rs, h)
+	assert.Equal(t, state.NodeId("B"), rs.Routes[vip].Nh)
+	h.NeighUpdate(rs, "B", "B", vip, 2, state.INF)
+	ComputeRoutes(rs, h)
+	assert.Equal(t, state.NodeId("A"), rs.Routes[vip].Nh)
+}
+
+func TestRouter_DrainRaisesAdvertisedMetrics(t *testing.T) {
+	tunables := ConfigureConstants()
+	h := &RouterHarness{}
+	own := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.1.0.0/24")}
+	transit := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.9.0.0/16")}
+	rs := &state.RouterState{
+		RouterTunables: tunables,
+		Id:             "S",
+		SelfSeqno:      make(map[state.RoutePrefix]uint16),
+		Routes:         make(map[state.RoutePrefix]state.SelRoute),
+		Sources:        make(map[state.Source]state.FD),
+		Neighbours:     MakeNeighbours("A", "B"),
+		Advertised: map[state.RoutePrefix]state.Advertisement{
+			own: {NodeId: "S", Expiry: time.Now().Add(time.Hour), MetricFn: func() uint32 { return 0 }},
+		},
+	}
+	_ = AddLink(rs, NewMockEndpoint("A", 10))
+	_ = AddLink(rs, NewMockEndpoint("B", 10))
+	h.NeighUpdate(rs, "A", "X", transit, 3, 5)
+	ComputeRoutes(rs, h)
+	h.GetActions()
+
+	SetDraining(rs, h, true)
+	a := h.GetActions()
+	// the source of the transit route is asked for a new seqno, so B can switch to an alternative that is unfeasible now
+	a.AssertContains(t, RequestSeqno("A", state.Source{NodeId: "X", RoutePrefix: transit}, 4, tunables.SeqnoRequestHopCount))
+	a.AssertContains(t, BroadcastUpdateRoute(rs.Routes[transit].PubRoute))
+	assert.Equal(t, uint32(15+DrainMetric), exportRoute(rs, "B", rs.Routes[transit].PubRoute).Metric)
+	assert.Equal(t, uint32(DrainMetric), exportRoute(rs, "B", rs.Routes[own].PubRoute).Metric)
+	// the routes are still selected locally with their real metric, and retractions stay retractions
+	assert.Equal(t, uint32(15), rs.Routes[transit].Metric)
+	retraction := rs.Routes[transit].PubRoute
+	retraction.Metric = state.INF
+	assert.Equal(t, state.INF, exportRoute(rs, "B", retraction).Metric)
+
+	SetDraining(rs, h, false)
+	assert.Equal(t, uint32(15), exportRoute(rs, "B", rs.Routes[transit].PubRoute).Metric)
+}
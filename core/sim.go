@@ -0,0 +1,882 @@
+package core
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/encodeous/nylon/state"
+)
+
+// Simulation runs the routing protocol of every router in a central config in-process, on a virtual clock.
+// Links deliver control messages with a latency and a loss rate. Probing is modelled by link liveness: a link is
+// usable one probe interval after it comes up, and is declared dead LinkDeadThreshold after it fails, or after the
+// detect threshold for links with fast failure detection.
+// Health checks are assumed to pass, and passive clients are not simulated.
+type Simulation struct {
+	tunables state.RouterTunables
+	cfg      *state.CentralCfg
+	edges    []state.Edge
+	scenario *state.SimScenario
+	origins  map[state.RoutePrefix][]state.NodeId
+	start    time.Time
+	now      time.Time
+	seq      uint64
+	tasks    simTasks
+	rand     *rand.Rand
+	nodes    map[state.NodeId]*simNode
+	links    map[state.Pair[state.NodeId, state.NodeId]]*simLink
+	report   *SimReport
+	phase    *SimPhase
+	open     map[simAnomalyKey]*SimAnomaly // anomalies that are still present
+	hadRoute map[state.NodeId]map[state.RoutePrefix]struct{}
+	dirty    bool // forwarding state changed since the last check
+}
+
+// SimReport is the outcome of a simulation
+type SimReport struct {
+	Phases    []*SimPhase   `json:"phases"`
+	Anomalies []*SimAnomaly `json:"anomalies"`
+}
+
+// SimPhase covers the time from one scenario event to the next, the first phase starts with every router booting
+type SimPhase struct {
+	At        time.Duration               `json:"at"`
+	Event     string                      `json:"event"`
+	Converged time.Duration               `json:"converged"` // from the event to the last forwarding change of the phase
+	Changes   []SimRouteChange            `json:"changes"`
+	Tables    map[state.NodeId][]SimRoute `json:"tables"` // selected routes of the running routers at the end of the phase
+}
+
+type SimRoute struct {
+	Prefix string       `json:"prefix"`
+	Nh     state.NodeId `json:"nh"`
+	Origin state.NodeId `json:"origin"`
+	Metric uint32       `json:"metric"`
+}
+
+// SimRouteChange is a change of next hop in the forwarding table of a router
+type SimRouteChange struct {
+	At   time.Duration `json:"at"`
+	Node state.NodeId  `json:"node"`
+	SimRoute
+	Removed bool `json:"removed,omitempty"`
+}
+
+const (
+	SimLoop        = "loop"
+	SimBlackhole   = "blackhole"
+	SimUnreachable = "unreachable"
+)
+
+// SimAnomaly is a period during which packets are not delivered although the destination can be reached.
+// Packets loop, are dropped by a router on the path that has no usable route or forwards into a dead link,
+// or are not sent at all because the router lost its route.
+type SimAnomaly struct {
+	Kind     string         `json:"kind"`
+	Prefix   string         `json:"prefix"`
+	Path     []state.NodeId `json:"path"` // the routers in the loop, or the path ending at the router that drops packets
+	Start    time.Duration  `json:"start"`
+	End      time.Duration  `json:"end"`
+	Resolved bool           `json:"resolved"` // false if the anomaly persisted until the end of the simulation
+}
+
+type simAnomalyKey struct {
+	kind   string
+	prefix state.RoutePrefix
+	at     string
+}
+
+type simTask struct {
+	at  time.Time
+	seq uint64
+	fn  func()
+}
+
+type simTasks []simTask
+
+func (t simTasks) Len() int { return len(t) }
+func (t simTasks) Less(i, j int) bool {
+	if t[i].at.Equal(t[j].at) {
+		return t[i].seq < t[j].seq
+	}
+	return t[i].at.Before(t[j].at)
+}
+func (t simTasks) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t *simTasks) Push(x any)   { *t = append(*t, x.(simTask)) }
+func (t *simTasks) Pop() any {
+	old := *t
+	task := old[len(old)-1]
+	*t = old[:len(old)-1]
+	return task
+}
+
+type simLink struct {
+	a, b        state.NodeId
+	latency     time.Duration // one way
+	loss        float64
+	down        bool      // taken down by the scenario
+	alive       bool      // packets currently reach the other side
+	changedAt   time.Time // when alive last changed
+	activeUntil time.Time // while dead, when the routers stop considering the link active
+	gen         int
+}
+
+func simLinkOf(a, b state.NodeId) state.Pair[state.NodeId, state.NodeId] {
+	if b < a {
+		a, b = b, a
+	}
+	return state.Pair[state.NodeId, state.NodeId]{V1: a, V2: b}
+}
+
+// simEndpoint is the endpoint of a simulated link, its metric is the round trip time plus the loss penalty
+type simEndpoint struct {
+	sim  *Simulation
+	link *simLink
+}
+
+func (e *simEndpoint) UpdatePing(time.Duration) {}
+
+func (e *simEndpoint) Metric() uint32 {
+	if !e.IsActive() {
+		return state.INF
+	}
+	penalty := float64(e.sim.tunables.LossPenalty) * e.link.loss
+	return state.DurationToMetric(2*e.link.latency + time.Duration(penalty))
+}
+
+func (e *simEndpoint) IsRemote() bool {
+	return false
+}
+
+func (e *simEndpoint) IsActive() bool {
+	// a link that comes back before it was declared dead stays active
+	if e.sim.now.Before(e.link.activeUntil) {
+		return true
+	}
+	return e.link.alive && !e.sim.now.Before(e.link.changedAt.Add(e.sim.tunables.ProbeDelay))
+}
+
+func (e *simEndpoint) AsNylonEndpoint() *state.NylonEndpoint {
+	return nil
+}
+
+type simNode struct {
+	sim   *Simulation
+	id    state.NodeId
+	state *state.RouterState
+	io    map[state.NodeId]*simIO
+	up    bool
+	epoch int // incremented when the router stops, so its timers stop too
+}
+
+// simIO holds the messages queued for a neighbour until the next flush
+type simIO struct {
+	seqnoReq map[state.Source]state.Pair[uint16, uint8]
+	dedup    map[state.Source]simSeqnoDedup
+	acks     map[state.RoutePrefix]struct{}
+	updates  map[state.RoutePrefix]state.PubRoute
+}
+
+type simSeqnoDedup struct {
+	seqno  uint16
+	expiry time.Time
+}
+
+type simBundle struct {
+	seqnoReq map[state.Source]state.Pair[uint16, uint8]
+	updates  map[state.RoutePrefix]state.PubRoute
+	acks     map[state.RoutePrefix]struct{}
+}
+
+func newSimIO() *simIO {
+	return &simIO{
+		seqnoReq: make(map[state.Source]state.Pair[uint16, uint8]),
+		dedup:    make(map[state.Source]simSeqnoDedup),
+		acks:     make(map[state.RoutePrefix]struct{}),
+		updates:  make(map[state.RoutePrefix]state.PubRoute),
+	}
+}
+
+// NewSimulation prepares a simulation of cfg. The config is validated like a config applied by a router.
+func NewSimulation(cfg *state.CentralCfg, scenario *state.SimScenario, tunables state.RouterTunables) (*Simulation, error) {
+	normalized, err := normalizeCentralConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if scenario == nil {
+		scenario = &state.SimScenario{}
+	}
+	if err := state.SimScenarioValidator(scenario); err != nil {
+		return nil, err
+	}
+	start := time.Unix(0, 0)
+	return &Simulation{
+		tunables: tunables,
+		cfg:      normalized,
+		edges:    normalized.GetEdges(),
+		scenario: scenario,
+		origins:  normalized.GetOrigins(),
+		start:    start,
+		now:      start,
+		rand:     rand.New(rand.NewPCG(scenario.Seed, 0)),
+		nodes:    make(map[state.NodeId]*simNode),
+		links:    make(map[state.Pair[state.NodeId, state.NodeId]]*simLink),
+		report:   &SimReport{},
+		open:     make(map[simAnomalyKey]*SimAnomaly),
+		hadRoute: make(map[state.NodeId]map[state.RoutePrefix]struct{}),
+	}, nil
+}
+
+// Run simulates the scenario from the moment every router starts
+func (sim *Simulation) Run() (*SimReport, error) {
+	duration := sim.scenario.Duration
+	if duration == 0 {
+		duration = time.Minute
+		if len(sim.scenario.Events) > 0 {
+			duration += sim.scenario.Events[len(sim.scenario.Events)-1].At
+		}
+	}
+	sim.syncLinks()
+	for _, router := range sim.cfg.Routers {
+		if err := sim.boot(router.Id, nil); err != nil {
+			return nil, err
+		}
+	}
+	sim.startPhase("start")
+	sim.refreshLinks()
+	for _, event := range sim.scenario.Events {
+		if event.At > duration {
+			break
+		}
+		sim.runUntil(sim.start.Add(event.At))
+		sim.endPhase()
+		sim.startPhase(event.String())
+		if err := sim.apply(event); err != nil {
+			return nil, fmt.Errorf("event at %s: %w", event.At, err)
+		}
+		sim.checkForwarding()
+	}
+	sim.runUntil(sim.start.Add(duration))
+	sim.endPhase()
+	return sim.report, nil
+}
+
+func (sim *Simulation) elapsed() time.Duration {
+	return sim.now.Sub(sim.start)
+}
+
+func (sim *Simulation) clock() time.Time {
+	return sim.now
+}
+
+func (sim *Simulation) schedule(delay time.Duration, fn func()) {
+	sim.seq++
+	heap.Push(&sim.tasks, simTask{at: sim.now.Add(delay), seq: sim.seq, fn: fn})
+}
+
+func (sim *Simulation) runUntil(t time.Time) {
+	for len(sim.tasks) > 0 && !sim.tasks[0].at.After(t) {
+		task := heap.Pop(&sim.tasks).(simTask)
+		sim.now = task.at
+		task.fn()
+		if sim.dirty {
+			sim.checkForwarding()
+		}
+	}
+	sim.now = t
+}
+
+func (sim *Simulation) startPhase(event string) {
+	sim.phase = &SimPhase{
+		At:    sim.elapsed(),
+		Event: event,
+	}
+	sim.report.Phases = append(sim.report.Phases, sim.phase)
+}
+
+func (sim *Simulation) endPhase() {
+	sim.phase.Tables = make(map[state.NodeId][]SimRoute)
+	for id, node := range sim.nodes {
+		if !node.up {
+			continue
+		}
+		routes := make([]SimRoute, 0, len(node.state.Routes))
+		for prefix, route := range node.state.Routes {
+			routes = append(routes, simRoute(prefix, route))
+		}
+		slices.SortFunc(routes, func(a, b SimRoute) int {
+			return strings.Compare(a.Prefix, b.Prefix)
+		})
+		sim.phase.Tables[id] = routes
+	}
+}
+
+func simRoute(prefix state.RoutePrefix, route state.SelRoute) SimRoute {
+	return SimRoute{
+		Prefix: prefix.String(),
+		Nh:     route.Nh,
+		Origin: route.NodeId,
+		Metric: route.Metric,
+	}
+}
+
+func (sim *Simulation) recordChange(change SimRouteChange) {
+	change.At = sim.elapsed()
+	sim.phase.Changes = append(sim.phase.Changes, change)
+	sim.phase.Converged = change.At - sim.phase.At
+	sim.dirty = true
+}
+
+func (sim *Simulation) apply(event state.SimEvent) error {
+	switch event.Action {
+	case state.SimLinkDown, state.SimLinkUp, state.SimLatency, state.SimLoss:
+		link := sim.links[simLinkOf(event.Link[0], event.Link[1])]
+		if link == nil {
+			return fmt.Errorf("%s are not connected in the graph, or neither has an endpoint", state.FormatSimLink(event.Link))
+		}
+		switch event.Action {
+		case state.SimLinkDown:
+			link.down = true
+		case state.SimLinkUp:
+			link.down = false
+		case state.SimLatency:
+			link.latency = event.Latency
+			sim.schedule(sim.tunables.ProbeDelay, func() { sim.recompute(link) })
+		case state.SimLoss:
+			link.loss = event.Loss
+			sim.schedule(sim.tunables.ProbeDelay, func() { sim.recompute(link) })
+		}
+	case state.SimCrash:
+		node := sim.nodes[event.Node]
+		if node == nil || !node.up {
+			return fmt.Errorf("router %s is not running", event.Node)
+		}
+		node.stop()
+	case state.SimRestart:
+		node := sim.nodes[event.Node]
+		if node == nil || node.up {
+			return fmt.Errorf("router %s has not crashed", event.Node)
+		}
+		if err := sim.boot(event.Node, node.state.SelfSeqno); err != nil {
+			return err
+		}
+	case state.SimConfig:
+		if err := sim.applyConfig(event.Central); err != nil {
+			return err
+		}
+	}
+	sim.refreshLinks()
+	return nil
+}
+
+func (sim *Simulation) applyConfig(cfg *state.CentralCfg) error {
+	if cfg == nil {
+		return fmt.Errorf("config was not loaded")
+	}
+	normalized, err := normalizeCentralConfig(cfg)
+	if err != nil {
+		return err
+	}
+	sim.cfg = normalized
+	sim.edges = normalized.GetEdges()
+	sim.origins = normalized.GetOrigins()
+	for id, node := range sim.nodes {
+		if !normalized.IsRouter(id) {
+			node.stop()
+			delete(sim.nodes, id)
+		}
+	}
+	sim.syncLinks()
+	for _, router := range normalized.Routers {
+		node := sim.nodes[router.Id]
+		if node == nil {
+			if err := sim.boot(router.Id, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if !node.up {
+			continue // applied when it restarts
+		}
+		if err := node.configure(normalized); err != nil {
+			return err
+		}
+		ComputeRoutes(node.state, node)
+	}
+	return nil
+}
+
+// syncLinks creates a link for every pair of routers that are connected in the graph, when at least one of them
+// has an endpoint the other can reach
+func (sim *Simulation) syncLinks() {
+	conditions := make(map[state.Pair[state.NodeId, state.NodeId]]state.SimLinkCfg)
+	for _, link := range sim.scenario.Links {
+		conditions[simLinkOf(link.Link[0], link.Link[1])] = link
+	}
+	desired := make(map[state.Pair[state.NodeId, state.NodeId]]struct{})
+	for _, router := range sim.cfg.Routers {
+		for _, peer := range sim.cfg.GetPeers(router.Id) {
+			if !sim.cfg.IsRouter(peer) {
+				continue
+			}
+			if len(router.Endpoints) == 0 && len(sim.cfg.GetRouter(peer).Endpoints) == 0 {
+				continue
+			}
+			key := simLinkOf(router.Id, peer)
+			desired[key] = struct{}{}
+			if _, ok := sim.links[key]; ok {
+				continue
+			}
+			link := &simLink{a: key.V1, b: key.V2, latency: sim.scenario.Latency}
+			if link.latency == 0 {
+				link.latency = time.Millisecond
+			}
+			if c, ok := conditions[key]; ok {
+				if c.Latency != 0 {
+					link.latency = c.Latency
+				}
+				link.loss = c.Loss
+			}
+			sim.links[key] = link
+		}
+	}
+	for key, link := range sim.links {
+		if _, ok := desired[key]; !ok {
+			link.down = true
+			sim.setAlive(link)
+			delete(sim.links, key)
+		}
+	}
+}
+
+func (sim *Simulation) refreshLinks() {
+	for _, link := range sim.links {
+		sim.setAlive(link)
+	}
+}
+
+func (sim *Simulation) running(id state.NodeId) bool {
+	node := sim.nodes[id]
+	return node != nil && node.up
+}
+
+// setAlive updates whether packets cross the link. Routers notice a new link on the next probe, and a failed one
+// when probes time out.
+func (sim *Simulation) setAlive(link *simLink) {
+	alive := !link.down && sim.running(link.a) && sim.running(link.b)
+	if alive == link.alive {
+		return
+	}
+	detect := sim.cfg.GetLink(sim.edges, link.a, link.b).Detect
+	if !alive {
+		if !sim.now.Before(link.changedAt.Add(sim.tunables.ProbeDelay)) {
+			threshold := sim.tunables.LinkDeadThreshold
+			if detect.Enabled() {
+				threshold = detect.DeadThreshold()
+			}
+			link.activeUntil = sim.now.Add(threshold)
+		} else {
+			link.activeUntil = sim.now
+		}
+	}
+	link.alive = alive
+	link.changedAt = sim.now
+	link.gen++
+	sim.dirty = true
+	if !alive && detect.Enabled() {
+		// fast failure detection reroutes as soon as the link is declared dead
+		gen := link.gen
+		sim.schedule(link.activeUntil.Sub(sim.now), func() {
+			if link.gen != gen {
+				return
+			}
+			for _, id := range []state.NodeId{link.a, link.b} {
+				if node := sim.nodes[id]; node != nil && node.up {
+					ComputeRoutes(node.state, node)
+					node.flush()
+				}
+			}
+		})
+	}
+	if alive {
+		gen := link.gen
+		sim.schedule(sim.tunables.ProbeDelay, func() {
+			if link.gen != gen {
+				return
+			}
+			for _, pair := range [][2]state.NodeId{{link.a, link.b}, {link.b, link.a}} {
+				if node := sim.nodes[pair[0]]; node != nil && node.up && node.state.GetNeighbour(pair[1]) != nil {
+					ComputeRoutes(node.state, node)
+					PushFullTable(node.state, node, pair[1])
+				}
+			}
+		})
+	}
+}
+
+// recompute reruns route selection on both ends of a link after its metric changed
+func (sim *Simulation) recompute(link *simLink) {
+	for _, id := range []state.NodeId{link.a, link.b} {
+		if node := sim.nodes[id]; node != nil && node.up {
+			ComputeRoutes(node.state, node)
+		}
+	}
+}
+
+func (sim *Simulation) boot(id state.NodeId, seqnos map[state.RoutePrefix]uint16) error {
+	node := &simNode{
+		sim: sim,
+		id:  id,
+		state: &state.RouterState{
+			RouterTunables: &sim.tunables,
+			Id:             id,
+			SelfSeqno:      make(map[state.RoutePrefix]uint16),
+			Routes:         make(map[state.RoutePrefix]state.SelRoute),
+			Sources:        make(map[state.Source]state.FD),
+			Neighbours:     make([]*state.Neighbour, 0),
+			Advertised:     make(map[state.RoutePrefix]state.Advertisement),
+			Clock:          sim.clock,
+		},
+		io: make(map[state.NodeId]*simIO),
+		up: true,
+	}
+	// routers keep their seqnos across restarts, see restoreRouterState
+	for prefix, seqno := range seqnos {
+		node.state.SetSeqno(prefix, seqno+1)
+	}
+	if err := node.configure(sim.cfg); err != nil {
+		return err
+	}
+	sim.nodes[id] = node
+	node.repeat(sim.tunables.RouteUpdateDelay, func() {
+		FullTableUpdate(node.state, node)
+	})
+	node.repeat(sim.tunables.StarvationDelay, func() {
+		SolveStarvation(node.state, node)
+	})
+	node.repeat(sim.tunables.NeighbourIOFlushDelay, node.flush)
+	node.repeat(sim.tunables.GcDelay, func() {
+		RunGC(node.state, node)
+	})
+	return nil
+}
+
+// checkForwarding follows every selected route hop by hop, and records the loops and blackholes it finds. Routers
+// that lost their route to a prefix that is still reachable over the simulated links are recorded as unreachable.
+func (sim *Simulation) checkForwarding() {
+	sim.dirty = false
+	present := make(map[simAnomalyKey][]state.NodeId)
+	record := func(key simAnomalyKey, path []state.NodeId) {
+		// keep the shortest path that shows the anomaly
+		if cur, seen := present[key]; !seen || len(path) < len(cur) || len(path) == len(cur) && slices.Compare(path, cur) < 0 {
+			present[key] = path
+		}
+	}
+	reachable := sim.reachability()
+	for id, node := range sim.nodes {
+		if !node.up {
+			continue
+		}
+		if sim.hadRoute[id] == nil {
+			sim.hadRoute[id] = make(map[state.RoutePrefix]struct{})
+		}
+		for prefix, route := range node.state.Routes {
+			if route.Metric == state.INF {
+				continue
+			}
+			sim.hadRoute[id][prefix] = struct{}{}
+			if key, path, ok := sim.tracePath(id, prefix); ok && (key.kind == SimLoop || reachable(id, prefix)) {
+				record(key, path)
+			}
+		}
+		for prefix := range sim.hadRoute[id] {
+			route, ok := node.state.Routes[prefix]
+			if ok && route.Metric != state.INF || !reachable(id, prefix) {
+				continue
+			}
+			kind := SimUnreachable
+			if ok {
+				kind = SimBlackhole // held after a retraction
+			}
+			record(simAnomalyKey{kind, prefix, string(id)}, []state.NodeId{id})
+		}
+	}
+	for key, anomaly := range sim.open {
+		if _, ok := present[key]; !ok {
+			anomaly.End = sim.elapsed()
+			anomaly.Resolved = true
+			delete(sim.open, key)
+		}
+	}
+	keys := make([]simAnomalyKey, 0, len(present))
+	for key := range present {
+		if _, ok := sim.open[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b simAnomalyKey) int {
+		return strings.Compare(a.kind+a.prefix.String()+a.at, b.kind+b.prefix.String()+b.at)
+	})
+	for _, key := range keys {
+		anomaly := &SimAnomaly{
+			Kind:   key.kind,
+			Prefix: key.prefix.String(),
+			Path:   present[key],
+			Start:  sim.elapsed(),
+		}
+		sim.open[key] = anomaly
+		sim.report.Anomalies = append(sim.report.Anomalies, anomaly)
+	}
+}
+
+// tracePath forwards a packet for prefix from id, and reports where it is lost
+// reachability reports whether a running origin of a prefix can be reached over the links that are up
+func (sim *Simulation) reachability() func(id state.NodeId, prefix state.RoutePrefix) bool {
+	adj := make(map[state.NodeId][]state.NodeId)
+	for _, link := range sim.links {
+		if link.alive {
+			adj[link.a] = append(adj[link.a], link.b)
+			adj[link.b] = append(adj[link.b], link.a)
+		}
+	}
+	component := make(map[state.NodeId]int)
+	for id := range sim.nodes {
+		if _, ok := component[id]; ok || !sim.running(id) {
+			continue
+		}
+		c := len(component) + 1
+		queue := []state.NodeId{id}
+		component[id] = c
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, next := range adj[cur] {
+				if _, ok := component[next]; !ok {
+					component[next] = c
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return func(id state.NodeId, prefix state.RoutePrefix) bool {
+		for _, origin := range sim.origins[prefix] {
+			if c, ok := component[origin]; ok && sim.running(origin) && c == component[id] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (sim *Simulation) tracePath(id state.NodeId, prefix state.RoutePrefix) (simAnomalyKey, []state.NodeId, bool) {
+	path := []state.NodeId{id}
+	cur := id
+	for {
+		route, ok := sim.nodes[cur].state.Routes[prefix]
+		if !ok || route.Metric == state.INF {
+			return simAnomalyKey{SimBlackhole, prefix, string(cur)}, path, true
+		}
+		if route.Nh == cur {
+			return simAnomalyKey{}, nil, false
+		}
+		link := sim.links[simLinkOf(cur, route.Nh)]
+		if link == nil || !link.alive {
+			return simAnomalyKey{SimBlackhole, prefix, string(cur)}, path, true
+		}
+		if idx := slices.Index(path, route.Nh); idx != -1 {
+			loop := canonicalCycle(path[idx:])
+			return simAnomalyKey{SimLoop, prefix, joinNodeIds(loop)}, loop, true
+		}
+		path = append(path, route.Nh)
+		cur = route.Nh
+	}
+}
+
+func (n *simNode) repeat(delay time.Duration, fn func()) {
+	epoch := n.epoch
+	var run func()
+	run = func() {
+		if n.epoch != epoch {
+			return
+		}
+		fn()
+		n.sim.schedule(delay, run)
+	}
+	n.sim.schedule(delay, run)
+}
+
+func (n *simNode) stop() {
+	n.up = false
+	n.epoch++
+	n.sim.dirty = true
+}
+
+// configure applies the central config to a simulated router, like reconcileRouterState and
+// reconcileAdvertisedPrefixes do for the daemon
+func (n *simNode) configure(cfg *state.CentralCfg) error {
+	err := ReconcileRouterConfig(n.state, cfg, func(neigh *state.Neighbour, _ state.RouterCfg) {
+		neigh.Eps = nil
+		if link, ok := n.sim.links[simLinkOf(n.id, neigh.Id)]; ok {
+			neigh.Eps = []state.Endpoint{&simEndpoint{sim: n.sim, link: link}}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for id := range n.io {
+		if n.state.GetNeighbour(id) == nil {
+			delete(n.io, id)
+		}
+	}
+	// health checks are assumed to pass
+	ReconcileAdvertised(n.state, cfg.GetRouter(n.id).Prefixes, func(prefix state.PrefixHealthWrapper) state.Advertisement {
+		metric := simPrefixMetric(prefix)
+		return state.Advertisement{
+			NodeId:   n.id,
+			Expiry:   maxConfigTime,
+			MetricFn: func() uint32 { return metric },
+		}
+	}, func(state.RoutePrefix) {})
+	return nil
+}
+
+// simPrefixMetric is the metric a prefix is advertised with when its health check passes instantly
+func simPrefixMetric(prefix state.PrefixHealthWrapper) uint32 {
+	switch p := prefix.PrefixHealth.(type) {
+	case *state.StaticPrefixHealth:
+		return p.Metric
+	case *state.BGPPrefixHealth:
+		return p.Metric
+	case *state.PingPrefixHealth:
+		return optionalMetric(p.Metric)
+	case *state.HTTPPrefixHealth:
+		return optionalMetric(p.Metric)
+	case *state.BabelPrefixHealth:
+		return optionalMetric(p.Metric)
+	case *state.AnycastPrefixHealth:
+		return p.Weight
+	}
+	return 0
+}
+
+func optionalMetric(metric *uint32) uint32 {
+	if metric == nil {
+		return 0
+	}
+	return *metric
+}
+
+func (n *simNode) getIO(neigh state.NodeId) *simIO {
+	nio, ok := n.io[neigh]
+	if !ok {
+		nio = newSimIO()
+		n.io[neigh] = nio
+	}
+	return nio
+}
+
+func (n *simNode) SendRouteUpdate(neigh state.NodeId, advRoute state.PubRoute) {
+	n.getIO(neigh).updates[advRoute.RoutePrefix] = exportRoute(n.state, neigh, advRoute)
+}
+
+func (n *simNode) SendAckRetract(neigh state.NodeId, prefix state.RoutePrefix) {
+	n.getIO(neigh).acks[prefix] = struct{}{}
+}
+
+func (n *simNode) BroadcastSendRouteUpdate(advRoute state.PubRoute) {
+	for _, neigh := range n.state.Neighbours {
+		n.SendRouteUpdate(neigh.Id, advRoute)
+	}
+}
+
+func (n *simNode) RequestSeqno(neigh state.NodeId, src state.Source, seqno uint16, hopCnt uint8) {
+	nio := n.getIO(neigh)
+	if old, ok := nio.dedup[src]; ok && n.sim.now.Before(old.expiry) {
+		if SeqnoGe(old.seqno, seqno) {
+			return // we have already sent such a request before
+		}
+		seqno = max(seqno, old.seqno)
+	}
+	nio.dedup[src] = simSeqnoDedup{seqno: seqno, expiry: n.sim.now.Add(n.state.SeqnoDedupTTL)}
+	req, ok := nio.seqnoReq[src]
+	if !ok || seqno < req.V1 {
+		req = state.Pair[uint16, uint8]{V1: seqno, V2: hopCnt}
+	} else if hopCnt > req.V2 {
+		req.V2 = hopCnt
+	}
+	nio.seqnoReq[src] = req
+}
+
+func (n *simNode) BroadcastRequestSeqno(src state.Source, seqno uint16, hopCnt uint8) {
+	for _, neigh := range n.state.Neighbours {
+		n.RequestSeqno(neigh.Id, src, seqno, hopCnt)
+	}
+}
+
+func (n *simNode) TableInsertRoute(prefix state.RoutePrefix, route state.SelRoute) {
+	n.sim.recordChange(SimRouteChange{Node: n.id, SimRoute: simRoute(prefix, route)})
+}
+
+func (n *simNode) TableDeleteRoute(prefix state.RoutePrefix) {
+	n.sim.recordChange(SimRouteChange{Node: n.id, SimRoute: SimRoute{Prefix: prefix.String()}, Removed: true})
+}
+
+func (n *simNode) RouterEvent(event string, desc string, args ...any) {}
+
+// flush sends the queued messages to every neighbour that is reachable, like flushIO
+func (n *simNode) flush() {
+	for _, neigh := range n.state.Neighbours {
+		nio, ok := n.io[neigh.Id]
+		if !ok || len(nio.seqnoReq) == 0 && len(nio.updates) == 0 && len(nio.acks) == 0 {
+			continue
+		}
+		best := neigh.BestEndpoint()
+		if best == nil || !best.IsActive() {
+			continue
+		}
+		bundle := &simBundle{seqnoReq: nio.seqnoReq, updates: nio.updates, acks: nio.acks}
+		nio.seqnoReq = make(map[state.Source]state.Pair[uint16, uint8])
+		nio.updates = make(map[state.RoutePrefix]state.PubRoute)
+		nio.acks = make(map[state.RoutePrefix]struct{})
+		n.sim.send(n.id, neigh.Id, bundle)
+	}
+}
+
+func (sim *Simulation) send(from, to state.NodeId, bundle *simBundle) {
+	link := sim.links[simLinkOf(from, to)]
+	if link == nil || !link.alive || sim.rand.Float64() < link.loss {
+		return
+	}
+	sim.schedule(link.latency, func() {
+		if node := sim.nodes[to]; node != nil && node.up {
+			node.receive(from, bundle)
+		}
+	})
+}
+
+// receive handles a bundle like the router's packet handlers
+func (n *simNode) receive(from state.NodeId, bundle *simBundle) {
+	if n.state.GetNeighbour(from) == nil {
+		return
+	}
+	for src, req := range bundle.seqnoReq {
+		if n.sim.cfg.TryGetNode(src.NodeId) == nil {
+			continue
+		}
+		HandleSeqnoRequest(n.state, n, from, src, req.V1, req.V2)
+	}
+	for _, update := range bundle.updates {
+		if !MayOriginate(n.state, update.NodeId, update.RoutePrefix) {
+			continue
+		}
+		HandleNeighbourUpdate(n.state, n, from, update)
+		ComputeRoutes(n.state, n)
+	}
+	for prefix := range bundle.acks {
+		HandleAckRetract(n.state, n, from, prefix)
+	}
+}
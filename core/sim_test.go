@@ -0,0 +1,181 @@
+package core
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/encodeous/nylon/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// simTestConfig builds a central config where every router has an endpoint and advertises its address
+func simTestConfig(graph []string, ids ...state.NodeId) *state.CentralCfg {
+	cfg := &state.CentralCfg{Graph: graph}
+	for i, id := range ids {
+		cfg.Routers = append(cfg.Routers, state.RouterCfg{
+			NodeCfg: state.NodeCfg{
+				Id:        id,
+				PubKey:    state.GenerateKey().Pubkey(),
+				Addresses: []netip.Addr{netip.AddrFrom4([4]byte{10, 0, 0, byte(i + 1)})},
+			},
+			Endpoints: []state.EndpointCfg{{Address: netip.AddrPortFrom(netip.AddrFrom4([4]byte{192, 168, 0, byte(i + 1)}), 57175).String()}},
+		})
+	}
+	return cfg
+}
+
+func simRouteOf(report *SimReport, phase int, node state.NodeId, prefix string) (SimRoute, bool) {
+	routes := report.Phases[phase].Tables[node]
+	idx := slices.IndexFunc(routes, func(route SimRoute) bool {
+		return route.Prefix == prefix
+	})
+	if idx == -1 {
+		return SimRoute{}, false
+	}
+	return routes[idx], true
+}
+
+func TestSimulation_ReroutesAroundFailedLink(t *testing.T) {
+	// b prefers the direct link to a, and the path through c is feasible
+	cfg := simTestConfig([]string{"a, b", "b, c", "c, a"}, "a", "b", "c")
+	scenario := &state.SimScenario{
+		Latency: 2 * time.Millisecond,
+		Links: []state.SimLinkCfg{
+			{Link: []state.NodeId{"a", "c"}, Latency: time.Millisecond},
+		},
+		Events: []state.SimEvent{
+			{At: 30 * time.Second, Action: state.SimLinkDown, Link: []state.NodeId{"a", "b"}},
+			{At: 60 * time.Second, Action: state.SimLinkUp, Link: []state.NodeId{"b", "a"}},
+		},
+	}
+	sim, err := NewSimulation(cfg, scenario, state.DefaultRouterTunables())
+	require.NoError(t, err)
+	report, err := sim.Run()
+	require.NoError(t, err)
+	require.Len(t, report.Phases, 3)
+
+	route, ok := simRouteOf(report, 0, "b", "10.0.0.1/32")
+	require.True(t, ok)
+	assert.Equal(t, state.NodeId("a"), route.Nh)
+	assert.Equal(t, "link_down a <-> b", report.Phases[1].Event)
+
+	// after the failure, b reaches a through c
+	route, ok = simRouteOf(report, 1, "b", "10.0.0.1/32")
+	require.True(t, ok)
+	assert.Equal(t, state.NodeId("c"), route.Nh)
+	assert.NotZero(t, report.Phases[1].Converged)
+	assert.LessOrEqual(t, report.Phases[1].Converged, state.DefaultRouterTunables().LinkDeadThreshold+time.Second)
+
+	route, ok = simRouteOf(report, 2, "b", "10.0.0.1/32")
+	require.True(t, ok)
+	assert.Equal(t, state.NodeId("a"), route.Nh)
+
+	// packets from b are dropped into the dead link until b notices the failure
+	idx := slices.IndexFunc(report.Anomalies, func(a *SimAnomaly) bool {
+		return a.Kind == SimBlackhole && a.Prefix == "10.0.0.1/32" && slices.Equal(a.Path, []state.NodeId{"b"})
+	})
+	require.NotEqual(t, -1, idx, "anomalies: %v", report.Anomalies)
+	blackhole := report.Anomalies[idx]
+	assert.Equal(t, 30*time.Second, blackhole.Start)
+	assert.True(t, blackhole.Resolved)
+	assert.LessOrEqual(t, blackhole.End, 30*time.Second+state.DefaultRouterTunables().LinkDeadThreshold+time.Second)
+	for _, anomaly := range report.Anomalies {
+		assert.True(t, anomaly.Resolved, "anomaly %+v persisted", anomaly)
+	}
+}
+
+func TestSimulation_CrashedRouterRejoins(t *testing.T) {
+	cfg := simTestConfig([]string{"a, b", "b, c"}, "a", "b", "c")
+	scenario := &state.SimScenario{
+		Events: []state.SimEvent{
+			{At: 20 * time.Second, Action: state.SimCrash, Node: "c"},
+			{At: 40 * time.Second, Action: state.SimRestart, Node: "c"},
+		},
+	}
+	sim, err := NewSimulation(cfg, scenario, state.DefaultRouterTunables())
+	require.NoError(t, err)
+	report, err := sim.Run()
+	require.NoError(t, err)
+
+	_, ok := report.Phases[1].Tables["c"]
+	assert.False(t, ok, "crashed routers have no table")
+	route, ok := simRouteOf(report, 1, "a", "10.0.0.3/32")
+	assert.True(t, !ok || route.Metric == state.INF, "a still routes to the crashed router: %+v", route)
+
+	route, ok = simRouteOf(report, 2, "a", "10.0.0.3/32")
+	require.True(t, ok)
+	assert.Equal(t, state.NodeId("b"), route.Nh)
+	assert.NotEqual(t, state.INF, route.Metric)
+}
+
+func TestSimulation_ConfigChangeAddsLink(t *testing.T) {
+	cfg := simTestConfig([]string{"a, b", "b, c"}, "a", "b", "c")
+	next := simTestConfig([]string{"a, b", "b, c", "a, c"}, "a", "b", "c")
+	for i := range next.Routers {
+		next.Routers[i].PubKey = cfg.Routers[i].PubKey
+	}
+	scenario := &state.SimScenario{
+		Events: []state.SimEvent{
+			{At: 20 * time.Second, Action: state.SimConfig, Config: "next.yaml", Central: next},
+		},
+	}
+	sim, err := NewSimulation(cfg, scenario, state.DefaultRouterTunables())
+	require.NoError(t, err)
+	report, err := sim.Run()
+	require.NoError(t, err)
+
+	route, ok := simRouteOf(report, 0, "a", "10.0.0.3/32")
+	require.True(t, ok)
+	assert.Equal(t, state.NodeId("b"), route.Nh)
+	route, ok = simRouteOf(report, 1, "a", "10.0.0.3/32")
+	require.True(t, ok)
+	assert.Equal(t, state.NodeId("c"), route.Nh)
+}
+
+func TestSimulation_RejectsUnknownLink(t *testing.T) {
+	cfg := simTestConfig([]string{"a, b"}, "a", "b", "c")
+	scenario := &state.SimScenario{
+		Events: []state.SimEvent{
+			{At: time.Second, Action: state.SimLinkDown, Link: []state.NodeId{"a", "c"}},
+		},
+	}
+	sim, err := NewSimulation(cfg, scenario, state.DefaultRouterTunables())
+	require.NoError(t, err)
+	_, err = sim.Run()
+	assert.ErrorContains(t, err, "event at 1s")
+}
+
+func TestSimulation_FastDetectionFailsOver(t *testing.T) {
+	cfg := simTestConfig([]string{"a, b [detect=50ms]", "b, c", "c, a"}, "a", "b", "c")
+	scenario := &state.SimScenario{
+		Latency: 2 * time.Millisecond,
+		Links: []state.SimLinkCfg{
+			{Link: []state.NodeId{"a", "c"}, Latency: time.Millisecond},
+		},
+		Events: []state.SimEvent{
+			{At: 30 * time.Second, Action: state.SimLinkDown, Link: []state.NodeId{"a", "b"}},
+		},
+	}
+	sim, err := NewSimulation(cfg, scenario, state.DefaultRouterTunables())
+	require.NoError(t, err)
+	report, err := sim.Run()
+	require.NoError(t, err)
+
+	route, ok := simRouteOf(report, 1, "b", "10.0.0.1/32")
+	require.True(t, ok)
+	assert.Equal(t, state.NodeId("c"), route.Nh)
+	// b has a feasible route through c, and moves to it as soon as the link is declared dead
+	idx := slices.IndexFunc(report.Phases[1].Changes, func(change SimRouteChange) bool {
+		return change.Node == "b" && change.Prefix == "10.0.0.1/32" && change.Nh == "c"
+	})
+	require.NotEqual(t, -1, idx)
+	assert.Less(t, report.Phases[1].Changes[idx].At, 30*time.Second+200*time.Millisecond)
+	for _, anomaly := range report.Anomalies {
+		if anomaly.Prefix == "10.0.0.1/32" {
+			assert.Less(t, anomaly.End-anomaly.Start, 200*time.Millisecond, "anomaly %+v", anomaly)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"log/slog"
 	"net"
 	"net/netip"
@@ -79,3 +80,22 @@ func RemoveRoute(logger *slog.Logger, dev tun.Device, itfName string, route neti
 		return Exec(logger, "/sbin/route", "-n", "delete", "-net", addr.String(), "-netmask", netmask, "-interface", itfName)
 	}
 }
+
+func ConfigureGatewayRoute(logger *slog.Logger, itfName string, route netip.Prefix, gateway netip.Addr) error {
+	if route.Addr().Is4() != gateway.Is4() {
+		return fmt.Errorf("routing %s via %s is not supported", route, gateway)
+	}
+	if route.Addr().Is6() {
+		return Exec(logger, "/sbin/route", "-n", "add", "-inet6", route.String(), gateway.WithZone(itfName).String())
+	} else {
+		return Exec(logger, "/sbin/route", "-n", "add", "-net", route.Addr().String(), "-netmask", PrefixToMaskString(route), gateway.String())
+	}
+}
+
+func RemoveGatewayRoute(logger *slog.Logger, itfName string, route netip.Prefix) error {
+	if route.Addr().Is6() {
+		return Exec(logger, "/sbin/route", "-n", "delete", "-inet6", route.String())
+	} else {
+		return Exec(logger, "/sbin/route", "-n", "delete", "-net", route.Addr().String(), "-netmask", PrefixToMaskString(route))
+	}
+}
@@ -43,3 +43,14 @@ func ConfigureRoute(logger *slog.Logger, dev tun.Device, itfName string, route n
 func RemoveRoute(logger *slog.Logger, dev tun.Device, itfName string, route netip.Prefix) error {
 	return Exec(logger, "ip", "route", "del", route.String(), "dev", itfName)
 }
+
+func ConfigureGatewayRoute(logger *slog.Logger, itfName string, route netip.Prefix, gateway netip.Addr) error {
+	if route.Addr().Is4() && gateway.Is6() {
+		return Exec(logger, "ip", "route", "replace", route.String(), "via", "inet6", gateway.String(), "dev", itfName)
+	}
+	return Exec(logger, "ip", "route", "replace", route.String(), "via", gateway.String(), "dev", itfName)
+}
+
+func RemoveGatewayRoute(logger *slog.Logger, itfName string, route netip.Prefix) error {
+	return Exec(logger, "ip", "route", "del", route.String(), "dev", itfName)
+}
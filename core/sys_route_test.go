@@ -21,10 +21,10 @@ func TestComputeSysRouteTableAppliesExcludesAndUnexcludes(t *testing.T) {
 		[]netip.Prefix{
 			pfx("10.0.2.0/25"),
 		},
-		map[netip.Prefix]state.SelRoute{
-			pfx("10.0.0.0/24"): {Nh: "b"},
-			pfx("10.0.1.0/24"): {Nh: "a"},
-			pfx("10.0.2.0/24"): {Nh: "c"},
+		map[state.RoutePrefix]state.SelRoute{
+			{Prefix: pfx("10.0.0.0/24")}: {Nh: "b"},
+			{Prefix: pfx("10.0.1.0/24")}: {Nh: "a"},
+			{Prefix: pfx("10.0.2.0/24")}: {Nh: "c"},
 		},
 	)
 
@@ -47,8 +47,8 @@ func TestComputeSysRouteTableLocalExcludeOverridesUnexclude(t *testing.T) {
 		[]netip.Prefix{
 			pfx("10.0.0.64/26"),
 		},
-		map[netip.Prefix]state.SelRoute{
-			pfx("10.0.0.0/24"): {Nh: "b"},
+		map[state.RoutePrefix]state.SelRoute{
+			{Prefix: pfx("10.0.0.0/24")}: {Nh: "b"},
 		},
 	)
 
@@ -65,8 +65,8 @@ func TestComputeSysRouteTableDoesNotMutateCentralExcludes(t *testing.T) {
 		centralExcludes,
 		nil,
 		nil,
-		map[netip.Prefix]state.SelRoute{
-			pfx("10.0.1.0/24"): {Nh: "a"},
+		map[state.RoutePrefix]state.SelRoute{
+			{Prefix: pfx("10.0.1.0/24")}: {Nh: "a"},
 		},
 	)
 
@@ -81,16 +81,31 @@ func TestComputeSysRouteTableCoalescesAdjacentResults(t *testing.T) {
 		nil,
 		nil,
 		nil,
-		map[netip.Prefix]state.SelRoute{
-			pfx("10.0.0.0/25"):   {Nh: "b"},
-			pfx("10.0.0.128/25"): {Nh: "b"},
+		map[state.RoutePrefix]state.SelRoute{
+			{Prefix: pfx("10.0.0.0/25")}:   {Nh: "b"},
+			{Prefix: pfx("10.0.0.128/25")}: {Nh: "b"},
 		},
 	)
 
 	assert.Equal(t, []netip.Prefix{pfx("10.0.0.0/24")}, sortedPrefixes(n.ComputeSysRouteTable()))
 }
 
-func sysRouteTestNylon(local state.NodeId, centralExcludes, localUnexcludes, localExcludes []netip.Prefix, routes map[netip.Prefix]state.SelRoute) *Nylon {
+func TestComputeSysRouteTableLeavesOutSourceSpecificRoutes(t *testing.T) {
+	n := sysRouteTestNylon(
+		"a",
+		nil,
+		nil,
+		nil,
+		map[state.RoutePrefix]state.SelRoute{
+			{Prefix: pfx("0.0.0.0/0"), Src: pfx("10.1.0.0/16")}: {Nh: "b"},
+			{Prefix: pfx("10.0.0.0/24")}:                        {Nh: "b"},
+		},
+	)
+
+	assert.Equal(t, []netip.Prefix{pfx("10.0.0.0/24")}, n.ComputeSysRouteTable())
+}
+
+func sysRouteTestNylon(local state.NodeId, centralExcludes, localUnexcludes, localExcludes []netip.Prefix, routes map[state.RoutePrefix]state.SelRoute) *Nylon {
 	return &Nylon{
 		ConfigState: state.ConfigState{
 			CentralCfg: state.CentralCfg{
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"log/slog"
 	"net"
 	"net/netip"
@@ -77,3 +78,32 @@ func RemoveRoute(logger *slog.Logger, dev tun.Device, itfName string, route neti
 		return Exec(logger, "route", "delete", addr.String(), "mask", maskStr, "0.0.0.0", "IF", ifIndex)
 	}
 }
+
+func ConfigureGatewayRoute(logger *slog.Logger, itfName string, route netip.Prefix, gateway netip.Addr) error {
+	if route.Addr().Is4() != gateway.Is4() {
+		return fmt.Errorf("routing %s via %s is not supported", route, gateway)
+	}
+	itf, err := net.InterfaceByName(itfName)
+	if err != nil {
+		return err
+	}
+	ifIndex := strconv.Itoa(itf.Index)
+
+	if route.Addr().Is6() {
+		return Exec(logger, "route", "add", route.String(), gateway.String(), "IF", ifIndex)
+	} else {
+		_, mask, _ := net.ParseCIDR(route.String())
+		maskStr := net.IP(mask.Mask).String()
+		return Exec(logger, "route", "add", route.Addr().String(), "mask", maskStr, gateway.String(), "IF", ifIndex)
+	}
+}
+
+func RemoveGatewayRoute(logger *slog.Logger, itfName string, route netip.Prefix) error {
+	if route.Addr().Is6() {
+		return Exec(logger, "route", "delete", route.String())
+	} else {
+		_, mask, _ := net.ParseCIDR(route.String())
+		maskStr := net.IP(mask.Mask).String()
+		return Exec(logger, "route", "delete", route.Addr().String(), "mask", maskStr)
+	}
+}
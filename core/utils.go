@@ -1,8 +1,6 @@
 package core
 
 import (
-
-
 	"github.com/encodeous/nylon/state"
 )
 
@@ -29,7 +27,6 @@ func SeqnoGe(a, b uint16) bool {
 	return !SeqnoLt(a, b)
 }
 
-
 func abs(a int) int {
 	if a < 0 {
 		return -a
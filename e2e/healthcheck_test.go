@@ -56,7 +56,7 @@ func TestHealthcheckPing(t *testing.T) {
 	// 1 would be default
 	central.Routers[0].Prefixes = []state.PrefixHealthWrapper{
 		{
-			&state.PingPrefixHealth{
+			PrefixHealth: &state.PingPrefixHealth{
 				Prefix: netip.MustParsePrefix("10.0.1.4/32"),
 				Addr:   netip.MustParseAddr("10.0.1.4"),
 				Metric: new(uint32(10)),
@@ -66,7 +66,7 @@ func TestHealthcheckPing(t *testing.T) {
 	// 2 would be fallback
 	central.Routers[1].Prefixes = []state.PrefixHealthWrapper{
 		{
-			&state.PingPrefixHealth{
+			PrefixHealth: &state.PingPrefixHealth{
 				Prefix: netip.MustParsePrefix("10.0.1.4/32"),
 				Addr:   netip.MustParseAddr("10.0.1.4"),
 				Metric: new(uint32(1000)),
@@ -181,7 +181,7 @@ health.service.test. 0 IN A %s
 	// Configure Primary with HTTP check (Metric 10)
 	central.Routers[1].Prefixes = []state.PrefixHealthWrapper{
 		{
-			&state.HTTPPrefixHealth{
+			PrefixHealth: &state.HTTPPrefixHealth{
 				Prefix: servicePrefix,
 				URL:    fmt.Sprintf("http://%s:8080/health", healthHost),
 				Delay:  new(1 * time.Second),
@@ -194,7 +194,7 @@ health.service.test. 0 IN A %s
 	backupMetric := uint32(1000)
 	central.Routers[2].Prefixes = []state.PrefixHealthWrapper{
 		{
-			&state.StaticPrefixHealth{
+			PrefixHealth: &state.StaticPrefixHealth{
 				Prefix: servicePrefix,
 				Metric: backupMetric,
 			},
@@ -45,7 +45,7 @@ func TestAckRetractSentOnWire(t *testing.T) {
 
 	errs := vh.Start()
 
-	bPrefix := netip.MustParsePrefix("10.0.0.2/32")
+	bPrefix := state.RoutePrefix{Prefix: netip.MustParsePrefix("10.0.0.2/32")}
 	na := vh.Nylons[0].Load()
 	nb := vh.Nylons[1].Load()
 
@@ -85,7 +85,7 @@ func TestAckRetractSentOnWire(t *testing.T) {
 	nb.Dispatch(func() error {
 		delete(nb.RouterState.Advertised, bPrefix)
 		nb.BroadcastSendRouteUpdate(state.PubRoute{
-			Source: state.Source{NodeId: "b", Prefix: bPrefix},
+			Source: state.Source{NodeId: "b", RoutePrefix: bPrefix},
 			FD:     state.FD{Seqno: nb.RouterState.GetSeqno(bPrefix), Metric: state.INF},
 		})
 		return nil
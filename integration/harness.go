@@ -137,7 +137,7 @@ func (v *VirtualHarness) NewNode(id state.NodeId, virtPrefix string) {
 			PubKey: privKey.Pubkey(),
 			Prefixes: []state.PrefixHealthWrapper{
 				{
-					&state.StaticPrefixHealth{
+					PrefixHealth: &state.StaticPrefixHealth{
 						Prefix: netip.MustParsePrefix(virtPrefix),
 						Metric: 0,
 					},
@@ -185,7 +185,7 @@ func (v *VirtualHarness) Start() chan error {
 	}
 	for e, n := range v.Endpoints {
 		idx := v.IndexOf(n)
-		v.Central.Routers[idx].Endpoints = append(v.Central.Routers[idx].Endpoints, e)
+		v.Central.Routers[idx].Endpoints = append(v.Central.Routers[idx].Endpoints, state.EndpointCfg{Address: e})
 	}
 	if v.LogLevel == nil {
 		v.LogLevel = new(slog.LevelDebug)
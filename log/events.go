@@ -12,6 +12,7 @@ const (
 	EventRouteUpdated      = "route_updated"
 	EventRouteRetracted    = "route_retracted"
 	EventRouteExpired      = "route_expired"
+	EventRouteFiltered     = "route_filtered"
 	EventMajorRouteChange  = "major_route_change"
 	EventInconsistentState = "inconsistent_state"
 	EventNoEndpointToNeigh = "no_endpoint_to_neighbour"
@@ -65,6 +65,7 @@ type Device struct {
 	}
 
 	TCFilters     []TCFilter
+	tcObserver    atomic.Pointer[TCObserver]
 	Allowedips    AllowedIPs
 	indexTable    IndexTable
 	cookieChecker CookieChecker
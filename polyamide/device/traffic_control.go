@@ -2,6 +2,7 @@ package device
 
 import (
 	"slices"
+	"sync/atomic"
 
 	"github.com/encodeous/nylon/polyamide/conn"
 )
@@ -26,12 +27,18 @@ const (
 const (
 	TcNormalPriority TCPriority = iota
 	TcMediumPriority
+	// TcDataHighPriority is the highest priority of data packets, below the control traffic at TcHighPriority
+	TcDataHighPriority
 	TcHighPriority
 	TcMaxPriority
 )
 
 type TCFilter func(dev *Device, packet *TCElement) (TCAction, error)
 
+// TCObserver sees every valid packet after the filters, with the action they decided on. It must not modify or
+// retain the packet.
+type TCObserver func(packet *TCElement, action TCAction)
+
 func TCFAllowedip(dev *Device, packet *TCElement) (TCAction, error) {
 	if packet.ToPeer != nil {
 		return TcForward, nil
@@ -68,6 +75,14 @@ type TCElement struct {
 	FromPeer *Peer                 // which peer (if any) sent us this Packet
 	ToPeer   *Peer                 // which peer to send this Packet to
 	Priority TCPriority            // Priority, higher is better
+	// Duplicate is a copy of the Packet that is forwarded along with it, to its own ToPeer and ToEp.
+	// It is released with the element if the element is not forwarded.
+	Duplicate *TCElement
+	// DuplicateTag identifies one transmission of a duplicated packet, so both of its copies carry the same tag and
+	// a resend of the same bytes does not. It travels after the packet in the encrypted payload, see wirePacket.
+	DuplicateTag uint64
+	// Queued, if set, counts the element when it is staged to its ToPeer. Its duplicate is counted by the same counter.
+	Queued *atomic.Uint64
 }
 
 func (elem *TCElement) clearPointers() {
@@ -77,6 +92,9 @@ func (elem *TCElement) clearPointers() {
 	elem.ToEp = nil
 	elem.FromPeer = nil
 	elem.ToPeer = nil
+	elem.Duplicate = nil
+	elem.DuplicateTag = 0
+	elem.Queued = nil
 }
 
 func (device *Device) NewTCElement() *TCElement {
@@ -85,10 +103,46 @@ func (device *Device) NewTCElement() *TCElement {
 	return elem
 }
 
+// DuplicateTCElement copies the packet of an element into a new element, and attaches it as the element's Duplicate
+func (device *Device) DuplicateTCElement(elem *TCElement) *TCElement {
+	if elem.Duplicate != nil {
+		device.releaseTCElement(elem.Duplicate)
+	}
+	dup := device.NewTCElement()
+	copy(dup.Buffer[MessageTransportHeaderSize:], elem.Packet)
+	dup.Packet = dup.Buffer[MessageTransportHeaderSize : MessageTransportHeaderSize+len(elem.Packet)]
+	dup.FromEp = elem.FromEp
+	dup.FromPeer = elem.FromPeer
+	dup.Priority = elem.Priority
+	dup.DuplicateTag = elem.DuplicateTag
+	dup.Queued = elem.Queued
+	elem.Duplicate = dup
+	return dup
+}
+
+// releaseTCElement returns an element, its buffer and its duplicate to the pools
+func (device *Device) releaseTCElement(elem *TCElement) {
+	if elem.Duplicate != nil {
+		device.releaseTCElement(elem.Duplicate)
+	}
+	device.PutMessageBuffer(elem.Buffer)
+	device.PutTCElement(elem)
+}
+
 func (device *Device) InstallFilter(filter TCFilter) {
 	device.TCFilters = append(device.TCFilters, filter)
 }
 
+// SetTCObserver sets the observer of the packets that passed through the filters, or removes it if nil. It may be
+// changed while packets are processed.
+func (device *Device) SetTCObserver(observer TCObserver) {
+	if observer == nil {
+		device.tcObserver.Store(nil)
+		return
+	}
+	device.tcObserver.Store(&observer)
+}
+
 type TCState struct {
 	priority     [][]*TCElement
 	bouncePkts   []*TCElement
@@ -109,7 +163,8 @@ func (device *Device) TCBatch(batch []*TCElement, tcs *TCState) {
 	for i, elem := range batch {
 		// process TC Filters
 		act := TcPass
-		if !elem.ParsePacket() || !elem.Validate() {
+		valid := elem.ParsePacket() && elem.Validate()
+		if !valid {
 			device.Log.Errorf("Found malformed packet, dropping packet")
 			act = TcDrop
 		} else {
@@ -129,26 +184,39 @@ func (device *Device) TCBatch(batch []*TCElement, tcs *TCState) {
 			device.Log.Errorf("Unexpectedly passed all filters!")
 			act = TcDrop
 		}
+		if observer := device.tcObserver.Load(); observer != nil && valid {
+			(*observer)(elem, act)
+		}
 
 		batch[i] = nil
 
 		switch act {
 		case TcDrop:
 			// cleanup
-			device.PutMessageBuffer(elem.Buffer)
-			device.PutTCElement(elem)
+			device.releaseTCElement(elem)
 		case TcBounce:
-			// bounce back to system
+			// bounce back to system, the system only needs one copy
+			if elem.Duplicate != nil {
+				device.releaseTCElement(elem.Duplicate)
+				elem.Duplicate = nil
+			}
 			tcs.bouncePkts = append(tcs.bouncePkts, elem)
 		case TcForward:
 			// reroute/forward packet
 			if elem.ToPeer == nil {
 				device.Log.Errorf("Failed to forward packet to destination, toPeer not set")
-				device.PutMessageBuffer(elem.Buffer)
-				device.PutTCElement(elem)
+				device.releaseTCElement(elem)
 				continue
 			}
 			tcs.priority[elem.Priority] = append(tcs.priority[elem.Priority], elem)
+			if dup := elem.Duplicate; dup != nil {
+				elem.Duplicate = nil
+				if dup.ToPeer == nil {
+					device.releaseTCElement(dup)
+				} else {
+					tcs.priority[dup.Priority] = append(tcs.priority[dup.Priority], dup)
+				}
+			}
 		default:
 			panic("unreachable default case")
 		}
@@ -193,11 +261,15 @@ func (device *Device) TCBatch(batch []*TCElement, tcs *TCState) {
 		}
 		if peer.isRunning.Load() {
 			obec := device.GetOutboundElementsContainer()
+			limit := device.duplicateTagLimit()
 			for i, elem := range elems {
 				obe := device.GetOutboundElement()
 				obe.nonce = 0
 				obe.endpoint = elem.ToEp
-				obe.packet = elem.Packet
+				obe.packet = elem.wirePacket(limit)
+				if elem.Queued != nil {
+					elem.Queued.Add(1)
+				}
 				obe.buffer = elem.Buffer
 				obec.elems = append(obec.elems, obe)
 				device.PutTCElement(elem)
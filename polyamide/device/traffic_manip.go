@@ -2,10 +2,12 @@ package device
 
 import (
 	"encoding/binary"
-	"golang.org/x/net/ipv4"
-	"golang.org/x/net/ipv6"
 	"net"
 	"net/netip"
+
+	"github.com/encodeous/nylon/polyamide/tun"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 // poly packets use other "IP Versions"
@@ -14,6 +16,22 @@ const (
 	PolyOffsetPayloadLength = 1
 )
 
+// offsets and protocol numbers used to classify IP packets
+const (
+	IPv4offsetFlags      = 6
+	IPv4offsetProtocol   = 9
+	IPv4offsetChecksum   = 10
+	IPv6offsetNextHeader = 6
+	TCPoffsetChecksum    = 16
+	UDPoffsetChecksum    = 6
+
+	ProtoICMP   = 1
+	ProtoTCP    = 6
+	ProtoUDP    = 17
+	ProtoICMPv6 = 58
+	ProtoSCTP   = 132
+)
+
 func (elem *TCElement) InitPacket(ver int, len uint16) {
 	elem.Packet = elem.Buffer[MessageTransportHeaderSize : MessageTransportHeaderSize+len]
 	elem.SetIPVersion(ver)
@@ -37,10 +55,58 @@ func (elem *TCElement) ParsePacket() bool {
 	if int(l) > len(elem.Packet) {
 		return false
 	}
+	elem.parseDuplicateTag(int(l))
 	elem.Packet = elem.Packet[:l]
 	return true
 }
 
+// duplicateTrailer marks the DuplicateTag that follows a packet in the encrypted payload. The padding after a packet
+// is zero, and receivers that do not know the trailer drop it with the padding when they trim the packet to its length.
+var duplicateTrailer = [4]byte{'n', 'y', 'd', 'p'}
+
+const duplicateTrailerSize = len(duplicateTrailer) + 8
+
+// parseDuplicateTag reads the DuplicateTag from the bytes after a packet of length l
+func (elem *TCElement) parseDuplicateTag(l int) {
+	elem.DuplicateTag = 0
+	if len(elem.Packet) < l+duplicateTrailerSize {
+		return
+	}
+	trailer := elem.Packet[l : l+duplicateTrailerSize]
+	if [4]byte(trailer) != duplicateTrailer {
+		return
+	}
+	elem.DuplicateTag = binary.BigEndian.Uint64(trailer[len(duplicateTrailer):])
+}
+
+// CanTagDuplicate returns true if the packet of an element still fits the tunnel MTU with a DuplicateTag after it.
+// Larger packets are sent without the tag, so the receiver cannot tell their copies apart.
+func (device *Device) CanTagDuplicate(elem *TCElement) bool {
+	return len(elem.Packet)+duplicateTrailerSize <= device.duplicateTagLimit()
+}
+
+// duplicateTagLimit returns the largest payload that a tagged packet may take
+func (device *Device) duplicateTagLimit() int {
+	mtu := int(device.tun.mtu.Load())
+	if mtu <= 0 || mtu > MaxContentSize {
+		return MaxContentSize
+	}
+	return mtu
+}
+
+// wirePacket returns the packet as it is sent to a peer, followed by the DuplicateTag if the packet has one and the
+// payload stays within limit
+func (elem *TCElement) wirePacket(limit int) []byte {
+	l := len(elem.Packet)
+	if elem.DuplicateTag == 0 || l+duplicateTrailerSize > limit {
+		return elem.Packet
+	}
+	packet := elem.Buffer[MessageTransportHeaderSize : MessageTransportHeaderSize+l+duplicateTrailerSize]
+	copy(packet[l:], duplicateTrailer[:])
+	binary.BigEndian.PutUint64(packet[l+len(duplicateTrailer):], elem.DuplicateTag)
+	return packet
+}
+
 func (elem *TCElement) Incoming() bool {
 	return elem.FromPeer != nil
 }
@@ -185,6 +251,177 @@ func (elem *TCElement) hasPacketHeader() bool {
 	return true
 }
 
+// GetProtocol returns the transport protocol of an IP packet (the IPv6 next header is not followed through extension headers)
+func (elem *TCElement) GetProtocol() uint8 {
+	ver := elem.GetIPVersion()
+	if ver == 4 {
+		return elem.Packet[IPv4offsetProtocol]
+	} else if ver == 6 {
+		return elem.Packet[IPv6offsetNextHeader]
+	}
+	return 0
+}
+
+// IsFragment returns true if the packet is an IPv4 fragment, such packets do not have a complete transport header
+func (elem *TCElement) IsFragment() bool {
+	if elem.GetIPVersion() != 4 {
+		return false
+	}
+	flags := binary.BigEndian.Uint16(elem.Packet[IPv4offsetFlags : IPv4offsetFlags+2])
+	// more fragments, or a non-zero fragment offset
+	return flags&0x3fff != 0
+}
+
+// TransportHeader returns the packet contents after the IP header, or nil if the packet is not an IP packet
+func (elem *TCElement) TransportHeader() []byte {
+	ver := elem.GetIPVersion()
+	if ver == 4 {
+		hl := int(elem.Packet[0]&0x0f) << 2
+		if hl < ipv4.HeaderLen || hl > len(elem.Packet) {
+			return nil
+		}
+		return elem.Packet[hl:]
+	} else if ver == 6 {
+		return elem.Packet[ipv6.HeaderLen:]
+	}
+	return nil
+}
+
+// GetPorts returns the source and destination ports of TCP, UDP and SCTP packets
+func (elem *TCElement) GetPorts() (uint16, uint16, bool) {
+	switch elem.GetProtocol() {
+	case ProtoTCP, ProtoUDP, ProtoSCTP:
+	default:
+		return 0, 0, false
+	}
+	if elem.IsFragment() {
+		return 0, 0, false
+	}
+	th := elem.TransportHeader()
+	if len(th) < 4 {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint16(th[0:2]), binary.BigEndian.Uint16(th[2:4]), true
+}
+
+// TranslateSrc sets the source address and port of a TCP or UDP packet, and fixes up the IPv4 header checksum and the
+// transport checksum. The address must be of the same family as the packet.
+func (elem *TCElement) TranslateSrc(addr netip.AddrPort) {
+	elem.translate(elem.GetSrcBytes(), 0, addr)
+}
+
+// TranslateDst sets the destination address and port of a TCP or UDP packet, and fixes up the IPv4 header checksum
+// and the transport checksum. The address must be of the same family as the packet.
+func (elem *TCElement) TranslateDst(addr netip.AddrPort) {
+	elem.translate(elem.GetDstBytes(), 2, addr)
+}
+
+func (elem *TCElement) translate(field []byte, portOffset int, addr netip.AddrPort) {
+	th := elem.TransportHeader()
+	// the address followed by the port, as covered by the transport checksum
+	var old, new [net.IPv6len + 2]byte
+	n := copy(old[:], field)
+	copy(old[n:], th[portOffset:portOffset+2])
+	if n == net.IPv4len {
+		a := addr.Addr().As4()
+		copy(new[:], a[:])
+	} else {
+		a := addr.Addr().As16()
+		copy(new[:], a[:])
+	}
+	binary.BigEndian.PutUint16(new[n:], addr.Port())
+
+	if elem.GetIPVersion() == 4 {
+		csum := elem.Packet[IPv4offsetChecksum : IPv4offsetChecksum+2]
+		binary.BigEndian.PutUint16(csum, tun.UpdateChecksum(binary.BigEndian.Uint16(csum), old[:n], new[:n]))
+	}
+	proto := elem.GetProtocol()
+	offset := TCPoffsetChecksum
+	if proto == ProtoUDP {
+		offset = UDPoffsetChecksum
+	}
+	if len(th) >= offset+2 {
+		csum := th[offset : offset+2]
+		// a zero UDP checksum means the sender did not compute one
+		if sum := binary.BigEndian.Uint16(csum); proto != ProtoUDP || sum != 0 {
+			sum = tun.UpdateChecksum(sum, old[:n+2], new[:n+2])
+			if proto == ProtoUDP && sum == 0 {
+				sum = 0xffff
+			}
+			binary.BigEndian.PutUint16(csum, sum)
+		}
+	}
+	copy(field, new[:n])
+	copy(th[portOffset:portOffset+2], new[n:n+2])
+}
+
+// FlowHash returns a hash of the packet's 5-tuple. Packets belonging to the same flow always hash to the same value.
+func (elem *TCElement) FlowHash() uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	// FNV-1a, inlined to avoid allocating on the data plane
+	h := uint32(offset32)
+	mix := func(b byte) {
+		h ^= uint32(b)
+		h *= prime32
+	}
+	for _, b := range elem.GetSrcBytes() {
+		mix(b)
+	}
+	for _, b := range elem.GetDstBytes() {
+		mix(b)
+	}
+	mix(elem.GetProtocol())
+	if sport, dport, ok := elem.GetPorts(); ok {
+		mix(byte(sport >> 8))
+		mix(byte(sport))
+		mix(byte(dport >> 8))
+		mix(byte(dport))
+	}
+	return h
+}
+
+// GetDSCP returns the differentiated services code point of an IP packet
+func (elem *TCElement) GetDSCP() uint8 {
+	ver := elem.GetIPVersion()
+	if ver == 4 {
+		return elem.Packet[1] >> 2
+	} else if ver == 6 {
+		// the traffic class straddles the first two bytes
+		return (elem.Packet[0]&0x0f)<<2 | elem.Packet[1]>>6
+	}
+	return 0
+}
+
+// PacketHash returns a hash of the whole packet, except for the fields that change at every hop (the TTL and the
+// IPv4 header checksum). Copies of a packet that took paths of different lengths hash to the same value.
+func (elem *TCElement) PacketHash() uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	var ttl, csum int
+	switch elem.GetIPVersion() {
+	case 4:
+		ttl, csum = 8, 10
+	case 6:
+		ttl, csum = 7, -2
+	default:
+		ttl, csum = -1, -2
+	}
+	h := uint64(offset64)
+	for i, b := range elem.Packet {
+		if i == ttl || i == csum || i == csum+1 {
+			continue
+		}
+		h ^= uint64(b)
+		h *= prime64
+	}
+	return h
+}
+
 func (elem *TCElement) TTLBytes() []byte {
 	if elem.GetIPVersion() == 4 {
 		return elem.Packet[8:9]
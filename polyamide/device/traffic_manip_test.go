@@ -2,6 +2,7 @@ package device
 
 import (
 	"encoding/binary"
+	"net/netip"
 	"testing"
 )
 
@@ -39,3 +40,158 @@ func TestParsePacketTrimsToAdvertisedLength(t *testing.T) {
 		t.Fatalf("expected packet length 48, got %d", len(elem.Packet))
 	}
 }
+
+func testIPv4Packet(buf *[MaxMessageSize]byte, proto uint8, src, dst [4]byte, sport, dport uint16) *TCElement {
+	packet := buf[MessageTransportHeaderSize : MessageTransportHeaderSize+28]
+	packet[0] = 4<<4 | 5
+	binary.BigEndian.PutUint16(packet[IPv4offsetTotalLength:IPv4offsetTotalLength+2], 28)
+	packet[IPv4offsetProtocol] = proto
+	copy(packet[IPv4offsetSrc:], src[:])
+	copy(packet[IPv4offsetDst:], dst[:])
+	binary.BigEndian.PutUint16(packet[20:22], sport)
+	binary.BigEndian.PutUint16(packet[22:24], dport)
+	return &TCElement{Buffer: buf, Packet: packet}
+}
+
+func TestGetPortsReadsTransportHeader(t *testing.T) {
+	var buf [MaxMessageSize]byte
+	elem := testIPv4Packet(&buf, ProtoUDP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 1234, 53)
+	sport, dport, ok := elem.GetPorts()
+	if !ok || sport != 1234 || dport != 53 {
+		t.Fatalf("expected ports 1234 -> 53, got %d -> %d (%v)", sport, dport, ok)
+	}
+
+	elem.Packet[IPv4offsetProtocol] = ProtoICMP
+	if _, _, ok := elem.GetPorts(); ok {
+		t.Fatal("expected icmp packet to have no ports")
+	}
+}
+
+func TestFlowHashIsStablePerFlow(t *testing.T) {
+	var a, b, c [MaxMessageSize]byte
+	flow1 := testIPv4Packet(&a, ProtoTCP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 40000, 443)
+	flow1Again := testIPv4Packet(&b, ProtoTCP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 40000, 443)
+	flow2 := testIPv4Packet(&c, ProtoTCP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 40001, 443)
+
+	if flow1.FlowHash() != flow1Again.FlowHash() {
+		t.Fatal("expected packets of the same flow to hash identically")
+	}
+	if flow1.FlowHash() == flow2.FlowHash() {
+		t.Fatal("expected packets of different flows to hash differently")
+	}
+}
+
+func TestGetDSCP(t *testing.T) {
+	var buf [MaxMessageSize]byte
+	elem := testIPv4Packet(&buf, ProtoUDP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 5004, 5004)
+	elem.Packet[1] = 46<<2 | 1 // EF, with an ECN bit
+	if dscp := elem.GetDSCP(); dscp != 46 {
+		t.Fatalf("expected dscp 46, got %d", dscp)
+	}
+
+	packet := buf[MessageTransportHeaderSize : MessageTransportHeaderSize+40]
+	packet[0] = 6<<4 | 46>>2
+	packet[1] = (46&0x3)<<6 | 0x05
+	elem = &TCElement{Buffer: &buf, Packet: packet}
+	if dscp := elem.GetDSCP(); dscp != 46 {
+		t.Fatalf("expected ipv6 dscp 46, got %d", dscp)
+	}
+}
+
+func TestPacketHashIgnoresHopFields(t *testing.T) {
+	var a, b [MaxMessageSize]byte
+	first := testIPv4Packet(&a, ProtoUDP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 5004, 5004)
+	second := testIPv4Packet(&b, ProtoUDP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 5004, 5004)
+	first.Packet[8] = 64
+	second.Packet[8] = 64
+	second.DecrementTTL()
+	second.DecrementTTL()
+	if first.PacketHash() != second.PacketHash() {
+		t.Fatal("expected copies that took different paths to hash identically")
+	}
+
+	second.Packet[len(second.Packet)-1] = 1
+	if first.PacketHash() == second.PacketHash() {
+		t.Fatal("expected packets with different payloads to hash differently")
+	}
+}
+
+func TestDuplicateTagSurvivesTheWire(t *testing.T) {
+	var buf [MaxMessageSize]byte
+	elem := testIPv4Packet(&buf, ProtoUDP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 5004, 5004)
+	if wire := elem.wirePacket(MaxContentSize); len(wire) != 28 {
+		t.Fatalf("expected an untagged packet to be sent as is, got %d bytes", len(wire))
+	}
+
+	elem.DuplicateTag = 0x0102030405060708
+	if wire := elem.wirePacket(28 + duplicateTrailerSize - 1); len(wire) != 28 {
+		t.Fatalf("expected a packet without room for the tag to be sent as is, got %d bytes", len(wire))
+	}
+	wire := elem.wirePacket(MaxContentSize)
+	// the sender pads the payload with zeros
+	received := &TCElement{Buffer: &buf, Packet: buf[MessageTransportHeaderSize : MessageTransportHeaderSize+len(wire)+8]}
+	clear(received.Packet[len(wire):])
+	if !received.ParsePacket() {
+		t.Fatal("expected tagged packet to parse")
+	}
+	if len(received.Packet) != 28 || received.DuplicateTag != 0x0102030405060708 {
+		t.Fatalf("expected the tag to be read and trimmed, got %d bytes and tag %x", len(received.Packet), received.DuplicateTag)
+	}
+
+	// padding alone is not a tag
+	received.Packet = buf[MessageTransportHeaderSize : MessageTransportHeaderSize+48]
+	clear(received.Packet[28:])
+	if !received.ParsePacket() || received.DuplicateTag != 0 {
+		t.Fatalf("expected padding not to be read as a tag, got %x", received.DuplicateTag)
+	}
+}
+
+// testChecksum returns the one's complement of the one's complement sum of the given buffers
+func testChecksum(bufs ...[]byte) uint16 {
+	var ac uint32
+	for _, b := range bufs {
+		for i := 0; i+1 < len(b); i += 2 {
+			ac += uint32(binary.BigEndian.Uint16(b[i:]))
+		}
+	}
+	for ac>>16 != 0 {
+		ac = ac>>16 + ac&0xffff
+	}
+	return ^uint16(ac)
+}
+
+func testUDPChecksums(packet []byte) (header, transport uint16) {
+	pseudo := append(append([]byte(nil), packet[IPv4offsetSrc:IPv4offsetDst+4]...), 0, ProtoUDP, 0, byte(len(packet)-20))
+	return testChecksum(packet[:20]), testChecksum(pseudo, packet[20:])
+}
+
+func TestTranslateFixesChecksums(t *testing.T) {
+	var buf [MaxMessageSize]byte
+	elem := testIPv4Packet(&buf, ProtoUDP, [4]byte{10, 0, 0, 1}, [4]byte{10, 0, 0, 2}, 40000, 8080)
+	binary.BigEndian.PutUint16(elem.Packet[24:26], 8) // udp length
+	elem.Packet[8] = 64
+	header, transport := testUDPChecksums(elem.Packet)
+	binary.BigEndian.PutUint16(elem.Packet[IPv4offsetChecksum:], header)
+	binary.BigEndian.PutUint16(elem.Packet[20+UDPoffsetChecksum:], transport)
+
+	elem.TranslateDst(netip.MustParseAddrPort("192.168.1.10:80"))
+	elem.TranslateSrc(netip.MustParseAddrPort("10.0.0.2:50000"))
+	if dst := elem.GetDst(); dst != netip.MustParseAddr("192.168.1.10") {
+		t.Fatalf("expected destination 192.168.1.10, got %v", dst)
+	}
+	if sport, dport, _ := elem.GetPorts(); sport != 50000 || dport != 80 {
+		t.Fatalf("expected ports 50000 -> 80, got %d -> %d", sport, dport)
+	}
+	// a packet with correct checksums sums to zero
+	header, transport = testUDPChecksums(elem.Packet)
+	if header != 0 || transport != 0 {
+		t.Fatalf("expected valid checksums after translation, got %x and %x", header, transport)
+	}
+
+	// udp packets without a checksum keep it unset
+	binary.BigEndian.PutUint16(elem.Packet[20+UDPoffsetChecksum:], 0)
+	elem.TranslateDst(netip.MustParseAddrPort("192.168.1.11:80"))
+	if csum := binary.BigEndian.Uint16(elem.Packet[20+UDPoffsetChecksum:]); csum != 0 {
+		t.Fatalf("expected the udp checksum to stay unset, got %x", csum)
+	}
+}
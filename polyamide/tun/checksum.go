@@ -100,3 +100,17 @@ func pseudoHeaderChecksumNoFold(protocol uint8, srcAddr, dstAddr []byte, totalLe
 	binary.BigEndian.PutUint16(tmp, totalLen)
 	return checksumNoFold(tmp, sum)
 }
+
+// UpdateChecksum returns the internet checksum field csum after the bytes old it covers were replaced by new, using the
+// incremental update of RFC 1624 (eqn. 3). old and new must have the same even length.
+func UpdateChecksum(csum uint16, old, new []byte) uint16 {
+	ac := uint64(^csum)
+	for i := 0; i+1 < len(old); i += 2 {
+		ac += uint64(^binary.BigEndian.Uint16(old[i:]))
+		ac += uint64(binary.BigEndian.Uint16(new[i:]))
+	}
+	for (ac >> 16) > 0 {
+		ac = (ac >> 16) + (ac & 0xffff)
+	}
+	return ^uint16(ac)
+}
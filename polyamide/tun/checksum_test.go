@@ -96,3 +96,21 @@ func BenchmarkChecksum(b *testing.B) {
 		})
 	}
 }
+
+func TestUpdateChecksum(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, length := range []int{20, 40, 1280} {
+		for _, field := range []int{2, 4, 16} {
+			buf := make([]byte, length)
+			rng.Read(buf)
+			csum := ^checksumRef(buf, 0)
+			offset := rng.Intn(length-field) &^ 1
+			old := append([]byte(nil), buf[offset:offset+field]...)
+			rng.Read(buf[offset : offset+field])
+			want := ^checksumRef(buf, 0)
+			if got := UpdateChecksum(csum, old, buf[offset:offset+field]); got != want {
+				t.Errorf("length %d, field %d: expected checksum %x, got %x", length, field, want, got)
+			}
+		}
+	}
+}
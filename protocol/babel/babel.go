@@ -0,0 +1,468 @@
+// Package babel implements the subset of the RFC 8966 wire format needed to exchange routes with standard Babel
+// routers such as babeld or BIRD.
+package babel
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+const (
+	Port     = 6696
+	Infinity = 0xFFFF
+
+	magic   = 42
+	version = 2
+)
+
+// Group is the link-local multicast group Babel routers listen on.
+var Group = netip.MustParseAddr("ff02::1:6")
+
+const (
+	tlvPad1         = 0
+	tlvPadN         = 1
+	tlvHello        = 4
+	tlvIHU          = 5
+	tlvRouterId     = 6
+	tlvNextHop      = 7
+	tlvUpdate       = 8
+	tlvRouteRequest = 9
+	tlvSeqnoRequest = 10
+)
+
+const (
+	aeWildcard  = 0
+	aeIPv4      = 1
+	aeIPv6      = 2
+	aeLinkLocal = 3
+)
+
+const (
+	updateFlagDefaultPrefix = 0x80
+	updateFlagRouterId      = 0x40
+)
+
+// a sub-TLV with this bit set must be understood, otherwise the enclosing TLV is ignored
+const subTlvMandatory = 0x80
+
+var linkLocalPrefix = [8]byte{0xfe, 0x80}
+
+type RouterId [8]byte
+
+func (r RouterId) String() string {
+	return fmt.Sprintf("%x:%x:%x:%x", r[0:2], r[2:4], r[4:6], r[6:8])
+}
+
+type Hello struct {
+	Unicast  bool
+	Seqno    uint16
+	Interval time.Duration
+}
+
+type IHU struct {
+	RxCost   uint16
+	Interval time.Duration
+	Addr     netip.Addr // the neighbour this IHU is addressed to, invalid if it is addressed to every receiver
+}
+
+// Update is a route announcement. RouterId and NextHop are resolved from the preceding TLVs of the packet,
+// NextHop is invalid if the sender did not specify one.
+type Update struct {
+	Prefix   netip.Prefix // invalid for a wildcard retraction
+	Interval time.Duration
+	Seqno    uint16
+	Metric   uint16
+	RouterId RouterId
+	NextHop  netip.Addr
+}
+
+type RouteRequest struct {
+	Prefix netip.Prefix // invalid for a wildcard request
+}
+
+type SeqnoRequest struct {
+	Prefix   netip.Prefix
+	Seqno    uint16
+	HopCount uint8
+	RouterId RouterId
+}
+
+// Packet is a decoded Babel packet. TLVs that are not understood are skipped.
+type Packet struct {
+	Hellos        []Hello
+	IHUs          []IHU
+	Updates       []Update
+	RouteRequests []RouteRequest
+	SeqnoRequests []SeqnoRequest
+}
+
+func centis(d time.Duration) uint16 {
+	return uint16(min(d/(10*time.Millisecond), 0xFFFF))
+}
+
+func fromCentis(c uint16) time.Duration {
+	return time.Duration(c) * 10 * time.Millisecond
+}
+
+// Encode serialises the packet. Updates are preceded by RouterId and NextHop TLVs whenever those change.
+func (p *Packet) Encode() ([]byte, error) {
+	body := make([]byte, 0, 512)
+	tlv := func(t uint8, value []byte) {
+		body = append(body, t, uint8(len(value)))
+		body = append(body, value...)
+	}
+	for _, h := range p.Hellos {
+		var flags uint16
+		if h.Unicast {
+			flags = 0x8000
+		}
+		v := binary.BigEndian.AppendUint16(nil, flags)
+		v = binary.BigEndian.AppendUint16(v, h.Seqno)
+		v = binary.BigEndian.AppendUint16(v, centis(h.Interval))
+		tlv(tlvHello, v)
+	}
+	for _, ihu := range p.IHUs {
+		ae, addr, err := encodeAddr(ihu.Addr, true)
+		if err != nil {
+			return nil, err
+		}
+		v := []byte{ae, 0}
+		v = binary.BigEndian.AppendUint16(v, ihu.RxCost)
+		v = binary.BigEndian.AppendUint16(v, centis(ihu.Interval))
+		tlv(tlvIHU, append(v, addr...))
+	}
+	var routerId *RouterId
+	var nextHop [2]netip.Addr // per address family of the prefix
+	for _, u := range p.Updates {
+		if routerId == nil || *routerId != u.RouterId {
+			v := []byte{0, 0}
+			tlv(tlvRouterId, append(v, u.RouterId[:]...))
+			routerId = &u.RouterId
+		}
+		family := 0
+		if u.Prefix.IsValid() && u.Prefix.Addr().Is4() {
+			family = 1
+		}
+		if u.NextHop.IsValid() && u.NextHop != nextHop[family] {
+			ae, addr, err := encodeAddr(u.NextHop, true)
+			if err != nil {
+				return nil, err
+			}
+			tlv(tlvNextHop, append([]byte{ae, 0}, addr...))
+			nextHop[family] = u.NextHop
+		}
+		ae, plen, prefix, err := encodePrefix(u.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		v := []byte{ae, 0, plen, 0}
+		v = binary.BigEndian.AppendUint16(v, centis(u.Interval))
+		v = binary.BigEndian.AppendUint16(v, u.Seqno)
+		v = binary.BigEndian.AppendUint16(v, u.Metric)
+		tlv(tlvUpdate, append(v, prefix...))
+	}
+	for _, r := range p.RouteRequests {
+		ae, plen, prefix, err := encodePrefix(r.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		tlv(tlvRouteRequest, append([]byte{ae, plen}, prefix...))
+	}
+	for _, r := range p.SeqnoRequests {
+		if !r.Prefix.IsValid() {
+			return nil, errors.New("seqno request must have a prefix")
+		}
+		ae, plen, prefix, err := encodePrefix(r.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		v := []byte{ae, plen}
+		v = binary.BigEndian.AppendUint16(v, r.Seqno)
+		v = append(v, r.HopCount, 0)
+		v = append(v, r.RouterId[:]...)
+		tlv(tlvSeqnoRequest, append(v, prefix...))
+	}
+	if len(body) > 0xFFFF {
+		return nil, fmt.Errorf("babel packet body too large: %d bytes", len(body))
+	}
+	buf := []byte{magic, version}
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(body)))
+	return append(buf, body...), nil
+}
+
+// encodeAddr returns the address encoding of a single address. Link-local IPv6 addresses use AE 3 if allowed.
+func encodeAddr(addr netip.Addr, allowLinkLocal bool) (uint8, []byte, error) {
+	switch {
+	case !addr.IsValid():
+		return aeWildcard, nil, nil
+	case addr.Is4():
+		a := addr.As4()
+		return aeIPv4, a[:], nil
+	case addr.Is6():
+		a := addr.As16()
+		if allowLinkLocal && [8]byte(a[:8]) == linkLocalPrefix {
+			return aeLinkLocal, a[8:], nil
+		}
+		return aeIPv6, a[:], nil
+	}
+	return 0, nil, fmt.Errorf("unsupported address %v", addr)
+}
+
+func encodePrefix(prefix netip.Prefix) (ae uint8, plen uint8, data []byte, err error) {
+	if !prefix.IsValid() {
+		return aeWildcard, 0, nil, nil
+	}
+	prefix = prefix.Masked()
+	ae, data, err = encodeAddr(prefix.Addr(), false)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return ae, uint8(prefix.Bits()), data[:(prefix.Bits()+7)/8], nil
+}
+
+// Decode parses a Babel packet. It returns an error if the packet header is malformed, malformed TLVs are skipped.
+func Decode(buf []byte) (*Packet, error) {
+	if len(buf) < 4 {
+		return nil, errors.New("babel packet too short")
+	}
+	if buf[0] != magic || buf[1] != version {
+		return nil, fmt.Errorf("not a babel version %d packet", version)
+	}
+	length := int(binary.BigEndian.Uint16(buf[2:4]))
+	if len(buf) < 4+length {
+		return nil, errors.New("babel packet truncated")
+	}
+	body := buf[4 : 4+length]
+
+	d := decoder{}
+	for len(body) > 0 {
+		if body[0] == tlvPad1 {
+			body = body[1:]
+			continue
+		}
+		if len(body) < 2 || len(body) < 2+int(body[1]) {
+			return nil, errors.New("babel tlv truncated")
+		}
+		t, value := body[0], body[2:2+int(body[1])]
+		body = body[2+len(value):]
+		// a malformed TLV is ignored without affecting the rest of the packet
+		_ = d.tlv(t, value)
+	}
+	return &d.packet, nil
+}
+
+type decoder struct {
+	packet        Packet
+	routerId      RouterId
+	nextHop       [2]netip.Addr // next hop for IPv6 and IPv4 prefixes
+	defaultPrefix [4][16]byte   // per address encoding, used for prefix compression
+}
+
+var errMalformed = errors.New("malformed tlv")
+
+func (d *decoder) tlv(t uint8, v []byte) error {
+	switch t {
+	case tlvPadN:
+	case tlvHello:
+		if len(v) < 6 {
+			return errMalformed
+		}
+		d.packet.Hellos = append(d.packet.Hellos, Hello{
+			Unicast:  binary.BigEndian.Uint16(v[0:2])&0x8000 != 0,
+			Seqno:    binary.BigEndian.Uint16(v[2:4]),
+			Interval: fromCentis(binary.BigEndian.Uint16(v[4:6])),
+		})
+	case tlvIHU:
+		if len(v) < 6 {
+			return errMalformed
+		}
+		addr, _, err := decodeAddr(v[0], v[6:])
+		if err != nil {
+			return err
+		}
+		d.packet.IHUs = append(d.packet.IHUs, IHU{
+			RxCost:   binary.BigEndian.Uint16(v[2:4]),
+			Interval: fromCentis(binary.BigEndian.Uint16(v[4:6])),
+			Addr:     addr,
+		})
+	case tlvRouterId:
+		if len(v) < 10 {
+			return errMalformed
+		}
+		d.routerId = RouterId(v[2:10])
+	case tlvNextHop:
+		if len(v) < 2 {
+			return errMalformed
+		}
+		addr, _, err := decodeAddr(v[0], v[2:])
+		if err != nil {
+			return err
+		}
+		if !addr.IsValid() {
+			return errMalformed
+		}
+		if addr.Is4() {
+			d.nextHop[1] = addr
+		} else {
+			d.nextHop[0] = addr
+		}
+	case tlvUpdate:
+		if len(v) < 10 {
+			return errMalformed
+		}
+		ae, flags, plen, omitted := v[0], v[1], v[2], v[3]
+		prefix, rest, err := d.decodePrefix(ae, plen, omitted, v[10:])
+		if err != nil {
+			return err
+		}
+		if mandatorySubTlv(rest) {
+			return nil
+		}
+		if flags&updateFlagDefaultPrefix != 0 && prefix.IsValid() {
+			a := prefix.Addr().AsSlice()
+			copy(d.defaultPrefix[ae][:], a)
+		}
+		if flags&updateFlagRouterId != 0 && prefix.IsValid() {
+			a := prefix.Addr().As16()
+			d.routerId = RouterId(a[8:])
+		}
+		u := Update{
+			Prefix:   prefix,
+			Interval: fromCentis(binary.BigEndian.Uint16(v[4:6])),
+			Seqno:    binary.BigEndian.Uint16(v[6:8]),
+			Metric:   binary.BigEndian.Uint16(v[8:10]),
+			RouterId: d.routerId,
+		}
+		if prefix.IsValid() && prefix.Addr().Is4() {
+			u.NextHop = d.nextHop[1]
+		} else {
+			u.NextHop = d.nextHop[0]
+		}
+		d.packet.Updates = append(d.packet.Updates, u)
+	case tlvRouteRequest:
+		if len(v) < 2 {
+			return errMalformed
+		}
+		prefix, rest, err := d.decodePrefix(v[0], v[1], 0, v[2:])
+		if err != nil {
+			return err
+		}
+		if mandatorySubTlv(rest) {
+			return nil
+		}
+		d.packet.RouteRequests = append(d.packet.RouteRequests, RouteRequest{Prefix: prefix})
+	case tlvSeqnoRequest:
+		if len(v) < 14 {
+			return errMalformed
+		}
+		prefix, rest, err := d.decodePrefix(v[0], v[1], 0, v[14:])
+		if err != nil {
+			return err
+		}
+		if mandatorySubTlv(rest) || !prefix.IsValid() {
+			return nil
+		}
+		d.packet.SeqnoRequests = append(d.packet.SeqnoRequests, SeqnoRequest{
+			Prefix:   prefix,
+			Seqno:    binary.BigEndian.Uint16(v[2:4]),
+			HopCount: v[4],
+			RouterId: RouterId(v[6:14]),
+		})
+	}
+	return nil
+}
+
+// decodeAddr decodes a full address, returning the remaining bytes
+func decodeAddr(ae uint8, v []byte) (netip.Addr, []byte, error) {
+	switch ae {
+	case aeWildcard:
+		return netip.Addr{}, v, nil
+	case aeIPv4:
+		if len(v) < 4 {
+			return netip.Addr{}, nil, errMalformed
+		}
+		return netip.AddrFrom4([4]byte(v[:4])), v[4:], nil
+	case aeIPv6:
+		if len(v) < 16 {
+			return netip.Addr{}, nil, errMalformed
+		}
+		return netip.AddrFrom16([16]byte(v[:16])), v[16:], nil
+	case aeLinkLocal:
+		if len(v) < 8 {
+			return netip.Addr{}, nil, errMalformed
+		}
+		var a [16]byte
+		copy(a[:8], linkLocalPrefix[:])
+		copy(a[8:], v[:8])
+		return netip.AddrFrom16(a), v[8:], nil
+	}
+	return netip.Addr{}, nil, fmt.Errorf("unknown address encoding %d", ae)
+}
+
+// decodePrefix decodes a possibly compressed prefix, returning the remaining bytes
+func (d *decoder) decodePrefix(ae, plen, omitted uint8, v []byte) (netip.Prefix, []byte, error) {
+	var size int
+	switch ae {
+	case aeWildcard:
+		if plen != 0 {
+			return netip.Prefix{}, nil, errMalformed
+		}
+		return netip.Prefix{}, v, nil
+	case aeIPv4:
+		size = 4
+	case aeIPv6:
+		size = 16
+	case aeLinkLocal:
+		if plen != 64 && plen != 128 {
+			return netip.Prefix{}, nil, errMalformed
+		}
+		size = 16
+		omitted = 0
+		plen -= 64
+	default:
+		return netip.Prefix{}, nil, fmt.Errorf("unknown address encoding %d", ae)
+	}
+	if int(plen) > size*8 || int(omitted) > size {
+		return netip.Prefix{}, nil, errMalformed
+	}
+	n := max(0, (int(plen)+7)/8-int(omitted))
+	if len(v) < n {
+		return netip.Prefix{}, nil, errMalformed
+	}
+	var a [16]byte
+	if ae == aeLinkLocal {
+		copy(a[:8], linkLocalPrefix[:])
+		copy(a[8:], v[:n])
+		plen += 64
+	} else {
+		copy(a[:omitted], d.defaultPrefix[ae][:omitted])
+		copy(a[omitted:], v[:n])
+	}
+	var addr netip.Addr
+	if size == 4 {
+		addr = netip.AddrFrom4([4]byte(a[:4]))
+	} else {
+		addr = netip.AddrFrom16(a)
+	}
+	return netip.PrefixFrom(addr, int(plen)).Masked(), v[n:], nil
+}
+
+func mandatorySubTlv(v []byte) bool {
+	for len(v) > 0 {
+		if v[0] == tlvPad1 {
+			v = v[1:]
+			continue
+		}
+		if v[0]&subTlvMandatory != 0 {
+			return true
+		}
+		if len(v) < 2 || len(v) < 2+int(v[1]) {
+			return false
+		}
+		v = v[2+int(v[1]):]
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package babel
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	rid := RouterId{1, 2, 3, 4, 5, 6, 7, 8}
+	pkt := Packet{
+		Hellos: []Hello{{Seqno: 12, Interval: 4 * time.Second}},
+		IHUs: []IHU{
+			{RxCost: 96, Interval: 12 * time.Second, Addr: netip.MustParseAddr("fe80::1")},
+			{RxCost: 256, Interval: 12 * time.Second, Addr: netip.MustParseAddr("2001:db8::1")},
+		},
+		Updates: []Update{
+			{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Interval: 16 * time.Second, Seqno: 3, Metric: 200, RouterId: rid, NextHop: netip.MustParseAddr("192.168.1.1")},
+			{Prefix: netip.MustParsePrefix("2001:db8:1::/48"), Interval: 16 * time.Second, Seqno: 3, Metric: Infinity, RouterId: rid},
+			{Prefix: netip.MustParsePrefix("10.2.0.0/24"), Interval: 16 * time.Second, Seqno: 4, Metric: 5, RouterId: RouterId{9}, NextHop: netip.MustParseAddr("192.168.1.1")},
+		},
+		RouteRequests: []RouteRequest{{}, {Prefix: netip.MustParsePrefix("10.1.0.0/16")}},
+		SeqnoRequests: []SeqnoRequest{{Prefix: netip.MustParsePrefix("2001:db8:1::/48"), Seqno: 4, HopCount: 64, RouterId: rid}},
+	}
+	buf, err := pkt.Encode()
+	assert.NoError(t, err)
+
+	decoded, err := Decode(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, pkt, *decoded)
+}
+
+func TestDecodeCompressedUpdates(t *testing.T) {
+	buf := []byte{
+		magic, version, 0, 0,
+		// update 2001:db8:1::/48, sets the default prefix and the router id
+		tlvUpdate, 26, aeIPv6, updateFlagDefaultPrefix | updateFlagRouterId, 128, 0, 0x01, 0x90, 0, 7, 0, 100,
+		0x20, 0x01, 0x0d, 0xb8, 0, 1, 0, 0, 0xa, 0xb, 0xc, 0xd, 0x1, 0x2, 0x3, 0x4,
+		// update 2001:db8:1::2/128, omitting the first 14 bytes
+		tlvUpdate, 12, aeIPv6, 0, 128, 14, 0x01, 0x90, 0, 7, 0, 200, 0, 2,
+		// update with a mandatory sub-TLV, which must be ignored
+		tlvUpdate, 15, aeIPv4, 0, 24, 0, 0x01, 0x90, 0, 7, 0, 200, 10, 3, 0, 128, 0,
+	}
+	buf[3] = uint8(len(buf) - 4)
+
+	pkt, err := Decode(buf)
+	assert.NoError(t, err)
+	assert.Len(t, pkt.Updates, 2)
+	assert.Equal(t, netip.MustParsePrefix("2001:db8:1:0:a0b:c0d:102:304/128"), pkt.Updates[0].Prefix)
+	assert.Equal(t, netip.MustParsePrefix("2001:db8:1:0:a0b:c0d:102:2/128"), pkt.Updates[1].Prefix)
+	assert.Equal(t, pkt.Updates[0].RouterId, pkt.Updates[1].RouterId)
+	assert.Equal(t, RouterId{0xa, 0xb, 0xc, 0xd, 0x1, 0x2, 0x3, 0x4}, pkt.Updates[1].RouterId)
+	assert.Equal(t, uint16(200), pkt.Updates[1].Metric)
+	assert.Equal(t, 4*time.Second, pkt.Updates[1].Interval)
+}
+
+func TestDecodeRejectsBadHeader(t *testing.T) {
+	_, err := Decode([]byte{magic, 1, 0, 0})
+	assert.Error(t, err)
+	_, err = Decode([]byte{magic, version, 0, 8, tlvPad1})
+	assert.Error(t, err)
+}
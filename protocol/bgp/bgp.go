@@ -0,0 +1,507 @@
+// Package bgp implements the subset of BGP-4 (RFC 4271) messages needed to exchange IPv4 and IPv6 unicast routes,
+// including multiprotocol extensions (RFC 4760) and four-octet AS numbers (RFC 6793).
+package bgp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+)
+
+const (
+	Port          = 179
+	Version       = 4
+	ASTrans       = 23456
+	MaxMessageLen = 4096
+
+	headerLen = 19
+)
+
+const (
+	MsgOpen         = 1
+	MsgUpdate       = 2
+	MsgNotification = 3
+	MsgKeepalive    = 4
+)
+
+// notification error codes
+const (
+	ErrHeader       = 1
+	ErrOpen         = 2
+	ErrUpdate       = 3
+	ErrHoldTimer    = 4
+	ErrFSM          = 5
+	ErrCease        = 6
+	ErrOpenBadPeer  = 2 // subcode of ErrOpen
+	ErrOpenHoldTime = 6 // subcode of ErrOpen
+)
+
+const (
+	OriginIGP        = 0
+	OriginEGP        = 1
+	OriginIncomplete = 2
+)
+
+const (
+	attrOrigin      = 1
+	attrASPath      = 2
+	attrNextHop     = 3
+	attrMED         = 4
+	attrLocalPref   = 5
+	attrMPReach     = 14
+	attrMPUnreach   = 15
+	flagOptional    = 0x80
+	flagTransitive  = 0x40
+	flagExtendedLen = 0x10
+
+	asSet      = 1
+	asSequence = 2
+
+	capMultiprotocol = 1
+	capFourOctetAS   = 65
+	paramCapability  = 2
+)
+
+// Family is an address family and subsequent address family pair
+type Family struct {
+	AFI  uint16
+	SAFI uint8
+}
+
+var (
+	IPv4Unicast = Family{AFI: 1, SAFI: 1}
+	IPv6Unicast = Family{AFI: 2, SAFI: 1}
+)
+
+var marker = [16]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// Message is a raw BGP message
+type Message struct {
+	Type uint8
+	Body []byte
+}
+
+// ReadMessage reads a single message, verifying its header
+func ReadMessage(r io.Reader) (Message, error) {
+	var header [headerLen]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Message{}, err
+	}
+	if [16]byte(header[:16]) != marker {
+		return Message{}, errors.New("bgp message has an invalid marker")
+	}
+	length := int(binary.BigEndian.Uint16(header[16:18]))
+	if length < headerLen || length > MaxMessageLen {
+		return Message{}, fmt.Errorf("bgp message has an invalid length %d", length)
+	}
+	body := make([]byte, length-headerLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return Message{}, err
+	}
+	return Message{Type: header[18], Body: body}, nil
+}
+
+func encodeMessage(t uint8, body []byte) ([]byte, error) {
+	if headerLen+len(body) > MaxMessageLen {
+		return nil, fmt.Errorf("bgp message too large: %d bytes", headerLen+len(body))
+	}
+	buf := append([]byte{}, marker[:]...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(headerLen+len(body)))
+	buf = append(buf, t)
+	return append(buf, body...), nil
+}
+
+type Open struct {
+	ASN      uint32
+	HoldTime uint16 // seconds
+	RouterId netip.Addr
+	Families []Family // multiprotocol capabilities, empty if the speaker did not send any
+	AS4      bool     // the speaker supports four-octet AS numbers
+}
+
+func (o *Open) Encode() ([]byte, error) {
+	if !o.RouterId.Is4() {
+		return nil, errors.New("bgp router id must be an IPv4 address")
+	}
+	caps := make([]byte, 0)
+	for _, f := range o.Families {
+		caps = append(caps, capMultiprotocol, 4)
+		caps = binary.BigEndian.AppendUint16(caps, f.AFI)
+		caps = append(caps, 0, f.SAFI)
+	}
+	if o.AS4 {
+		caps = append(caps, capFourOctetAS, 4)
+		caps = binary.BigEndian.AppendUint32(caps, o.ASN)
+	}
+	asn := o.ASN
+	if asn > 0xFFFF {
+		asn = ASTrans
+	}
+	body := []byte{Version}
+	body = binary.BigEndian.AppendUint16(body, uint16(asn))
+	body = binary.BigEndian.AppendUint16(body, o.HoldTime)
+	id := o.RouterId.As4()
+	body = append(body, id[:]...)
+	if len(caps) == 0 {
+		body = append(body, 0)
+	} else {
+		body = append(body, uint8(len(caps)+2), paramCapability, uint8(len(caps)))
+		body = append(body, caps...)
+	}
+	return encodeMessage(MsgOpen, body)
+}
+
+func DecodeOpen(body []byte) (*Open, error) {
+	if len(body) < 10 {
+		return nil, errors.New("bgp open too short")
+	}
+	if body[0] != Version {
+		return nil, fmt.Errorf("unsupported bgp version %d", body[0])
+	}
+	o := &Open{
+		ASN:      uint32(binary.BigEndian.Uint16(body[1:3])),
+		HoldTime: binary.BigEndian.Uint16(body[3:5]),
+		RouterId: netip.AddrFrom4([4]byte(body[5:9])),
+	}
+	params := body[10:]
+	if len(params) < int(body[9]) {
+		return nil, errors.New("bgp open parameters truncated")
+	}
+	params = params[:body[9]]
+	for len(params) >= 2 {
+		t, value := params[0], params[2:]
+		if len(value) < int(params[1]) {
+			return nil, errors.New("bgp open parameter truncated")
+		}
+		value = value[:params[1]]
+		params = params[2+len(value):]
+		if t != paramCapability {
+			continue
+		}
+		for len(value) >= 2 {
+			code, c := value[0], value[2:]
+			if len(c) < int(value[1]) {
+				return nil, errors.New("bgp capability truncated")
+			}
+			c = c[:value[1]]
+			value = value[2+len(c):]
+			switch {
+			case code == capMultiprotocol && len(c) == 4:
+				o.Families = append(o.Families, Family{AFI: binary.BigEndian.Uint16(c[0:2]), SAFI: c[3]})
+			case code == capFourOctetAS && len(c) == 4:
+				o.AS4 = true
+				o.ASN = binary.BigEndian.Uint32(c)
+			}
+		}
+	}
+	return o, nil
+}
+
+func EncodeKeepalive() []byte {
+	buf, _ := encodeMessage(MsgKeepalive, nil)
+	return buf
+}
+
+type Notification struct {
+	Code    uint8
+	Subcode uint8
+	Data    []byte
+}
+
+func (n *Notification) Encode() []byte {
+	buf, _ := encodeMessage(MsgNotification, append([]byte{n.Code, n.Subcode}, n.Data...))
+	return buf
+}
+
+func (n *Notification) Error() string {
+	return fmt.Sprintf("bgp notification %d/%d", n.Code, n.Subcode)
+}
+
+func DecodeNotification(body []byte) (*Notification, error) {
+	if len(body) < 2 {
+		return nil, errors.New("bgp notification too short")
+	}
+	return &Notification{Code: body[0], Subcode: body[1], Data: body[2:]}, nil
+}
+
+// Update announces and withdraws routes. All announced prefixes share the same path attributes,
+// IPv4 prefixes use NextHop and IPv6 prefixes use MPNextHop.
+type Update struct {
+	Withdrawn []netip.Prefix
+	NLRI      []netip.Prefix
+	Origin    uint8
+	ASPath    []uint32
+	NextHop   netip.Addr
+	MPNextHop netip.Addr
+	MED       *uint32
+	LocalPref *uint32
+}
+
+func (u *Update) Encode(as4 bool) ([]byte, error) {
+	withdrawn4, withdrawn6 := splitFamilies(u.Withdrawn)
+	nlri4, nlri6 := splitFamilies(u.NLRI)
+
+	attrs := make([]byte, 0)
+	if len(u.NLRI) != 0 {
+		attrs = appendAttr(attrs, flagTransitive, attrOrigin, []byte{u.Origin})
+		path := make([]byte, 0)
+		if len(u.ASPath) != 0 {
+			path = append(path, asSequence, uint8(len(u.ASPath)))
+			for _, asn := range u.ASPath {
+				if as4 {
+					path = binary.BigEndian.AppendUint32(path, asn)
+				} else {
+					if asn > 0xFFFF {
+						asn = ASTrans
+					}
+					path = binary.BigEndian.AppendUint16(path, uint16(asn))
+				}
+			}
+		}
+		attrs = appendAttr(attrs, flagTransitive, attrASPath, path)
+		if len(nlri4) != 0 {
+			if !u.NextHop.Is4() {
+				return nil, errors.New("ipv4 routes need an ipv4 next hop")
+			}
+			nh := u.NextHop.As4()
+			attrs = appendAttr(attrs, flagTransitive, attrNextHop, nh[:])
+		}
+		if u.MED != nil {
+			attrs = appendAttr(attrs, flagOptional, attrMED, binary.BigEndian.AppendUint32(nil, *u.MED))
+		}
+		if u.LocalPref != nil {
+			attrs = appendAttr(attrs, flagTransitive, attrLocalPref, binary.BigEndian.AppendUint32(nil, *u.LocalPref))
+		}
+		if len(nlri6) != 0 {
+			if !u.MPNextHop.Is6() || u.MPNextHop.Is4In6() {
+				return nil, errors.New("ipv6 routes need an ipv6 next hop")
+			}
+			nh := u.MPNextHop.As16()
+			v := binary.BigEndian.AppendUint16(nil, IPv6Unicast.AFI)
+			v = append(v, IPv6Unicast.SAFI, 16)
+			v = append(v, nh[:]...)
+			v = append(v, 0)
+			attrs = appendAttr(attrs, flagOptional, attrMPReach, appendPrefixes(v, nlri6))
+		}
+	}
+	if len(withdrawn6) != 0 {
+		v := binary.BigEndian.AppendUint16(nil, IPv6Unicast.AFI)
+		v = append(v, IPv6Unicast.SAFI)
+		attrs = appendAttr(attrs, flagOptional, attrMPUnreach, appendPrefixes(v, withdrawn6))
+	}
+
+	w := appendPrefixes(nil, withdrawn4)
+	body := binary.BigEndian.AppendUint16(nil, uint16(len(w)))
+	body = append(body, w...)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(attrs)))
+	body = append(body, attrs...)
+	body = appendPrefixes(body, nlri4)
+	return encodeMessage(MsgUpdate, body)
+}
+
+func splitFamilies(prefixes []netip.Prefix) (v4, v6 []netip.Prefix) {
+	for _, p := range prefixes {
+		if p.Addr().Is4() {
+			v4 = append(v4, p)
+		} else {
+			v6 = append(v6, p)
+		}
+	}
+	return
+}
+
+func appendAttr(buf []byte, flags, t uint8, value []byte) []byte {
+	if len(value) > 0xFF {
+		buf = append(buf, flags|flagExtendedLen, t)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(value)))
+	} else {
+		buf = append(buf, flags, t, uint8(len(value)))
+	}
+	return append(buf, value...)
+}
+
+func appendPrefixes(buf []byte, prefixes []netip.Prefix) []byte {
+	for _, p := range prefixes {
+		p = p.Masked()
+		buf = append(buf, uint8(p.Bits()))
+		buf = append(buf, p.Addr().AsSlice()[:(p.Bits()+7)/8]...)
+	}
+	return buf
+}
+
+func decodePrefixes(v []byte, is4 bool) ([]netip.Prefix, error) {
+	size := 16
+	if is4 {
+		size = 4
+	}
+	prefixes := make([]netip.Prefix, 0)
+	for len(v) > 0 {
+		bits := int(v[0])
+		n := (bits + 7) / 8
+		if bits > size*8 || len(v) < 1+n {
+			return nil, errors.New("bgp prefix malformed")
+		}
+		var a [16]byte
+		copy(a[:], v[1:1+n])
+		v = v[1+n:]
+		var addr netip.Addr
+		if is4 {
+			addr = netip.AddrFrom4([4]byte(a[:4]))
+		} else {
+			addr = netip.AddrFrom16(a)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, bits).Masked())
+	}
+	return prefixes, nil
+}
+
+func DecodeUpdate(body []byte, as4 bool) (*Update, error) {
+	errMalformed := errors.New("bgp update malformed")
+	if len(body) < 4 {
+		return nil, errMalformed
+	}
+	wlen := int(binary.BigEndian.Uint16(body[0:2]))
+	if len(body) < 4+wlen {
+		return nil, errMalformed
+	}
+	u := &Update{}
+	var err error
+	if u.Withdrawn, err = decodePrefixes(body[2:2+wlen], true); err != nil {
+		return nil, err
+	}
+	rest := body[2+wlen:]
+	alen := int(binary.BigEndian.Uint16(rest[0:2]))
+	if len(rest) < 2+alen {
+		return nil, errMalformed
+	}
+	attrs := rest[2 : 2+alen]
+	if u.NLRI, err = decodePrefixes(rest[2+alen:], true); err != nil {
+		return nil, err
+	}
+
+	for len(attrs) > 0 {
+		if len(attrs) < 3 {
+			return nil, errMalformed
+		}
+		flags, t := attrs[0], attrs[1]
+		var length int
+		if flags&flagExtendedLen != 0 {
+			if len(attrs) < 4 {
+				return nil, errMalformed
+			}
+			length = int(binary.BigEndian.Uint16(attrs[2:4]))
+			attrs = attrs[4:]
+		} else {
+			length = int(attrs[2])
+			attrs = attrs[3:]
+		}
+		if len(attrs) < length {
+			return nil, errMalformed
+		}
+		v := attrs[:length]
+		attrs = attrs[length:]
+		switch t {
+		case attrOrigin:
+			if len(v) != 1 {
+				return nil, errMalformed
+			}
+			u.Origin = v[0]
+		case attrASPath:
+			size := 2
+			if as4 {
+				size = 4
+			}
+			for len(v) > 0 {
+				if len(v) < 2 || len(v) < 2+int(v[1])*size {
+					return nil, errMalformed
+				}
+				count := int(v[1])
+				for i := range count {
+					asn := v[2+i*size : 2+(i+1)*size]
+					if as4 {
+						u.ASPath = append(u.ASPath, binary.BigEndian.Uint32(asn))
+					} else {
+						u.ASPath = append(u.ASPath, uint32(binary.BigEndian.Uint16(asn)))
+					}
+				}
+				v = v[2+count*size:]
+			}
+		case attrNextHop:
+			if len(v) != 4 {
+				return nil, errMalformed
+			}
+			u.NextHop = netip.AddrFrom4([4]byte(v))
+		case attrMED:
+			if len(v) != 4 {
+				return nil, errMalformed
+			}
+			u.MED = new(binary.BigEndian.Uint32(v))
+		case attrLocalPref:
+			if len(v) != 4 {
+				return nil, errMalformed
+			}
+			u.LocalPref = new(binary.BigEndian.Uint32(v))
+		case attrMPReach:
+			if len(v) < 5 {
+				return nil, errMalformed
+			}
+			family := Family{AFI: binary.BigEndian.Uint16(v[0:2]), SAFI: v[2]}
+			nhLen := int(v[3])
+			if len(v) < 5+nhLen {
+				return nil, errMalformed
+			}
+			nh := v[4 : 4+nhLen]
+			nlri := v[5+nhLen:]
+			if family != IPv6Unicast && family != IPv4Unicast {
+				continue
+			}
+			prefixes, err := decodePrefixes(nlri, family == IPv4Unicast)
+			if err != nil {
+				return nil, err
+			}
+			// a link-local next hop may follow the global one, which is all we use
+			switch len(nh) {
+			case 4:
+				u.MPNextHop = netip.AddrFrom4([4]byte(nh))
+			case 16, 32:
+				u.MPNextHop = netip.AddrFrom16([16]byte(nh[:16]))
+			default:
+				return nil, errMalformed
+			}
+			u.NLRI = append(u.NLRI, prefixes...)
+		case attrMPUnreach:
+			if len(v) < 3 {
+				return nil, errMalformed
+			}
+			family := Family{AFI: binary.BigEndian.Uint16(v[0:2]), SAFI: v[2]}
+			if family != IPv6Unicast && family != IPv4Unicast {
+				continue
+			}
+			prefixes, err := decodePrefixes(v[3:], family == IPv4Unicast)
+			if err != nil {
+				return nil, err
+			}
+			u.Withdrawn = append(u.Withdrawn, prefixes...)
+		}
+	}
+	return u, nil
+}
+
+// NextHopFor returns the next hop of an announced prefix
+func (u *Update) NextHopFor(prefix netip.Prefix) netip.Addr {
+	if prefix.Addr().Is4() && u.NextHop.IsValid() {
+		return u.NextHop
+	}
+	return u.MPNextHop
+}
+
+// HasFamily reports whether an open message allows routes of the given family, RFC 4760 section 8
+func (o *Open) HasFamily(f Family) bool {
+	if len(o.Families) == 0 {
+		return f == IPv4Unicast
+	}
+	return slices.Contains(o.Families, f)
+}
@@ -0,0 +1,80 @@
+package bgp
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readBody(t *testing.T, buf []byte, typ uint8) []byte {
+	msg, err := ReadMessage(bytes.NewReader(buf))
+	assert.NoError(t, err)
+	assert.Equal(t, typ, msg.Type)
+	return msg.Body
+}
+
+func TestOpenRoundTrip(t *testing.T) {
+	open := Open{
+		ASN:      4200000001,
+		HoldTime: 90,
+		RouterId: netip.MustParseAddr("10.0.0.1"),
+		Families: []Family{IPv4Unicast, IPv6Unicast},
+		AS4:      true,
+	}
+	buf, err := open.Encode()
+	assert.NoError(t, err)
+	decoded, err := DecodeOpen(readBody(t, buf, MsgOpen))
+	assert.NoError(t, err)
+	assert.Equal(t, open, *decoded)
+	assert.True(t, decoded.HasFamily(IPv6Unicast))
+
+	// a speaker without capabilities only supports IPv4 unicast and two-octet AS numbers
+	legacy := Open{ASN: 65001, HoldTime: 30, RouterId: netip.MustParseAddr("10.0.0.2")}
+	buf, err = legacy.Encode()
+	assert.NoError(t, err)
+	decoded, err = DecodeOpen(readBody(t, buf, MsgOpen))
+	assert.NoError(t, err)
+	assert.Equal(t, legacy, *decoded)
+	assert.True(t, decoded.HasFamily(IPv4Unicast))
+	assert.False(t, decoded.HasFamily(IPv6Unicast))
+}
+
+func TestUpdateRoundTrip(t *testing.T) {
+	for _, as4 := range []bool{true, false} {
+		update := Update{
+			Withdrawn: []netip.Prefix{netip.MustParsePrefix("10.9.0.0/16"), netip.MustParsePrefix("fd00:9::/48")},
+			NLRI:      []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16"), netip.MustParsePrefix("10.2.3.0/24"), netip.MustParsePrefix("fd00:1::/48")},
+			Origin:    OriginIGP,
+			ASPath:    []uint32{65001, 65002},
+			NextHop:   netip.MustParseAddr("192.0.2.1"),
+			MPNextHop: netip.MustParseAddr("2001:db8::1"),
+			LocalPref: new(uint32(100)),
+		}
+		buf, err := update.Encode(as4)
+		assert.NoError(t, err)
+		decoded, err := DecodeUpdate(readBody(t, buf, MsgUpdate), as4)
+		assert.NoError(t, err)
+		assert.Equal(t, update, *decoded)
+		assert.Equal(t, update.NextHop, decoded.NextHopFor(update.NLRI[0]))
+		assert.Equal(t, update.MPNextHop, decoded.NextHopFor(update.NLRI[2]))
+	}
+}
+
+func TestUpdateWithdrawOnly(t *testing.T) {
+	update := Update{Withdrawn: []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")}}
+	buf, err := update.Encode(true)
+	assert.NoError(t, err)
+	decoded, err := DecodeUpdate(readBody(t, buf, MsgUpdate), true)
+	assert.NoError(t, err)
+	assert.Equal(t, update.Withdrawn, decoded.Withdrawn)
+	assert.Empty(t, decoded.NLRI)
+}
+
+func TestReadMessageRejectsBadMarker(t *testing.T) {
+	buf := EncodeKeepalive()
+	buf[0] = 0
+	_, err := ReadMessage(bytes.NewReader(buf))
+	assert.Error(t, err)
+}
@@ -180,11 +180,15 @@ func (*Ny_ProbeOp) isNy_Type() {}
 func (*Ny_AckRetractOp) isNy_Type() {}
 
 type Ny_Update struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	RouterId      string                 `protobuf:"bytes,1,opt,name=RouterId,proto3" json:"RouterId,omitempty"`
-	Prefix        []byte                 `protobuf:"bytes,2,opt,name=Prefix,proto3" json:"Prefix,omitempty"`
-	Seqno         uint32                 `protobuf:"varint,3,opt,name=Seqno,proto3" json:"Seqno,omitempty"`
-	Metric        uint32                 `protobuf:"varint,4,opt,name=Metric,proto3" json:"Metric,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	RouterId string                 `protobuf:"bytes,1,opt,name=RouterId,proto3" json:"RouterId,omitempty"`
+	Prefix   []byte                 `protobuf:"bytes,2,opt,name=Prefix,proto3" json:"Prefix,omitempty"`
+	Seqno    uint32                 `protobuf:"varint,3,opt,name=Seqno,proto3" json:"Seqno,omitempty"`
+	Metric   uint32                 `protobuf:"varint,4,opt,name=Metric,proto3" json:"Metric,omitempty"`
+	// source prefix of a source-specific route (RFC 9079), empty otherwise
+	SrcPrefix []byte `protobuf:"bytes,5,opt,name=SrcPrefix,proto3" json:"SrcPrefix,omitempty"`
+	// origin's signature over (RouterId, Prefix, SrcPrefix, Seqno), set when signed routes are enabled
+	Signature     []byte `protobuf:"bytes,6,opt,name=Signature,proto3" json:"Signature,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -247,9 +251,24 @@ func (x *Ny_Update) GetMetric() uint32 {
 	return 0
 }
 
+func (x *Ny_Update) GetSrcPrefix() []byte {
+	if x != nil {
+		return x.SrcPrefix
+	}
+	return nil
+}
+
+func (x *Ny_Update) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
 type Ny_AckRetract struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Prefix        []byte                 `protobuf:"bytes,1,opt,name=Prefix,proto3" json:"Prefix,omitempty"`
+	SrcPrefix     []byte                 `protobuf:"bytes,2,opt,name=SrcPrefix,proto3" json:"SrcPrefix,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -291,12 +310,20 @@ func (x *Ny_AckRetract) GetPrefix() []byte {
 	return nil
 }
 
+func (x *Ny_AckRetract) GetSrcPrefix() []byte {
+	if x != nil {
+		return x.SrcPrefix
+	}
+	return nil
+}
+
 type Ny_SeqnoRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	RouterId      string                 `protobuf:"bytes,1,opt,name=RouterId,proto3" json:"RouterId,omitempty"`
 	Prefix        []byte                 `protobuf:"bytes,2,opt,name=Prefix,proto3" json:"Prefix,omitempty"`
 	Seqno         uint32                 `protobuf:"varint,3,opt,name=Seqno,proto3" json:"Seqno,omitempty"`
 	HopCount      uint32                 `protobuf:"varint,4,opt,name=HopCount,proto3" json:"HopCount,omitempty"`
+	SrcPrefix     []byte                 `protobuf:"bytes,5,opt,name=SrcPrefix,proto3" json:"SrcPrefix,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -359,10 +386,19 @@ func (x *Ny_SeqnoRequest) GetHopCount() uint32 {
 	return 0
 }
 
+func (x *Ny_SeqnoRequest) GetSrcPrefix() []byte {
+	if x != nil {
+		return x.SrcPrefix
+	}
+	return nil
+}
+
 type Ny_Probe struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Token         uint64                 `protobuf:"varint,1,opt,name=Token,proto3" json:"Token,omitempty"`
 	ResponseToken *uint64                `protobuf:"varint,2,opt,name=ResponseToken,proto3,oneof" json:"ResponseToken,omitempty"`
+	// the configured endpoint of the receiver that a ping was sent to, so the receiver can apply its attributes
+	Endpoint      *string `protobuf:"bytes,3,opt,name=Endpoint,proto3,oneof" json:"Endpoint,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -411,35 +447,48 @@ func (x *Ny_Probe) GetResponseToken() uint64 {
 	return 0
 }
 
+func (x *Ny_Probe) GetEndpoint() string {
+	if x != nil && x.Endpoint != nil {
+		return *x.Endpoint
+	}
+	return ""
+}
+
 var File_protocol_nylon_proto protoreflect.FileDescriptor
 
 const file_protocol_nylon_proto_rawDesc = "" +
 	"\n" +
 	"\x14protocol/nylon.proto\x12\x05proto\"6\n" +
 	"\x0fTransportBundle\x12#\n" +
-	"\aPackets\x18\x01 \x03(\v2\t.proto.NyR\aPackets\"\xc9\x04\n" +
+	"\aPackets\x18\x01 \x03(\v2\t.proto.NyR\aPackets\"\xf2\x05\n" +
 	"\x02Ny\x12,\n" +
 	"\aRouteOp\x18\x01 \x01(\v2\x10.proto.Ny.UpdateH\x00R\aRouteOp\x12@\n" +
 	"\x0eSeqnoRequestOp\x18\x02 \x01(\v2\x16.proto.Ny.SeqnoRequestH\x00R\x0eSeqnoRequestOp\x12+\n" +
 	"\aProbeOp\x18\x03 \x01(\v2\x0f.proto.Ny.ProbeH\x00R\aProbeOp\x12:\n" +
-	"\fAckRetractOp\x18\x04 \x01(\v2\x14.proto.Ny.AckRetractH\x00R\fAckRetractOp\x1aj\n" +
+	"\fAckRetractOp\x18\x04 \x01(\v2\x14.proto.Ny.AckRetractH\x00R\fAckRetractOp\x1a\xa6\x01\n" +
 	"\x06Update\x12\x1a\n" +
 	"\bRouterId\x18\x01 \x01(\tR\bRouterId\x12\x16\n" +
 	"\x06Prefix\x18\x02 \x01(\fR\x06Prefix\x12\x14\n" +
 	"\x05Seqno\x18\x03 \x01(\rR\x05Seqno\x12\x16\n" +
-	"\x06Metric\x18\x04 \x01(\rR\x06Metric\x1a$\n" +
+	"\x06Metric\x18\x04 \x01(\rR\x06Metric\x12\x1c\n" +
+	"\tSrcPrefix\x18\x05 \x01(\fR\tSrcPrefix\x12\x1c\n" +
+	"\tSignature\x18\x06 \x01(\fR\tSignature\x1aB\n" +
 	"\n" +
 	"AckRetract\x12\x16\n" +
-	"\x06Prefix\x18\x01 \x01(\fR\x06Prefix\x1at\n" +
+	"\x06Prefix\x18\x01 \x01(\fR\x06Prefix\x12\x1c\n" +
+	"\tSrcPrefix\x18\x02 \x01(\fR\tSrcPrefix\x1a\x92\x01\n" +
 	"\fSeqnoRequest\x12\x1a\n" +
 	"\bRouterId\x18\x01 \x01(\tR\bRouterId\x12\x16\n" +
 	"\x06Prefix\x18\x02 \x01(\fR\x06Prefix\x12\x14\n" +
 	"\x05Seqno\x18\x03 \x01(\rR\x05Seqno\x12\x1a\n" +
-	"\bHopCount\x18\x04 \x01(\rR\bHopCount\x1aZ\n" +
+	"\bHopCount\x18\x04 \x01(\rR\bHopCount\x12\x1c\n" +
+	"\tSrcPrefix\x18\x05 \x01(\fR\tSrcPrefix\x1a\x88\x01\n" +
 	"\x05Probe\x12\x14\n" +
 	"\x05Token\x18\x01 \x01(\x04R\x05Token\x12)\n" +
-	"\rResponseToken\x18\x02 \x01(\x04H\x00R\rResponseToken\x88\x01\x01B\x10\n" +
-	"\x0e_ResponseTokenB\x06\n" +
+	"\rResponseToken\x18\x02 \x01(\x04H\x00R\rResponseToken\x88\x01\x01\x12\x1f\n" +
+	"\bEndpoint\x18\x03 \x01(\tH\x01R\bEndpoint\x88\x01\x01B\x10\n" +
+	"\x0e_ResponseTokenB\v\n" +
+	"\t_EndpointB\x06\n" +
 	"\x04typeB\vZ\tprotocol/b\x06proto3"
 
 var (
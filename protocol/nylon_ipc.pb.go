@@ -73,6 +73,55 @@ func (ReloadResult) EnumDescriptor() ([]byte, []int) {
 	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{0}
 }
 
+type DrainAction int32
+
+const (
+	DrainAction_DRAIN_STATUS DrainAction = 0
+	DrainAction_DRAIN        DrainAction = 1
+	DrainAction_UNDRAIN      DrainAction = 2
+)
+
+// Enum value maps for DrainAction.
+var (
+	DrainAction_name = map[int32]string{
+		0: "DRAIN_STATUS",
+		1: "DRAIN",
+		2: "UNDRAIN",
+	}
+	DrainAction_value = map[string]int32{
+		"DRAIN_STATUS": 0,
+		"DRAIN":        1,
+		"UNDRAIN":      2,
+	}
+)
+
+func (x DrainAction) Enum() *DrainAction {
+	p := new(DrainAction)
+	*p = x
+	return p
+}
+
+func (x DrainAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DrainAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_protocol_nylon_ipc_proto_enumTypes[1].Descriptor()
+}
+
+func (DrainAction) Type() protoreflect.EnumType {
+	return &file_protocol_nylon_ipc_proto_enumTypes[1]
+}
+
+func (x DrainAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DrainAction.Descriptor instead.
+func (DrainAction) EnumDescriptor() ([]byte, []int) {
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{1}
+}
+
 type EndpointProbeStatus int32
 
 const (
@@ -112,11 +161,11 @@ func (x EndpointProbeStatus) String() string {
 }
 
 func (EndpointProbeStatus) Descriptor() protoreflect.EnumDescriptor {
-	return file_protocol_nylon_ipc_proto_enumTypes[1].Descriptor()
+	return file_protocol_nylon_ipc_proto_enumTypes[2].Descriptor()
 }
 
 func (EndpointProbeStatus) Type() protoreflect.EnumType {
-	return &file_protocol_nylon_ipc_proto_enumTypes[1]
+	return &file_protocol_nylon_ipc_proto_enumTypes[2]
 }
 
 func (x EndpointProbeStatus) Number() protoreflect.EnumNumber {
@@ -125,7 +174,7 @@ func (x EndpointProbeStatus) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use EndpointProbeStatus.Descriptor instead.
 func (EndpointProbeStatus) EnumDescriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{1}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{2}
 }
 
 type StatusRequest struct {
@@ -288,17 +337,122 @@ func (*TraceRequest) Descriptor() ([]byte, []int) {
 	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{3}
 }
 
+type CaptureRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filter        string                 `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`    // capture filter expression, empty to capture every packet
+	Snaplen       uint32                 `protobuf:"varint,2,opt,name=snaplen,proto3" json:"snaplen,omitempty"` // bytes kept of each packet, 0 for the default
+	Rate          uint32                 `protobuf:"varint,3,opt,name=rate,proto3" json:"rate,omitempty"`       // packets captured per second at most, 0 for the default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CaptureRequest) Reset() {
+	*x = CaptureRequest{}
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CaptureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CaptureRequest) ProtoMessage() {}
+
+func (x *CaptureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CaptureRequest.ProtoReflect.Descriptor instead.
+func (*CaptureRequest) Descriptor() ([]byte, []int) {
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CaptureRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *CaptureRequest) GetSnaplen() uint32 {
+	if x != nil {
+		return x.Snaplen
+	}
+	return 0
+}
+
+func (x *CaptureRequest) GetRate() uint32 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
+
+type DrainRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Action        DrainAction            `protobuf:"varint,1,opt,name=action,proto3,enum=proto.DrainAction" json:"action,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainRequest) Reset() {
+	*x = DrainRequest{}
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainRequest) ProtoMessage() {}
+
+func (x *DrainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainRequest.ProtoReflect.Descriptor instead.
+func (*DrainRequest) Descriptor() ([]byte, []int) {
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DrainRequest) GetAction() DrainAction {
+	if x != nil {
+		return x.Action
+	}
+	return DrainAction_DRAIN_STATUS
+}
+
 type Source struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
 	Prefix        string                 `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	SrcPrefix     string                 `protobuf:"bytes,3,opt,name=src_prefix,json=srcPrefix,proto3" json:"src_prefix,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Source) Reset() {
 	*x = Source{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[4]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -310,7 +464,7 @@ func (x *Source) String() string {
 func (*Source) ProtoMessage() {}
 
 func (x *Source) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[4]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -323,7 +477,7 @@ func (x *Source) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Source.ProtoReflect.Descriptor instead.
 func (*Source) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{4}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *Source) GetNodeId() string {
@@ -340,6 +494,13 @@ func (x *Source) GetPrefix() string {
 	return ""
 }
 
+func (x *Source) GetSrcPrefix() string {
+	if x != nil {
+		return x.SrcPrefix
+	}
+	return ""
+}
+
 type FD struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Seqno         uint32                 `protobuf:"varint,1,opt,name=seqno,proto3" json:"seqno,omitempty"`
@@ -350,7 +511,7 @@ type FD struct {
 
 func (x *FD) Reset() {
 	*x = FD{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[5]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -362,7 +523,7 @@ func (x *FD) String() string {
 func (*FD) ProtoMessage() {}
 
 func (x *FD) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[5]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -375,7 +536,7 @@ func (x *FD) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FD.ProtoReflect.Descriptor instead.
 func (*FD) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{5}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *FD) GetSeqno() uint32 {
@@ -402,7 +563,7 @@ type PubRoute struct {
 
 func (x *PubRoute) Reset() {
 	*x = PubRoute{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[6]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -414,7 +575,7 @@ func (x *PubRoute) String() string {
 func (*PubRoute) ProtoMessage() {}
 
 func (x *PubRoute) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[6]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -427,7 +588,7 @@ func (x *PubRoute) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PubRoute.ProtoReflect.Descriptor instead.
 func (*PubRoute) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{6}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *PubRoute) GetSource() *Source {
@@ -454,7 +615,7 @@ type NeighRoute struct {
 
 func (x *NeighRoute) Reset() {
 	*x = NeighRoute{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[7]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -466,7 +627,7 @@ func (x *NeighRoute) String() string {
 func (*NeighRoute) ProtoMessage() {}
 
 func (x *NeighRoute) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[7]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -479,7 +640,7 @@ func (x *NeighRoute) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NeighRoute.ProtoReflect.Descriptor instead.
 func (*NeighRoute) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{7}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *NeighRoute) GetPubRoute() *PubRoute {
@@ -502,13 +663,14 @@ type SelRoute struct {
 	Nh            string                 `protobuf:"bytes,2,opt,name=nh,proto3" json:"nh,omitempty"`
 	ExpireAtUnix  int64                  `protobuf:"varint,3,opt,name=expire_at_unix,json=expireAtUnix,proto3" json:"expire_at_unix,omitempty"`
 	RetractedBy   []string               `protobuf:"bytes,4,rep,name=retracted_by,json=retractedBy,proto3" json:"retracted_by,omitempty"`
+	Multipath     []string               `protobuf:"bytes,5,rep,name=multipath,proto3" json:"multipath,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SelRoute) Reset() {
 	*x = SelRoute{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[8]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -520,7 +682,7 @@ func (x *SelRoute) String() string {
 func (*SelRoute) ProtoMessage() {}
 
 func (x *SelRoute) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[8]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -533,7 +695,7 @@ func (x *SelRoute) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SelRoute.ProtoReflect.Descriptor instead.
 func (*SelRoute) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{8}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *SelRoute) GetPubRoute() *PubRoute {
@@ -564,6 +726,13 @@ func (x *SelRoute) GetRetractedBy() []string {
 	return nil
 }
 
+func (x *SelRoute) GetMultipath() []string {
+	if x != nil {
+		return x.Multipath
+	}
+	return nil
+}
+
 type Advertisement struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
@@ -571,13 +740,14 @@ type Advertisement struct {
 	Metric        uint32                 `protobuf:"varint,3,opt,name=metric,proto3" json:"metric,omitempty"`
 	ExpiryUnix    int64                  `protobuf:"varint,4,opt,name=expiry_unix,json=expiryUnix,proto3" json:"expiry_unix,omitempty"`
 	PassiveHold   bool                   `protobuf:"varint,5,opt,name=passive_hold,json=passiveHold,proto3" json:"passive_hold,omitempty"`
+	SrcPrefix     string                 `protobuf:"bytes,6,opt,name=src_prefix,json=srcPrefix,proto3" json:"src_prefix,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Advertisement) Reset() {
 	*x = Advertisement{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[9]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -589,7 +759,7 @@ func (x *Advertisement) String() string {
 func (*Advertisement) ProtoMessage() {}
 
 func (x *Advertisement) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[9]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -602,7 +772,7 @@ func (x *Advertisement) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Advertisement.ProtoReflect.Descriptor instead.
 func (*Advertisement) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{9}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *Advertisement) GetNodeId() string {
@@ -640,6 +810,13 @@ func (x *Advertisement) GetPassiveHold() bool {
 	return false
 }
 
+func (x *Advertisement) GetSrcPrefix() string {
+	if x != nil {
+		return x.SrcPrefix
+	}
+	return ""
+}
+
 type EndpointInfo struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
 	Address         string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
@@ -649,13 +826,26 @@ type EndpointInfo struct {
 	Metric          uint32                 `protobuf:"varint,5,opt,name=metric,proto3" json:"metric,omitempty"`
 	FilteredRttNs   int64                  `protobuf:"varint,7,opt,name=filtered_rtt_ns,json=filteredRttNs,proto3" json:"filtered_rtt_ns,omitempty"`
 	StabilizedRttNs int64                  `protobuf:"varint,8,opt,name=stabilized_rtt_ns,json=stabilizedRttNs,proto3" json:"stabilized_rtt_ns,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	JitterNs        int64                  `protobuf:"varint,9,opt,name=jitter_ns,json=jitterNs,proto3" json:"jitter_ns,omitempty"`
+	LossRatio       float64                `protobuf:"fixed64,10,opt,name=loss_ratio,json=lossRatio,proto3" json:"loss_ratio,omitempty"`
+	LostProbes      uint64                 `protobuf:"varint,11,opt,name=lost_probes,json=lostProbes,proto3" json:"lost_probes,omitempty"`
+	LateProbes      uint64                 `protobuf:"varint,12,opt,name=late_probes,json=lateProbes,proto3" json:"late_probes,omitempty"`
+	Backup          bool                   `protobuf:"varint,13,opt,name=backup,proto3" json:"backup,omitempty"`
+	Metered         bool                   `protobuf:"varint,14,opt,name=metered,proto3" json:"metered,omitempty"`
+	// data packets sent and received through the endpoint
+	TxPackets uint64 `protobuf:"varint,15,opt,name=tx_packets,json=txPackets,proto3" json:"tx_packets,omitempty"`
+	TxBytes   uint64 `protobuf:"varint,16,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	RxPackets uint64 `protobuf:"varint,17,opt,name=rx_packets,json=rxPackets,proto3" json:"rx_packets,omitempty"`
+	RxBytes   uint64 `protobuf:"varint,18,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`
+	// fraction of the traffic of a bonded link sent through the endpoint
+	BondShare     float64 `protobuf:"fixed64,19,opt,name=bond_share,json=bondShare,proto3" json:"bond_share,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EndpointInfo) Reset() {
 	*x = EndpointInfo{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[10]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -667,7 +857,7 @@ func (x *EndpointInfo) String() string {
 func (*EndpointInfo) ProtoMessage() {}
 
 func (x *EndpointInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[10]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -680,7 +870,7 @@ func (x *EndpointInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EndpointInfo.ProtoReflect.Descriptor instead.
 func (*EndpointInfo) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{10}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *EndpointInfo) GetAddress() string {
@@ -732,6 +922,83 @@ func (x *EndpointInfo) GetStabilizedRttNs() int64 {
 	return 0
 }
 
+func (x *EndpointInfo) GetJitterNs() int64 {
+	if x != nil {
+		return x.JitterNs
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetLossRatio() float64 {
+	if x != nil {
+		return x.LossRatio
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetLostProbes() uint64 {
+	if x != nil {
+		return x.LostProbes
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetLateProbes() uint64 {
+	if x != nil {
+		return x.LateProbes
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetBackup() bool {
+	if x != nil {
+		return x.Backup
+	}
+	return false
+}
+
+func (x *EndpointInfo) GetMetered() bool {
+	if x != nil {
+		return x.Metered
+	}
+	return false
+}
+
+func (x *EndpointInfo) GetTxPackets() uint64 {
+	if x != nil {
+		return x.TxPackets
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetTxBytes() uint64 {
+	if x != nil {
+		return x.TxBytes
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetRxPackets() uint64 {
+	if x != nil {
+		return x.RxPackets
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetRxBytes() uint64 {
+	if x != nil {
+		return x.RxBytes
+	}
+	return 0
+}
+
+func (x *EndpointInfo) GetBondShare() float64 {
+	if x != nil {
+		return x.BondShare
+	}
+	return 0
+}
+
 type WireGuardPeerStats struct {
 	state                       protoimpl.MessageState `protogen:"open.v1"`
 	LatestHandshakeUnix         int64                  `protobuf:"varint,1,opt,name=latest_handshake_unix,json=latestHandshakeUnix,proto3" json:"latest_handshake_unix,omitempty"`
@@ -745,7 +1012,7 @@ type WireGuardPeerStats struct {
 
 func (x *WireGuardPeerStats) Reset() {
 	*x = WireGuardPeerStats{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[11]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -757,7 +1024,7 @@ func (x *WireGuardPeerStats) String() string {
 func (*WireGuardPeerStats) ProtoMessage() {}
 
 func (x *WireGuardPeerStats) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[11]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -770,7 +1037,7 @@ func (x *WireGuardPeerStats) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WireGuardPeerStats.ProtoReflect.Descriptor instead.
 func (*WireGuardPeerStats) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{11}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *WireGuardPeerStats) GetLatestHandshakeUnix() int64 {
@@ -809,21 +1076,24 @@ func (x *WireGuardPeerStats) GetEndpoint() string {
 }
 
 type NeighbourInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	PeerId        string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
-	PublicKey     string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
-	PassiveClient bool                   `protobuf:"varint,3,opt,name=passive_client,json=passiveClient,proto3" json:"passive_client,omitempty"`
-	Endpoints     []*EndpointInfo        `protobuf:"bytes,4,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
-	Routes        []*NeighRoute          `protobuf:"bytes,5,rep,name=routes,proto3" json:"routes,omitempty"`
-	Advertised    []*Advertisement       `protobuf:"bytes,6,rep,name=advertised,proto3" json:"advertised,omitempty"`
-	Wireguard     *WireGuardPeerStats    `protobuf:"bytes,7,opt,name=wireguard,proto3" json:"wireguard,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	PeerId              string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	PublicKey           string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	PassiveClient       bool                   `protobuf:"varint,3,opt,name=passive_client,json=passiveClient,proto3" json:"passive_client,omitempty"`
+	Endpoints           []*EndpointInfo        `protobuf:"bytes,4,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
+	Routes              []*NeighRoute          `protobuf:"bytes,5,rep,name=routes,proto3" json:"routes,omitempty"`
+	Advertised          []*Advertisement       `protobuf:"bytes,6,rep,name=advertised,proto3" json:"advertised,omitempty"`
+	Wireguard           *WireGuardPeerStats    `protobuf:"bytes,7,opt,name=wireguard,proto3" json:"wireguard,omitempty"`
+	UnauthorizedUpdates uint64                 `protobuf:"varint,8,opt,name=unauthorized_updates,json=unauthorizedUpdates,proto3" json:"unauthorized_updates,omitempty"`
+	Bond                string                 `protobuf:"bytes,9,opt,name=bond,proto3" json:"bond,omitempty"`                                             // bond mode of the link, empty if it is not bonded
+	SpoofedPackets      uint64                 `protobuf:"varint,10,opt,name=spoofed_packets,json=spoofedPackets,proto3" json:"spoofed_packets,omitempty"` // data packets dropped because the peer may not send from their source address
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *NeighbourInfo) Reset() {
 	*x = NeighbourInfo{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[12]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -835,7 +1105,7 @@ func (x *NeighbourInfo) String() string {
 func (*NeighbourInfo) ProtoMessage() {}
 
 func (x *NeighbourInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[12]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -848,7 +1118,7 @@ func (x *NeighbourInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NeighbourInfo.ProtoReflect.Descriptor instead.
 func (*NeighbourInfo) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{12}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *NeighbourInfo) GetPeerId() string {
@@ -900,18 +1170,41 @@ func (x *NeighbourInfo) GetWireguard() *WireGuardPeerStats {
 	return nil
 }
 
+func (x *NeighbourInfo) GetUnauthorizedUpdates() uint64 {
+	if x != nil {
+		return x.UnauthorizedUpdates
+	}
+	return 0
+}
+
+func (x *NeighbourInfo) GetBond() string {
+	if x != nil {
+		return x.Bond
+	}
+	return ""
+}
+
+func (x *NeighbourInfo) GetSpoofedPackets() uint64 {
+	if x != nil {
+		return x.SpoofedPackets
+	}
+	return 0
+}
+
 type RouteTableEntry struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
 	Nh            string                 `protobuf:"bytes,2,opt,name=nh,proto3" json:"nh,omitempty"`
 	Blackhole     bool                   `protobuf:"varint,3,opt,name=blackhole,proto3" json:"blackhole,omitempty"`
+	Multipath     []string               `protobuf:"bytes,4,rep,name=multipath,proto3" json:"multipath,omitempty"`
+	SrcPrefix     string                 `protobuf:"bytes,5,opt,name=src_prefix,json=srcPrefix,proto3" json:"src_prefix,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *RouteTableEntry) Reset() {
 	*x = RouteTableEntry{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[13]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -923,7 +1216,7 @@ func (x *RouteTableEntry) String() string {
 func (*RouteTableEntry) ProtoMessage() {}
 
 func (x *RouteTableEntry) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[13]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -936,7 +1229,7 @@ func (x *RouteTableEntry) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RouteTableEntry.ProtoReflect.Descriptor instead.
 func (*RouteTableEntry) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{13}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *RouteTableEntry) GetPrefix() string {
@@ -960,6 +1253,20 @@ func (x *RouteTableEntry) GetBlackhole() bool {
 	return false
 }
 
+func (x *RouteTableEntry) GetMultipath() []string {
+	if x != nil {
+		return x.Multipath
+	}
+	return nil
+}
+
+func (x *RouteTableEntry) GetSrcPrefix() string {
+	if x != nil {
+		return x.SrcPrefix
+	}
+	return ""
+}
+
 type RouteTables struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Selected      []*SelRoute            `protobuf:"bytes,1,rep,name=selected,proto3" json:"selected,omitempty"`
@@ -971,7 +1278,7 @@ type RouteTables struct {
 
 func (x *RouteTables) Reset() {
 	*x = RouteTables{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[14]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -983,7 +1290,7 @@ func (x *RouteTables) String() string {
 func (*RouteTables) ProtoMessage() {}
 
 func (x *RouteTables) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[14]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -996,7 +1303,7 @@ func (x *RouteTables) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RouteTables.ProtoReflect.Descriptor instead.
 func (*RouteTables) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{14}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *RouteTables) GetSelected() []*SelRoute {
@@ -1024,13 +1331,14 @@ type SeqnoEntry struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
 	Seqno         uint32                 `protobuf:"varint,2,opt,name=seqno,proto3" json:"seqno,omitempty"`
+	SrcPrefix     string                 `protobuf:"bytes,3,opt,name=src_prefix,json=srcPrefix,proto3" json:"src_prefix,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SeqnoEntry) Reset() {
 	*x = SeqnoEntry{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[15]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1042,7 +1350,7 @@ func (x *SeqnoEntry) String() string {
 func (*SeqnoEntry) ProtoMessage() {}
 
 func (x *SeqnoEntry) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[15]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1055,7 +1363,7 @@ func (x *SeqnoEntry) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SeqnoEntry.ProtoReflect.Descriptor instead.
 func (*SeqnoEntry) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{15}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *SeqnoEntry) GetPrefix() string {
@@ -1072,6 +1380,13 @@ func (x *SeqnoEntry) GetSeqno() uint32 {
 	return 0
 }
 
+func (x *SeqnoEntry) GetSrcPrefix() string {
+	if x != nil {
+		return x.SrcPrefix
+	}
+	return ""
+}
+
 type FeasibilityDistance struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Source        *Source                `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
@@ -1082,7 +1397,7 @@ type FeasibilityDistance struct {
 
 func (x *FeasibilityDistance) Reset() {
 	*x = FeasibilityDistance{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[16]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1094,7 +1409,7 @@ func (x *FeasibilityDistance) String() string {
 func (*FeasibilityDistance) ProtoMessage() {}
 
 func (x *FeasibilityDistance) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[16]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1107,7 +1422,7 @@ func (x *FeasibilityDistance) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FeasibilityDistance.ProtoReflect.Descriptor instead.
 func (*FeasibilityDistance) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{16}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *FeasibilityDistance) GetSource() *Source {
@@ -1124,21 +1439,252 @@ func (x *FeasibilityDistance) GetFd() *FD {
 	return nil
 }
 
+type DampenedSource struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        *Source                `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Penalty       float64                `protobuf:"fixed64,2,opt,name=penalty,proto3" json:"penalty,omitempty"`
+	Suppressed    bool                   `protobuf:"varint,3,opt,name=suppressed,proto3" json:"suppressed,omitempty"`
+	ReuseInMs     int64                  `protobuf:"varint,4,opt,name=reuse_in_ms,json=reuseInMs,proto3" json:"reuse_in_ms,omitempty"` // time until a suppressed source is used again if it stays stable
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DampenedSource) Reset() {
+	*x = DampenedSource{}
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DampenedSource) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DampenedSource) ProtoMessage() {}
+
+func (x *DampenedSource) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DampenedSource.ProtoReflect.Descriptor instead.
+func (*DampenedSource) Descriptor() ([]byte, []int) {
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DampenedSource) GetSource() *Source {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *DampenedSource) GetPenalty() float64 {
+	if x != nil {
+		return x.Penalty
+	}
+	return 0
+}
+
+func (x *DampenedSource) GetSuppressed() bool {
+	if x != nil {
+		return x.Suppressed
+	}
+	return false
+}
+
+func (x *DampenedSource) GetReuseInMs() int64 {
+	if x != nil {
+		return x.ReuseInMs
+	}
+	return 0
+}
+
+type ACLRuleStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // the default action is named "default"
+	Action        string                 `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	Hits          uint64                 `protobuf:"varint,3,opt,name=hits,proto3" json:"hits,omitempty"`   // packets matched by the rule
+	Drops         uint64                 `protobuf:"varint,4,opt,name=drops,proto3" json:"drops,omitempty"` // packets dropped by the rule
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ACLRuleStats) Reset() {
+	*x = ACLRuleStats{}
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ACLRuleStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ACLRuleStats) ProtoMessage() {}
+
+func (x *ACLRuleStats) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ACLRuleStats.ProtoReflect.Descriptor instead.
+func (*ACLRuleStats) Descriptor() ([]byte, []int) {
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ACLRuleStats) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ACLRuleStats) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *ACLRuleStats) GetHits() uint64 {
+	if x != nil {
+		return x.Hits
+	}
+	return 0
+}
+
+func (x *ACLRuleStats) GetDrops() uint64 {
+	if x != nil {
+		return x.Drops
+	}
+	return 0
+}
+
+type QoSClassStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // packets that match no class are counted as "default"
+	Priority      string                 `protobuf:"bytes,2,opt,name=priority,proto3" json:"priority,omitempty"`
+	RateBps       uint64                 `protobuf:"varint,3,opt,name=rate_bps,json=rateBps,proto3" json:"rate_bps,omitempty"` // rate limit of the class to each peer in bits per second, 0 if unlimited
+	Packets       uint64                 `protobuf:"varint,4,opt,name=packets,proto3" json:"packets,omitempty"`                // packets sent in the class
+	Bytes         uint64                 `protobuf:"varint,5,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Drops         uint64                 `protobuf:"varint,6,opt,name=drops,proto3" json:"drops,omitempty"`   // packets dropped above the rate limit
+	Queued        uint64                 `protobuf:"varint,7,opt,name=queued,proto3" json:"queued,omitempty"` // packets staged to peers, including the copies of duplicated packets
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QoSClassStats) Reset() {
+	*x = QoSClassStats{}
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QoSClassStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QoSClassStats) ProtoMessage() {}
+
+func (x *QoSClassStats) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QoSClassStats.ProtoReflect.Descriptor instead.
+func (*QoSClassStats) Descriptor() ([]byte, []int) {
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *QoSClassStats) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *QoSClassStats) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *QoSClassStats) GetRateBps() uint64 {
+	if x != nil {
+		return x.RateBps
+	}
+	return 0
+}
+
+func (x *QoSClassStats) GetPackets() uint64 {
+	if x != nil {
+		return x.Packets
+	}
+	return 0
+}
+
+func (x *QoSClassStats) GetBytes() uint64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *QoSClassStats) GetDrops() uint64 {
+	if x != nil {
+		return x.Drops
+	}
+	return 0
+}
+
+func (x *QoSClassStats) GetQueued() uint64 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
 type NodeStats struct {
-	state                 protoimpl.MessageState `protogen:"open.v1"`
-	NeighbourCount        int32                  `protobuf:"varint,1,opt,name=neighbour_count,json=neighbourCount,proto3" json:"neighbour_count,omitempty"`
-	ActiveEndpointCount   int32                  `protobuf:"varint,2,opt,name=active_endpoint_count,json=activeEndpointCount,proto3" json:"active_endpoint_count,omitempty"`
-	SelectedRouteCount    int32                  `protobuf:"varint,3,opt,name=selected_route_count,json=selectedRouteCount,proto3" json:"selected_route_count,omitempty"`
-	AdvertisedPrefixCount int32                  `protobuf:"varint,4,opt,name=advertised_prefix_count,json=advertisedPrefixCount,proto3" json:"advertised_prefix_count,omitempty"`
-	TxBytes               uint64                 `protobuf:"varint,5,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
-	RxBytes               uint64                 `protobuf:"varint,6,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`
-	unknownFields         protoimpl.UnknownFields
-	sizeCache             protoimpl.SizeCache
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	NeighbourCount         int32                  `protobuf:"varint,1,opt,name=neighbour_count,json=neighbourCount,proto3" json:"neighbour_count,omitempty"`
+	ActiveEndpointCount    int32                  `protobuf:"varint,2,opt,name=active_endpoint_count,json=activeEndpointCount,proto3" json:"active_endpoint_count,omitempty"`
+	SelectedRouteCount     int32                  `protobuf:"varint,3,opt,name=selected_route_count,json=selectedRouteCount,proto3" json:"selected_route_count,omitempty"`
+	AdvertisedPrefixCount  int32                  `protobuf:"varint,4,opt,name=advertised_prefix_count,json=advertisedPrefixCount,proto3" json:"advertised_prefix_count,omitempty"`
+	TxBytes                uint64                 `protobuf:"varint,5,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	RxBytes                uint64                 `protobuf:"varint,6,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`
+	DuplicatesSent         uint64                 `protobuf:"varint,7,opt,name=duplicates_sent,json=duplicatesSent,proto3" json:"duplicates_sent,omitempty"`
+	DuplicatesDropped      uint64                 `protobuf:"varint,8,opt,name=duplicates_dropped,json=duplicatesDropped,proto3" json:"duplicates_dropped,omitempty"`
+	PortForwardConnections uint64                 `protobuf:"varint,9,opt,name=port_forward_connections,json=portForwardConnections,proto3" json:"port_forward_connections,omitempty"` // connections tracked through the port forwards of this node
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
 }
 
 func (x *NodeStats) Reset() {
 	*x = NodeStats{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[17]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1150,7 +1696,7 @@ func (x *NodeStats) String() string {
 func (*NodeStats) ProtoMessage() {}
 
 func (x *NodeStats) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[17]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1163,7 +1709,7 @@ func (x *NodeStats) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NodeStats.ProtoReflect.Descriptor instead.
 func (*NodeStats) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{17}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *NodeStats) GetNeighbourCount() int32 {
@@ -1208,6 +1754,27 @@ func (x *NodeStats) GetRxBytes() uint64 {
 	return 0
 }
 
+func (x *NodeStats) GetDuplicatesSent() uint64 {
+	if x != nil {
+		return x.DuplicatesSent
+	}
+	return 0
+}
+
+func (x *NodeStats) GetDuplicatesDropped() uint64 {
+	if x != nil {
+		return x.DuplicatesDropped
+	}
+	return 0
+}
+
+func (x *NodeStats) GetPortForwardConnections() uint64 {
+	if x != nil {
+		return x.PortForwardConnections
+	}
+	return 0
+}
+
 type NodeStatus struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
 	NodeId          string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
@@ -1219,13 +1786,14 @@ type NodeStatus struct {
 	Advertised      []*Advertisement       `protobuf:"bytes,7,rep,name=advertised,proto3" json:"advertised,omitempty"`
 	Seqnos          []*SeqnoEntry          `protobuf:"bytes,8,rep,name=seqnos,proto3" json:"seqnos,omitempty"`
 	Stats           *NodeStats             `protobuf:"bytes,9,opt,name=stats,proto3" json:"stats,omitempty"`
+	Draining        bool                   `protobuf:"varint,10,opt,name=draining,proto3" json:"draining,omitempty"`
 	unknownFields   protoimpl.UnknownFields
 	sizeCache       protoimpl.SizeCache
 }
 
 func (x *NodeStatus) Reset() {
 	*x = NodeStatus{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[18]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1237,7 +1805,7 @@ func (x *NodeStatus) String() string {
 func (*NodeStatus) ProtoMessage() {}
 
 func (x *NodeStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[18]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1250,7 +1818,7 @@ func (x *NodeStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NodeStatus.ProtoReflect.Descriptor instead.
 func (*NodeStatus) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{18}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *NodeStatus) GetNodeId() string {
@@ -1316,19 +1884,29 @@ func (x *NodeStatus) GetStats() *NodeStats {
 	return nil
 }
 
+func (x *NodeStatus) GetDraining() bool {
+	if x != nil {
+		return x.Draining
+	}
+	return false
+}
+
 type StatusResponse struct {
 	state                protoimpl.MessageState `protogen:"open.v1"`
 	Node                 *NodeStatus            `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
 	Neighbours           []*NeighbourInfo       `protobuf:"bytes,2,rep,name=neighbours,proto3" json:"neighbours,omitempty"`
 	Routes               *RouteTables           `protobuf:"bytes,3,opt,name=routes,proto3" json:"routes,omitempty"`
 	FeasibilityDistances []*FeasibilityDistance `protobuf:"bytes,4,rep,name=feasibility_distances,json=feasibilityDistances,proto3" json:"feasibility_distances,omitempty"`
+	DampenedSources      []*DampenedSource      `protobuf:"bytes,5,rep,name=dampened_sources,json=dampenedSources,proto3" json:"dampened_sources,omitempty"`
+	AclRules             []*ACLRuleStats        `protobuf:"bytes,6,rep,name=acl_rules,json=aclRules,proto3" json:"acl_rules,omitempty"`
+	QosClasses           []*QoSClassStats       `protobuf:"bytes,7,rep,name=qos_classes,json=qosClasses,proto3" json:"qos_classes,omitempty"`
 	unknownFields        protoimpl.UnknownFields
 	sizeCache            protoimpl.SizeCache
 }
 
 func (x *StatusResponse) Reset() {
 	*x = StatusResponse{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[19]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1340,7 +1918,7 @@ func (x *StatusResponse) String() string {
 func (*StatusResponse) ProtoMessage() {}
 
 func (x *StatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[19]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1353,7 +1931,7 @@ func (x *StatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
 func (*StatusResponse) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{19}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *StatusResponse) GetNode() *NodeStatus {
@@ -1384,6 +1962,27 @@ func (x *StatusResponse) GetFeasibilityDistances() []*FeasibilityDistance {
 	return nil
 }
 
+func (x *StatusResponse) GetDampenedSources() []*DampenedSource {
+	if x != nil {
+		return x.DampenedSources
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetAclRules() []*ACLRuleStats {
+	if x != nil {
+		return x.AclRules
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetQosClasses() []*QoSClassStats {
+	if x != nil {
+		return x.QosClasses
+	}
+	return nil
+}
+
 type EndpointProbeResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
@@ -1396,7 +1995,7 @@ type EndpointProbeResult struct {
 
 func (x *EndpointProbeResult) Reset() {
 	*x = EndpointProbeResult{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[20]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1408,7 +2007,7 @@ func (x *EndpointProbeResult) String() string {
 func (*EndpointProbeResult) ProtoMessage() {}
 
 func (x *EndpointProbeResult) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[20]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1421,7 +2020,7 @@ func (x *EndpointProbeResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EndpointProbeResult.ProtoReflect.Descriptor instead.
 func (*EndpointProbeResult) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{20}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *EndpointProbeResult) GetAddress() string {
@@ -1461,7 +2060,7 @@ type ProbeResponse struct {
 
 func (x *ProbeResponse) Reset() {
 	*x = ProbeResponse{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[21]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1473,7 +2072,7 @@ func (x *ProbeResponse) String() string {
 func (*ProbeResponse) ProtoMessage() {}
 
 func (x *ProbeResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[21]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1486,7 +2085,7 @@ func (x *ProbeResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ProbeResponse.ProtoReflect.Descriptor instead.
 func (*ProbeResponse) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{21}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *ProbeResponse) GetResults() []*EndpointProbeResult {
@@ -1506,7 +2105,7 @@ type ReloadResponse struct {
 
 func (x *ReloadResponse) Reset() {
 	*x = ReloadResponse{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[22]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1518,7 +2117,7 @@ func (x *ReloadResponse) String() string {
 func (*ReloadResponse) ProtoMessage() {}
 
 func (x *ReloadResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[22]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1531,7 +2130,7 @@ func (x *ReloadResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReloadResponse.ProtoReflect.Descriptor instead.
 func (*ReloadResponse) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{22}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *ReloadResponse) GetResult() ReloadResult {
@@ -1548,6 +2147,82 @@ func (x *ReloadResponse) GetMessage() string {
 	return ""
 }
 
+type DrainResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Draining       bool                   `protobuf:"varint,1,opt,name=draining,proto3" json:"draining,omitempty"`
+	Drained        bool                   `protobuf:"varint,2,opt,name=drained,proto3" json:"drained,omitempty"`             // no transit traffic was forwarded for the quiet period
+	IdleMs         int64                  `protobuf:"varint,3,opt,name=idle_ms,json=idleMs,proto3" json:"idle_ms,omitempty"` // time since transit traffic was last forwarded, -1 if never
+	TransitPackets uint64                 `protobuf:"varint,4,opt,name=transit_packets,json=transitPackets,proto3" json:"transit_packets,omitempty"`
+	Unobservable   bool                   `protobuf:"varint,5,opt,name=unobservable,proto3" json:"unobservable,omitempty"` // transit traffic is forwarded by the system routing table, and cannot be observed
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DrainResponse) Reset() {
+	*x = DrainResponse{}
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainResponse) ProtoMessage() {}
+
+func (x *DrainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainResponse.ProtoReflect.Descriptor instead.
+func (*DrainResponse) Descriptor() ([]byte, []int) {
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *DrainResponse) GetDraining() bool {
+	if x != nil {
+		return x.Draining
+	}
+	return false
+}
+
+func (x *DrainResponse) GetDrained() bool {
+	if x != nil {
+		return x.Drained
+	}
+	return false
+}
+
+func (x *DrainResponse) GetIdleMs() int64 {
+	if x != nil {
+		return x.IdleMs
+	}
+	return 0
+}
+
+func (x *DrainResponse) GetTransitPackets() uint64 {
+	if x != nil {
+		return x.TransitPackets
+	}
+	return 0
+}
+
+func (x *DrainResponse) GetUnobservable() bool {
+	if x != nil {
+		return x.Unobservable
+	}
+	return false
+}
+
 type TraceEvent struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Line          string                 `protobuf:"bytes,1,opt,name=line,proto3" json:"line,omitempty"`
@@ -1557,7 +2232,7 @@ type TraceEvent struct {
 
 func (x *TraceEvent) Reset() {
 	*x = TraceEvent{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[23]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1569,7 +2244,7 @@ func (x *TraceEvent) String() string {
 func (*TraceEvent) ProtoMessage() {}
 
 func (x *TraceEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[23]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1582,7 +2257,7 @@ func (x *TraceEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TraceEvent.ProtoReflect.Descriptor instead.
 func (*TraceEvent) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{23}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{29}
 }
 
 func (x *TraceEvent) GetLine() string {
@@ -1592,6 +2267,106 @@ func (x *TraceEvent) GetLine() string {
 	return ""
 }
 
+type CapturedPacket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TimestampNs   int64                  `protobuf:"varint,1,opt,name=timestamp_ns,json=timestampNs,proto3" json:"timestamp_ns,omitempty"` // unix time the packet passed the traffic control filters
+	Incoming      bool                   `protobuf:"varint,2,opt,name=incoming,proto3" json:"incoming,omitempty"`                          // received from a peer, otherwise sent by this host
+	FromPeer      string                 `protobuf:"bytes,3,opt,name=from_peer,json=fromPeer,proto3" json:"from_peer,omitempty"`
+	ToPeer        string                 `protobuf:"bytes,4,opt,name=to_peer,json=toPeer,proto3" json:"to_peer,omitempty"`
+	Action        string                 `protobuf:"bytes,5,opt,name=action,proto3" json:"action,omitempty"`  // forward, bounce or drop
+	Length        uint32                 `protobuf:"varint,6,opt,name=length,proto3" json:"length,omitempty"` // length of the packet before it was truncated to the snaplen
+	Data          []byte                 `protobuf:"bytes,7,opt,name=data,proto3" json:"data,omitempty"`
+	Skipped       uint64                 `protobuf:"varint,8,opt,name=skipped,proto3" json:"skipped,omitempty"` // matching packets that were not captured since the previous one, due to the rate limit or a slow client
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapturedPacket) Reset() {
+	*x = CapturedPacket{}
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapturedPacket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapturedPacket) ProtoMessage() {}
+
+func (x *CapturedPacket) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapturedPacket.ProtoReflect.Descriptor instead.
+func (*CapturedPacket) Descriptor() ([]byte, []int) {
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *CapturedPacket) GetTimestampNs() int64 {
+	if x != nil {
+		return x.TimestampNs
+	}
+	return 0
+}
+
+func (x *CapturedPacket) GetIncoming() bool {
+	if x != nil {
+		return x.Incoming
+	}
+	return false
+}
+
+func (x *CapturedPacket) GetFromPeer() string {
+	if x != nil {
+		return x.FromPeer
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetToPeer() string {
+	if x != nil {
+		return x.ToPeer
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetLength() uint32 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *CapturedPacket) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *CapturedPacket) GetSkipped() uint64 {
+	if x != nil {
+		return x.Skipped
+	}
+	return 0
+}
+
 type IpcRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types that are valid to be assigned to Request:
@@ -1600,6 +2375,8 @@ type IpcRequest struct {
 	//	*IpcRequest_Probe
 	//	*IpcRequest_Reload
 	//	*IpcRequest_Trace
+	//	*IpcRequest_Drain
+	//	*IpcRequest_Capture
 	Request       isIpcRequest_Request `protobuf_oneof:"request"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -1607,7 +2384,7 @@ type IpcRequest struct {
 
 func (x *IpcRequest) Reset() {
 	*x = IpcRequest{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[24]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1619,7 +2396,7 @@ func (x *IpcRequest) String() string {
 func (*IpcRequest) ProtoMessage() {}
 
 func (x *IpcRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[24]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1632,7 +2409,7 @@ func (x *IpcRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IpcRequest.ProtoReflect.Descriptor instead.
 func (*IpcRequest) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{24}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{31}
 }
 
 func (x *IpcRequest) GetRequest() isIpcRequest_Request {
@@ -1678,6 +2455,24 @@ func (x *IpcRequest) GetTrace() *TraceRequest {
 	return nil
 }
 
+func (x *IpcRequest) GetDrain() *DrainRequest {
+	if x != nil {
+		if x, ok := x.Request.(*IpcRequest_Drain); ok {
+			return x.Drain
+		}
+	}
+	return nil
+}
+
+func (x *IpcRequest) GetCapture() *CaptureRequest {
+	if x != nil {
+		if x, ok := x.Request.(*IpcRequest_Capture); ok {
+			return x.Capture
+		}
+	}
+	return nil
+}
+
 type isIpcRequest_Request interface {
 	isIpcRequest_Request()
 }
@@ -1698,6 +2493,14 @@ type IpcRequest_Trace struct {
 	Trace *TraceRequest `protobuf:"bytes,4,opt,name=trace,proto3,oneof"`
 }
 
+type IpcRequest_Drain struct {
+	Drain *DrainRequest `protobuf:"bytes,5,opt,name=drain,proto3,oneof"`
+}
+
+type IpcRequest_Capture struct {
+	Capture *CaptureRequest `protobuf:"bytes,6,opt,name=capture,proto3,oneof"`
+}
+
 func (*IpcRequest_Status) isIpcRequest_Request() {}
 
 func (*IpcRequest_Probe) isIpcRequest_Request() {}
@@ -1706,6 +2509,10 @@ func (*IpcRequest_Reload) isIpcRequest_Request() {}
 
 func (*IpcRequest_Trace) isIpcRequest_Request() {}
 
+func (*IpcRequest_Drain) isIpcRequest_Request() {}
+
+func (*IpcRequest_Capture) isIpcRequest_Request() {}
+
 type IpcResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	Ok    bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
@@ -1716,6 +2523,8 @@ type IpcResponse struct {
 	//	*IpcResponse_Probe
 	//	*IpcResponse_Reload
 	//	*IpcResponse_Trace
+	//	*IpcResponse_Drain
+	//	*IpcResponse_Captured
 	Response      isIpcResponse_Response `protobuf_oneof:"response"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -1723,7 +2532,7 @@ type IpcResponse struct {
 
 func (x *IpcResponse) Reset() {
 	*x = IpcResponse{}
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[25]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1735,7 +2544,7 @@ func (x *IpcResponse) String() string {
 func (*IpcResponse) ProtoMessage() {}
 
 func (x *IpcResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_protocol_nylon_ipc_proto_msgTypes[25]
+	mi := &file_protocol_nylon_ipc_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1748,7 +2557,7 @@ func (x *IpcResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IpcResponse.ProtoReflect.Descriptor instead.
 func (*IpcResponse) Descriptor() ([]byte, []int) {
-	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{25}
+	return file_protocol_nylon_ipc_proto_rawDescGZIP(), []int{32}
 }
 
 func (x *IpcResponse) GetOk() bool {
@@ -1808,6 +2617,24 @@ func (x *IpcResponse) GetTrace() *TraceEvent {
 	return nil
 }
 
+func (x *IpcResponse) GetDrain() *DrainResponse {
+	if x != nil {
+		if x, ok := x.Response.(*IpcResponse_Drain); ok {
+			return x.Drain
+		}
+	}
+	return nil
+}
+
+func (x *IpcResponse) GetCaptured() *CapturedPacket {
+	if x != nil {
+		if x, ok := x.Response.(*IpcResponse_Captured); ok {
+			return x.Captured
+		}
+	}
+	return nil
+}
+
 type isIpcResponse_Response interface {
 	isIpcResponse_Response()
 }
@@ -1828,6 +2655,14 @@ type IpcResponse_Trace struct {
 	Trace *TraceEvent `protobuf:"bytes,6,opt,name=trace,proto3,oneof"`
 }
 
+type IpcResponse_Drain struct {
+	Drain *DrainResponse `protobuf:"bytes,7,opt,name=drain,proto3,oneof"`
+}
+
+type IpcResponse_Captured struct {
+	Captured *CapturedPacket `protobuf:"bytes,8,opt,name=captured,proto3,oneof"`
+}
+
 func (*IpcResponse_Status) isIpcResponse_Response() {}
 
 func (*IpcResponse_Probe) isIpcResponse_Response() {}
@@ -1836,6 +2671,10 @@ func (*IpcResponse_Reload) isIpcResponse_Response() {}
 
 func (*IpcResponse_Trace) isIpcResponse_Response() {}
 
+func (*IpcResponse_Drain) isIpcResponse_Response() {}
+
+func (*IpcResponse_Captured) isIpcResponse_Response() {}
+
 var File_protocol_nylon_ipc_proto protoreflect.FileDescriptor
 
 const file_protocol_nylon_ipc_proto_rawDesc = "" +
@@ -1847,10 +2686,18 @@ const file_protocol_nylon_ipc_proto_rawDesc = "" +
 	"\n" +
 	"timeout_ms\x18\x02 \x01(\rR\ttimeoutMs\"\x0f\n" +
 	"\rReloadRequest\"\x0e\n" +
-	"\fTraceRequest\"9\n" +
+	"\fTraceRequest\"V\n" +
+	"\x0eCaptureRequest\x12\x16\n" +
+	"\x06filter\x18\x01 \x01(\tR\x06filter\x12\x18\n" +
+	"\asnaplen\x18\x02 \x01(\rR\asnaplen\x12\x12\n" +
+	"\x04rate\x18\x03 \x01(\rR\x04rate\":\n" +
+	"\fDrainRequest\x12*\n" +
+	"\x06action\x18\x01 \x01(\x0e2\x12.proto.DrainActionR\x06action\"X\n" +
 	"\x06Source\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x16\n" +
-	"\x06prefix\x18\x02 \x01(\tR\x06prefix\"2\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\x12\x1d\n" +
+	"\n" +
+	"src_prefix\x18\x03 \x01(\tR\tsrcPrefix\"2\n" +
 	"\x02FD\x12\x14\n" +
 	"\x05seqno\x18\x01 \x01(\rR\x05seqno\x12\x16\n" +
 	"\x06metric\x18\x02 \x01(\rR\x06metric\"L\n" +
@@ -1860,19 +2707,22 @@ const file_protocol_nylon_ipc_proto_rawDesc = "" +
 	"\n" +
 	"NeighRoute\x12,\n" +
 	"\tpub_route\x18\x01 \x01(\v2\x0f.proto.PubRouteR\bpubRoute\x12$\n" +
-	"\x0eexpire_at_unix\x18\x02 \x01(\x03R\fexpireAtUnix\"\x91\x01\n" +
+	"\x0eexpire_at_unix\x18\x02 \x01(\x03R\fexpireAtUnix\"\xaf\x01\n" +
 	"\bSelRoute\x12,\n" +
 	"\tpub_route\x18\x01 \x01(\v2\x0f.proto.PubRouteR\bpubRoute\x12\x0e\n" +
 	"\x02nh\x18\x02 \x01(\tR\x02nh\x12$\n" +
 	"\x0eexpire_at_unix\x18\x03 \x01(\x03R\fexpireAtUnix\x12!\n" +
-	"\fretracted_by\x18\x04 \x03(\tR\vretractedBy\"\x9c\x01\n" +
+	"\fretracted_by\x18\x04 \x03(\tR\vretractedBy\x12\x1c\n" +
+	"\tmultipath\x18\x05 \x03(\tR\tmultipath\"\xbb\x01\n" +
 	"\rAdvertisement\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x16\n" +
 	"\x06prefix\x18\x02 \x01(\tR\x06prefix\x12\x16\n" +
 	"\x06metric\x18\x03 \x01(\rR\x06metric\x12\x1f\n" +
 	"\vexpiry_unix\x18\x04 \x01(\x03R\n" +
 	"expiryUnix\x12!\n" +
-	"\fpassive_hold\x18\x05 \x01(\bR\vpassiveHold\"\xfb\x01\n" +
+	"\fpassive_hold\x18\x05 \x01(\bR\vpassiveHold\x12\x1d\n" +
+	"\n" +
+	"src_prefix\x18\x06 \x01(\tR\tsrcPrefix\"\xbe\x04\n" +
 	"\fEndpointInfo\x12\x18\n" +
 	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x1f\n" +
 	"\bresolved\x18\x02 \x01(\tH\x00R\bresolved\x88\x01\x01\x12\x16\n" +
@@ -1881,7 +2731,25 @@ const file_protocol_nylon_ipc_proto_rawDesc = "" +
 	"remoteInit\x12\x16\n" +
 	"\x06metric\x18\x05 \x01(\rR\x06metric\x12&\n" +
 	"\x0ffiltered_rtt_ns\x18\a \x01(\x03R\rfilteredRttNs\x12*\n" +
-	"\x11stabilized_rtt_ns\x18\b \x01(\x03R\x0fstabilizedRttNsB\v\n" +
+	"\x11stabilized_rtt_ns\x18\b \x01(\x03R\x0fstabilizedRttNs\x12\x1b\n" +
+	"\tjitter_ns\x18\t \x01(\x03R\bjitterNs\x12\x1d\n" +
+	"\n" +
+	"loss_ratio\x18\n" +
+	" \x01(\x01R\tlossRatio\x12\x1f\n" +
+	"\vlost_probes\x18\v \x01(\x04R\n" +
+	"lostProbes\x12\x1f\n" +
+	"\vlate_probes\x18\f \x01(\x04R\n" +
+	"lateProbes\x12\x16\n" +
+	"\x06backup\x18\r \x01(\bR\x06backup\x12\x18\n" +
+	"\ametered\x18\x0e \x01(\bR\ametered\x12\x1d\n" +
+	"\n" +
+	"tx_packets\x18\x0f \x01(\x04R\ttxPackets\x12\x19\n" +
+	"\btx_bytes\x18\x10 \x01(\x04R\atxBytes\x12\x1d\n" +
+	"\n" +
+	"rx_packets\x18\x11 \x01(\x04R\trxPackets\x12\x19\n" +
+	"\brx_bytes\x18\x12 \x01(\x04R\arxBytes\x12\x1d\n" +
+	"\n" +
+	"bond_share\x18\x13 \x01(\x01R\tbondShareB\v\n" +
 	"\t_resolved\"\xf0\x01\n" +
 	"\x12WireGuardPeerStats\x122\n" +
 	"\x15latest_handshake_unix\x18\x01 \x01(\x03R\x13latestHandshakeUnix\x12\x19\n" +
@@ -1889,7 +2757,7 @@ const file_protocol_nylon_ipc_proto_rawDesc = "" +
 	"\brx_bytes\x18\x03 \x01(\x04R\arxBytes\x12B\n" +
 	"\x1dpersistent_keepalive_interval\x18\x04 \x01(\rR\x1bpersistentKeepaliveInterval\x12\x1f\n" +
 	"\bendpoint\x18\x05 \x01(\tH\x00R\bendpoint\x88\x01\x01B\v\n" +
-	"\t_endpoint\"\xbb\x02\n" +
+	"\t_endpoint\"\xab\x03\n" +
 	"\rNeighbourInfo\x12\x17\n" +
 	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12\x1d\n" +
 	"\n" +
@@ -1900,29 +2768,61 @@ const file_protocol_nylon_ipc_proto_rawDesc = "" +
 	"\n" +
 	"advertised\x18\x06 \x03(\v2\x14.proto.AdvertisementR\n" +
 	"advertised\x127\n" +
-	"\twireguard\x18\a \x01(\v2\x19.proto.WireGuardPeerStatsR\twireguard\"W\n" +
+	"\twireguard\x18\a \x01(\v2\x19.proto.WireGuardPeerStatsR\twireguard\x121\n" +
+	"\x14unauthorized_updates\x18\b \x01(\x04R\x13unauthorizedUpdates\x12\x12\n" +
+	"\x04bond\x18\t \x01(\tR\x04bond\x12'\n" +
+	"\x0fspoofed_packets\x18\n" +
+	" \x01(\x04R\x0espoofedPackets\"\x94\x01\n" +
 	"\x0fRouteTableEntry\x12\x16\n" +
 	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x0e\n" +
 	"\x02nh\x18\x02 \x01(\tR\x02nh\x12\x1c\n" +
-	"\tblackhole\x18\x03 \x01(\bR\tblackhole\"\x98\x01\n" +
+	"\tblackhole\x18\x03 \x01(\bR\tblackhole\x12\x1c\n" +
+	"\tmultipath\x18\x04 \x03(\tR\tmultipath\x12\x1d\n" +
+	"\n" +
+	"src_prefix\x18\x05 \x01(\tR\tsrcPrefix\"\x98\x01\n" +
 	"\vRouteTables\x12+\n" +
 	"\bselected\x18\x01 \x03(\v2\x0f.proto.SelRouteR\bselected\x120\n" +
 	"\aforward\x18\x02 \x03(\v2\x16.proto.RouteTableEntryR\aforward\x12*\n" +
-	"\x04exit\x18\x03 \x03(\v2\x16.proto.RouteTableEntryR\x04exit\":\n" +
+	"\x04exit\x18\x03 \x03(\v2\x16.proto.RouteTableEntryR\x04exit\"Y\n" +
 	"\n" +
 	"SeqnoEntry\x12\x16\n" +
 	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x14\n" +
-	"\x05seqno\x18\x02 \x01(\rR\x05seqno\"W\n" +
+	"\x05seqno\x18\x02 \x01(\rR\x05seqno\x12\x1d\n" +
+	"\n" +
+	"src_prefix\x18\x03 \x01(\tR\tsrcPrefix\"W\n" +
 	"\x13FeasibilityDistance\x12%\n" +
 	"\x06source\x18\x01 \x01(\v2\r.proto.SourceR\x06source\x12\x19\n" +
-	"\x02fd\x18\x02 \x01(\v2\t.proto.FDR\x02fd\"\x88\x02\n" +
+	"\x02fd\x18\x02 \x01(\v2\t.proto.FDR\x02fd\"\x91\x01\n" +
+	"\x0eDampenedSource\x12%\n" +
+	"\x06source\x18\x01 \x01(\v2\r.proto.SourceR\x06source\x12\x18\n" +
+	"\apenalty\x18\x02 \x01(\x01R\apenalty\x12\x1e\n" +
+	"\n" +
+	"suppressed\x18\x03 \x01(\bR\n" +
+	"suppressed\x12\x1e\n" +
+	"\vreuse_in_ms\x18\x04 \x01(\x03R\treuseInMs\"d\n" +
+	"\fACLRuleStats\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06action\x18\x02 \x01(\tR\x06action\x12\x12\n" +
+	"\x04hits\x18\x03 \x01(\x04R\x04hits\x12\x14\n" +
+	"\x05drops\x18\x04 \x01(\x04R\x05drops\"\xb8\x01\n" +
+	"\rQoSClassStats\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\bpriority\x18\x02 \x01(\tR\bpriority\x12\x19\n" +
+	"\brate_bps\x18\x03 \x01(\x04R\arateBps\x12\x18\n" +
+	"\apackets\x18\x04 \x01(\x04R\apackets\x12\x14\n" +
+	"\x05bytes\x18\x05 \x01(\x04R\x05bytes\x12\x14\n" +
+	"\x05drops\x18\x06 \x01(\x04R\x05drops\x12\x16\n" +
+	"\x06queued\x18\a \x01(\x04R\x06queued\"\x9a\x03\n" +
 	"\tNodeStats\x12'\n" +
 	"\x0fneighbour_count\x18\x01 \x01(\x05R\x0eneighbourCount\x122\n" +
 	"\x15active_endpoint_count\x18\x02 \x01(\x05R\x13activeEndpointCount\x120\n" +
 	"\x14selected_route_count\x18\x03 \x01(\x05R\x12selectedRouteCount\x126\n" +
 	"\x17advertised_prefix_count\x18\x04 \x01(\x05R\x15advertisedPrefixCount\x12\x19\n" +
 	"\btx_bytes\x18\x05 \x01(\x04R\atxBytes\x12\x19\n" +
-	"\brx_bytes\x18\x06 \x01(\x04R\arxBytes\"\xdc\x02\n" +
+	"\brx_bytes\x18\x06 \x01(\x04R\arxBytes\x12'\n" +
+	"\x0fduplicates_sent\x18\a \x01(\x04R\x0eduplicatesSent\x12-\n" +
+	"\x12duplicates_dropped\x18\b \x01(\x04R\x11duplicatesDropped\x128\n" +
+	"\x18port_forward_connections\x18\t \x01(\x04R\x16portForwardConnections\"\xf8\x02\n" +
 	"\n" +
 	"NodeStatus\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1c\n" +
@@ -1937,14 +2837,20 @@ const file_protocol_nylon_ipc_proto_rawDesc = "" +
 	"advertised\x18\a \x03(\v2\x14.proto.AdvertisementR\n" +
 	"advertised\x12)\n" +
 	"\x06seqnos\x18\b \x03(\v2\x11.proto.SeqnoEntryR\x06seqnos\x12&\n" +
-	"\x05stats\x18\t \x01(\v2\x10.proto.NodeStatsR\x05stats\"\xea\x01\n" +
+	"\x05stats\x18\t \x01(\v2\x10.proto.NodeStatsR\x05stats\x12\x1a\n" +
+	"\bdraining\x18\n" +
+	" \x01(\bR\bdraining\"\x95\x03\n" +
 	"\x0eStatusResponse\x12%\n" +
 	"\x04node\x18\x01 \x01(\v2\x11.proto.NodeStatusR\x04node\x124\n" +
 	"\n" +
 	"neighbours\x18\x02 \x03(\v2\x14.proto.NeighbourInfoR\n" +
 	"neighbours\x12*\n" +
 	"\x06routes\x18\x03 \x01(\v2\x12.proto.RouteTablesR\x06routes\x12O\n" +
-	"\x15feasibility_distances\x18\x04 \x03(\v2\x1a.proto.FeasibilityDistanceR\x14feasibilityDistances\"\xb0\x01\n" +
+	"\x15feasibility_distances\x18\x04 \x03(\v2\x1a.proto.FeasibilityDistanceR\x14feasibilityDistances\x12@\n" +
+	"\x10dampened_sources\x18\x05 \x03(\v2\x15.proto.DampenedSourceR\x0fdampenedSources\x120\n" +
+	"\tacl_rules\x18\x06 \x03(\v2\x13.proto.ACLRuleStatsR\baclRules\x125\n" +
+	"\vqos_classes\x18\a \x03(\v2\x14.proto.QoSClassStatsR\n" +
+	"qosClasses\"\xb0\x01\n" +
 	"\x13EndpointProbeResult\x12\x18\n" +
 	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x1f\n" +
 	"\bresolved\x18\x04 \x01(\tH\x00R\bresolved\x88\x01\x01\x122\n" +
@@ -1956,31 +2862,54 @@ const file_protocol_nylon_ipc_proto_rawDesc = "" +
 	"\aresults\x18\x01 \x03(\v2\x1a.proto.EndpointProbeResultR\aresults\"W\n" +
 	"\x0eReloadResponse\x12+\n" +
 	"\x06result\x18\x01 \x01(\x0e2\x13.proto.ReloadResultR\x06result\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\" \n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xab\x01\n" +
+	"\rDrainResponse\x12\x1a\n" +
+	"\bdraining\x18\x01 \x01(\bR\bdraining\x12\x18\n" +
+	"\adrained\x18\x02 \x01(\bR\adrained\x12\x17\n" +
+	"\aidle_ms\x18\x03 \x01(\x03R\x06idleMs\x12'\n" +
+	"\x0ftransit_packets\x18\x04 \x01(\x04R\x0etransitPackets\x12\"\n" +
+	"\funobservable\x18\x05 \x01(\bR\funobservable\" \n" +
 	"\n" +
 	"TraceEvent\x12\x12\n" +
-	"\x04line\x18\x01 \x01(\tR\x04line\"\xd1\x01\n" +
+	"\x04line\x18\x01 \x01(\tR\x04line\"\xe3\x01\n" +
+	"\x0eCapturedPacket\x12!\n" +
+	"\ftimestamp_ns\x18\x01 \x01(\x03R\vtimestampNs\x12\x1a\n" +
+	"\bincoming\x18\x02 \x01(\bR\bincoming\x12\x1b\n" +
+	"\tfrom_peer\x18\x03 \x01(\tR\bfromPeer\x12\x17\n" +
+	"\ato_peer\x18\x04 \x01(\tR\x06toPeer\x12\x16\n" +
+	"\x06action\x18\x05 \x01(\tR\x06action\x12\x16\n" +
+	"\x06length\x18\x06 \x01(\rR\x06length\x12\x12\n" +
+	"\x04data\x18\a \x01(\fR\x04data\x12\x18\n" +
+	"\askipped\x18\b \x01(\x04R\askipped\"\xb1\x02\n" +
 	"\n" +
 	"IpcRequest\x12.\n" +
 	"\x06status\x18\x01 \x01(\v2\x14.proto.StatusRequestH\x00R\x06status\x12+\n" +
 	"\x05probe\x18\x02 \x01(\v2\x13.proto.ProbeRequestH\x00R\x05probe\x12.\n" +
 	"\x06reload\x18\x03 \x01(\v2\x14.proto.ReloadRequestH\x00R\x06reload\x12+\n" +
-	"\x05trace\x18\x04 \x01(\v2\x13.proto.TraceRequestH\x00R\x05traceB\t\n" +
-	"\arequest\"\xfa\x01\n" +
+	"\x05trace\x18\x04 \x01(\v2\x13.proto.TraceRequestH\x00R\x05trace\x12+\n" +
+	"\x05drain\x18\x05 \x01(\v2\x13.proto.DrainRequestH\x00R\x05drain\x121\n" +
+	"\acapture\x18\x06 \x01(\v2\x15.proto.CaptureRequestH\x00R\acaptureB\t\n" +
+	"\arequest\"\xdd\x02\n" +
 	"\vIpcResponse\x12\x0e\n" +
 	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\x12/\n" +
 	"\x06status\x18\x03 \x01(\v2\x15.proto.StatusResponseH\x00R\x06status\x12,\n" +
 	"\x05probe\x18\x04 \x01(\v2\x14.proto.ProbeResponseH\x00R\x05probe\x12/\n" +
 	"\x06reload\x18\x05 \x01(\v2\x15.proto.ReloadResponseH\x00R\x06reload\x12)\n" +
-	"\x05trace\x18\x06 \x01(\v2\x11.proto.TraceEventH\x00R\x05traceB\n" +
+	"\x05trace\x18\x06 \x01(\v2\x11.proto.TraceEventH\x00R\x05trace\x12,\n" +
+	"\x05drain\x18\a \x01(\v2\x14.proto.DrainResponseH\x00R\x05drain\x123\n" +
+	"\bcaptured\x18\b \x01(\v2\x15.proto.CapturedPacketH\x00R\bcapturedB\n" +
 	"\n" +
 	"\bresponse*I\n" +
 	"\fReloadResult\x12\b\n" +
 	"\x04NOOP\x10\x00\x12\v\n" +
 	"\aAPPLIED\x10\x01\x12\f\n" +
 	"\bREJECTED\x10\x02\x12\x14\n" +
-	"\x10RESTART_REQUIRED\x10\x03*\xb5\x01\n" +
+	"\x10RESTART_REQUIRED\x10\x03*7\n" +
+	"\vDrainAction\x12\x10\n" +
+	"\fDRAIN_STATUS\x10\x00\x12\t\n" +
+	"\x05DRAIN\x10\x01\x12\v\n" +
+	"\aUNDRAIN\x10\x02*\xb5\x01\n" +
 	"\x13EndpointProbeStatus\x12%\n" +
 	"!ENDPOINT_PROBE_STATUS_UNSPECIFIED\x10\x00\x12\x1a\n" +
 	"\x16ENDPOINT_PROBE_REPLIED\x10\x01\x12\x1a\n" +
@@ -2000,75 +2929,92 @@ func file_protocol_nylon_ipc_proto_rawDescGZIP() []byte {
 	return file_protocol_nylon_ipc_proto_rawDescData
 }
 
-var file_protocol_nylon_ipc_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_protocol_nylon_ipc_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_protocol_nylon_ipc_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_protocol_nylon_ipc_proto_msgTypes = make([]protoimpl.MessageInfo, 33)
 var file_protocol_nylon_ipc_proto_goTypes = []any{
 	(ReloadResult)(0),           // 0: proto.ReloadResult
-	(EndpointProbeStatus)(0),    // 1: proto.EndpointProbeStatus
-	(*StatusRequest)(nil),       // 2: proto.StatusRequest
-	(*ProbeRequest)(nil),        // 3: proto.ProbeRequest
-	(*ReloadRequest)(nil),       // 4: proto.ReloadRequest
-	(*TraceRequest)(nil),        // 5: proto.TraceRequest
-	(*Source)(nil),              // 6: proto.Source
-	(*FD)(nil),                  // 7: proto.FD
-	(*PubRoute)(nil),            // 8: proto.PubRoute
-	(*NeighRoute)(nil),          // 9: proto.NeighRoute
-	(*SelRoute)(nil),            // 10: proto.SelRoute
-	(*Advertisement)(nil),       // 11: proto.Advertisement
-	(*EndpointInfo)(nil),        // 12: proto.EndpointInfo
-	(*WireGuardPeerStats)(nil),  // 13: proto.WireGuardPeerStats
-	(*NeighbourInfo)(nil),       // 14: proto.NeighbourInfo
-	(*RouteTableEntry)(nil),     // 15: proto.RouteTableEntry
-	(*RouteTables)(nil),         // 16: proto.RouteTables
-	(*SeqnoEntry)(nil),          // 17: proto.SeqnoEntry
-	(*FeasibilityDistance)(nil), // 18: proto.FeasibilityDistance
-	(*NodeStats)(nil),           // 19: proto.NodeStats
-	(*NodeStatus)(nil),          // 20: proto.NodeStatus
-	(*StatusResponse)(nil),      // 21: proto.StatusResponse
-	(*EndpointProbeResult)(nil), // 22: proto.EndpointProbeResult
-	(*ProbeResponse)(nil),       // 23: proto.ProbeResponse
-	(*ReloadResponse)(nil),      // 24: proto.ReloadResponse
-	(*TraceEvent)(nil),          // 25: proto.TraceEvent
-	(*IpcRequest)(nil),          // 26: proto.IpcRequest
-	(*IpcResponse)(nil),         // 27: proto.IpcResponse
+	(DrainAction)(0),            // 1: proto.DrainAction
+	(EndpointProbeStatus)(0),    // 2: proto.EndpointProbeStatus
+	(*StatusRequest)(nil),       // 3: proto.StatusRequest
+	(*ProbeRequest)(nil),        // 4: proto.ProbeRequest
+	(*ReloadRequest)(nil),       // 5: proto.ReloadRequest
+	(*TraceRequest)(nil),        // 6: proto.TraceRequest
+	(*CaptureRequest)(nil),      // 7: proto.CaptureRequest
+	(*DrainRequest)(nil),        // 8: proto.DrainRequest
+	(*Source)(nil),              // 9: proto.Source
+	(*FD)(nil),                  // 10: proto.FD
+	(*PubRoute)(nil),            // 11: proto.PubRoute
+	(*NeighRoute)(nil),          // 12: proto.NeighRoute
+	(*SelRoute)(nil),            // 13: proto.SelRoute
+	(*Advertisement)(nil),       // 14: proto.Advertisement
+	(*EndpointInfo)(nil),        // 15: proto.EndpointInfo
+	(*WireGuardPeerStats)(nil),  // 16: proto.WireGuardPeerStats
+	(*NeighbourInfo)(nil),       // 17: proto.NeighbourInfo
+	(*RouteTableEntry)(nil),     // 18: proto.RouteTableEntry
+	(*RouteTables)(nil),         // 19: proto.RouteTables
+	(*SeqnoEntry)(nil),          // 20: proto.SeqnoEntry
+	(*FeasibilityDistance)(nil), // 21: proto.FeasibilityDistance
+	(*DampenedSource)(nil),      // 22: proto.DampenedSource
+	(*ACLRuleStats)(nil),        // 23: proto.ACLRuleStats
+	(*QoSClassStats)(nil),       // 24: proto.QoSClassStats
+	(*NodeStats)(nil),           // 25: proto.NodeStats
+	(*NodeStatus)(nil),          // 26: proto.NodeStatus
+	(*StatusResponse)(nil),      // 27: proto.StatusResponse
+	(*EndpointProbeResult)(nil), // 28: proto.EndpointProbeResult
+	(*ProbeResponse)(nil),       // 29: proto.ProbeResponse
+	(*ReloadResponse)(nil),      // 30: proto.ReloadResponse
+	(*DrainResponse)(nil),       // 31: proto.DrainResponse
+	(*TraceEvent)(nil),          // 32: proto.TraceEvent
+	(*CapturedPacket)(nil),      // 33: proto.CapturedPacket
+	(*IpcRequest)(nil),          // 34: proto.IpcRequest
+	(*IpcResponse)(nil),         // 35: proto.IpcResponse
 }
 var file_protocol_nylon_ipc_proto_depIdxs = []int32{
-	6,  // 0: proto.PubRoute.source:type_name -> proto.Source
-	7,  // 1: proto.PubRoute.fd:type_name -> proto.FD
-	8,  // 2: proto.NeighRoute.pub_route:type_name -> proto.PubRoute
-	8,  // 3: proto.SelRoute.pub_route:type_name -> proto.PubRoute
-	12, // 4: proto.NeighbourInfo.endpoints:type_name -> proto.EndpointInfo
-	9,  // 5: proto.NeighbourInfo.routes:type_name -> proto.NeighRoute
-	11, // 6: proto.NeighbourInfo.advertised:type_name -> proto.Advertisement
-	13, // 7: proto.NeighbourInfo.wireguard:type_name -> proto.WireGuardPeerStats
-	10, // 8: proto.RouteTables.selected:type_name -> proto.SelRoute
-	15, // 9: proto.RouteTables.forward:type_name -> proto.RouteTableEntry
-	15, // 10: proto.RouteTables.exit:type_name -> proto.RouteTableEntry
-	6,  // 11: proto.FeasibilityDistance.source:type_name -> proto.Source
-	7,  // 12: proto.FeasibilityDistance.fd:type_name -> proto.FD
-	11, // 13: proto.NodeStatus.advertised:type_name -> proto.Advertisement
-	17, // 14: proto.NodeStatus.seqnos:type_name -> proto.SeqnoEntry
-	19, // 15: proto.NodeStatus.stats:type_name -> proto.NodeStats
-	20, // 16: proto.StatusResponse.node:type_name -> proto.NodeStatus
-	14, // 17: proto.StatusResponse.neighbours:type_name -> proto.NeighbourInfo
-	16, // 18: proto.StatusResponse.routes:type_name -> proto.RouteTables
-	18, // 19: proto.StatusResponse.feasibility_distances:type_name -> proto.FeasibilityDistance
-	1,  // 20: proto.EndpointProbeResult.status:type_name -> proto.EndpointProbeStatus
-	22, // 21: proto.ProbeResponse.results:type_name -> proto.EndpointProbeResult
-	0,  // 22: proto.ReloadResponse.result:type_name -> proto.ReloadResult
-	2,  // 23: proto.IpcRequest.status:type_name -> proto.StatusRequest
-	3,  // 24: proto.IpcRequest.probe:type_name -> proto.ProbeRequest
-	4,  // 25: proto.IpcRequest.reload:type_name -> proto.ReloadRequest
-	5,  // 26: proto.IpcRequest.trace:type_name -> proto.TraceRequest
-	21, // 27: proto.IpcResponse.status:type_name -> proto.StatusResponse
-	23, // 28: proto.IpcResponse.probe:type_name -> proto.ProbeResponse
-	24, // 29: proto.IpcResponse.reload:type_name -> proto.ReloadResponse
-	25, // 30: proto.IpcResponse.trace:type_name -> proto.TraceEvent
-	31, // [31:31] is the sub-list for method output_type
-	31, // [31:31] is the sub-list for method input_type
-	31, // [31:31] is the sub-list for extension type_name
-	31, // [31:31] is the sub-list for extension extendee
-	0,  // [0:31] is the sub-list for field type_name
+	1,  // 0: proto.DrainRequest.action:type_name -> proto.DrainAction
+	9,  // 1: proto.PubRoute.source:type_name -> proto.Source
+	10, // 2: proto.PubRoute.fd:type_name -> proto.FD
+	11, // 3: proto.NeighRoute.pub_route:type_name -> proto.PubRoute
+	11, // 4: proto.SelRoute.pub_route:type_name -> proto.PubRoute
+	15, // 5: proto.NeighbourInfo.endpoints:type_name -> proto.EndpointInfo
+	12, // 6: proto.NeighbourInfo.routes:type_name -> proto.NeighRoute
+	14, // 7: proto.NeighbourInfo.advertised:type_name -> proto.Advertisement
+	16, // 8: proto.NeighbourInfo.wireguard:type_name -> proto.WireGuardPeerStats
+	13, // 9: proto.RouteTables.selected:type_name -> proto.SelRoute
+	18, // 10: proto.RouteTables.forward:type_name -> proto.RouteTableEntry
+	18, // 11: proto.RouteTables.exit:type_name -> proto.RouteTableEntry
+	9,  // 12: proto.FeasibilityDistance.source:type_name -> proto.Source
+	10, // 13: proto.FeasibilityDistance.fd:type_name -> proto.FD
+	9,  // 14: proto.DampenedSource.source:type_name -> proto.Source
+	14, // 15: proto.NodeStatus.advertised:type_name -> proto.Advertisement
+	20, // 16: proto.NodeStatus.seqnos:type_name -> proto.SeqnoEntry
+	25, // 17: proto.NodeStatus.stats:type_name -> proto.NodeStats
+	26, // 18: proto.StatusResponse.node:type_name -> proto.NodeStatus
+	17, // 19: proto.StatusResponse.neighbours:type_name -> proto.NeighbourInfo
+	19, // 20: proto.StatusResponse.routes:type_name -> proto.RouteTables
+	21, // 21: proto.StatusResponse.feasibility_distances:type_name -> proto.FeasibilityDistance
+	22, // 22: proto.StatusResponse.dampened_sources:type_name -> proto.DampenedSource
+	23, // 23: proto.StatusResponse.acl_rules:type_name -> proto.ACLRuleStats
+	24, // 24: proto.StatusResponse.qos_classes:type_name -> proto.QoSClassStats
+	2,  // 25: proto.EndpointProbeResult.status:type_name -> proto.EndpointProbeStatus
+	28, // 26: proto.ProbeResponse.results:type_name -> proto.EndpointProbeResult
+	0,  // 27: proto.ReloadResponse.result:type_name -> proto.ReloadResult
+	3,  // 28: proto.IpcRequest.status:type_name -> proto.StatusRequest
+	4,  // 29: proto.IpcRequest.probe:type_name -> proto.ProbeRequest
+	5,  // 30: proto.IpcRequest.reload:type_name -> proto.ReloadRequest
+	6,  // 31: proto.IpcRequest.trace:type_name -> proto.TraceRequest
+	8,  // 32: proto.IpcRequest.drain:type_name -> proto.DrainRequest
+	7,  // 33: proto.IpcRequest.capture:type_name -> proto.CaptureRequest
+	27, // 34: proto.IpcResponse.status:type_name -> proto.StatusResponse
+	29, // 35: proto.IpcResponse.probe:type_name -> proto.ProbeResponse
+	30, // 36: proto.IpcResponse.reload:type_name -> proto.ReloadResponse
+	32, // 37: proto.IpcResponse.trace:type_name -> proto.TraceEvent
+	31, // 38: proto.IpcResponse.drain:type_name -> proto.DrainResponse
+	33, // 39: proto.IpcResponse.captured:type_name -> proto.CapturedPacket
+	40, // [40:40] is the sub-list for method output_type
+	40, // [40:40] is the sub-list for method input_type
+	40, // [40:40] is the sub-list for extension type_name
+	40, // [40:40] is the sub-list for extension extendee
+	0,  // [0:40] is the sub-list for field type_name
 }
 
 func init() { file_protocol_nylon_ipc_proto_init() }
@@ -2076,28 +3022,32 @@ func file_protocol_nylon_ipc_proto_init() {
 	if File_protocol_nylon_ipc_proto != nil {
 		return
 	}
-	file_protocol_nylon_ipc_proto_msgTypes[10].OneofWrappers = []any{}
-	file_protocol_nylon_ipc_proto_msgTypes[11].OneofWrappers = []any{}
-	file_protocol_nylon_ipc_proto_msgTypes[20].OneofWrappers = []any{}
-	file_protocol_nylon_ipc_proto_msgTypes[24].OneofWrappers = []any{
+	file_protocol_nylon_ipc_proto_msgTypes[12].OneofWrappers = []any{}
+	file_protocol_nylon_ipc_proto_msgTypes[13].OneofWrappers = []any{}
+	file_protocol_nylon_ipc_proto_msgTypes[25].OneofWrappers = []any{}
+	file_protocol_nylon_ipc_proto_msgTypes[31].OneofWrappers = []any{
 		(*IpcRequest_Status)(nil),
 		(*IpcRequest_Probe)(nil),
 		(*IpcRequest_Reload)(nil),
 		(*IpcRequest_Trace)(nil),
+		(*IpcRequest_Drain)(nil),
+		(*IpcRequest_Capture)(nil),
 	}
-	file_protocol_nylon_ipc_proto_msgTypes[25].OneofWrappers = []any{
+	file_protocol_nylon_ipc_proto_msgTypes[32].OneofWrappers = []any{
 		(*IpcResponse_Status)(nil),
 		(*IpcResponse_Probe)(nil),
 		(*IpcResponse_Reload)(nil),
 		(*IpcResponse_Trace)(nil),
+		(*IpcResponse_Drain)(nil),
+		(*IpcResponse_Captured)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protocol_nylon_ipc_proto_rawDesc), len(file_protocol_nylon_ipc_proto_rawDesc)),
-			NumEnums:      2,
-			NumMessages:   26,
+			NumEnums:      3,
+			NumMessages:   33,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,287 @@
+package state
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go4.org/netipx"
+)
+
+type ACLAction string
+
+const (
+	ACLAccept ACLAction = "accept"
+	ACLDeny   ACLAction = "deny"
+)
+
+// ACLTagPrefix marks a tag in the src and dst of an acl rule, e.g. tag:web
+const ACLTagPrefix = "tag:"
+
+// ACLCfg restricts the traffic nodes may send through the mesh. Packets are checked by the router they enter the mesh
+// at, which is the sending router or the router a passive client is connected to, and optionally again by the router
+// they leave the mesh at. Rules are evaluated in order and the first match wins. Replies to accepted packets are
+// always accepted.
+type ACLCfg struct {
+	Default       ACLAction `yaml:"default,omitempty"`         // action for packets no rule matches, defaults to accept
+	EnforceAtExit bool      `yaml:"enforce_at_exit,omitempty"` // also check packets at the router they leave the mesh at
+	Rules         []ACLRule `yaml:"rules,omitempty"`
+}
+
+// ACLRule matches packets and accepts or denies them. Empty match fields match every packet.
+type ACLRule struct {
+	Name   string      `yaml:"name,omitempty"` // shown in nylon status and metrics, defaults to the rule's position
+	Action ACLAction   `yaml:"action"`
+	Src    []string    `yaml:"src,omitempty"`   // nodes, groups, tag:<tag> or prefixes the packet is sent from
+	Dst    []string    `yaml:"dst,omitempty"`   // nodes, groups, tag:<tag> or prefixes the packet is sent to
+	Proto  string      `yaml:"proto,omitempty"` // tcp, udp, sctp, icmp (icmp and icmpv6) or a protocol number
+	Ports  []PortRange `yaml:"ports,omitempty"` // destination ports, only tcp, udp and sctp packets have ports
+}
+
+// PortRange is a range of ports, written as a single port or as first-last
+type PortRange struct {
+	First, Last uint16
+}
+
+func (p PortRange) String() string {
+	if p.First == p.Last {
+		return strconv.Itoa(int(p.First))
+	}
+	return fmt.Sprintf("%d-%d", p.First, p.Last)
+}
+
+func (p PortRange) Contains(port uint16) bool {
+	return p.First <= port && port <= p.Last
+}
+
+func (p PortRange) MarshalYAML() (interface{}, error) {
+	if p.First == p.Last {
+		return p.First, nil
+	}
+	return p.String(), nil
+}
+
+func (p *PortRange) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var port uint16
+	if err := unmarshal(&port); err == nil {
+		*p = PortRange{First: port, Last: port}
+		return nil
+	}
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	parsed, err := ParsePortRange(s)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// ParsePortRange parses a port, or a range of ports written as first-last
+func ParsePortRange(s string) (PortRange, error) {
+	first, last, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	from, err := strconv.ParseUint(strings.TrimSpace(first), 10, 16)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q", s)
+	}
+	to := from
+	if isRange {
+		to, err = strconv.ParseUint(strings.TrimSpace(last), 10, 16)
+		if err != nil || to < from {
+			return PortRange{}, fmt.Errorf("invalid port range %q", s)
+		}
+	}
+	return PortRange{First: uint16(from), Last: uint16(to)}, nil
+}
+
+var protocolNames = map[string][]uint8{
+	"tcp":  {6},
+	"udp":  {17},
+	"sctp": {132},
+	"icmp": {1, 58},
+}
+
+// parseProtocol returns the protocol numbers of a protocol name or number, or nil for an empty string
+func parseProtocol(s string) ([]uint8, error) {
+	proto := strings.ToLower(strings.TrimSpace(s))
+	if proto == "" {
+		return nil, nil
+	}
+	if protos, ok := protocolNames[proto]; ok {
+		return protos, nil
+	}
+	if num, err := strconv.ParseUint(proto, 10, 8); err == nil {
+		return []uint8{uint8(num)}, nil
+	}
+	return nil, fmt.Errorf("unknown protocol %q", s)
+}
+
+// ACLPacket is the view of a packet that acl rules are matched against
+type ACLPacket struct {
+	Src, Dst netip.Addr
+	Proto    uint8
+	Port     uint16 // destination port
+	HasPort  bool
+}
+
+type aclRule struct {
+	src, dst *netipx.IPSet // nil matches every address
+	protos   []uint8
+	ports    []PortRange
+	accept   bool
+}
+
+func (r aclRule) matches(p ACLPacket) bool {
+	if r.src != nil && !r.src.Contains(p.Src) {
+		return false
+	}
+	if r.dst != nil && !r.dst.Contains(p.Dst) {
+		return false
+	}
+	if r.protos != nil && !slices.Contains(r.protos, p.Proto) {
+		return false
+	}
+	if r.ports != nil && (!p.HasPort || !slices.ContainsFunc(r.ports, func(pr PortRange) bool {
+		return pr.Contains(p.Port)
+	})) {
+		return false
+	}
+	return true
+}
+
+// ACL is the compiled acl of the mesh
+type ACL struct {
+	rules         []aclRule
+	names         []string
+	accept        bool // default action
+	EnforceAtExit bool
+	// Clients are the keys of the passive clients, a router is the ingress of the packets of its clients
+	Clients map[NyPublicKey]struct{}
+}
+
+// Match evaluates the rules against a packet. It returns the index of the matching rule, or -1 if no rule matched
+// and the default action applies, and whether the packet is accepted.
+func (a *ACL) Match(p ACLPacket) (int, bool) {
+	for i, rule := range a.rules {
+		if rule.matches(p) {
+			return i, rule.accept
+		}
+	}
+	return -1, a.accept
+}
+
+// RuleNames returns the name of every rule, in order
+func (a *ACL) RuleNames() []string {
+	return a.names
+}
+
+// RuleAction returns the action of a rule, or the default action for -1
+func (a *ACL) RuleAction(i int) ACLAction {
+	accept := a.accept
+	if i >= 0 {
+		accept = a.rules[i].accept
+	}
+	if accept {
+		return ACLAccept
+	}
+	return ACLDeny
+}
+
+// CompileACL resolves the nodes, groups and tags referenced by the acl into address sets.
+// A node is identified by its addresses and the prefixes it advertises. It returns nil if the mesh has no acl.
+func (c *CentralCfg) CompileACL() (*ACL, error) {
+	if c.ACL == nil {
+		return nil, nil
+	}
+	nodes := c.GetNodes()
+	ids := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		ids = append(ids, string(n.Id))
+	}
+	groups, err := ParseGroups(c.Graph, ids)
+	if err != nil {
+		return nil, err
+	}
+	addNode := func(b *netipx.IPSetBuilder, id NodeId) {
+		idx := slices.IndexFunc(nodes, func(n NodeCfg) bool {
+			return n.Id == id
+		})
+		for _, addr := range nodes[idx].Addresses {
+			b.Add(addr)
+		}
+		for _, prefix := range nodes[idx].Prefixes {
+			b.AddPrefix(prefix.GetPrefix())
+		}
+	}
+	resolve := func(selectors []string) (*netipx.IPSet, error) {
+		if len(selectors) == 0 {
+			return nil, nil
+		}
+		var b netipx.IPSetBuilder
+		for _, sel := range selectors {
+			sel = strings.TrimSpace(sel)
+			if tag, ok := strings.CutPrefix(sel, ACLTagPrefix); ok {
+				for _, n := range nodes {
+					if slices.Contains(n.Tags, tag) {
+						addNode(&b, n.Id)
+					}
+				}
+				continue
+			}
+			if prefix, err := netip.ParsePrefix(sel); err == nil {
+				b.AddPrefix(prefix.Masked())
+				continue
+			}
+			if addr, err := netip.ParseAddr(sel); err == nil {
+				b.Add(addr)
+				continue
+			}
+			name := strings.ToLower(sel)
+			if slices.Contains(ids, name) {
+				addNode(&b, NodeId(name))
+			} else if members, ok := groups[name]; ok {
+				for _, member := range members {
+					addNode(&b, member)
+				}
+			} else {
+				return nil, fmt.Errorf("invalid acl: %s is not a valid node, group, tag or prefix", sel)
+			}
+		}
+		return b.IPSet()
+	}
+
+	acl := &ACL{
+		accept:        c.ACL.Default != ACLDeny,
+		EnforceAtExit: c.ACL.EnforceAtExit,
+		Clients:       make(map[NyPublicKey]struct{}, len(c.Clients)),
+	}
+	for _, client := range c.Clients {
+		acl.Clients[client.PubKey] = struct{}{}
+	}
+	for i, rule := range c.ACL.Rules {
+		cr := aclRule{accept: rule.Action == ACLAccept}
+		if cr.src, err = resolve(rule.Src); err != nil {
+			return nil, err
+		}
+		if cr.dst, err = resolve(rule.Dst); err != nil {
+			return nil, err
+		}
+		if cr.protos, err = parseProtocol(rule.Proto); err != nil {
+			return nil, fmt.Errorf("invalid acl: %v", err)
+		}
+		if len(rule.Ports) != 0 {
+			cr.ports = rule.Ports
+		}
+		name := rule.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		acl.rules = append(acl.rules, cr)
+		acl.names = append(acl.names, name)
+	}
+	return acl, nil
+}
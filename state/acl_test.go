@@ -0,0 +1,81 @@
+package state
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func aclTestConfig(acl *ACLCfg) *CentralCfg {
+	return &CentralCfg{
+		Routers: []RouterCfg{
+			{NodeCfg: NodeCfg{Id: "alice", Addresses: []netip.Addr{netip.MustParseAddr("10.0.0.1")}, Tags: []string{"web"}}},
+			{NodeCfg: NodeCfg{Id: "bob", Addresses: []netip.Addr{netip.MustParseAddr("10.0.0.2")}}},
+		},
+		Clients: []ClientCfg{
+			{NodeCfg: NodeCfg{Id: "phone", Addresses: []netip.Addr{netip.MustParseAddr("10.0.1.1")}}},
+		},
+		Graph: []string{"servers = alice, bob", "servers, phone"},
+		ACL:   acl,
+	}
+}
+
+func TestCompileACL(t *testing.T) {
+	cfg := aclTestConfig(&ACLCfg{
+		Default: ACLDeny,
+		Rules: []ACLRule{
+			{Name: "phone-web", Action: ACLAccept, Src: []string{"phone"}, Dst: []string{"tag:web"}, Proto: "tcp", Ports: []PortRange{{443, 443}, {8000, 8100}}},
+			{Action: ACLDeny, Src: []string{"10.0.1.0/24"}},
+			{Action: ACLAccept, Src: []string{"servers"}, Proto: "icmp"},
+		},
+	})
+	acl, err := cfg.CompileACL()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"phone-web", "1", "2"}, acl.RuleNames())
+	assert.Len(t, acl.Clients, 1)
+
+	phone, alice, bob := netip.MustParseAddr("10.0.1.1"), netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")
+	for _, tc := range []struct {
+		name   string
+		packet ACLPacket
+		rule   int
+		accept bool
+	}{
+		{"port in list", ACLPacket{Src: phone, Dst: alice, Proto: 6, Port: 443, HasPort: true}, 0, true},
+		{"port in range", ACLPacket{Src: phone, Dst: alice, Proto: 6, Port: 8080, HasPort: true}, 0, true},
+		{"port not in list", ACLPacket{Src: phone, Dst: alice, Proto: 6, Port: 22, HasPort: true}, 1, false},
+		{"untagged destination", ACLPacket{Src: phone, Dst: bob, Proto: 6, Port: 443, HasPort: true}, 1, false},
+		{"group", ACLPacket{Src: bob, Dst: alice, Proto: 58}, 2, true},
+		{"default", ACLPacket{Src: bob, Dst: alice, Proto: 17, Port: 53, HasPort: true}, -1, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, accept := acl.Match(tc.packet)
+			assert.Equal(t, tc.rule, rule)
+			assert.Equal(t, tc.accept, accept)
+		})
+	}
+	assert.Equal(t, ACLDeny, acl.RuleAction(-1))
+	assert.Equal(t, ACLAccept, acl.RuleAction(0))
+
+	cfg.ACL = nil
+	acl, err = cfg.CompileACL()
+	assert.NoError(t, err)
+	assert.Nil(t, acl)
+}
+
+func TestPortRangeYAML(t *testing.T) {
+	var rule ACLRule
+	require.NoError(t, yaml.Unmarshal([]byte("action: accept\nports: [443, \"8000-8100\"]\n"), &rule))
+	assert.Equal(t, []PortRange{{443, 443}, {8000, 8100}}, rule.Ports)
+
+	out, err := yaml.Marshal(rule)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "- 443\n")
+	assert.Contains(t, string(out), "- 8000-8100\n")
+
+	_, err = ParsePortRange("100-10")
+	assert.Error(t, err)
+}
@@ -0,0 +1,242 @@
+package state
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// CapturePacket is the view of a packet that capture filters are matched against
+type CapturePacket struct {
+	Src, Dst         netip.Addr
+	Proto            uint8
+	SrcPort, DstPort uint16
+	HasPorts         bool
+	// Incoming is set for packets received from a peer, otherwise the packet was sent by this host
+	Incoming         bool
+	FromPeer, ToPeer NodeId
+	Action           string // forward, bounce or drop
+}
+
+// CaptureFilter selects the packets of a capture
+type CaptureFilter func(p *CapturePacket) bool
+
+// ParseCaptureFilter compiles a capture filter expression. The syntax follows tcpdump, primitives are combined with
+// and, or, not and parentheses:
+//
+//	[src|dst] host ADDR, [src|dst] net PREFIX, [src|dst] port PORT[-PORT], or a bare ADDR or PREFIX
+//	tcp, udp, sctp, icmp, proto NAME|NUMBER, ip, ip6
+//	peer NODE, in, out, action forward|bounce|drop
+//
+// An empty expression matches every packet.
+func ParseCaptureFilter(expr string) (CaptureFilter, error) {
+	p := &captureParser{tokens: tokenizeCaptureFilter(expr)}
+	if len(p.tokens) == 0 {
+		return func(*CapturePacket) bool { return true }, nil
+	}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok != "" {
+		return nil, fmt.Errorf("unexpected %q in capture filter", tok)
+	}
+	return filter, nil
+}
+
+func tokenizeCaptureFilter(expr string) []string {
+	for _, op := range []string{"(", ")", "&&", "||", "!"} {
+		expr = strings.ReplaceAll(expr, op, " "+op+" ")
+	}
+	return strings.Fields(strings.ToLower(expr))
+}
+
+type captureParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *captureParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *captureParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.pos++
+	}
+	return tok
+}
+
+// operand returns the argument of a primitive
+func (p *captureParser) operand(primitive string) (string, error) {
+	tok := p.next()
+	switch tok {
+	case "", "(", ")", "and", "&&", "or", "||", "not", "!":
+		return "", fmt.Errorf("%s in capture filter needs an argument", primitive)
+	}
+	return tok, nil
+}
+
+func (p *captureParser) parseOr() (CaptureFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" || p.peek() == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(pkt *CapturePacket) bool { return l(pkt) || right(pkt) }
+	}
+	return left, nil
+}
+
+func (p *captureParser) parseAnd() (CaptureFilter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" || p.peek() == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(pkt *CapturePacket) bool { return l(pkt) && right(pkt) }
+	}
+	return left, nil
+}
+
+func (p *captureParser) parseUnary() (CaptureFilter, error) {
+	switch p.peek() {
+	case "not", "!":
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(pkt *CapturePacket) bool { return !inner(pkt) }, nil
+	case "(":
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing ) in capture filter")
+		}
+		return inner, nil
+	}
+	return p.parsePrimitive()
+}
+
+func (p *captureParser) parsePrimitive() (CaptureFilter, error) {
+	tok := p.next()
+	// src and dst qualify the address and port primitives
+	src, dst := true, true
+	switch tok {
+	case "src":
+		dst = false
+		tok = p.next()
+	case "dst":
+		src = false
+		tok = p.next()
+	}
+	qualified := !src || !dst
+
+	switch tok {
+	case "host", "net":
+		arg, err := p.operand(tok)
+		if err != nil {
+			return nil, err
+		}
+		return captureAddrFilter(arg, src, dst)
+	case "port":
+		arg, err := p.operand(tok)
+		if err != nil {
+			return nil, err
+		}
+		pr, err := ParsePortRange(arg)
+		if err != nil {
+			return nil, err
+		}
+		return func(pkt *CapturePacket) bool {
+			return pkt.HasPorts && (src && pr.Contains(pkt.SrcPort) || dst && pr.Contains(pkt.DstPort))
+		}, nil
+	}
+	if qualified {
+		// a bare address or prefix
+		return captureAddrFilter(tok, src, dst)
+	}
+
+	switch tok {
+	case "":
+		return nil, fmt.Errorf("capture filter ends unexpectedly")
+	case "ip":
+		return func(pkt *CapturePacket) bool { return pkt.Dst.Is4() }, nil
+	case "ip6":
+		return func(pkt *CapturePacket) bool { return pkt.Dst.Is6() }, nil
+	case "in":
+		return func(pkt *CapturePacket) bool { return pkt.Incoming }, nil
+	case "out":
+		return func(pkt *CapturePacket) bool { return !pkt.Incoming }, nil
+	case "peer":
+		arg, err := p.operand(tok)
+		if err != nil {
+			return nil, err
+		}
+		id := NodeId(arg)
+		return func(pkt *CapturePacket) bool { return pkt.FromPeer == id || pkt.ToPeer == id }, nil
+	case "action":
+		arg, err := p.operand(tok)
+		if err != nil {
+			return nil, err
+		}
+		if arg != "forward" && arg != "bounce" && arg != "drop" {
+			return nil, fmt.Errorf("unknown capture action %q, expected forward, bounce or drop", arg)
+		}
+		return func(pkt *CapturePacket) bool { return pkt.Action == arg }, nil
+	case "proto":
+		arg, err := p.operand(tok)
+		if err != nil {
+			return nil, err
+		}
+		return captureProtoFilter(arg)
+	}
+	if _, ok := protocolNames[tok]; ok {
+		return captureProtoFilter(tok)
+	}
+	return captureAddrFilter(tok, src, dst)
+}
+
+func captureProtoFilter(arg string) (CaptureFilter, error) {
+	protos, err := parseProtocol(arg)
+	if err != nil {
+		return nil, err
+	}
+	return func(pkt *CapturePacket) bool { return slices.Contains(protos, pkt.Proto) }, nil
+}
+
+func captureAddrFilter(arg string, src, dst bool) (CaptureFilter, error) {
+	prefix, err := netip.ParsePrefix(arg)
+	if err != nil {
+		addr, aerr := netip.ParseAddr(arg)
+		if aerr != nil {
+			return nil, fmt.Errorf("unknown capture filter primitive %q", arg)
+		}
+		prefix = AddrToPrefix(addr)
+	}
+	prefix = prefix.Masked()
+	return func(pkt *CapturePacket) bool {
+		return src && prefix.Contains(pkt.Src) || dst && prefix.Contains(pkt.Dst)
+	}, nil
+}
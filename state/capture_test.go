@@ -0,0 +1,46 @@
+package state
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCaptureFilter(t *testing.T) {
+	ssh := &CapturePacket{
+		Src: netip.MustParseAddr("10.0.0.1"), Dst: netip.MustParseAddr("10.0.1.7"),
+		Proto: 6, SrcPort: 40000, DstPort: 22, HasPorts: true,
+		Incoming: true, FromPeer: "bob", ToPeer: "carol", Action: "forward",
+	}
+	for expr, want := range map[string]bool{
+		"":                               true,
+		"tcp port 22":                    false, // primitives must be joined with and
+		"tcp and port 22":                true,
+		"udp or icmp":                    false,
+		"proto 6 && dst port 22":         true,
+		"src port 22":                    false,
+		"host 10.0.0.1 and peer bob":     true,
+		"dst host 10.0.0.1":              false,
+		"net 10.0.1.0/24":                true,
+		"src 10.0.1.0/24":                false,
+		"10.0.0.0/16 and in":             true,
+		"!(peer carol or out)":           false,
+		"not action drop and port 10-30": true,
+		"ip6 or action bounce":           false,
+	} {
+		filter, err := ParseCaptureFilter(expr)
+		if expr == "tcp port 22" {
+			assert.Error(t, err, expr)
+			continue
+		}
+		require.NoError(t, err, expr)
+		assert.Equal(t, want, filter(ssh), expr)
+	}
+
+	for _, expr := range []string{"host", "port http", "(tcp", "action accept", "proto gre", "bogus", "tcp and"} {
+		_, err := ParseCaptureFilter(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net/netip"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/goccy/go-yaml"
 	"go4.org/netipx"
@@ -16,13 +18,75 @@ type NodeCfg struct {
 	PubKey    NyPublicKey
 	Addresses []netip.Addr          `yaml:",omitempty"`
 	Prefixes  []PrefixHealthWrapper `yaml:",omitempty"`
+	Tags      []string              `yaml:",omitempty"` // labels acl rules can select the node by
 }
 
 // RouterCfg represents a central representation of a node that can route
 type RouterCfg struct {
 	NodeCfg   `yaml:",inline"`
-	Endpoints []string
+	Endpoints []EndpointCfg
+	// Detect enables fast failure detection on every link to this router that does not set its own
+	Detect *DetectCfg `yaml:"detect,omitempty"`
 }
+
+// Endpoint returns the endpoint of the router with the given address
+func (r RouterCfg) Endpoint(address string) (EndpointCfg, bool) {
+	idx := slices.IndexFunc(r.Endpoints, func(ep EndpointCfg) bool {
+		return ep.Address == address
+	})
+	if idx == -1 {
+		return EndpointCfg{}, false
+	}
+	return r.Endpoints[idx], true
+}
+
+// EndpointClass orders the endpoints of a router, backup endpoints are only used while no primary endpoint is active
+type EndpointClass string
+
+const (
+	EndpointPrimary EndpointClass = "primary"
+	EndpointBackup  EndpointClass = "backup"
+)
+
+// EndpointAttrs are the attributes of an endpoint of a router
+type EndpointAttrs struct {
+	Class   EndpointClass `yaml:"class,omitempty"`   // defaults to primary
+	Metered bool          `yaml:"metered,omitempty"` // data is expensive, the endpoint is penalized and probed less often
+}
+
+func (a EndpointAttrs) IsBackup() bool {
+	return a.Class == EndpointBackup
+}
+
+// EndpointCfg is an endpoint of a router. It is written as a plain address, or as a mapping if it has attributes.
+type EndpointCfg struct {
+	Address       string `yaml:"address"`
+	EndpointAttrs `yaml:",inline"`
+}
+
+func (e EndpointCfg) MarshalYAML() (interface{}, error) {
+	if e.EndpointAttrs == (EndpointAttrs{}) {
+		return e.Address, nil
+	}
+	type plain EndpointCfg
+	return plain(e), nil
+}
+
+func (e *EndpointCfg) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var address string
+	if err := unmarshal(&address); err == nil {
+		*e = EndpointCfg{Address: address}
+		return nil
+	}
+	type plain EndpointCfg
+	var ep plain
+	if err := unmarshal(&ep); err != nil {
+		return err
+	}
+	*e = EndpointCfg(ep)
+	return nil
+}
+
 type ClientCfg struct {
 	NodeCfg `yaml:",inline"`
 }
@@ -37,6 +101,56 @@ type LocalDistributionCfg struct {
 	Url string
 }
 
+// LinkAttrs are the attributes of a link between two nodes, set in the graph
+type LinkAttrs struct {
+	Cost     uint32    // static penalty added to the measured metric of the link
+	Backup   bool      // only route through the link when no other link reaches the destination
+	Endpoint string    // endpoint preferred over the measured best endpoint while it is active
+	Detect   DetectCfg // fast failure detection, disabled if the interval is zero
+	Bond     BondMode  // spread traffic across every active endpoint of the link
+}
+
+// BondMode is how traffic to a neighbour is spread across the active endpoints of the link, weighted by their metrics
+type BondMode string
+
+const (
+	BondNone   BondMode = ""
+	BondFlow   BondMode = "flow"   // every packet of a flow goes through the same endpoint
+	BondPacket BondMode = "packet" // packets are striped across the endpoints, and may arrive out of order
+)
+
+const (
+	// DefaultDetectMultiplier is the number of liveness probes that can go unanswered before a link is declared dead
+	DefaultDetectMultiplier = 3
+	MinDetectInterval       = 10 * time.Millisecond
+)
+
+// DetectCfg configures BFD-like failure detection. Active endpoints of the link are sent a liveness probe every
+// Interval, and are declared dead once nothing was heard back for Multiplier intervals.
+type DetectCfg struct {
+	Interval   time.Duration `yaml:"interval"`
+	Multiplier int           `yaml:"multiplier,omitempty"` // defaults to DefaultDetectMultiplier
+}
+
+func (d DetectCfg) Enabled() bool {
+	return d.Interval > 0
+}
+
+// DeadThreshold is how long an endpoint stays active without hearing back from it
+func (d DetectCfg) DeadThreshold() time.Duration {
+	mult := d.Multiplier
+	if mult == 0 {
+		mult = DefaultDetectMultiplier
+	}
+	return d.Interval * time.Duration(mult)
+}
+
+// Edge is a link of the graph
+type Edge struct {
+	Pair[NodeId, NodeId]
+	LinkAttrs
+}
+
 type CentralCfg struct {
 	Dist       *DistributionCfg `yaml:",omitempty"`
 	Routers    []RouterCfg
@@ -44,6 +158,17 @@ type CentralCfg struct {
 	Graph      []string
 	Timestamp  int64
 	ExcludeIPs []netip.Prefix `yaml:"exclude_ips,omitempty"` // split tunnel, default excluded ip ranges for the whole network, if empty, all advertised prefixes will be included
+	Policies   []PolicyCfg    `yaml:"policies,omitempty"`    // route import/export policies, evaluated by the nodes they apply to
+	// SignedRoutes requires every route to carry a signature of its origin, so a transit router cannot forge the origin or seqno
+	SignedRoutes bool `yaml:"signed_routes,omitempty"`
+	// Dampening suppresses routes from sources that flap repeatedly, nil disables dampening
+	Dampening *DampeningCfg `yaml:"dampening,omitempty"`
+	// Duplicate selects real-time traffic that is sent over two paths at once
+	Duplicate []DuplicateRule `yaml:"duplicate,omitempty"`
+	// ACL restricts the traffic nodes may send through the mesh, nil accepts all traffic
+	ACL *ACLCfg `yaml:"acl,omitempty"`
+	// QoS assigns data packets to priority classes, nil sends every data packet at normal priority
+	QoS *QoSCfg `yaml:"qos,omitempty"`
 }
 
 // LocalCfg represents local node-level configuration
@@ -58,15 +183,46 @@ type LocalCfg struct {
 	DnsResolvers      []string              `yaml:"dns_resolvers,omitempty"`      // DNS resolvers used for endpoints and config repositories
 	InterfaceName     string                `yaml:"interface_name,omitempty"`     // the name of the nylon interface
 	LogPath           string                `yaml:"log_path,omitempty"`           // if not empty, nylon will write to this file
+	StatePath         string                `yaml:"state_path,omitempty"`         // file that seqnos are kept in across restarts, defaults to nylon.state next to the node config
 	ObservabilityAddr string                `yaml:"observability_addr,omitempty"` // HTTP address for metrics, health, readiness, and service discovery
+	ServeStatus       bool                  `yaml:"serve_status,omitempty"`       // serve the full status snapshot on /status of the observability address, it exposes peers, endpoints, keys and routes
+	MultipathBand     float64               `yaml:"multipath_band,omitempty"`     // if >= 1, spread flows across feasible next hops with metric <= best * multipath_band
+	Babel             []BabelInterfaceCfg   `yaml:"babel,omitempty"`              // interfaces to exchange routes with standard Babel routers on
+	BGP               *BGPCfg               `yaml:"bgp,omitempty"`                // BGP speaker announcing mesh routes to external peers
 	UnexcludeIPs      []netip.Prefix        `yaml:"unexclude_ips,omitempty"`      // split tunnel, subtracts from centrally excluded ip ranges
 	ExcludeIPs        []netip.Prefix        `yaml:"exclude_ips,omitempty"`        // split tunnel, adds to the centrally excluded ip ranges
+	PortForwards      []PortForward         `yaml:"port_forwards,omitempty"`      // ports of this node forwarded to other hosts
 	PreUp             []string              `yaml:"pre_up,omitempty"`             // a list of commands executed in order before the nylon interface is brought up
 	PreDown           []string              `yaml:"pre_down,omitempty"`           // a list of commands executed in order before the nylon interface is brought down
 	PostUp            []string              `yaml:"post_up,omitempty"`            // a list of commands executed in order after the nylon interface is brought up
 	PostDown          []string              `yaml:"post_down,omitempty"`          // a list of commands executed in order after the nylon interface is brought down
 }
 
+// BabelInterfaceCfg enables the RFC 8966 wire format on a physical interface. Mesh routes are announced to the Babel
+// routers on the interface, and their routes can be imported into the mesh with babel prefixes.
+type BabelInterfaceCfg struct {
+	Interface   string `yaml:"interface"`
+	Cost        uint16 `yaml:"cost,omitempty"`         // cost of the link to babel neighbours, defaults to 96
+	MetricScale uint32 `yaml:"metric_scale,omitempty"` // nylon metric (microseconds) per babel metric unit, defaults to 100
+}
+
+// BGPCfg configures the built-in BGP speaker. Selected mesh routes are announced to every peer with the local node as
+// the next hop, and routes received from peers can be imported into the mesh with bgp prefixes.
+type BGPCfg struct {
+	ASN      uint32       `yaml:"asn"`
+	RouterId netip.Addr   `yaml:"router_id,omitempty"` // IPv4 BGP identifier, derived from the node key if unset
+	Listen   string       `yaml:"listen,omitempty"`    // address to accept connections from passive peers on, e.g. ":179"
+	Peers    []BGPPeerCfg `yaml:"peers"`
+}
+
+type BGPPeerCfg struct {
+	Addr     netip.Addr   `yaml:"addr"`
+	Port     uint16       `yaml:"port,omitempty"`      // defaults to 179
+	ASN      uint32       `yaml:"asn"`                 // the session is iBGP if this is the local asn
+	Passive  bool         `yaml:"passive,omitempty"`   // wait for the peer to connect instead of connecting to it
+	NextHops []netip.Addr `yaml:"next_hops,omitempty"` // next hop per address family, defaults to the session's local address or the node's addresses
+}
+
 func (c *CentralCfg) Clone() (error, *CentralCfg) {
 	data, err := yaml.Marshal(c)
 	if err != nil {
@@ -80,25 +236,25 @@ func (c *CentralCfg) Clone() (error, *CentralCfg) {
 }
 
 // GetPrefixes returns all unique prefixes from all nodes
-func (c *CentralCfg) GetPrefixes() []netip.Prefix {
-	prefixMap := make(map[netip.Prefix]bool)
+func (c *CentralCfg) GetPrefixes() []RoutePrefix {
+	prefixMap := make(map[RoutePrefix]bool)
 
 	// Collect from routers
 	for _, router := range c.Routers {
 		for _, prefix := range router.Prefixes {
-			prefixMap[prefix.GetPrefix()] = true
+			prefixMap[prefix.GetRoutePrefix()] = true
 		}
 	}
 
 	// Collect from clients
 	for _, client := range c.Clients {
 		for _, prefix := range client.Prefixes {
-			prefixMap[prefix.GetPrefix()] = true
+			prefixMap[prefix.GetRoutePrefix()] = true
 		}
 	}
 
 	// Convert to slice
-	prefixes := make([]netip.Prefix, 0, len(prefixMap))
+	prefixes := make([]RoutePrefix, 0, len(prefixMap))
 	for prefix := range prefixMap {
 		prefixes = append(prefixes, prefix)
 	}
@@ -106,6 +262,45 @@ func (c *CentralCfg) GetPrefixes() []netip.Prefix {
 	return prefixes
 }
 
+// GetOrigins returns the routers allowed to originate each prefix: the routers that advertise it,
+// and the routers peered with a passive client that advertises it.
+func (c *CentralCfg) GetOrigins() map[RoutePrefix][]NodeId {
+	origins := make(map[RoutePrefix][]NodeId)
+	for _, router := range c.Routers {
+		for _, prefix := range router.Prefixes {
+			origins[prefix.GetRoutePrefix()] = append(origins[prefix.GetRoutePrefix()], router.Id)
+		}
+	}
+	for _, client := range c.Clients {
+		for _, peer := range c.GetPeers(client.Id) {
+			if !c.IsRouter(peer) {
+				continue
+			}
+			for _, prefix := range client.Prefixes {
+				origins[prefix.GetRoutePrefix()] = append(origins[prefix.GetRoutePrefix()], peer)
+			}
+		}
+	}
+	for prefix, nodes := range origins {
+		slices.Sort(nodes)
+		origins[prefix] = slices.Compact(nodes)
+	}
+	return origins
+}
+
+// GetAnycastPrefixes returns the prefixes advertised with the anycast type by any router
+func (c *CentralCfg) GetAnycastPrefixes() map[RoutePrefix]struct{} {
+	anycast := make(map[RoutePrefix]struct{})
+	for _, router := range c.Routers {
+		for _, prefix := range router.Prefixes {
+			if _, ok := prefix.PrefixHealth.(*AnycastPrefixHealth); ok {
+				anycast[prefix.GetRoutePrefix()] = struct{}{}
+			}
+		}
+	}
+	return anycast
+}
+
 func (c *CentralCfg) GetNodes() []NodeCfg {
 	nodes := make([]NodeCfg, 0)
 	for _, n := range c.Routers {
@@ -162,13 +357,54 @@ Group1, Group1 // every node is connected to every other node
 
 node8, node9 // node8 and node9 will be connected
 
+node8, node10 [cost=5000, backup, endpoint=node10.example.com:57175] // attributes apply to every link of the line
+
 graph represents the above graph
 nodes represents a set of unique terminal nodes that the graph will evaluate down to
 */
 func ParseGraph(graph []string, nodes []string) ([]Pair[NodeId, NodeId], error) {
+	edges, _, err := parseGraph(graph, nodes)
+	if err != nil {
+		return nil, err
+	}
+	pairings := make([]Pair[NodeId, NodeId], 0, len(edges))
+	for _, edge := range edges {
+		pairings = append(pairings, edge.Pair)
+	}
+	return pairings, nil
+}
+
+// ParseGraphEdges is like ParseGraph, but also returns the attributes of each link.
+// If a link is declared by several lines with attributes, the last one wins.
+func ParseGraphEdges(graph []string, nodes []string) ([]Edge, error) {
+	edges, _, err := parseGraph(graph, nodes)
+	return edges, err
+}
+
+// ParseGroups returns the group definitions of the graph, expanded down to the nodes they contain.
+func ParseGroups(graph []string, nodes []string) (map[string][]NodeId, error) {
+	_, expansion, err := parseGraph(graph, nodes)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]NodeId, len(expansion))
+	for grp, members := range expansion {
+		for _, member := range members {
+			groups[grp] = append(groups[grp], NodeId(member))
+		}
+	}
+	return groups, nil
+}
+
+type parsedPairing struct {
+	Pair[string, string]
+	attrs *LinkAttrs
+}
+
+func parseGraph(graph []string, nodes []string) ([]Edge, map[string][]string, error) {
 	// why can't we just have unordered_set<Pair<NodeId, NodeId>> :(
 
-	parsedPairings := make([]Pair[string, string], 0)
+	parsedPairings := make([]parsedPairing, 0)
 
 	groups := make(map[string][]string)
 
@@ -177,16 +413,17 @@ func ParseGraph(graph []string, nodes []string) ([]Pair[NodeId, NodeId], error)
 	// pass 0, collect all symbols
 
 	for _, line := range graph {
+		line, _, _, _ = splitLinkAttrs(line) // reported in pass 1
 		line = strings.ToLower(strings.TrimSpace(line))
 		if strings.Contains(line, "=") {
 			// group definition
 			spl := strings.Split(line, "=")
 			if len(spl) != 2 {
-				return nil, fmt.Errorf("invalid graph: %s. group definition must contain one '='", line)
+				return nil, nil, fmt.Errorf("invalid graph: %s. group definition must contain one '='", line)
 			}
 			grp := strings.TrimSpace(spl[0])
 			if slices.Contains(nodes, grp) {
-				return nil, fmt.Errorf("invalid graph: group name must not be a node name: %s", grp)
+				return nil, nil, fmt.Errorf("invalid graph: group name must not be a node name: %s", grp)
 			}
 			symbols = append(symbols, grp)
 		}
@@ -201,16 +438,23 @@ func ParseGraph(graph []string, nodes []string) ([]Pair[NodeId, NodeId], error)
 
 	// pass 1, parse graph
 	for _, line := range graph {
+		line, rawAttrs, hasAttrs, err := splitLinkAttrs(line)
+		if err != nil {
+			return nil, nil, err
+		}
 		line = strings.ToLower(strings.TrimSpace(line))
 		if strings.Contains(line, "=") {
+			if hasAttrs {
+				return nil, nil, fmt.Errorf("invalid graph: %s. group definitions cannot have link attributes", line)
+			}
 			spl := strings.Split(line, "=")
 			grp := strings.TrimSpace(spl[0])
 			if _, ok := groups[grp]; ok {
-				return nil, fmt.Errorf("invalid graph: duplicate group name: %s", grp)
+				return nil, nil, fmt.Errorf("invalid graph: duplicate group name: %s", grp)
 			}
 			lst, err := parseSymbolList(spl[1], symbols)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			// track dependencies
 			deps := make([]string, 0)
@@ -230,20 +474,24 @@ func ParseGraph(graph []string, nodes []string) ([]Pair[NodeId, NodeId], error)
 		} else {
 			names, err := parseSymbolList(line, symbols)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if len(names) < 2 {
-				return nil, fmt.Errorf("invalid graph: invalid pairing, %v", names)
+				return nil, nil, fmt.Errorf("invalid graph: invalid pairing, %v", names)
+			}
+			var attrs *LinkAttrs
+			if hasAttrs {
+				if attrs, err = parseLinkAttrs(rawAttrs); err != nil {
+					return nil, nil, err
+				}
 			}
 			interconnectNodes := make([]NodeId, 0)
 			for _, name := range names {
 				for _, node := range interconnectNodes {
-					parsedPairings = append(parsedPairings, MakeSortedPair(string(node), name))
+					parsedPairings = append(parsedPairings, parsedPairing{MakeSortedPair(string(node), name), attrs})
 				}
 				interconnectNodes = append(interconnectNodes, NodeId(name))
 			}
-			SortPairs(parsedPairings)
-			parsedPairings = slices.Compact(parsedPairings)
 		}
 	}
 
@@ -264,7 +512,7 @@ func ParseGraph(graph []string, nodes []string) ([]Pair[NodeId, NodeId], error)
 				cycleNodes = append(cycleNodes, node)
 			}
 			slices.Sort(cycleNodes)
-			return nil, fmt.Errorf("invalid graph: cycle detected in graph: %v", cycleNodes)
+			return nil, nil, fmt.Errorf("invalid graph: cycle detected in graph: %v", cycleNodes)
 		}
 		delete(topo, group)
 
@@ -293,7 +541,7 @@ func ParseGraph(graph []string, nodes []string) ([]Pair[NodeId, NodeId], error)
 	}
 
 	// pass 3, rewrite pairings
-	pairings := make([]Pair[NodeId, NodeId], 0)
+	links := make(map[Pair[NodeId, NodeId]]LinkAttrs)
 	for _, pair := range parsedPairings {
 		x := make([]NodeId, 0)
 		if slices.Contains(nodes, pair.V1) {
@@ -313,15 +561,91 @@ func ParseGraph(graph []string, nodes []string) ([]Pair[NodeId, NodeId], error)
 		}
 		for _, x1 := range x {
 			for _, y1 := range y {
-				if x1 != y1 {
-					pairings = append(pairings, MakeSortedPair(x1, y1))
+				if x1 == y1 {
+					continue
+				}
+				link := MakeSortedPair(x1, y1)
+				if _, ok := links[link]; !ok || pair.attrs != nil {
+					// lines without attributes do not reset the attributes of a link
+					links[link] = *cmp.Or(pair.attrs, &LinkAttrs{})
 				}
 			}
 		}
-		SortPairs(pairings)
-		pairings = slices.Compact(pairings)
 	}
-	return pairings, nil
+	pairings := make([]Pair[NodeId, NodeId], 0, len(links))
+	for link := range links {
+		pairings = append(pairings, link)
+	}
+	SortPairs(pairings)
+	edges := make([]Edge, 0, len(pairings))
+	for _, link := range pairings {
+		edges = append(edges, Edge{Pair: link, LinkAttrs: links[link]})
+	}
+	return edges, expansion, nil
+}
+
+// splitLinkAttrs splits a graph line into its node list and its trailing [attribute] list.
+// The attributes are bracketed by the first '[' and the last ']', so they may contain IPv6 endpoints.
+func splitLinkAttrs(line string) (string, string, bool, error) {
+	start := strings.Index(line, "[")
+	if start == -1 {
+		return line, "", false, nil
+	}
+	end := strings.LastIndex(line, "]")
+	if end < start || strings.TrimSpace(line[end+1:]) != "" {
+		return "", "", false, fmt.Errorf("invalid graph: %s. link attributes must be enclosed in [] at the end of the line", line)
+	}
+	return line[:start], line[start+1 : end], true, nil
+}
+
+func parseLinkAttrs(raw string) (*LinkAttrs, error) {
+	attrs := &LinkAttrs{}
+	for _, attr := range strings.Split(raw, ",") {
+		attr = strings.TrimSpace(attr)
+		if attr == "" {
+			continue
+		}
+		key, value, hasValue := strings.Cut(attr, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch {
+		case key == "cost" && hasValue:
+			cost, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || uint32(cost) >= INFM {
+				return nil, fmt.Errorf("invalid graph: invalid link cost %s", value)
+			}
+			attrs.Cost = uint32(cost)
+		case key == "backup" && !hasValue:
+			attrs.Backup = true
+		case key == "endpoint" && hasValue && value != "":
+			attrs.Endpoint = value
+		case key == "detect" && hasValue:
+			interval, err := time.ParseDuration(value)
+			if err != nil || interval < MinDetectInterval {
+				return nil, fmt.Errorf("invalid graph: invalid detect interval %s, must be at least %s", value, MinDetectInterval)
+			}
+			attrs.Detect.Interval = interval
+		case key == "bond" && hasValue:
+			switch mode := BondMode(strings.ToLower(value)); mode {
+			case BondFlow, BondPacket:
+				attrs.Bond = mode
+			default:
+				return nil, fmt.Errorf("invalid graph: invalid bond mode %s, expected %s or %s", value, BondFlow, BondPacket)
+			}
+		case key == "detect_multiplier" && hasValue:
+			mult, err := strconv.Atoi(value)
+			if err != nil || mult < 1 {
+				return nil, fmt.Errorf("invalid graph: invalid detect multiplier %s", value)
+			}
+			attrs.Detect.Multiplier = mult
+		default:
+			return nil, fmt.Errorf("invalid graph: invalid link attribute %s", attr)
+		}
+	}
+	if attrs.Detect.Multiplier != 0 && !attrs.Detect.Enabled() {
+		return nil, fmt.Errorf("invalid graph: detect_multiplier requires a detect interval")
+	}
+	return attrs, nil
 }
 
 func MakeSortedPair[T cmp.Ordered](a, b T) Pair[T, T] {
@@ -360,6 +684,47 @@ func (e *CentralCfg) GetPeers(curId NodeId) []NodeId {
 	return nodes
 }
 
+// GetEdges returns the links of the graph along with their attributes
+func (e *CentralCfg) GetEdges() []Edge {
+	allNodes := make([]string, 0)
+	for _, node := range e.GetNodes() {
+		allNodes = append(allNodes, string(node.Id))
+	}
+	edges, err := ParseGraphEdges(e.Graph, allNodes)
+	if err != nil {
+		panic(err)
+	}
+	return edges
+}
+
+// GetLink returns the attributes of the link between a and b, out of the edges returned by GetEdges
+func (e *CentralCfg) GetLink(edges []Edge, a, b NodeId) LinkAttrs {
+	link := MakeSortedPair(a, b)
+	attrs := LinkAttrs{}
+	for _, edge := range edges {
+		if edge.Pair == link {
+			attrs = edge.LinkAttrs
+			break
+		}
+	}
+	if !attrs.Detect.Enabled() {
+		// fall back to the routers' settings, using the one that detects failures sooner
+		for _, id := range []NodeId{a, b} {
+			if !e.IsRouter(id) {
+				continue
+			}
+			detect := e.GetRouter(id).Detect
+			if detect == nil || !detect.Enabled() {
+				continue
+			}
+			if !attrs.Detect.Enabled() || detect.DeadThreshold() < attrs.Detect.DeadThreshold() {
+				attrs.Detect = *detect
+			}
+		}
+	}
+	return attrs
+}
+
 func (e *CentralCfg) FindNodeBy(pkey NyPublicKey) *NodeId {
 	for _, n := range e.Routers {
 		if n.PubKey == pkey {
@@ -400,7 +765,7 @@ func expandNodeAddresses(node *NodeCfg) {
 			Prefix: prefix,
 			Metric: 0,
 		}
-		node.Prefixes = append([]PrefixHealthWrapper{{&advAddress}}, node.Prefixes...)
+		node.Prefixes = append([]PrefixHealthWrapper{{PrefixHealth: &advAddress}}, node.Prefixes...)
 		prefixes[prefix] = struct{}{}
 	}
 }
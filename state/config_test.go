@@ -4,6 +4,7 @@ import (
 	"net/netip"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -151,6 +152,89 @@ func TestParseGraph_InvalidGraph(t *testing.T) {
 	failGraph(t, `a=a`)
 }
 
+func TestParseGraphEdges_Attributes(t *testing.T) {
+	nodes := []string{"1", "2", "3", "4"}
+	input := `a = 1,2
+a, 3
+1, 3 [cost=500, backup]
+a, 4 [endpoint=[2001:DB8::1]:57175]
+2, 4 [cost = 10]
+2, 4
+3, 4 [bond=Packet]`
+	edges, err := ParseGraphEdges(strings.Split(input, "\n"), nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, []Edge{
+		{Pair: Pair[NodeId, NodeId]{"1", "3"}, LinkAttrs: LinkAttrs{Cost: 500, Backup: true}},
+		{Pair: Pair[NodeId, NodeId]{"1", "4"}, LinkAttrs: LinkAttrs{Endpoint: "[2001:DB8::1]:57175"}},
+		{Pair: Pair[NodeId, NodeId]{"2", "3"}},
+		// the later line without attributes keeps the cost
+		{Pair: Pair[NodeId, NodeId]{"2", "4"}, LinkAttrs: LinkAttrs{Cost: 10}},
+		{Pair: Pair[NodeId, NodeId]{"3", "4"}, LinkAttrs: LinkAttrs{Bond: BondPacket}},
+	}, edges)
+
+	cfg := CentralCfg{
+		Routers: []RouterCfg{{NodeCfg: NodeCfg{Id: "1"}}, {NodeCfg: NodeCfg{Id: "3"}}},
+		Graph:   []string{"1, 3 [cost=500, backup]"},
+	}
+	edges = cfg.GetEdges()
+	assert.Equal(t, LinkAttrs{Cost: 500, Backup: true}, cfg.GetLink(edges, "3", "1"))
+
+	failGraph(t, `1, 2 [cost=-1]`)
+	failGraph(t, `1, 2 [cost]`)
+	failGraph(t, `1, 2 [fast]`)
+	failGraph(t, `1, 2 [backup=yes]`)
+	failGraph(t, `1, 2 [cost=1`)
+	failGraph(t, `1, 2 [cost=1] 3`)
+	failGraph(t, `a = 1, 2 [cost=1]`)
+	failGraph(t, `1, 2 [detect=1ms]`)
+	failGraph(t, `1, 2 [detect_multiplier=3]`)
+	failGraph(t, `1, 2 [detect=50ms, detect_multiplier=0]`)
+	failGraph(t, `1, 2 [bond=round-robin]`)
+	failGraph(t, `1, 2 [bond]`)
+}
+
+func TestGetLink_Detect(t *testing.T) {
+	cfg := CentralCfg{
+		Routers: []RouterCfg{
+			{NodeCfg: NodeCfg{Id: "1"}, Detect: &DetectCfg{Interval: 100 * time.Millisecond}},
+			{NodeCfg: NodeCfg{Id: "2"}, Detect: &DetectCfg{Interval: 50 * time.Millisecond, Multiplier: 4}},
+			{NodeCfg: NodeCfg{Id: "3"}},
+			{NodeCfg: NodeCfg{Id: "4"}},
+		},
+		Graph: []string{"1, 2", "1, 3", "3, 4", "2, 3 [detect=20ms, detect_multiplier=5]"},
+	}
+	edges := cfg.GetEdges()
+	// the router that detects failures sooner wins
+	assert.Equal(t, DetectCfg{Interval: 50 * time.Millisecond, Multiplier: 4}, cfg.GetLink(edges, "1", "2").Detect)
+	assert.Equal(t, 200*time.Millisecond, cfg.GetLink(edges, "1", "2").Detect.DeadThreshold())
+	assert.Equal(t, DetectCfg{Interval: 100 * time.Millisecond}, cfg.GetLink(edges, "3", "1").Detect)
+	// the graph overrides the routers
+	assert.Equal(t, DetectCfg{Interval: 20 * time.Millisecond, Multiplier: 5}, cfg.GetLink(edges, "2", "3").Detect)
+	assert.False(t, cfg.GetLink(edges, "3", "4").Detect.Enabled())
+}
+
+func TestGetOrigins(t *testing.T) {
+	static := func(prefix string) PrefixHealthWrapper {
+		return PrefixHealthWrapper{PrefixHealth: &StaticPrefixHealth{Prefix: netip.MustParsePrefix(prefix)}}
+	}
+	cfg := CentralCfg{
+		Routers: []RouterCfg{
+			{NodeCfg: NodeCfg{Id: "a", Prefixes: []PrefixHealthWrapper{static("10.0.0.0/24"), static("10.9.0.0/24")}}},
+			{NodeCfg: NodeCfg{Id: "b", Prefixes: []PrefixHealthWrapper{static("10.9.0.0/24")}}},
+			{NodeCfg: NodeCfg{Id: "c"}},
+		},
+		Clients: []ClientCfg{
+			{NodeCfg: NodeCfg{Id: "phone", Prefixes: []PrefixHealthWrapper{static("10.5.0.1/32")}}},
+		},
+		Graph: []string{"a, b, c", "phone, b", "phone, c"},
+	}
+	assert.Equal(t, map[RoutePrefix][]NodeId{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/24")}: {"a"},
+		{Prefix: netip.MustParsePrefix("10.9.0.0/24")}: {"a", "b"},
+		{Prefix: netip.MustParsePrefix("10.5.0.1/32")}: {"b", "c"},
+	}, cfg.GetOrigins())
+}
+
 func TestExpandCentralConfigIsIdempotent(t *testing.T) {
 	addr := netip.MustParseAddr("192.0.2.1")
 	cfg := CentralCfg{
@@ -1,7 +1,10 @@
 package state
 
 import (
+	"crypto"
 	"crypto/rand"
+	"encoding/binary"
+
 	"github.com/encodeous/nylon/polyamide/device"
 	"go.step.sm/crypto/x25519"
 )
@@ -31,3 +34,29 @@ func (k NyPrivateKey) Pubkey() NyPublicKey {
 	}
 	return NyPublicKey(val)
 }
+
+// routeSigningPayload encodes the parts of a route that only its origin may choose.
+// The metric is not covered, since every hop rewrites it.
+func routeSigningPayload(src Source, seqno uint16) []byte {
+	prefix, _ := src.Prefix.MarshalBinary()
+	srcPrefix, _ := src.Src.MarshalBinary()
+	buf := []byte("nylon-route-v1")
+	buf = append(buf, byte(len(src.NodeId)))
+	buf = append(buf, src.NodeId...)
+	buf = append(buf, byte(len(prefix)))
+	buf = append(buf, prefix...)
+	buf = append(buf, byte(len(srcPrefix)))
+	buf = append(buf, srcPrefix...)
+	return binary.BigEndian.AppendUint16(buf, seqno)
+}
+
+// SignRoute signs the origination of src at seqno with the origin's node key. The signature stays valid when the
+// seqno wraps around, so it only proves the origin, not that the update is recent.
+func SignRoute(key NyPrivateKey, src Source, seqno uint16) ([]byte, error) {
+	return x25519.PrivateKey(key[:]).Sign(rand.Reader, routeSigningPayload(src, seqno), crypto.Hash(0))
+}
+
+// VerifyRoute checks that sig was produced by SignRoute with the private key of key.
+func VerifyRoute(key NyPublicKey, src Source, seqno uint16, sig []byte) bool {
+	return len(sig) == x25519.SignatureSize && x25519.Verify(key[:], routeSigningPayload(src, seqno), sig)
+}
@@ -1,8 +1,10 @@
 package state
 
 import (
-	"github.com/stretchr/testify/assert"
+	"net/netip"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestPubkey(t *testing.T) {
@@ -24,3 +26,24 @@ func TestGenerateKey(t *testing.T) {
 	_, err := pub.MarshalText()
 	assert.NoError(t, err)
 }
+
+func TestSignRoute(t *testing.T) {
+	key := GenerateKey()
+	src := Source{
+		NodeId:      "db",
+		RoutePrefix: RoutePrefix{Prefix: netip.MustParsePrefix("10.9.0.0/24")},
+	}
+	sig, err := SignRoute(key, src, 7)
+	assert.NoError(t, err)
+	assert.True(t, VerifyRoute(key.Pubkey(), src, 7, sig))
+
+	assert.False(t, VerifyRoute(key.Pubkey(), src, 8, sig))
+	assert.False(t, VerifyRoute(GenerateKey().Pubkey(), src, 7, sig))
+	assert.False(t, VerifyRoute(key.Pubkey(), src, 7, nil))
+	forged := src
+	forged.NodeId = "leaf"
+	assert.False(t, VerifyRoute(key.Pubkey(), forged, 7, sig))
+	forged = src
+	forged.Src = netip.MustParsePrefix("10.1.0.0/16")
+	assert.False(t, VerifyRoute(key.Pubkey(), forged, 7, sig))
+}
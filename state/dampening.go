@@ -0,0 +1,191 @@
+package state
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DampeningRetractPenalty is added when a source becomes unreachable
+	DampeningRetractPenalty = 1000
+	// DampeningChangePenalty is added when the metric of a source changes by more than LargeChangeThreshold
+	DampeningChangePenalty = 500
+)
+
+// DampeningCfg enables RFC 2439 style route flap dampening. Every router tracks a penalty per source that decays
+// exponentially, and ignores the source while the penalty is above the suppress threshold.
+type DampeningCfg struct {
+	HalfLife    time.Duration `yaml:"half_life,omitempty"`    // time for the penalty to decay by half, defaults to 15m
+	Suppress    float64       `yaml:"suppress,omitempty"`     // penalty above which a source is suppressed, defaults to 2000
+	Reuse       float64       `yaml:"reuse,omitempty"`        // penalty below which a suppressed source is used again, defaults to 750
+	MaxSuppress time.Duration `yaml:"max_suppress,omitempty"` // the longest a source can stay suppressed once it is stable, defaults to 1h
+}
+
+func (c DampeningCfg) withDefaults() DampeningCfg {
+	if c.HalfLife == 0 {
+		c.HalfLife = 15 * time.Minute
+	}
+	if c.Suppress == 0 {
+		c.Suppress = 2000
+	}
+	if c.Reuse == 0 {
+		c.Reuse = 750
+	}
+	if c.MaxSuppress == 0 {
+		c.MaxSuppress = time.Hour
+	}
+	return c
+}
+
+type DampeningEntry struct {
+	Penalty    float64
+	Updated    time.Time // when Penalty was last decayed
+	Suppressed bool
+	Metric     uint32 // the metric last observed for the source
+}
+
+// Dampener holds the flap penalties of every source seen by a router.
+type Dampener struct {
+	cfg     DampeningCfg
+	ceiling float64
+	Entries map[Source]*DampeningEntry
+	// offers holds the metrics last advertised for each source, by the neighbours that were heard at the time
+	offers map[Source]map[NodeId]uint32
+}
+
+func NewDampener(cfg DampeningCfg) *Dampener {
+	cfg = cfg.withDefaults()
+	return &Dampener{
+		cfg: cfg,
+		// RFC 2439 section 4.8.4, the penalty never exceeds what decays to the reuse threshold within MaxSuppress
+		ceiling: cfg.Reuse * math.Exp2(float64(cfg.MaxSuppress)/float64(cfg.HalfLife)),
+		Entries: make(map[Source]*DampeningEntry),
+		offers:  make(map[Source]map[NodeId]uint32),
+	}
+}
+
+// SameConfig reports whether the dampener was created from cfg
+func (d *Dampener) SameConfig(cfg DampeningCfg) bool {
+	return d != nil && d.cfg == cfg.withDefaults()
+}
+
+func (d *Dampener) decay(e *DampeningEntry, now time.Time) {
+	if elapsed := now.Sub(e.Updated); elapsed > 0 {
+		e.Penalty *= math.Exp2(-float64(elapsed) / float64(d.cfg.HalfLife))
+	}
+	e.Updated = now
+}
+
+// Observe records the current metric of a source, penalising retractions and large metric changes.
+// It returns whether the source is suppressed. A nil Dampener never suppresses anything.
+func (d *Dampener) Observe(src Source, metric uint32, largeChange uint32, now time.Time) bool {
+	if d == nil {
+		return false
+	}
+	from := metric
+	if e, ok := d.Entries[src]; ok {
+		from = e.Metric
+	}
+	return d.observeChange(src, from, metric, largeChange, now)
+}
+
+// observeChange records that the metric of a source changed from one value to another, penalising retractions and
+// large changes, and returns whether the source is suppressed. A source without an entry starts without a penalty.
+func (d *Dampener) observeChange(src Source, from, to uint32, largeChange uint32, now time.Time) bool {
+	e, ok := d.Entries[src]
+	if !ok {
+		if to == INF {
+			return false
+		}
+		e = &DampeningEntry{Updated: now, Metric: to}
+		d.Entries[src] = e
+		from = to
+	}
+	d.decay(e, now)
+	if from != INF && to == INF {
+		e.Penalty += DampeningRetractPenalty
+	} else if from != INF && to != INF && max(from, to)-min(from, to) > largeChange {
+		e.Penalty += DampeningChangePenalty
+	}
+	e.Penalty = min(e.Penalty, d.ceiling)
+	e.Metric = to
+
+	if e.Penalty > d.cfg.Suppress {
+		e.Suppressed = true
+	} else if e.Penalty < d.cfg.Reuse {
+		e.Suppressed = false
+	}
+	if !e.Suppressed && to == INF && e.Penalty < 1 {
+		// nothing left to remember about this source
+		delete(d.Entries, src)
+	}
+	return e.Suppressed
+}
+
+// ObserveAdvertised records the metrics advertised for every source, keyed by the neighbour that advertised them, and
+// returns the sources that are suppressed. heard lists the neighbours that currently have a link, a source they do not
+// advertise counts as retracted by them. Only the neighbours heard in both this and the previous observation are
+// compared, and their metrics exclude the cost of our link to them, so gaining or losing a link, or a change of its
+// cost, is not penalised as a flap of the sources behind it.
+func (d *Dampener) ObserveAdvertised(heard []NodeId, advertised map[Source]map[NodeId]uint32, largeChange uint32, now time.Time) map[Source]bool {
+	if d == nil {
+		return nil
+	}
+	sources := make(map[Source]struct{}, len(advertised))
+	for src := range advertised {
+		sources[src] = struct{}{}
+	}
+	for src := range d.offers {
+		sources[src] = struct{}{}
+	}
+	for src := range d.Entries {
+		sources[src] = struct{}{}
+	}
+	suppressed := make(map[Source]bool)
+	for src := range sources {
+		prev := d.offers[src]
+		offers := make(map[NodeId]uint32, len(heard))
+		best, oldBest, newBest, compared := INF, INF, INF, false
+		for _, id := range heard {
+			metric, ok := advertised[src][id]
+			if !ok {
+				metric = INF
+			}
+			offers[id] = metric
+			best = min(best, metric)
+			if old, ok := prev[id]; ok {
+				compared = true
+				oldBest = min(oldBest, old)
+				newBest = min(newBest, metric)
+			}
+		}
+		from, to := best, best
+		if compared {
+			from, to = oldBest, newBest
+		} else if e, ok := d.Entries[src]; ok {
+			// nobody we heard before can tell whether the source changed
+			from, to = e.Metric, e.Metric
+		}
+		if d.observeChange(src, from, to, largeChange, now) {
+			suppressed[src] = true
+		}
+		if _, ok := d.Entries[src]; !ok && best == INF {
+			delete(d.offers, src)
+		} else {
+			d.offers[src] = offers
+		}
+	}
+	return suppressed
+}
+
+// ReuseIn returns how long until a suppressed source is used again, assuming it stays stable
+func (d *Dampener) ReuseIn(e *DampeningEntry, now time.Time) time.Duration {
+	if !e.Suppressed {
+		return 0
+	}
+	penalty := e.Penalty * math.Exp2(-float64(now.Sub(e.Updated))/float64(d.cfg.HalfLife))
+	if penalty <= d.cfg.Reuse {
+		return 0
+	}
+	return time.Duration(float64(d.cfg.HalfLife) * math.Log2(penalty/d.cfg.Reuse))
+}
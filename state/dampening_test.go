@@ -0,0 +1,81 @@
+package state
+
+import (
+	"math"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDampenerPenaltyDecaysWithHysteresis(t *testing.T) {
+	d := NewDampener(DampeningCfg{HalfLife: time.Minute, MaxSuppress: 4 * time.Minute})
+	src := Source{NodeId: "a", RoutePrefix: RoutePrefix{Prefix: netip.MustParsePrefix("10.0.0.0/24")}}
+	now := time.Unix(0, 0)
+
+	// sources that were never reachable are not tracked
+	assert.False(t, d.Observe(src, INF, 100, now))
+	assert.Empty(t, d.Entries)
+
+	assert.False(t, d.Observe(src, 10, 100, now))
+	assert.False(t, d.Observe(src, INF, 100, now))
+	assert.Equal(t, float64(DampeningRetractPenalty), d.Entries[src].Penalty)
+	assert.False(t, d.Observe(src, 10, 100, now))
+	assert.False(t, d.Observe(src, INF, 100, now))
+	assert.False(t, d.Observe(src, 10, 100, now))
+	// a metric change within the threshold is not penalised, a large one is
+	assert.False(t, d.Observe(src, 50, 100, now))
+	assert.True(t, d.Observe(src, 500, 100, now))
+	assert.Equal(t, float64(2*DampeningRetractPenalty+DampeningChangePenalty), d.Entries[src].Penalty)
+
+	// the penalty halves every half life, and the source is reused once it drops below the reuse threshold
+	assert.InDelta(t, float64(time.Minute)*math.Log2(2500.0/750), float64(d.ReuseIn(d.Entries[src], now)), float64(time.Millisecond))
+	assert.True(t, d.Observe(src, 500, 100, now.Add(time.Minute)))
+	assert.InDelta(t, 1250, d.Entries[src].Penalty, 0.01)
+	assert.False(t, d.Observe(src, 500, 100, now.Add(2*time.Minute)))
+
+	// the penalty is capped so a source is never suppressed for longer than max_suppress
+	for range 100 {
+		d.Observe(src, INF, 100, now)
+		d.Observe(src, 10, 100, now)
+	}
+	assert.InDelta(t, 750*16, d.Entries[src].Penalty, 0.01)
+}
+
+func TestDampenerForgetsStableRetractedSources(t *testing.T) {
+	d := NewDampener(DampeningCfg{})
+	src := Source{NodeId: "a", RoutePrefix: RoutePrefix{Prefix: netip.MustParsePrefix("10.0.0.0/24")}}
+	now := time.Unix(0, 0)
+	d.Observe(src, 10, 100, now)
+	d.Observe(src, INF, 100, now)
+	d.Observe(src, INF, 100, now.Add(24*time.Hour))
+	assert.Empty(t, d.Entries)
+
+	var disabled *Dampener
+	assert.False(t, disabled.Observe(src, INF, 100, now))
+}
+
+func TestDampenerComparesNeighboursHeardTwice(t *testing.T) {
+	d := NewDampener(DampeningCfg{})
+	src := Source{NodeId: "x", RoutePrefix: RoutePrefix{Prefix: netip.MustParsePrefix("10.0.0.0/24")}}
+	now := time.Unix(0, 0)
+	adv := func(offers map[NodeId]uint32) map[Source]map[NodeId]uint32 {
+		return map[Source]map[NodeId]uint32{src: offers}
+	}
+
+	d.ObserveAdvertised([]NodeId{"a", "b"}, adv(map[NodeId]uint32{"a": 10, "b": 500}), 100, now)
+	// a is no longer heard, and then heard again without the source
+	d.ObserveAdvertised([]NodeId{"b"}, adv(map[NodeId]uint32{"b": 500}), 100, now)
+	d.ObserveAdvertised([]NodeId{"a", "b"}, adv(map[NodeId]uint32{"b": 500}), 100, now)
+	assert.Zero(t, d.Entries[src].Penalty)
+
+	// b retracts the source while it is heard
+	d.ObserveAdvertised([]NodeId{"a", "b"}, nil, 100, now)
+	assert.Equal(t, float64(DampeningRetractPenalty), d.Entries[src].Penalty)
+
+	// once the penalty has decayed, nothing is left of the source
+	d.ObserveAdvertised([]NodeId{"a", "b"}, nil, 100, now.Add(24*time.Hour))
+	assert.Empty(t, d.Entries)
+	assert.Empty(t, d.offers)
+}
@@ -25,7 +25,7 @@ func TestBundleUnbundle(t *testing.T) {
 				PubKey: NyPublicKey{},
 				Prefixes: []PrefixHealthWrapper{
 					{
-						&StaticPrefixHealth{
+						PrefixHealth: &StaticPrefixHealth{
 							Prefix: netip.MustParsePrefix("10.0.0.1/32"),
 							Metric: 0,
 						},
@@ -63,19 +63,19 @@ func TestBundleTamper(t *testing.T) {
 				PubKey: NyPublicKey{},
 				Prefixes: []PrefixHealthWrapper{
 					{
-						&StaticPrefixHealth{
+						PrefixHealth: &StaticPrefixHealth{
 							Prefix: netip.MustParsePrefix("10.0.0.1/32"),
 							Metric: 0,
 						},
 					},
 					{
-						&StaticPrefixHealth{
+						PrefixHealth: &StaticPrefixHealth{
 							Prefix: netip.MustParsePrefix("10.0.0.2/32"),
 							Metric: 0,
 						},
 					},
 					{
-						&StaticPrefixHealth{
+						PrefixHealth: &StaticPrefixHealth{
 							Prefix: netip.MustParsePrefix("10.0.0.3/8"),
 							Metric: 0,
 						},
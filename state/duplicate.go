@@ -0,0 +1,37 @@
+package state
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// MaxDSCP is the largest differentiated services code point
+const MaxDSCP = 63
+
+// DuplicateRule selects packets that are sent over the two best endpoints or next hops at once, trading bandwidth for
+// reliability. Every node forwarding a selected packet duplicates it, and drops the copies it has already seen.
+// A packet must match every field the rule sets, and a rule must set at least one.
+type DuplicateRule struct {
+	Prefixes []netip.Prefix `yaml:"prefixes,omitempty"` // destination must be within one of these prefixes
+	DSCP     []uint8        `yaml:"dscp,omitempty"`     // differentiated services code point, e.g. 46 for voice
+}
+
+// Matches returns true if a packet to dst with the given dscp is selected by the rule
+func (r DuplicateRule) Matches(dst netip.Addr, dscp uint8) bool {
+	if len(r.Prefixes) != 0 && !slices.ContainsFunc(r.Prefixes, func(p netip.Prefix) bool {
+		return p.Contains(dst)
+	}) {
+		return false
+	}
+	if len(r.DSCP) != 0 && !slices.Contains(r.DSCP, dscp) {
+		return false
+	}
+	return true
+}
+
+// MatchDuplicate returns true if any of the rules selects a packet to dst with the given dscp
+func MatchDuplicate(rules []DuplicateRule, dst netip.Addr, dscp uint8) bool {
+	return slices.ContainsFunc(rules, func(r DuplicateRule) bool {
+		return r.Matches(dst, dscp)
+	})
+}
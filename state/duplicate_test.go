@@ -0,0 +1,22 @@
+package state
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateRuleMatches(t *testing.T) {
+	voice := DuplicateRule{DSCP: []uint8{46}}
+	control := DuplicateRule{Prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}, DSCP: []uint8{48}}
+	dst := netip.MustParseAddr("10.0.0.1")
+
+	assert.True(t, voice.Matches(netip.MustParseAddr("10.9.0.1"), 46))
+	assert.False(t, voice.Matches(dst, 0))
+	assert.True(t, control.Matches(dst, 48))
+	assert.False(t, control.Matches(netip.MustParseAddr("10.0.1.1"), 48), "every field of a rule must match")
+
+	assert.True(t, MatchDuplicate([]DuplicateRule{control, voice}, dst, 46))
+	assert.False(t, MatchDuplicate(nil, dst, 46))
+}
@@ -5,6 +5,7 @@ import (
 	"math"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/encodeous/nylon/polyamide/conn"
@@ -28,9 +29,39 @@ type NylonEndpoint struct {
 	prevMedian    time.Duration
 	lastHeardBack time.Time
 	expRTT        float64
+	lastRTT       time.Duration
+	jitter        float64
+	outcomes      []bool // recent probe outcomes, true if the probe was answered on time
+	lostProbes    uint64
+	lateProbes    uint64
 	remoteInit    bool
+	detect        DetectCfg     // fast failure detection of the link, shortens the dead threshold
+	probeInterval time.Duration // how often the endpoint is probed, a backed off interval extends the dead threshold
+	probeGrace    time.Time     // the endpoint stays active until then after its probe interval shrinks
+	attrs         EndpointAttrs // configured attributes, or for remote endpoints those of the local endpoint they reach
+	localPrimary  time.Time     // when a remote endpoint last reached a primary local endpoint
+	localFree     time.Time     // when a remote endpoint last reached an unmetered local endpoint
 	WgEndpoint    conn.Endpoint
 	Address       string
+	Traffic       EndpointTraffic
+}
+
+// EndpointTraffic counts the data packets sent and received through an endpoint, it is updated on the data path
+type EndpointTraffic struct {
+	TxPackets atomic.Uint64
+	TxBytes   atomic.Uint64
+	RxPackets atomic.Uint64
+	RxBytes   atomic.Uint64
+}
+
+func (t *EndpointTraffic) CountTx(bytes int) {
+	t.TxPackets.Add(1)
+	t.TxBytes.Add(uint64(bytes))
+}
+
+func (t *EndpointTraffic) CountRx(bytes int) {
+	t.RxPackets.Add(1)
+	t.RxBytes.Add(uint64(bytes))
 }
 
 func (ep *NylonEndpoint) AsNylonEndpoint() *NylonEndpoint {
@@ -53,22 +84,161 @@ func (ep *NylonEndpoint) GetWgEndpoint(device *device.Device, resolver *Endpoint
 	return ep.WgEndpoint, nil
 }
 
-func (n *Neighbour) BestEndpoint() Endpoint {
-	var best Endpoint
+// SetLink updates the attributes of the link to this neighbour, and the failure detection of its endpoints
+func (n *Neighbour) SetLink(link LinkAttrs) {
+	n.Link = link
+	for _, ep := range n.Eps {
+		if nep := ep.AsNylonEndpoint(); nep != nil {
+			nep.SetDetect(link.Detect)
+		}
+	}
+}
+
+// AddEndpoint adds an endpoint to the link to this neighbour
+func (n *Neighbour) AddEndpoint(ep *NylonEndpoint) {
+	ep.SetDetect(n.Link.Detect)
+	n.Eps = append(n.Eps, ep)
+}
+
+// IsPreferred reports whether ep is the preferred endpoint of the link to this neighbour
+func (n *Neighbour) IsPreferred(ep Endpoint) bool {
+	if n.Link.Endpoint == "" {
+		return false
+	}
+	nep := ep.AsNylonEndpoint()
+	return nep != nil && nep.Address == n.Link.Endpoint
+}
 
-	for _, link := range n.Eps {
-		if !link.IsActive() {
-			continue
+// BestEndpoint returns the active endpoint used to reach the neighbour. Backup endpoints are only used when no primary
+// endpoint is active.
+func (n *Neighbour) BestEndpoint() Endpoint {
+	for _, backup := range []bool{false, true} {
+		var best Endpoint
+		for _, link := range n.Eps {
+			if !link.IsActive() || IsBackupEndpoint(link) != backup {
+				continue
+			}
+			if n.IsPreferred(link) {
+				return link
+			}
+			if best == nil || link.Metric() < best.Metric() {
+				best = link
+			}
 		}
-		if best == nil || link.Metric() < best.Metric() {
-			best = link
+		if best != nil {
+			return best
 		}
 	}
-	return best
+	return nil
+}
+
+func IsBackupEndpoint(ep Endpoint) bool {
+	nep := ep.AsNylonEndpoint()
+	return nep != nil && nep.Attrs().IsBackup()
 }
 
 func (u *NylonEndpoint) isActiveUnlocked() bool {
-	return time.Since(u.lastHeardBack) <= u.t.LinkDeadThreshold
+	now := time.Now()
+	return now.Sub(u.lastHeardBack) <= u.deadThresholdUnlocked() || now.Before(u.probeGrace)
+}
+
+func (u *NylonEndpoint) deadThresholdUnlocked() time.Duration {
+	if u.detect.Enabled() {
+		return u.detect.DeadThreshold()
+	}
+	return u.probeThresholdUnlocked(u.probeInterval)
+}
+
+// probeThresholdUnlocked scales the dead threshold with the probe interval, so a backed off endpoint misses as many
+// probes as one probed every ProbeDelay before it is declared dead
+func (u *NylonEndpoint) probeThresholdUnlocked(interval time.Duration) time.Duration {
+	if interval <= u.t.ProbeDelay {
+		return u.t.LinkDeadThreshold
+	}
+	return time.Duration(float64(u.t.LinkDeadThreshold) * float64(interval) / float64(u.t.ProbeDelay))
+}
+
+// SetProbeInterval sets how often the endpoint is probed. When the interval shrinks, the endpoint is declared dead
+// once the shorter threshold passes without a reply, but never later than the longer threshold allowed.
+func (u *NylonEndpoint) SetProbeInterval(interval time.Duration) {
+	u.Lock()
+	defer u.Unlock()
+	if interval < u.probeInterval && !u.detect.Enabled() && u.isActiveUnlocked() {
+		deadline := u.lastHeardBack.Add(u.deadThresholdUnlocked())
+		if u.probeGrace.After(deadline) {
+			deadline = u.probeGrace
+		}
+		if shorter := time.Now().Add(u.probeThresholdUnlocked(interval)); shorter.Before(deadline) {
+			deadline = shorter
+		}
+		u.probeGrace = deadline
+	}
+	u.probeInterval = interval
+}
+
+// SetAttrs sets the configured attributes of the endpoint
+func (u *NylonEndpoint) SetAttrs(attrs EndpointAttrs) {
+	u.Lock()
+	defer u.Unlock()
+	u.attrs = attrs
+}
+
+// ReachedLocal records that a remote endpoint reached a local endpoint with the given attributes. As the local
+// endpoint a packet arrives on cannot be chosen when replying, the remote endpoint is only treated as a backup or
+// metered while it has not reached a primary or unmetered local endpoint within the dead threshold.
+func (u *NylonEndpoint) ReachedLocal(attrs EndpointAttrs) {
+	u.Lock()
+	defer u.Unlock()
+	now := time.Now()
+	if !attrs.IsBackup() {
+		u.localPrimary = now
+	}
+	if !attrs.Metered {
+		u.localFree = now
+	}
+	u.attrs = attrs
+}
+
+// Attrs returns the attributes the endpoint is used with
+func (u *NylonEndpoint) Attrs() EndpointAttrs {
+	u.RLock()
+	defer u.RUnlock()
+	attrs := u.attrs
+	if u.remoteInit {
+		threshold := u.deadThresholdUnlocked()
+		if attrs.IsBackup() && time.Since(u.localPrimary) <= threshold {
+			attrs.Class = EndpointPrimary
+		}
+		if attrs.Metered && time.Since(u.localFree) <= threshold {
+			attrs.Metered = false
+		}
+	}
+	return attrs
+}
+
+// IsStable reports whether the rtt estimate of the endpoint is settled, and the last sample is within variance of it
+func (u *NylonEndpoint) IsStable(variance float64) bool {
+	u.RLock()
+	defer u.RUnlock()
+	if len(u.history) < u.t.MinimumConfidenceWindow || u.lastRTT == 0 {
+		return false
+	}
+	// ignore sub-millisecond changes, which are noise on fast links
+	return math.Abs(float64(u.lastRTT)-u.expRTT) <= max(variance*u.expRTT, float64(time.Millisecond))
+}
+
+// SetDetect sets the failure detection of the link the endpoint belongs to
+func (u *NylonEndpoint) SetDetect(detect DetectCfg) {
+	u.Lock()
+	defer u.Unlock()
+	u.detect = detect
+}
+
+// DeadThreshold is how long the endpoint stays active without hearing back from it
+func (u *NylonEndpoint) DeadThreshold() time.Duration {
+	u.RLock()
+	defer u.RUnlock()
+	return u.deadThresholdUnlocked()
 }
 
 func (u *NylonEndpoint) IsActive() bool {
@@ -84,6 +254,9 @@ func (u *NylonEndpoint) Renew() {
 		u.history = u.history[:0]
 		u.expRTT = math.Inf(1)
 		u.dirty = true
+		u.outcomes = u.outcomes[:0]
+		u.lastRTT = 0
+		u.jitter = 0
 	}
 	u.lastHeardBack = time.Now()
 }
@@ -163,6 +336,63 @@ func (u *NylonEndpoint) UpdatePing(ping time.Duration) {
 		u.history = u.history[1:]
 	}
 	u.dirty = true
+
+	// jitter is smoothed like RFC 3550 interarrival jitter, over the raw rtt
+	if u.lastRTT != 0 {
+		d := math.Abs(float64(ping - u.lastRTT))
+		u.jitter += (d - u.jitter) / 16
+	}
+	u.lastRTT = ping
+
+	late := ping > u.t.ProbeLateThreshold
+	if late {
+		u.lateProbes++
+	}
+	u.recordOutcomeUnlocked(!late)
+}
+
+// RecordProbeLoss records a probe that was never answered
+func (u *NylonEndpoint) RecordProbeLoss() {
+	u.Lock()
+	defer u.Unlock()
+	u.lostProbes++
+	u.recordOutcomeUnlocked(false)
+}
+
+func (u *NylonEndpoint) recordOutcomeUnlocked(ok bool) {
+	u.outcomes = append(u.outcomes, ok)
+	if len(u.outcomes) > u.t.ProbeLossWindow {
+		u.outcomes = u.outcomes[1:]
+	}
+}
+
+// LossRatio is the fraction of recent probes that were lost or answered late
+func (u *NylonEndpoint) LossRatio() float64 {
+	u.RLock()
+	defer u.RUnlock()
+	if len(u.outcomes) == 0 {
+		return 0
+	}
+	bad := 0
+	for _, ok := range u.outcomes {
+		if !ok {
+			bad++
+		}
+	}
+	return float64(bad) / float64(len(u.outcomes))
+}
+
+func (u *NylonEndpoint) Jitter() time.Duration {
+	u.RLock()
+	defer u.RUnlock()
+	return time.Duration(int64(u.jitter))
+}
+
+// ProbeCounters returns the total number of lost and late probes seen on this endpoint
+func (u *NylonEndpoint) ProbeCounters() (lost uint64, late uint64) {
+	u.RLock()
+	defer u.RUnlock()
+	return u.lostProbes, u.lateProbes
 }
 
 func (u *NylonEndpoint) Metric() uint32 {
@@ -170,7 +400,13 @@ func (u *NylonEndpoint) Metric() uint32 {
 	if !u.IsActive() {
 		return INF
 	}
-	return DurationToMetric(u.StabilizedPing())
+	rtt := u.StabilizedPing()
+	// penalize unstable links, so a lossy link does not win over a slightly slower clean one
+	penalty := u.t.JitterWeight*float64(u.Jitter()) + float64(u.t.LossPenalty)*u.LossRatio()
+	if u.Attrs().Metered {
+		penalty += float64(u.t.MeteredPenalty)
+	}
+	return DurationToMetric(rtt + time.Duration(penalty))
 }
 
 func (u *NylonEndpoint) IsRemote() bool {
@@ -207,6 +207,51 @@ func TestEndpointNormal(t *testing.T) {
 	assert.Less(t, len(distinctValues), int(time.Hour*2/time.Minute))
 }
 
+func TestEndpointLossPenalizesMetric(t *testing.T) {
+	tunables := DefaultRouterTunables()
+	lossy := NewEndpoint("127.0.0.1:1", false, nil, &tunables)
+	clean := NewEndpoint("127.0.0.1:2", false, nil, &tunables)
+	lossy.Renew()
+	clean.Renew()
+
+	for i := 0; i < tunables.WindowSamples; i++ {
+		clean.UpdatePing(30 * time.Millisecond)
+		if i%5 == 0 {
+			lossy.RecordProbeLoss()
+			continue
+		}
+		lossy.UpdatePing(20 * time.Millisecond)
+	}
+
+	assert.InDelta(t, 0.2, lossy.LossRatio(), 0.01)
+	assert.Equal(t, 0.0, clean.LossRatio())
+	lost, late := lossy.ProbeCounters()
+	assert.Equal(t, uint64(tunables.WindowSamples/5), lost)
+	assert.Equal(t, uint64(0), late)
+	assert.Greater(t, lossy.Metric(), clean.Metric())
+}
+
+func TestEndpointLateProbesAndJitter(t *testing.T) {
+	tunables := DefaultRouterTunables()
+	dep := NewEndpoint("127.0.0.1:0", false, nil, &tunables)
+	dep.Renew()
+
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			dep.UpdatePing(10 * time.Millisecond)
+		} else {
+			dep.UpdatePing(50 * time.Millisecond)
+		}
+	}
+	assert.InDelta(t, float64(40*time.Millisecond), float64(dep.Jitter()), float64(time.Millisecond))
+	assert.Equal(t, 0.0, dep.LossRatio())
+
+	dep.UpdatePing(tunables.ProbeLateThreshold + time.Millisecond)
+	_, late := dep.ProbeCounters()
+	assert.Equal(t, uint64(1), late)
+	assert.Greater(t, dep.LossRatio(), 0.0)
+}
+
 func TestParseEndpoint(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -270,3 +315,119 @@ func TestParseEndpoint(t *testing.T) {
 		})
 	}
 }
+
+func TestNeighbourPrefersLinkEndpoint(t *testing.T) {
+	tunables := DefaultRouterTunables()
+	fast := NewEndpoint("192.0.2.1:57175", false, nil, &tunables)
+	slow := NewEndpoint("198.51.100.1:57175", false, nil, &tunables)
+	fast.Renew()
+	slow.Renew()
+	fast.UpdatePing(5 * time.Millisecond)
+	slow.UpdatePing(50 * time.Millisecond)
+
+	neigh := &Neighbour{Id: "peer", Eps: []Endpoint{fast, slow}}
+	assert.Same(t, fast, neigh.BestEndpoint())
+
+	neigh.Link.Endpoint = slow.Address
+	assert.True(t, neigh.IsPreferred(slow))
+	assert.Same(t, slow, neigh.BestEndpoint())
+}
+
+func TestNeighbourUsesBackupEndpointsLast(t *testing.T) {
+	tunables := DefaultRouterTunables()
+	primary := NewEndpoint("192.0.2.1:57175", false, nil, &tunables)
+	backup := NewEndpoint("198.51.100.1:57175", false, nil, &tunables)
+	backup.SetAttrs(EndpointAttrs{Class: EndpointBackup})
+	primary.UpdatePing(50 * time.Millisecond)
+	backup.UpdatePing(5 * time.Millisecond)
+
+	// a faster or preferred backup is not used while a primary endpoint is active
+	neigh := &Neighbour{Id: "peer", Eps: []Endpoint{primary, backup}, Link: LinkAttrs{Endpoint: backup.Address}}
+	backup.Renew()
+	assert.Same(t, backup, neigh.BestEndpoint())
+	primary.Renew()
+	assert.Same(t, primary, neigh.BestEndpoint())
+}
+
+func TestEndpointMeteredPenalty(t *testing.T) {
+	tunables := DefaultRouterTunables()
+	tunables.MinimumConfidenceWindow = 1
+	ep := NewEndpoint("192.0.2.1:57175", false, nil, &tunables)
+	ep.Renew()
+	ep.UpdatePing(10 * time.Millisecond)
+	clean := ep.Metric()
+	ep.SetAttrs(EndpointAttrs{Metered: true})
+	assert.Equal(t, clean+DurationToMetric(tunables.MeteredPenalty), ep.Metric())
+}
+
+func TestRemoteEndpointReachedLocal(t *testing.T) {
+	tunables := DefaultRouterTunables()
+	ep := NewEndpoint("192.0.2.1:57175", true, nil, &tunables)
+	lte := EndpointAttrs{Class: EndpointBackup, Metered: true}
+	ep.ReachedLocal(lte)
+	assert.Equal(t, lte, ep.Attrs())
+
+	// a remote endpoint that also reaches a primary endpoint is not a backup, whichever it reached last
+	ep.ReachedLocal(EndpointAttrs{})
+	ep.ReachedLocal(lte)
+	assert.Equal(t, EndpointAttrs{Class: EndpointPrimary}, ep.Attrs())
+
+	ep.localPrimary = time.Now().Add(-2 * tunables.LinkDeadThreshold)
+	ep.localFree = ep.localPrimary
+	assert.Equal(t, lte, ep.Attrs())
+}
+
+func TestEndpointDetectThreshold(t *testing.T) {
+	tunables := DefaultRouterTunables()
+	ep := NewEndpoint("192.0.2.1:57175", false, nil, &tunables)
+	neigh := &Neighbour{Id: "peer"}
+	neigh.AddEndpoint(ep)
+	assert.Equal(t, tunables.LinkDeadThreshold, ep.DeadThreshold())
+
+	neigh.SetLink(LinkAttrs{Detect: DetectCfg{Interval: 10 * time.Millisecond, Multiplier: 2}})
+	assert.Equal(t, 20*time.Millisecond, ep.DeadThreshold())
+	ep.Renew()
+	assert.True(t, ep.IsActive())
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, ep.IsActive())
+
+	// endpoints added later use the detection of the link
+	late := NewEndpoint("192.0.2.2:57175", false, nil, &tunables)
+	neigh.AddEndpoint(late)
+	assert.Equal(t, 20*time.Millisecond, late.DeadThreshold())
+}
+
+func TestEndpointProbeInterval(t *testing.T) {
+	tunables := DefaultRouterTunables()
+	tunables.ProbeDelay = 10 * time.Millisecond
+	tunables.LinkDeadThreshold = 50 * time.Millisecond
+	ep := NewEndpoint("192.0.2.1:57175", false, nil, &tunables)
+
+	// a backed off endpoint may miss as many probes as one probed at full rate
+	ep.SetProbeInterval(40 * time.Millisecond)
+	assert.Equal(t, 200*time.Millisecond, ep.DeadThreshold())
+	ep.Renew()
+	time.Sleep(80 * time.Millisecond)
+	assert.True(t, ep.IsActive())
+
+	// returning to full rate keeps the endpoint active until a faster probe should have been answered
+	ep.SetProbeInterval(tunables.ProbeDelay)
+	assert.Equal(t, tunables.LinkDeadThreshold, ep.DeadThreshold())
+	assert.True(t, ep.IsActive())
+	time.Sleep(70 * time.Millisecond)
+	assert.False(t, ep.IsActive())
+}
+
+func TestEndpointIsStable(t *testing.T) {
+	tunables := DefaultRouterTunables()
+	ep := NewEndpoint("192.0.2.1:57175", false, nil, &tunables)
+	assert.False(t, ep.IsStable(0.25))
+	for range tunables.MinimumConfidenceWindow {
+		ep.UpdatePing(20 * time.Millisecond)
+	}
+	assert.True(t, ep.IsStable(0.25))
+	ep.UpdatePing(22 * time.Millisecond)
+	assert.True(t, ep.IsStable(0.25))
+	ep.UpdatePing(40 * time.Millisecond)
+	assert.False(t, ep.IsStable(0.25))
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"cmp"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/goccy/go-yaml"
+)
+
+// RouterSnapshot is the part of the router state that is kept across restarts
+type RouterSnapshot struct {
+	Generation int64           `yaml:"generation"` // timestamp of the last applied central config
+	Seqnos     []SnapshotSeqno `yaml:"seqnos,omitempty"`
+}
+
+// SnapshotSeqno is the seqno of a prefix originated by this node
+type SnapshotSeqno struct {
+	Prefix netip.Prefix  `yaml:"prefix"`
+	Src    *netip.Prefix `yaml:"src,omitempty"`
+	Seqno  uint16        `yaml:"seqno"`
+}
+
+func (s SnapshotSeqno) RoutePrefix() RoutePrefix {
+	prefix := RoutePrefix{Prefix: s.Prefix}
+	if s.Src != nil {
+		prefix.Src = *s.Src
+	}
+	return prefix
+}
+
+// Snapshot captures the router's own seqnos
+func (s *RouterState) Snapshot(generation int64) RouterSnapshot {
+	snapshot := RouterSnapshot{Generation: generation}
+	for prefix, seqno := range s.SelfSeqno {
+		entry := SnapshotSeqno{Prefix: prefix.Prefix, Seqno: seqno}
+		if prefix.IsSourceSpecific() {
+			entry.Src = &prefix.Src
+		}
+		snapshot.Seqnos = append(snapshot.Seqnos, entry)
+	}
+	slices.SortFunc(snapshot.Seqnos, func(a, b SnapshotSeqno) int {
+		if c := a.Prefix.Addr().Compare(b.Prefix.Addr()); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Prefix.Bits(), b.Prefix.Bits()); c != 0 {
+			return c
+		}
+		return a.RoutePrefix().Src.Addr().Compare(b.RoutePrefix().Src.Addr())
+	})
+	return snapshot
+}
+
+func ReadRouterSnapshot(path string) (*RouterSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot RouterSnapshot
+	if err := yaml.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// WriteRouterSnapshot replaces the file at path, so a crash while writing never leaves a truncated snapshot behind
+func WriteRouterSnapshot(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
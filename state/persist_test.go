@@ -0,0 +1,41 @@
+package state
+
+import (
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterSnapshotRoundTrip(t *testing.T) {
+	lan := RoutePrefix{Prefix: netip.MustParsePrefix("10.1.0.0/16")}
+	exit := RoutePrefix{Prefix: netip.MustParsePrefix("0.0.0.0/0"), Src: netip.MustParsePrefix("10.5.0.0/24")}
+	s := &RouterState{SelfSeqno: map[RoutePrefix]uint16{lan: 7, exit: 65535}}
+
+	snapshot := s.Snapshot(1740832962209309000)
+	data, err := yaml.Marshal(snapshot)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "nylon.state")
+	require.NoError(t, WriteRouterSnapshot(path, data))
+
+	read, err := ReadRouterSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, *read)
+	restored := make(map[RoutePrefix]uint16)
+	for _, entry := range read.Seqnos {
+		restored[entry.RoutePrefix()] = entry.Seqno
+	}
+	assert.Equal(t, s.SelfSeqno, restored)
+
+	// the snapshot is rewritten in place
+	require.NoError(t, WriteRouterSnapshot(path, []byte("generation: 1\n")))
+	read, err = ReadRouterSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, RouterSnapshot{Generation: 1}, *read)
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{path}, matches)
+}
@@ -0,0 +1,193 @@
+package state
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+type PolicyAction string
+
+const (
+	PolicyAccept PolicyAction = "accept"
+	PolicyReject PolicyAction = "reject"
+	// PolicyOffset adds the rule's offset to the metric and continues to the next rule
+	PolicyOffset PolicyAction = "offset"
+)
+
+// PolicyRule matches a route and applies an action to it. Empty match fields match every route.
+type PolicyRule struct {
+	Prefixes   []netip.Prefix `yaml:"prefixes,omitempty"`   // destination must be within one of these prefixes
+	Origins    []string       `yaml:"origins,omitempty"`    // nodes or groups that originated the route
+	Neighbours []string       `yaml:"neighbours,omitempty"` // neighbour the update is received from (import) or sent to (export)
+	NextHops   []string       `yaml:"next_hops,omitempty"`  // next hop of the route, for imports this is the sending neighbour
+	MinMetric  *uint32        `yaml:"min_metric,omitempty"` // the route's metric must be at least this
+	MaxMetric  *uint32        `yaml:"max_metric,omitempty"` // the route's metric must be at most this
+	Action     PolicyAction   `yaml:"action"`
+	Offset     uint32         `yaml:"offset,omitempty"` // added to the metric by the offset action
+}
+
+// PolicyCfg is a set of import and export rules applied by the routers listed in Nodes.
+// Rules are evaluated in order, the first accept or reject wins, and routes that match no such rule are accepted.
+type PolicyCfg struct {
+	Nodes  []string     `yaml:"nodes"` // nodes or groups this policy applies to
+	Import []PolicyRule `yaml:"import,omitempty"`
+	Export []PolicyRule `yaml:"export,omitempty"`
+}
+
+// PolicyRoute is the view of a route that policy rules are matched against.
+type PolicyRoute struct {
+	RoutePrefix
+	Origin    NodeId
+	Neighbour NodeId
+	NextHop   NodeId
+	Metric    uint32
+}
+
+type policyRule struct {
+	PolicyRule
+	origins    []NodeId
+	neighbours []NodeId
+	nextHops   []NodeId
+}
+
+// RoutePolicy is the compiled policy of a single router.
+type RoutePolicy struct {
+	imports []policyRule
+	exports []policyRule
+}
+
+// Import evaluates the import rules against a route received from a neighbour.
+// It returns the metric to store the route with, or false if the route is rejected.
+func (p *RoutePolicy) Import(route PolicyRoute) (uint32, bool) {
+	if p == nil {
+		return route.Metric, true
+	}
+	return evalPolicy(p.imports, route)
+}
+
+// Export evaluates the export rules against a route about to be sent to a neighbour.
+// It returns the metric to advertise the route with, or false if the route is rejected.
+func (p *RoutePolicy) Export(route PolicyRoute) (uint32, bool) {
+	if p == nil {
+		return route.Metric, true
+	}
+	return evalPolicy(p.exports, route)
+}
+
+func evalPolicy(rules []policyRule, route PolicyRoute) (uint32, bool) {
+	for _, rule := range rules {
+		if !rule.matches(route) {
+			continue
+		}
+		switch rule.Action {
+		case PolicyAccept:
+			return route.Metric, true
+		case PolicyReject:
+			return INF, false
+		case PolicyOffset:
+			route.Metric = uint32(min(uint64(INFM), uint64(route.Metric)+uint64(rule.Offset)))
+		}
+	}
+	return route.Metric, true
+}
+
+func (r policyRule) matches(route PolicyRoute) bool {
+	if len(r.Prefixes) != 0 && !slices.ContainsFunc(r.Prefixes, func(p netip.Prefix) bool {
+		return p.Bits() <= route.Prefix.Bits() && p.Contains(route.Prefix.Addr())
+	}) {
+		return false
+	}
+	if r.origins != nil && !slices.Contains(r.origins, route.Origin) {
+		return false
+	}
+	if r.neighbours != nil && !slices.Contains(r.neighbours, route.Neighbour) {
+		return false
+	}
+	if r.nextHops != nil && !slices.Contains(r.nextHops, route.NextHop) {
+		return false
+	}
+	if r.MinMetric != nil && route.Metric < *r.MinMetric {
+		return false
+	}
+	if r.MaxMetric != nil && route.Metric > *r.MaxMetric {
+		return false
+	}
+	return true
+}
+
+// CompileRoutePolicy collects the rules of every policy that applies to node, in config order.
+// It returns nil if no policy applies to the node.
+func (c *CentralCfg) CompileRoutePolicy(node NodeId) (*RoutePolicy, error) {
+	if len(c.Policies) == 0 {
+		return nil, nil
+	}
+	nodes := make([]string, 0)
+	for _, n := range c.GetNodes() {
+		nodes = append(nodes, string(n.Id))
+	}
+	groups, err := ParseGroups(c.Graph, nodes)
+	if err != nil {
+		return nil, err
+	}
+	resolve := func(names []string) ([]NodeId, error) {
+		if len(names) == 0 {
+			return nil, nil
+		}
+		ids := make([]NodeId, 0)
+		for _, name := range names {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if slices.Contains(nodes, name) {
+				ids = append(ids, NodeId(name))
+			} else if members, ok := groups[name]; ok {
+				ids = append(ids, members...)
+			} else {
+				return nil, fmt.Errorf("invalid policy: %s is not a valid node/group", name)
+			}
+		}
+		return ids, nil
+	}
+	compile := func(rules []PolicyRule) ([]policyRule, error) {
+		compiled := make([]policyRule, 0, len(rules))
+		for _, rule := range rules {
+			cr := policyRule{PolicyRule: rule}
+			if cr.origins, err = resolve(rule.Origins); err != nil {
+				return nil, err
+			}
+			if cr.neighbours, err = resolve(rule.Neighbours); err != nil {
+				return nil, err
+			}
+			if cr.nextHops, err = resolve(rule.NextHops); err != nil {
+				return nil, err
+			}
+			compiled = append(compiled, cr)
+		}
+		return compiled, nil
+	}
+
+	var policy *RoutePolicy
+	for _, cfg := range c.Policies {
+		applies, err := resolve(cfg.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		imports, err := compile(cfg.Import)
+		if err != nil {
+			return nil, err
+		}
+		exports, err := compile(cfg.Export)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(applies, node) {
+			continue
+		}
+		if policy == nil {
+			policy = &RoutePolicy{}
+		}
+		policy.imports = append(policy.imports, imports...)
+		policy.exports = append(policy.exports, exports...)
+	}
+	return policy, nil
+}
@@ -0,0 +1,135 @@
+package state
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func policyTestConfig(policies ...PolicyCfg) *CentralCfg {
+	return &CentralCfg{
+		Routers: []RouterCfg{
+			{NodeCfg: NodeCfg{Id: "edge"}},
+			{NodeCfg: NodeCfg{Id: "branch1"}},
+			{NodeCfg: NodeCfg{Id: "branch2"}},
+			{NodeCfg: NodeCfg{Id: "partner"}},
+			{NodeCfg: NodeCfg{Id: "lab"}},
+		},
+		Graph: []string{
+			"branches = branch1, branch2",
+			"edge, branches, partner, lab",
+		},
+		Policies: policies,
+	}
+}
+
+func TestRoutePolicy_FirstTerminalRuleWins(t *testing.T) {
+	cfg := policyTestConfig(PolicyCfg{
+		Nodes: []string{"edge"},
+		Import: []PolicyRule{
+			{Origins: []string{"branches"}, Action: PolicyOffset, Offset: 10},
+			{MinMetric: new(uint32(50)), Action: PolicyReject},
+			{Origins: []string{"branch1"}, Action: PolicyAccept},
+			{Action: PolicyReject},
+		},
+	})
+	policy, err := cfg.CompileRoutePolicy("edge")
+	assert.NoError(t, err)
+
+	route := PolicyRoute{
+		RoutePrefix: RoutePrefix{Prefix: netip.MustParsePrefix("10.20.1.0/24")},
+		Origin:      "branch1",
+		Neighbour:   "branch1",
+		NextHop:     "branch1",
+		Metric:      30,
+	}
+	metric, ok := policy.Import(route)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(40), metric)
+
+	// the offset pushes the metric over the reject threshold
+	route.Metric = 45
+	_, ok = policy.Import(route)
+	assert.False(t, ok)
+
+	// branch2 only matches the catch-all reject
+	route.Origin, route.Metric = "branch2", 1
+	_, ok = policy.Import(route)
+	assert.False(t, ok)
+
+	// routes from nodes without a policy are unaffected
+	other, err := cfg.CompileRoutePolicy("lab")
+	assert.NoError(t, err)
+	assert.Nil(t, other)
+	metric, ok = other.Import(route)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), metric)
+}
+
+func TestRoutePolicy_MatchesPrefixAndNeighbour(t *testing.T) {
+	cfg := policyTestConfig(PolicyCfg{
+		Nodes: []string{"edge", "lab"},
+		Export: []PolicyRule{
+			{Prefixes: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")}, Neighbours: []string{"partner"}, Action: PolicyReject},
+		},
+	}, PolicyCfg{
+		Nodes: []string{"lab"},
+		Export: []PolicyRule{
+			{Origins: []string{"lab"}, Action: PolicyAccept},
+			{Action: PolicyReject},
+		},
+	})
+	edge, err := cfg.CompileRoutePolicy("edge")
+	assert.NoError(t, err)
+	lab, err := cfg.CompileRoutePolicy("lab")
+	assert.NoError(t, err)
+
+	branch := PolicyRoute{
+		RoutePrefix: RoutePrefix{Prefix: netip.MustParsePrefix("10.20.1.0/24")},
+		Origin:      "branch1",
+		Neighbour:   "partner",
+		NextHop:     "branch1",
+		Metric:      10,
+	}
+	_, ok := edge.Export(branch)
+	assert.False(t, ok)
+
+	// a prefix that only partially overlaps the rule does not match
+	branch.Prefix = netip.MustParsePrefix("10.0.0.0/8")
+	_, ok = edge.Export(branch)
+	assert.True(t, ok)
+
+	// the lab node only exports its own routes, so it never becomes transit
+	branch.Neighbour = "edge"
+	_, ok = lab.Export(branch)
+	assert.False(t, ok)
+	branch.Origin = "lab"
+	_, ok = lab.Export(branch)
+	assert.True(t, ok)
+}
+
+func TestCentralConfigValidator_Policy(t *testing.T) {
+	valid := policyTestConfig(PolicyCfg{
+		Nodes:  []string{"branches"},
+		Import: []PolicyRule{{NextHops: []string{"edge"}, Action: PolicyOffset, Offset: 5}},
+	})
+	assert.NoError(t, CentralConfigValidator(valid))
+
+	invalid := map[string]PolicyCfg{
+		"unknown node":   {Nodes: []string{"nope"}},
+		"empty nodes":    {},
+		"unknown action": {Nodes: []string{"edge"}, Import: []PolicyRule{{Action: "drop"}}},
+		"missing offset": {Nodes: []string{"edge"}, Import: []PolicyRule{{Action: PolicyOffset}}},
+		"stray offset":   {Nodes: []string{"edge"}, Export: []PolicyRule{{Action: PolicyAccept, Offset: 1}}},
+		"unknown origin": {Nodes: []string{"edge"}, Export: []PolicyRule{{Origins: []string{"nope"}, Action: PolicyReject}}},
+		"metric range": {Nodes: []string{"edge"}, Export: []PolicyRule{{
+			MinMetric: new(uint32(10)), MaxMetric: new(uint32(5)), Action: PolicyReject,
+		}}},
+	}
+	for name, policy := range invalid {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, CentralConfigValidator(policyTestConfig(policy)))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package state
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+)
+
+// PortForward forwards the TCP or UDP traffic that reaches a port of this node through the mesh to another host, like
+// a DNAT rule. Targets routed by the mesh are reached with the listen address as the source, so their replies return
+// through this node. Other targets, e.g. hosts on the local network, are reached through the host's routing table and
+// see the original source, so they must route the mesh back through this node.
+//
+// A port forward whose listen address is not in the mesh, e.g. a public address of the host, listens on the host
+// instead, and relays the connections it accepts to a target in the mesh from the address of this node.
+type PortForward struct {
+	Proto  string         `yaml:"proto"`  // tcp or udp
+	Listen netip.AddrPort `yaml:"listen"` // an address of this node or of the host, and the port to forward
+	To     netip.AddrPort `yaml:"to"`     // the target, of the same address family as listen
+}
+
+// ListensOnHost returns true if a port forward of a node listens on the host rather than on an address of the node
+func (fwd PortForward) ListensOnHost(node NodeCfg) bool {
+	return !ownsAddr(node, fwd.Listen.Addr())
+}
+
+// PortForwardProto returns the protocol number of a port forward
+func PortForwardProto(proto string) (uint8, error) {
+	switch proto {
+	case "tcp":
+		return 6, nil
+	case "udp":
+		return 17, nil
+	}
+	return 0, fmt.Errorf("unknown port forward protocol %q, expected tcp or udp", proto)
+}
+
+func validatePortForwards(central *CentralCfg, node *LocalCfg) error {
+	type listener struct {
+		proto  string
+		listen netip.AddrPort
+	}
+	seen := make(map[listener]struct{})
+	for _, fwd := range node.PortForwards {
+		if _, err := PortForwardProto(fwd.Proto); err != nil {
+			return err
+		}
+		if !fwd.Listen.IsValid() || fwd.Listen.Port() == 0 || !fwd.To.IsValid() || fwd.To.Port() == 0 {
+			return fmt.Errorf("port forward %s %s -> %s must have an address and a port on both sides", fwd.Proto, fwd.Listen, fwd.To)
+		}
+		if fwd.Listen.Addr().Is4() != fwd.To.Addr().Is4() {
+			return fmt.Errorf("port forward %s -> %s must not mix ipv4 and ipv6", fwd.Listen, fwd.To)
+		}
+		key := listener{fwd.Proto, fwd.Listen}
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("port %s %s is forwarded more than once", fwd.Proto, fwd.Listen)
+		}
+		seen[key] = struct{}{}
+		if central == nil || !central.IsNode(node.Id) || !fwd.ListensOnHost(central.GetNode(node.Id)) {
+			continue
+		}
+		if slices.ContainsFunc(central.GetNodes(), func(other NodeCfg) bool { return slices.Contains(other.Addresses, fwd.Listen.Addr()) }) {
+			return fmt.Errorf("port forward listen address %s is not an address of %s", fwd.Listen.Addr(), node.Id)
+		}
+		if !slices.ContainsFunc(central.GetNodes(), func(other NodeCfg) bool { return ownsAddr(other, fwd.To.Addr()) }) {
+			return fmt.Errorf("port forward %s listens on the host, so its target %s must be an address in the mesh", fwd.Listen, fwd.To)
+		}
+	}
+	return nil
+}
+
+// ownsAddr returns true if an address is one of the addresses of a node, or within one of its prefixes
+func ownsAddr(node NodeCfg, addr netip.Addr) bool {
+	return slices.Contains(node.Addresses, addr) || slices.ContainsFunc(node.Prefixes, func(prefix PrefixHealthWrapper) bool {
+		return prefix.GetPrefix().Contains(addr)
+	})
+}
@@ -19,7 +19,21 @@ import (
 type PrefixHealthConfig interface {
 	GetPrefix() netip.Prefix
 	sameConfig(other PrefixHealthConfig, tunables *RouterTunables) bool
-	newMonitor(log *slog.Logger, tunables *RouterTunables, resolver *DNSResolver) PrefixHealthMonitor
+	newMonitor(log *slog.Logger, tunables *RouterTunables, resolver *DNSResolver, routes ExternalRoutes) PrefixHealthMonitor
+}
+
+// ExternalProtocol is a routing protocol that routes can be imported into the mesh from
+type ExternalProtocol string
+
+const (
+	ExternalBabel ExternalProtocol = "babel"
+	ExternalBGP   ExternalProtocol = "bgp"
+)
+
+// ExternalRoutes looks up routes learned from outside the mesh, such as from Babel routers on a local interface
+type ExternalRoutes interface {
+	// ExternalMetric returns the metric of the best route to prefix learned from proto, or INF if there is none
+	ExternalMetric(proto ExternalProtocol, prefix netip.Prefix) uint32
 }
 
 type PrefixHealthMonitor interface {
@@ -42,7 +56,7 @@ func (s *StaticPrefixHealth) sameConfig(other PrefixHealthConfig, _ *RouterTunab
 	return ok && s.Prefix == o.Prefix && s.Metric == o.Metric
 }
 
-func (s *StaticPrefixHealth) newMonitor(_ *slog.Logger, _ *RouterTunables, _ *DNSResolver) PrefixHealthMonitor {
+func (s *StaticPrefixHealth) newMonitor(_ *slog.Logger, _ *RouterTunables, _ *DNSResolver, _ ExternalRoutes) PrefixHealthMonitor {
 	return staticPrefixHealthMonitor{metric: s.Metric}
 }
 
@@ -103,7 +117,7 @@ func (p *PingPrefixHealth) sameConfig(other PrefixHealthConfig, tunables *Router
 		prefixHealthMaxFailures(p.MaxFailures, tunables) == prefixHealthMaxFailures(o.MaxFailures, tunables)
 }
 
-func (p *PingPrefixHealth) newMonitor(log *slog.Logger, tunables *RouterTunables, _ *DNSResolver) PrefixHealthMonitor {
+func (p *PingPrefixHealth) newMonitor(log *slog.Logger, tunables *RouterTunables, _ *DNSResolver, _ ExternalRoutes) PrefixHealthMonitor {
 	monitor := &pingPrefixHealthMonitor{
 		log:         log,
 		prefix:      p.Prefix,
@@ -241,7 +255,7 @@ func (h *HTTPPrefixHealth) sameConfig(other PrefixHealthConfig, tunables *Router
 		prefixHealthDelay(h.Delay, tunables) == prefixHealthDelay(o.Delay, tunables)
 }
 
-func (h *HTTPPrefixHealth) newMonitor(log *slog.Logger, tunables *RouterTunables, resolver *DNSResolver) PrefixHealthMonitor {
+func (h *HTTPPrefixHealth) newMonitor(log *slog.Logger, tunables *RouterTunables, resolver *DNSResolver, _ ExternalRoutes) PrefixHealthMonitor {
 	if resolver == nil {
 		resolver = NewDNSResolver(nil)
 	}
@@ -308,24 +322,159 @@ func (h *httpPrefixHealthMonitor) run() {
 	}
 }
 
+// BabelPrefixHealth advertises a prefix while a route to it is learned from Babel routers on one of the node's babel
+// interfaces, using the metric of that route
+type BabelPrefixHealth struct {
+	Prefix netip.Prefix `yaml:"prefix"`
+	Metric *uint32      `yaml:"metric,omitempty"` // metric override, the prefix is still withdrawn when there is no babel route
+}
+
+func (b *BabelPrefixHealth) GetPrefix() netip.Prefix {
+	return b.Prefix
+}
+
+func (b *BabelPrefixHealth) sameConfig(other PrefixHealthConfig, _ *RouterTunables) bool {
+	o, ok := other.(*BabelPrefixHealth)
+	return ok && b.Prefix == o.Prefix && sameOptionalUint32(b.Metric, o.Metric)
+}
+
+func (b *BabelPrefixHealth) newMonitor(_ *slog.Logger, _ *RouterTunables, _ *DNSResolver, routes ExternalRoutes) PrefixHealthMonitor {
+	return babelPrefixHealthMonitor{prefix: b.Prefix, metric: b.Metric, routes: routes}
+}
+
+type babelPrefixHealthMonitor struct {
+	prefix netip.Prefix
+	metric *uint32
+	routes ExternalRoutes
+}
+
+func (b babelPrefixHealthMonitor) GetMetric() uint32 {
+	if b.routes == nil {
+		return INF
+	}
+	metric := b.routes.ExternalMetric(ExternalBabel, b.prefix)
+	if metric != INF && b.metric != nil {
+		return *b.metric
+	}
+	return metric
+}
+
+func (babelPrefixHealthMonitor) Stop() {}
+
+// BGPPrefixHealth advertises a prefix with a fixed metric while one of the node's BGP peers announces it
+type BGPPrefixHealth struct {
+	Prefix netip.Prefix `yaml:"prefix"`
+	Metric uint32       `yaml:"metric,omitempty"` // the metric to advertise for this prefix
+}
+
+func (b *BGPPrefixHealth) GetPrefix() netip.Prefix {
+	return b.Prefix
+}
+
+func (b *BGPPrefixHealth) sameConfig(other PrefixHealthConfig, _ *RouterTunables) bool {
+	o, ok := other.(*BGPPrefixHealth)
+	return ok && b.Prefix == o.Prefix && b.Metric == o.Metric
+}
+
+func (b *BGPPrefixHealth) newMonitor(_ *slog.Logger, _ *RouterTunables, _ *DNSResolver, routes ExternalRoutes) PrefixHealthMonitor {
+	return bgpPrefixHealthMonitor{prefix: b.Prefix, metric: b.Metric, routes: routes}
+}
+
+type bgpPrefixHealthMonitor struct {
+	prefix netip.Prefix
+	metric uint32
+	routes ExternalRoutes
+}
+
+func (b bgpPrefixHealthMonitor) GetMetric() uint32 {
+	if b.routes == nil || b.routes.ExternalMetric(ExternalBGP, b.prefix) == INF {
+		return INF
+	}
+	return b.metric
+}
+
+func (bgpPrefixHealthMonitor) Stop() {}
+
+// AnycastPrefixHealth advertises a service address shared by several nodes. Each node raises the metric by the
+// latency of its health check and its weight, so traffic reaches the closest healthy instance.
+type AnycastPrefixHealth struct {
+	Prefix      netip.Prefix   `yaml:"prefix"`
+	Weight      uint32         `yaml:"weight,omitempty"`       // added to the metric, a higher weight draws less traffic
+	Addr        netip.Addr     `yaml:"addr,omitempty"`         // if set, the service is checked by pinging this address
+	URL         string         `yaml:"url,omitempty"`          // if set, the service is checked with an HTTP GET to this URL
+	MaxFailures *int           `yaml:"max_failures,omitempty"` // number of failed pings before returning infinite metric
+	Delay       *time.Duration `yaml:"delay,omitempty"`        // delay between health checks
+}
+
+func (a *AnycastPrefixHealth) GetPrefix() netip.Prefix {
+	return a.Prefix
+}
+
+func (a *AnycastPrefixHealth) sameConfig(other PrefixHealthConfig, tunables *RouterTunables) bool {
+	o, ok := other.(*AnycastPrefixHealth)
+	return ok &&
+		a.Prefix == o.Prefix &&
+		a.Weight == o.Weight &&
+		a.Addr == o.Addr &&
+		a.URL == o.URL &&
+		prefixHealthDelay(a.Delay, tunables) == prefixHealthDelay(o.Delay, tunables) &&
+		prefixHealthMaxFailures(a.MaxFailures, tunables) == prefixHealthMaxFailures(o.MaxFailures, tunables)
+}
+
+func (a *AnycastPrefixHealth) newMonitor(log *slog.Logger, tunables *RouterTunables, resolver *DNSResolver, routes ExternalRoutes) PrefixHealthMonitor {
+	var check PrefixHealthMonitor = staticPrefixHealthMonitor{}
+	if a.URL != "" {
+		check = (&HTTPPrefixHealth{Prefix: a.Prefix, URL: a.URL, Delay: a.Delay}).newMonitor(log, tunables, resolver, routes)
+	} else if a.Addr.IsValid() {
+		check = (&PingPrefixHealth{Prefix: a.Prefix, Addr: a.Addr, MaxFailures: a.MaxFailures, Delay: a.Delay}).newMonitor(log, tunables, resolver, routes)
+	}
+	return anycastPrefixHealthMonitor{check: check, weight: a.Weight}
+}
+
+type anycastPrefixHealthMonitor struct {
+	check  PrefixHealthMonitor
+	weight uint32
+}
+
+func (a anycastPrefixHealthMonitor) GetMetric() uint32 {
+	metric := a.check.GetMetric()
+	if metric == INF {
+		return INF
+	}
+	return uint32(min(uint64(metric)+uint64(a.weight), uint64(INFM)))
+}
+
+func (a anycastPrefixHealthMonitor) Stop() {
+	a.check.Stop()
+}
+
 type PrefixHealthWrapper struct {
 	PrefixHealth PrefixHealthConfig
+	// Src optionally restricts the advertised route to traffic from this source prefix (RFC 9079)
+	Src netip.Prefix
 }
 
 func (p PrefixHealthWrapper) GetPrefix() netip.Prefix {
 	return p.PrefixHealth.GetPrefix()
 }
 
+func (p PrefixHealthWrapper) GetRoutePrefix() RoutePrefix {
+	return RoutePrefix{Prefix: p.GetPrefix(), Src: p.Src}
+}
+
 // SameConfig reports whether two prefix health checks have equivalent configuration.
 func (p PrefixHealthWrapper) SameConfig(other PrefixHealthWrapper, tunables *RouterTunables) bool {
+	if p.Src != other.Src {
+		return false
+	}
 	if p.PrefixHealth == nil || other.PrefixHealth == nil {
 		return p.PrefixHealth == other.PrefixHealth
 	}
 	return p.PrefixHealth.sameConfig(other.PrefixHealth, tunables)
 }
 
-func (p PrefixHealthWrapper) NewMonitor(log *slog.Logger, tunables *RouterTunables, resolver *DNSResolver) PrefixHealthMonitor {
-	return p.PrefixHealth.newMonitor(log, tunables, resolver)
+func (p PrefixHealthWrapper) NewMonitor(log *slog.Logger, tunables *RouterTunables, resolver *DNSResolver, routes ExternalRoutes) PrefixHealthMonitor {
+	return p.PrefixHealth.newMonitor(log, tunables, resolver, routes)
 }
 
 func (p PrefixHealthWrapper) StaticMetric() (uint32, bool) {
@@ -357,32 +506,76 @@ func prefixHealthMaxFailures(value *int, tunables *RouterTunables) int {
 	return tunables.HealthCheckMaxFailures
 }
 
+// srcYAML returns nil for destination-only prefixes so that src is omitted.
+func (p PrefixHealthWrapper) srcYAML() *netip.Prefix {
+	if !p.Src.IsValid() {
+		return nil
+	}
+	return &p.Src
+}
+
 func (p PrefixHealthWrapper) MarshalYAML() (interface{}, error) {
 	switch v := p.PrefixHealth.(type) {
 	case *StaticPrefixHealth:
 		return struct {
-			Type                string `yaml:"type"`
+			Type                string        `yaml:"type"`
+			Src                 *netip.Prefix `yaml:"src,omitempty"`
 			*StaticPrefixHealth `yaml:",inline"`
 		}{
 			Type:               "static",
+			Src:                p.srcYAML(),
 			StaticPrefixHealth: v,
 		}, nil
 	case *PingPrefixHealth:
 		return struct {
-			Type              string `yaml:"type"`
+			Type              string        `yaml:"type"`
+			Src               *netip.Prefix `yaml:"src,omitempty"`
 			*PingPrefixHealth `yaml:",inline"`
 		}{
 			Type:             "ping",
+			Src:              p.srcYAML(),
 			PingPrefixHealth: v,
 		}, nil
 	case *HTTPPrefixHealth:
 		return struct {
-			Type              string `yaml:"type"`
+			Type              string        `yaml:"type"`
+			Src               *netip.Prefix `yaml:"src,omitempty"`
 			*HTTPPrefixHealth `yaml:",inline"`
 		}{
 			Type:             "http",
+			Src:              p.srcYAML(),
 			HTTPPrefixHealth: v,
 		}, nil
+	case *BabelPrefixHealth:
+		return struct {
+			Type               string        `yaml:"type"`
+			Src                *netip.Prefix `yaml:"src,omitempty"`
+			*BabelPrefixHealth `yaml:",inline"`
+		}{
+			Type:              "babel",
+			Src:               p.srcYAML(),
+			BabelPrefixHealth: v,
+		}, nil
+	case *BGPPrefixHealth:
+		return struct {
+			Type             string        `yaml:"type"`
+			Src              *netip.Prefix `yaml:"src,omitempty"`
+			*BGPPrefixHealth `yaml:",inline"`
+		}{
+			Type:            "bgp",
+			Src:             p.srcYAML(),
+			BGPPrefixHealth: v,
+		}, nil
+	case *AnycastPrefixHealth:
+		return struct {
+			Type                 string        `yaml:"type"`
+			Src                  *netip.Prefix `yaml:"src,omitempty"`
+			*AnycastPrefixHealth `yaml:",inline"`
+		}{
+			Type:                "anycast",
+			Src:                 p.srcYAML(),
+			AnycastPrefixHealth: v,
+		}, nil
 	default:
 		return nil, nil
 	}
@@ -390,11 +583,13 @@ func (p PrefixHealthWrapper) MarshalYAML() (interface{}, error) {
 
 func (p *PrefixHealthWrapper) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var raw struct {
-		Type string `yaml:"type"`
+		Type string       `yaml:"type"`
+		Src  netip.Prefix `yaml:"src"`
 	}
 	if err := unmarshal(&raw); err != nil {
 		return err
 	}
+	p.Src = raw.Src
 
 	switch raw.Type {
 	case "static":
@@ -415,6 +610,24 @@ func (p *PrefixHealthWrapper) UnmarshalYAML(unmarshal func(interface{}) error) e
 			return err
 		}
 		p.PrefixHealth = &hp
+	case "babel":
+		var bp BabelPrefixHealth
+		if err := unmarshal(&bp); err != nil {
+			return err
+		}
+		p.PrefixHealth = &bp
+	case "bgp":
+		var bp BGPPrefixHealth
+		if err := unmarshal(&bp); err != nil {
+			return err
+		}
+		p.PrefixHealth = &bp
+	case "anycast":
+		var ap AnycastPrefixHealth
+		if err := unmarshal(&ap); err != nil {
+			return err
+		}
+		p.PrefixHealth = &ap
 	default:
 		return fmt.Errorf("unknown prefix health type: %s", raw.Type)
 	}
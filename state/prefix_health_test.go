@@ -62,6 +62,62 @@ delay: 10s
 prefix: 172.16.0.0/16
 url: http://example.com/health
 delay: 5s
+`,
+		},
+		{
+			name: "BabelPrefixHealth",
+			wrapper: PrefixHealthWrapper{
+				PrefixHealth: &BabelPrefixHealth{
+					Prefix: netip.MustParsePrefix("10.20.0.0/16"),
+					Metric: new(uint32(50)),
+				},
+			},
+			yamlStr: `type: babel
+prefix: 10.20.0.0/16
+metric: 50
+`,
+		},
+		{
+			name: "BGPPrefixHealth",
+			wrapper: PrefixHealthWrapper{
+				PrefixHealth: &BGPPrefixHealth{
+					Prefix: netip.MustParsePrefix("10.30.0.0/16"),
+					Metric: 40,
+				},
+			},
+			yamlStr: `type: bgp
+prefix: 10.30.0.0/16
+metric: 40
+`,
+		},
+		{
+			name: "AnycastPrefixHealth",
+			wrapper: PrefixHealthWrapper{
+				PrefixHealth: &AnycastPrefixHealth{
+					Prefix: netip.MustParsePrefix("10.53.0.53/32"),
+					Weight: 5000,
+					URL:    "http://10.53.0.53/health",
+				},
+			},
+			yamlStr: `type: anycast
+prefix: 10.53.0.53/32
+weight: 5000
+url: http://10.53.0.53/health
+`,
+		},
+		{
+			name: "SourceSpecificPrefixHealth",
+			wrapper: PrefixHealthWrapper{
+				PrefixHealth: &StaticPrefixHealth{
+					Prefix: netip.MustParsePrefix("0.0.0.0/0"),
+					Metric: 100,
+				},
+				Src: netip.MustParsePrefix("10.5.0.0/24"),
+			},
+			yamlStr: `type: static
+prefix: 0.0.0.0/0
+src: 10.5.0.0/24
+metric: 100
 `,
 		},
 	}
@@ -78,7 +134,7 @@ delay: 5s
 			err := yaml.Unmarshal([]byte(tt.yamlStr), &wrapper)
 			assert.NoError(t, err)
 			assert.NotNil(t, wrapper.PrefixHealth)
-			assert.Equal(t, tt.wrapper.GetPrefix(), wrapper.GetPrefix())
+			assert.Equal(t, tt.wrapper.GetRoutePrefix(), wrapper.GetRoutePrefix())
 		})
 
 		t.Run(tt.name+" RoundTrip", func(t *testing.T) {
@@ -92,7 +148,7 @@ delay: 5s
 			assert.NoError(t, err)
 
 			// Verify
-			assert.Equal(t, tt.wrapper.GetPrefix(), wrapper.GetPrefix())
+			assert.Equal(t, tt.wrapper.GetRoutePrefix(), wrapper.GetRoutePrefix())
 
 			switch orig := tt.wrapper.PrefixHealth.(type) {
 			case *StaticPrefixHealth:
@@ -110,6 +166,18 @@ delay: 5s
 				assert.True(t, ok)
 				assert.Equal(t, orig.URL, result.URL)
 				assert.Equal(t, orig.Delay, result.Delay)
+			case *BabelPrefixHealth:
+				result, ok := wrapper.PrefixHealth.(*BabelPrefixHealth)
+				assert.True(t, ok)
+				assert.Equal(t, orig.Metric, result.Metric)
+			case *BGPPrefixHealth:
+				result, ok := wrapper.PrefixHealth.(*BGPPrefixHealth)
+				assert.True(t, ok)
+				assert.Equal(t, orig.Metric, result.Metric)
+			case *AnycastPrefixHealth:
+				result, ok := wrapper.PrefixHealth.(*AnycastPrefixHealth)
+				assert.True(t, ok)
+				assert.Equal(t, orig, result)
 			}
 		})
 	}
@@ -155,3 +223,15 @@ func TestHTTPPrefixHealthClosesResponseBody(t *testing.T) {
 		})
 	}
 }
+
+func TestAnycastPrefixHealthAddsWeightToCheckLatency(t *testing.T) {
+	monitor := anycastPrefixHealthMonitor{check: staticPrefixHealthMonitor{metric: 1200}, weight: 5000}
+	assert.Equal(t, uint32(6200), monitor.GetMetric())
+
+	// a failed check withdraws the prefix regardless of the weight
+	monitor.check = staticPrefixHealthMonitor{metric: INF}
+	assert.Equal(t, INF, monitor.GetMetric())
+
+	monitor = anycastPrefixHealthMonitor{check: staticPrefixHealthMonitor{metric: INFM}, weight: 5000}
+	assert.Equal(t, INFM, monitor.GetMetric())
+}
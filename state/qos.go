@@ -0,0 +1,172 @@
+package state
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type QoSPriority string
+
+const (
+	QoSNormal QoSPriority = "normal"
+	QoSMedium QoSPriority = "medium"
+	QoSHigh   QoSPriority = "high" // the priority of nylon control traffic
+)
+
+// QoSCfg assigns data packets to classes. Every router forwarding a packet classifies it, and sends the packets of
+// higher priority classes to a peer first. Classes are evaluated in order and the first class with a matching rule
+// wins, packets that match no class are sent at normal priority without a rate limit.
+type QoSCfg struct {
+	Classes []QoSClass `yaml:"classes"`
+}
+
+type QoSClass struct {
+	Name     string      `yaml:"name"`
+	Priority QoSPriority `yaml:"priority,omitempty"` // defaults to normal
+	// Rate limits the traffic of the class to each peer, packets above it are dropped. Zero is unlimited.
+	Rate  Bitrate    `yaml:"rate,omitempty"`
+	Match []QoSMatch `yaml:"match"`
+}
+
+// QoSMatch selects packets for a class. A packet must match every field the rule sets.
+type QoSMatch struct {
+	Prefixes []netip.Prefix `yaml:"prefixes,omitempty"` // destination must be within one of these prefixes
+	DSCP     []uint8        `yaml:"dscp,omitempty"`     // differentiated services code point
+	Proto    string         `yaml:"proto,omitempty"`    // tcp, udp, sctp, icmp (icmp and icmpv6) or a protocol number
+	Ports    []PortRange    `yaml:"ports,omitempty"`    // source or destination port, so both directions of a flow match
+}
+
+// Bitrate is a rate in bits per second, written as a number of bits with an optional kbit, mbit or gbit suffix
+type Bitrate uint64
+
+var bitrateUnits = []struct {
+	suffix string
+	scale  uint64
+}{
+	{"gbit", 1_000_000_000},
+	{"mbit", 1_000_000},
+	{"kbit", 1_000},
+	{"bit", 1},
+}
+
+// BytesPerSecond returns the rate in bytes per second
+func (b Bitrate) BytesPerSecond() float64 {
+	return float64(b) / 8
+}
+
+func (b Bitrate) String() string {
+	for _, unit := range bitrateUnits {
+		if b != 0 && uint64(b)%unit.scale == 0 {
+			return fmt.Sprintf("%d%s", uint64(b)/unit.scale, unit.suffix)
+		}
+	}
+	return "0bit"
+}
+
+func ParseBitrate(s string) (Bitrate, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	scale := uint64(1)
+	for _, unit := range bitrateUnits {
+		if num, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, scale = strings.TrimSpace(num), unit.scale
+			break
+		}
+	}
+	num, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q, expected e.g. 500kbit or 10mbit", s)
+	}
+	return Bitrate(num * scale), nil
+}
+
+func (b Bitrate) MarshalYAML() (interface{}, error) {
+	return b.String(), nil
+}
+
+func (b *Bitrate) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	rate, err := ParseBitrate(s)
+	if err != nil {
+		return err
+	}
+	*b = rate
+	return nil
+}
+
+// QoSPacket is the view of a packet that qos rules are matched against
+type QoSPacket struct {
+	Dst              netip.Addr
+	DSCP             uint8
+	Proto            uint8
+	SrcPort, DstPort uint16
+	HasPorts         bool
+}
+
+type qosMatch struct {
+	QoSMatch
+	protos []uint8
+}
+
+func (m qosMatch) matches(p QoSPacket) bool {
+	if len(m.Prefixes) != 0 && !slices.ContainsFunc(m.Prefixes, func(prefix netip.Prefix) bool {
+		return prefix.Contains(p.Dst)
+	}) {
+		return false
+	}
+	if len(m.DSCP) != 0 && !slices.Contains(m.DSCP, p.DSCP) {
+		return false
+	}
+	if m.protos != nil && !slices.Contains(m.protos, p.Proto) {
+		return false
+	}
+	if len(m.Ports) != 0 && (!p.HasPorts || !slices.ContainsFunc(m.Ports, func(pr PortRange) bool {
+		return pr.Contains(p.SrcPort) || pr.Contains(p.DstPort)
+	})) {
+		return false
+	}
+	return true
+}
+
+// QoS is the compiled classifier of the mesh
+type QoS struct {
+	Classes []QoSClass
+	matches [][]qosMatch
+}
+
+// Classify returns the index of the class of a packet, or -1 if it matches no class
+func (q *QoS) Classify(p QoSPacket) int {
+	for i, matches := range q.matches {
+		if slices.ContainsFunc(matches, func(m qosMatch) bool {
+			return m.matches(p)
+		}) {
+			return i
+		}
+	}
+	return -1
+}
+
+// CompileQoS returns the classifier of the mesh, or nil if the mesh has no classes
+func (c *CentralCfg) CompileQoS() (*QoS, error) {
+	if c.QoS == nil || len(c.QoS.Classes) == 0 {
+		return nil, nil
+	}
+	q := &QoS{Classes: c.QoS.Classes}
+	for _, class := range c.QoS.Classes {
+		matches := make([]qosMatch, 0, len(class.Match))
+		for _, match := range class.Match {
+			protos, err := parseProtocol(match.Proto)
+			if err != nil {
+				return nil, fmt.Errorf("invalid qos class %s: %v", class.Name, err)
+			}
+			matches = append(matches, qosMatch{QoSMatch: match, protos: protos})
+		}
+		q.matches = append(q.matches, matches)
+	}
+	return q, nil
+}
@@ -0,0 +1,51 @@
+package state
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQoSClassify(t *testing.T) {
+	cfg := &CentralCfg{QoS: &QoSCfg{Classes: []QoSClass{
+		{Name: "voice", Priority: QoSHigh, Match: []QoSMatch{{DSCP: []uint8{46}}, {Proto: "udp", Ports: []PortRange{{First: 5060, Last: 5061}}}}},
+		{Name: "backup", Priority: QoSNormal, Rate: 10_000_000, Match: []QoSMatch{{Prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.9.0/24")}, Proto: "tcp"}}},
+	}}}
+	q, err := cfg.CompileQoS()
+	require.NoError(t, err)
+	dst := netip.MustParseAddr("10.0.9.1")
+
+	assert.Equal(t, 0, q.Classify(QoSPacket{Dst: dst, DSCP: 46, Proto: 6}))
+	assert.Equal(t, 0, q.Classify(QoSPacket{Dst: dst, Proto: 17, SrcPort: 5061, DstPort: 40000, HasPorts: true}), "replies match by their source port")
+	assert.Equal(t, 1, q.Classify(QoSPacket{Dst: dst, Proto: 6, SrcPort: 40000, DstPort: 22, HasPorts: true}))
+	assert.Equal(t, -1, q.Classify(QoSPacket{Dst: dst, Proto: 17, SrcPort: 40000, DstPort: 53, HasPorts: true}))
+	assert.Equal(t, -1, q.Classify(QoSPacket{Dst: netip.MustParseAddr("10.0.1.1"), Proto: 6}), "every field of a rule must match")
+
+	cfg.QoS.Classes = nil
+	q, err = cfg.CompileQoS()
+	require.NoError(t, err)
+	assert.Nil(t, q)
+}
+
+func TestBitrate(t *testing.T) {
+	for s, want := range map[string]Bitrate{"500kbit": 500_000, "10 Mbit": 10_000_000, "1gbit": 1_000_000_000, "64000": 64_000} {
+		rate, err := ParseBitrate(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, rate, s)
+	}
+	_, err := ParseBitrate("10mb")
+	assert.Error(t, err)
+
+	assert.Equal(t, "1500kbit", Bitrate(1_500_000).String())
+	assert.Equal(t, 125_000.0, Bitrate(1_000_000).BytesPerSecond())
+
+	var class QoSClass
+	require.NoError(t, yaml.Unmarshal([]byte("name: bulk\nrate: 2mbit\n"), &class))
+	assert.Equal(t, Bitrate(2_000_000), class.Rate)
+	out, err := yaml.Marshal(class)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "rate: 2mbit")
+}
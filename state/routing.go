@@ -11,21 +11,45 @@ import (
 
 type NodeId string
 
+// RoutePrefix is a destination prefix with an optional source prefix (RFC 9079).
+// Src is the zero prefix for routes that are not source-specific.
+type RoutePrefix struct {
+	Prefix netip.Prefix
+	Src    netip.Prefix
+}
+
+func (p RoutePrefix) IsSourceSpecific() bool {
+	return p.Src.IsValid()
+}
+
+// Contains reports whether a packet from src to dst matches this route.
+func (p RoutePrefix) Contains(src, dst netip.Addr) bool {
+	return p.Prefix.Contains(dst) && (!p.IsSourceSpecific() || p.Src.Contains(src))
+}
+
+func (p RoutePrefix) String() string {
+	if p.IsSourceSpecific() {
+		return fmt.Sprintf("%s from %s", p.Prefix, p.Src)
+	}
+	return p.Prefix.String()
+}
+
 // Source is a pair of a router-id and a prefix (Babel Section 2.7).
+// Source-specific routes carry their source prefix as part of the prefix (RFC 9079 Section 5.2).
 type Source struct {
 	NodeId
-	netip.Prefix
+	RoutePrefix
 }
 
 func (s Source) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("router", string(s.NodeId)),
-		slog.String("prefix", s.Prefix.String()),
+		slog.String("prefix", s.RoutePrefix.String()),
 	)
 }
 
 func (s Source) String() string {
-	return fmt.Sprintf("(router: %s, prefix: %s)", s.NodeId, s.Prefix)
+	return fmt.Sprintf("(router: %s, prefix: %s)", s.NodeId, s.RoutePrefix)
 }
 
 type Advertisement struct {
@@ -38,15 +62,34 @@ type Advertisement struct {
 type RouterState struct {
 	*RouterTunables
 	Id         NodeId
-	SelfSeqno  map[netip.Prefix]uint16
-	Routes     map[netip.Prefix]SelRoute
+	SelfSeqno  map[RoutePrefix]uint16
+	Routes     map[RoutePrefix]SelRoute
 	Sources    map[Source]FD
 	Neighbours []*Neighbour
 	// Advertised is a map tracking the prefix and the time it will be advertised until
-	Advertised map[netip.Prefix]Advertisement
+	Advertised map[RoutePrefix]Advertisement
+	// Policy filters the routes we import from and export to neighbours, nil accepts everything
+	Policy *RoutePolicy
+	// Dampening tracks flapping sources, nil disables dampening
+	Dampening *Dampener
+	// Anycast holds the prefixes that several routers advertise as a shared service
+	Anycast map[RoutePrefix]struct{}
+	// Origins maps each prefix to the routers that may originate it
+	Origins map[RoutePrefix][]NodeId
+	// Draining advertises every route with a raised metric, so traffic moves off this router
+	Draining bool
+	// Clock returns the current time, nil uses the system clock
+	Clock func() time.Time
+}
+
+func (s *RouterState) Now() time.Time {
+	if s.Clock == nil {
+		return time.Now()
+	}
+	return s.Clock()
 }
 
-func (s *RouterState) GetSeqno(prefix netip.Prefix) uint16 {
+func (s *RouterState) GetSeqno(prefix RoutePrefix) uint16 {
 	seq, ok := s.SelfSeqno[prefix]
 	if !ok {
 		return 0
@@ -54,7 +97,7 @@ func (s *RouterState) GetSeqno(prefix netip.Prefix) uint16 {
 	return seq
 }
 
-func (s *RouterState) SetSeqno(prefix netip.Prefix, seqno uint16) {
+func (s *RouterState) SetSeqno(prefix RoutePrefix, seqno uint16) {
 	s.SelfSeqno[prefix] = seqno
 }
 
@@ -69,8 +112,9 @@ func (s *RouterState) StringRoutes() string {
 
 type Neighbour struct {
 	Id     NodeId
-	Routes map[netip.Prefix]NeighRoute
+	Routes map[RoutePrefix]NeighRoute
 	Eps    []Endpoint
+	Link   LinkAttrs // attributes of our link to this neighbour, from the graph
 }
 
 type FD struct {
@@ -95,13 +139,13 @@ type PubRoute struct {
 }
 
 func (r PubRoute) String() string {
-	return fmt.Sprintf("(router: %s, prefix: %s, seqno: %d, metric: %d)", r.NodeId, r.Prefix, r.Seqno, r.Metric)
+	return fmt.Sprintf("(router: %s, prefix: %s, seqno: %d, metric: %d)", r.NodeId, r.RoutePrefix, r.Seqno, r.Metric)
 }
 
 func (r PubRoute) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("router", string(r.NodeId)),
-		slog.String("prefix", r.Prefix.String()),
+		slog.String("prefix", r.RoutePrefix.String()),
 		slog.Uint64("seqno", uint64(r.Seqno)),
 		slog.Uint64("metric", uint64(r.Metric)),
 	)
@@ -117,17 +161,23 @@ type SelRoute struct {
 	Nh          NodeId    // next hop node
 	ExpireAt    time.Time // when the route expires
 	RetractedBy []NodeId
+	// Multipath contains every next hop (including Nh) that traffic may be spread across, sorted by id.
+	// It is empty unless more than one feasible next hop is within the multipath band.
+	Multipath []NodeId
 }
 
 func (r SelRoute) String() string {
-	return fmt.Sprintf("(nh: %s, router: %s, prefix: %s, seqno: %d, metric: %d)", r.Nh, r.NodeId, r.Prefix, r.Seqno, r.Metric)
+	if len(r.Multipath) != 0 {
+		return fmt.Sprintf("(nh: %s, router: %s, prefix: %s, seqno: %d, metric: %d, multipath: %v)", r.Nh, r.NodeId, r.RoutePrefix, r.Seqno, r.Metric, r.Multipath)
+	}
+	return fmt.Sprintf("(nh: %s, router: %s, prefix: %s, seqno: %d, metric: %d)", r.Nh, r.NodeId, r.RoutePrefix, r.Seqno, r.Metric)
 }
 
 func (r SelRoute) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.Any("nh", r.Nh), // Use Any if Nh is an object/interface
 		slog.String("router", string(r.NodeId)),
-		slog.String("prefix", r.Prefix.String()),
+		slog.String("prefix", r.RoutePrefix.String()),
 		slog.Uint64("seqno", uint64(r.Seqno)),
 		slog.Uint64("metric", uint64(r.Metric)),
 	)
@@ -38,3 +38,26 @@ port: abcd
 	err := yaml.Unmarshal([]byte(x1), &y1)
 	assert.ErrorContains(t, err, "cannot unmarshal string")
 }
+
+func TestEndpointCfgYAML(t *testing.T) {
+	x := `endpoints:
+  - 192.0.2.1:57175
+  - address: lte.example.org:57175
+    class: backup
+    metered: true
+`
+	var cfg struct {
+		Endpoints []EndpointCfg `yaml:"endpoints"`
+	}
+	assert.NoError(t, yaml.Unmarshal([]byte(x), &cfg))
+	assert.Equal(t, []EndpointCfg{
+		{Address: "192.0.2.1:57175"},
+		{Address: "lte.example.org:57175", EndpointAttrs: EndpointAttrs{Class: EndpointBackup, Metered: true}},
+	}, cfg.Endpoints)
+
+	// endpoints without attributes stay plain strings
+	out, err := yaml.Marshal(cfg)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "- 192.0.2.1:57175\n")
+	assert.NotContains(t, string(out), "address: 192.0.2.1")
+}
@@ -0,0 +1,164 @@
+package state
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// SimAction is the kind of change a simulation event makes to the network
+type SimAction string
+
+const (
+	SimLinkDown SimAction = "link_down" // packets on the link are dropped
+	SimLinkUp   SimAction = "link_up"
+	SimLatency  SimAction = "latency" // sets the one-way latency of the link
+	SimLoss     SimAction = "loss"    // sets the fraction of packets dropped on the link
+	SimCrash    SimAction = "crash"   // the node stops, its neighbours notice when probes time out
+	SimRestart  SimAction = "restart" // the node starts again with an empty routing table
+	SimConfig   SimAction = "config"  // every node applies another central config
+)
+
+// SimScenario is a sequence of timed changes to run against a central config with `nylon sim`
+type SimScenario struct {
+	Duration time.Duration `yaml:"duration,omitempty"` // simulated time, defaults to one minute after the last event
+	Seed     uint64        `yaml:"seed,omitempty"`     // seeds packet loss, so runs are reproducible
+	Latency  time.Duration `yaml:"latency,omitempty"`  // one-way latency of links that are not listed, defaults to 1ms
+	Links    []SimLinkCfg  `yaml:"links,omitempty"`    // initial conditions of individual links
+	Events   []SimEvent    `yaml:"events,omitempty"`
+}
+
+type SimLinkCfg struct {
+	Link    []NodeId      `yaml:"link"`
+	Latency time.Duration `yaml:"latency,omitempty"`
+	Loss    float64       `yaml:"loss,omitempty"`
+}
+
+type SimEvent struct {
+	At      time.Duration `yaml:"at"`
+	Action  SimAction     `yaml:"action"`
+	Link    []NodeId      `yaml:"link,omitempty"`
+	Node    NodeId        `yaml:"node,omitempty"`
+	Latency time.Duration `yaml:"latency,omitempty"`
+	Loss    float64       `yaml:"loss,omitempty"`
+	Config  string        `yaml:"config,omitempty"` // path of the central config, relative to the scenario file
+	Central *CentralCfg   `yaml:"-"`                // the config read from Config
+}
+
+func (e SimEvent) String() string {
+	switch e.Action {
+	case SimLinkDown, SimLinkUp:
+		return fmt.Sprintf("%s %s", e.Action, FormatSimLink(e.Link))
+	case SimLatency:
+		return fmt.Sprintf("latency %s %s", FormatSimLink(e.Link), e.Latency)
+	case SimLoss:
+		return fmt.Sprintf("loss %s %g%%", FormatSimLink(e.Link), e.Loss*100)
+	case SimCrash, SimRestart:
+		return fmt.Sprintf("%s %s", e.Action, e.Node)
+	case SimConfig:
+		return fmt.Sprintf("config %s", e.Config)
+	}
+	return string(e.Action)
+}
+
+func FormatSimLink(link []NodeId) string {
+	if len(link) != 2 {
+		return fmt.Sprint(link)
+	}
+	return fmt.Sprintf("%s <-> %s", link[0], link[1])
+}
+
+// ReadSimScenario reads a scenario and the central configs its config events refer to
+func ReadSimScenario(path string) (*SimScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scenario SimScenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, err
+	}
+	if err := SimScenarioValidator(&scenario); err != nil {
+		return nil, err
+	}
+	for i, event := range scenario.Events {
+		if event.Action != SimConfig {
+			continue
+		}
+		cfgPath := event.Config
+		if !filepath.IsAbs(cfgPath) {
+			cfgPath = filepath.Join(filepath.Dir(path), cfgPath)
+		}
+		cfgData, err := os.ReadFile(cfgPath)
+		if err != nil {
+			return nil, fmt.Errorf("event at %s: %w", event.At, err)
+		}
+		var cfg CentralCfg
+		if err := yaml.Unmarshal(cfgData, &cfg); err != nil {
+			return nil, fmt.Errorf("event at %s: %w", event.At, err)
+		}
+		scenario.Events[i].Central = &cfg
+	}
+	return &scenario, nil
+}
+
+// SimScenarioValidator checks the scenario and orders its events by time
+func SimScenarioValidator(s *SimScenario) error {
+	if s.Duration < 0 || s.Latency < 0 {
+		return fmt.Errorf("scenario duration and latency must be positive")
+	}
+	for _, link := range s.Links {
+		if err := validateSimLink(link.Link); err != nil {
+			return err
+		}
+		if link.Latency < 0 {
+			return fmt.Errorf("link %s: latency must be positive", FormatSimLink(link.Link))
+		}
+		if link.Loss < 0 || link.Loss > 1 {
+			return fmt.Errorf("link %s: loss must be between 0 and 1", FormatSimLink(link.Link))
+		}
+	}
+	for _, event := range s.Events {
+		if event.At < 0 {
+			return fmt.Errorf("event %s: at must be positive", event)
+		}
+		switch event.Action {
+		case SimLinkDown, SimLinkUp, SimLatency, SimLoss:
+			if err := validateSimLink(event.Link); err != nil {
+				return fmt.Errorf("event at %s: %w", event.At, err)
+			}
+			if event.Action == SimLatency && event.Latency < 0 {
+				return fmt.Errorf("event at %s: latency must be positive", event.At)
+			}
+			if event.Action == SimLoss && (event.Loss < 0 || event.Loss > 1) {
+				return fmt.Errorf("event at %s: loss must be between 0 and 1", event.At)
+			}
+		case SimCrash, SimRestart:
+			if event.Node == "" {
+				return fmt.Errorf("event at %s: %s requires a node", event.At, event.Action)
+			}
+		case SimConfig:
+			if event.Config == "" {
+				return fmt.Errorf("event at %s: config requires the path of a central config", event.At)
+			}
+		default:
+			return fmt.Errorf("event at %s: unknown action %q", event.At, event.Action)
+		}
+	}
+	slices.SortStableFunc(s.Events, func(a, b SimEvent) int {
+		return cmp.Compare(a.At, b.At)
+	})
+	return nil
+}
+
+func validateSimLink(link []NodeId) error {
+	if len(link) != 2 || link[0] == link[1] {
+		return fmt.Errorf("link %s must name two different nodes", FormatSimLink(link))
+	}
+	return nil
+}
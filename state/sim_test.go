@@ -0,0 +1,61 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSimScenario(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "next.yaml"), []byte("routers:\n  - id: alice\ngraph: []\n"), 0600))
+	path := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+seed: 7
+links:
+  - link: [alice, bob]
+    latency: 20ms
+    loss: 0.01
+events:
+  - at: 1m
+    action: config
+    config: next.yaml
+  - at: 30s
+    action: link_down
+    link: [alice, bob]
+`), 0600))
+
+	scenario, err := ReadSimScenario(path)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), scenario.Seed)
+	assert.Equal(t, []SimLinkCfg{{Link: []NodeId{"alice", "bob"}, Latency: 20 * time.Millisecond, Loss: 0.01}}, scenario.Links)
+	require.Len(t, scenario.Events, 2)
+	assert.Equal(t, SimLinkDown, scenario.Events[0].Action)
+	assert.Equal(t, "link_down alice <-> bob", scenario.Events[0].String())
+	require.NotNil(t, scenario.Events[1].Central)
+	assert.Equal(t, NodeId("alice"), scenario.Events[1].Central.Routers[0].Id)
+}
+
+func TestSimScenarioValidator(t *testing.T) {
+	tests := []struct {
+		name  string
+		event SimEvent
+		err   string
+	}{
+		{"unknown action", SimEvent{Action: "reboot"}, `unknown action "reboot"`},
+		{"link with one node", SimEvent{Action: SimLinkUp, Link: []NodeId{"alice"}}, "must name two different nodes"},
+		{"loss above one", SimEvent{Action: SimLoss, Link: []NodeId{"alice", "bob"}, Loss: 2}, "loss must be between 0 and 1"},
+		{"crash without node", SimEvent{Action: SimCrash}, "crash requires a node"},
+		{"config without path", SimEvent{Action: SimConfig}, "config requires the path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SimScenarioValidator(&SimScenario{Events: []SimEvent{tt.event}})
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
@@ -5,13 +5,20 @@ import "time"
 // RouterTunables contains all timing and algorithm parameters for the router.
 // These are set once at startup and should not be mutated after the Nylon instance starts.
 type RouterTunables struct {
-	HopCost               uint32 // add a 5 microsecond hop cost to prevent loops on ultra-fast networks.
-	LargeChangeThreshold  uint32 // 100 milliseconds change
-	SeqnoRequestHopCount  uint8
-	RouteUpdateDelay      time.Duration
-	ProbeDelay            time.Duration
-	ProbeRecoveryDelay    time.Duration
-	ProbeDiscoveryDelay   time.Duration
+	HopCost              uint32 // add a 5 microsecond hop cost to prevent loops on ultra-fast networks.
+	LargeChangeThreshold uint32 // 100 milliseconds change
+	SeqnoRequestHopCount uint8
+	RouteUpdateDelay     time.Duration
+	ProbeDelay           time.Duration
+	ProbeRecoveryDelay   time.Duration
+	ProbeDiscoveryDelay  time.Duration
+	// ProbeBackoffMax is the longest interval active endpoints are probed at while their link is idle and its rtt is
+	// stable. The interval doubles from ProbeDelay up to this limit, values at or below ProbeDelay disable the backoff
+	ProbeBackoffMax time.Duration
+	// ProbeBackoffVariance is the relative change of the rtt that returns a link to probing every ProbeDelay
+	ProbeBackoffVariance float64
+	// DetectTick is how often links with fast failure detection are checked for due liveness probes and failures
+	DetectTick            time.Duration
 	StarvationDelay       time.Duration
 	SeqnoDedupTTL         time.Duration
 	NeighbourIOFlushDelay time.Duration
@@ -24,10 +31,42 @@ type RouterTunables struct {
 	// MinimumConfidenceWindow is the minimum number of samples before we lower the ping
 	MinimumConfidenceWindow int
 
+	// ProbeLossWindow is the number of recent probes used to compute the loss ratio
+	ProbeLossWindow int
+	// ProbeLateThreshold is the rtt above which a probe reply is counted as late, late replies count as lost
+	ProbeLateThreshold time.Duration
+	JitterWeight       float64       // metric += JitterWeight * jitter
+	LossPenalty        time.Duration // metric += LossPenalty * loss ratio
+	MeteredPenalty     time.Duration // metric += MeteredPenalty on metered endpoints
+	// MeteredProbeDelay replaces ProbeDelay as the shortest probe interval of metered endpoints
+	MeteredProbeDelay time.Duration
+
 	GcDelay            time.Duration
 	LinkDeadThreshold  time.Duration
 	RouteExpiryTime    time.Duration
 	LinkSwitchDeadband float64 // We will switch to a new feasible route if: metric(new) * LinkSwitchDeadband <= metric(old)
+	// MultipathBand enables equal-cost multipath forwarding. Feasible routes with metric <= metric(selected) * MultipathBand
+	// are used alongside the selected route. Values below 1 disable multipath.
+	MultipathBand float64
+	// DuplicateWindow is how long a duplicated packet is remembered where it leaves the mesh, so the copy that took the
+	// slower path is dropped.
+	// DuplicateWindowPackets bounds how many packets are remembered at once.
+	DuplicateWindow        time.Duration
+	DuplicateWindowPackets int
+	// ACLFlowTimeout is how long replies to a flow accepted by the acl are accepted after its last packet.
+	// ACLFlowCapacity bounds how many flows are remembered at once, the least recently used are forgotten first.
+	ACLFlowTimeout  time.Duration
+	ACLFlowCapacity uint64
+	// PortForwardTCPTimeout and PortForwardUDPTimeout are how long a connection through a port forward is tracked after
+	// its last packet. PortForwardCapacity bounds how many connections are tracked at once.
+	PortForwardTCPTimeout time.Duration
+	PortForwardUDPTimeout time.Duration
+	PortForwardCapacity   uint64
+	// QoSBurst sizes the burst a rate limited qos class may send at once, as the traffic of this long at its rate
+	QoSBurst time.Duration
+	// AnycastSwitchThreshold is how much better another node advertising an anycast prefix must be before we move away
+	// from the node currently selected for it, so existing flows are not moved by small health-check fluctuations
+	AnycastSwitchThreshold uint32
 
 	// client configuration
 	ClientKeepaliveInterval time.Duration
@@ -36,6 +75,13 @@ type RouterTunables struct {
 	// central updates
 	CentralUpdateDelay time.Duration
 
+	// StatePersistDelay is how often the router state file is rewritten if it changed
+	StatePersistDelay time.Duration
+
+	// draining
+	DrainQuietPeriod time.Duration // a draining router is drained once it has not forwarded transit traffic for this long
+	DrainTimeout     time.Duration // the longest we drain for before shutting down on SIGTERM
+
 	// healthcheck defaults
 	HealthCheckDelay       time.Duration
 	HealthCheckMaxFailures int
@@ -43,6 +89,15 @@ type RouterTunables struct {
 	EndpointResolveExpiry time.Duration
 	EndpointResolveDelay  time.Duration
 
+	// babel interfaces
+	BabelHelloInterval  time.Duration
+	BabelUpdateInterval time.Duration
+
+	// bgp speaker
+	BGPHoldTime     time.Duration
+	BGPConnectRetry time.Duration
+	BGPUpdateDelay  time.Duration // delay between synchronising the routes announced to bgp peers
+
 	MaxConfigSize int64
 }
 
@@ -68,6 +123,9 @@ func DefaultRouterTunables() RouterTunables {
 		ProbeDelay:            probeDelay,
 		ProbeRecoveryDelay:    time.Millisecond * 1500,
 		ProbeDiscoveryDelay:   time.Second * 10,
+		ProbeBackoffMax:       time.Second * 30,
+		ProbeBackoffVariance:  0.25,
+		DetectTick:            MinDetectInterval,
 		StarvationDelay:       time.Millisecond * 100,
 		SeqnoDedupTTL:         time.Second * 3,
 		NeighbourIOFlushDelay: time.Millisecond * 500,
@@ -78,22 +136,54 @@ func DefaultRouterTunables() RouterTunables {
 		OutlierPercentage:       0.05,
 		MinimumConfidenceWindow: int(time.Second * 15 / probeDelay),
 
+		ProbeLossWindow:    int((time.Second * 60) / probeDelay),
+		ProbeLateThreshold: probeDelay,
+		JitterWeight:       1,
+		LossPenalty:        time.Second,
+		MeteredPenalty:     time.Millisecond * 500,
+		MeteredProbeDelay:  5 * probeDelay,
+
 		GcDelay:            time.Millisecond * 1000,
 		LinkDeadThreshold:  5 * probeDelay,
 		RouteExpiryTime:    5 * routeUpdateDelay,
 		LinkSwitchDeadband: 1.1,
 
+		DuplicateWindow:        time.Millisecond * 500,
+		DuplicateWindowPackets: 4096,
+
+		ACLFlowTimeout:  time.Minute * 2,
+		ACLFlowCapacity: 1 << 16,
+		QoSBurst:        time.Millisecond * 50,
+
+		PortForwardTCPTimeout: time.Hour,
+		PortForwardUDPTimeout: time.Minute * 2,
+		PortForwardCapacity:   1 << 16,
+
+		AnycastSwitchThreshold: 20 * 1000, // 20 milliseconds
+
 		ClientKeepaliveInterval: 3 * probeDelay,
 		ClientDeadThreshold:     6 * probeDelay, // 2 * ClientKeepaliveInterval
 
 		CentralUpdateDelay: time.Second * 10,
 
+		StatePersistDelay: time.Second * 10,
+
+		DrainQuietPeriod: time.Second * 3,
+		DrainTimeout:     time.Second * 15,
+
 		HealthCheckDelay:       time.Second * 15,
 		HealthCheckMaxFailures: 3,
 
 		EndpointResolveExpiry: time.Minute * 1,
 		EndpointResolveDelay:  time.Second * 15,
 
+		BabelHelloInterval:  time.Second * 4, // babeld defaults
+		BabelUpdateInterval: time.Second * 16,
+
+		BGPHoldTime:     time.Second * 90,
+		BGPConnectRetry: time.Second * 30,
+		BGPUpdateDelay:  time.Second * 5,
+
 		MaxConfigSize: 1 << 20, // 1 MB
 	}
 }
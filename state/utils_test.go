@@ -33,7 +33,7 @@ func SampleNetwork(t *testing.T, numClients, numRouters int, fullyConnected bool
 			PubKey: keyStore[client].Pubkey(),
 			Prefixes: []PrefixHealthWrapper{
 				{
-					&StaticPrefixHealth{
+					PrefixHealth: &StaticPrefixHealth{
 						Prefix: netip.MustParsePrefix(fmt.Sprintf("10.1.0.%d/32", idx)),
 						Metric: 0,
 					},
@@ -54,15 +54,15 @@ func SampleNetwork(t *testing.T, numClients, numRouters int, fullyConnected bool
 				PubKey: keyStore[router].Pubkey(),
 				Prefixes: []PrefixHealthWrapper{
 					{
-						&StaticPrefixHealth{
+						PrefixHealth: &StaticPrefixHealth{
 							Prefix: netip.MustParsePrefix(fmt.Sprintf("10.1.0.%d/32", idx)),
 							Metric: 0,
 						},
 					},
 				},
 			},
-			Endpoints: []string{
-				fmt.Sprintf("192.168.0.%d:25565", idx),
+			Endpoints: []EndpointCfg{
+				{Address: fmt.Sprintf("192.168.0.%d:25565", idx)},
 			},
 		})
 	}
@@ -43,6 +43,27 @@ func NodeConfigValidator(central *CentralCfg, node *LocalCfg) error {
 			return fmt.Errorf("observability address must be a valid host:port: %v", err)
 		}
 	}
+	if node.MultipathBand != 0 && node.MultipathBand < 1 {
+		return fmt.Errorf("multipath band must be at least 1")
+	}
+	seenBabel := make(map[string]struct{})
+	for _, babel := range node.Babel {
+		if babel.Interface == "" {
+			return fmt.Errorf("babel interface name must not be empty")
+		}
+		if _, ok := seenBabel[babel.Interface]; ok {
+			return fmt.Errorf("babel interface %s is configured more than once", babel.Interface)
+		}
+		seenBabel[babel.Interface] = struct{}{}
+		if babel.Cost == 0xFFFF {
+			return fmt.Errorf("babel cost of interface %s must be less than 65535", babel.Interface)
+		}
+	}
+	if node.BGP != nil {
+		if err := validateBGP(node.BGP); err != nil {
+			return err
+		}
+	}
 	if node.Dist != nil {
 		_, err := url.Parse(node.Dist.Url)
 		if err != nil {
@@ -62,6 +83,9 @@ func NodeConfigValidator(central *CentralCfg, node *LocalCfg) error {
 			return fmt.Errorf("invalid prefix %s", p)
 		}
 	}
+	if err := validatePortForwards(central, node); err != nil {
+		return err
+	}
 	// check that node is in central config
 	if central != nil && !central.IsNode(node.Id) {
 		return fmt.Errorf("node %s is not in central config", node.Id)
@@ -91,9 +115,17 @@ func CentralConfigValidator(cfg *CentralCfg) error {
 			return fmt.Errorf("duplicate router id %s", node.Id)
 		}
 		for _, endpoint := range node.Endpoints {
-			if _, _, err := parseEndpoint(endpoint); err != nil {
+			if _, _, err := parseEndpoint(endpoint.Address); err != nil {
 				return fmt.Errorf("router %s has invalid endpoint: %w", node.Id, err)
 			}
+			if err := validateEndpointAttrs(endpoint.EndpointAttrs); err != nil {
+				return fmt.Errorf("router %s endpoint %s: %w", node.Id, endpoint.Address, err)
+			}
+		}
+		if node.Detect != nil {
+			if err := validateDetect(*node.Detect); err != nil {
+				return fmt.Errorf("router %s: %w", node.Id, err)
+			}
 		}
 		nodes = append(nodes, string(node.Id))
 	}
@@ -107,33 +139,61 @@ func CentralConfigValidator(cfg *CentralCfg) error {
 		}
 		nodes = append(nodes, string(node.Id))
 	}
-	_, err := ParseGraph(cfg.Graph, nodes)
+	edges, err := ParseGraphEdges(cfg.Graph, nodes)
 	if err != nil {
 		return err
 	}
+	for _, edge := range edges {
+		if edge.Endpoint == "" {
+			continue
+		}
+		// the endpoint must belong to one of the routers on the link
+		if !slices.ContainsFunc(cfg.Routers, func(router RouterCfg) bool {
+			if router.Id != edge.V1 && router.Id != edge.V2 {
+				return false
+			}
+			_, ok := router.Endpoint(edge.Endpoint)
+			return ok
+		}) {
+			return fmt.Errorf("invalid graph: link endpoint %s is not an endpoint of %s or %s", edge.Endpoint, edge.V1, edge.V2)
+		}
+	}
 
 	// ensure each node contains unique prefixes (anycast routing allows duplicate prefixes across nodes)
 	for _, router := range cfg.Routers {
-		routerPrefixes := make(map[netip.Prefix]struct{})
+		routerPrefixes := make(map[RoutePrefix]struct{})
 		for _, p := range router.Prefixes {
-			if _, ok := routerPrefixes[p.GetPrefix()]; ok {
+			if _, ok := routerPrefixes[p.GetRoutePrefix()]; ok {
 				return fmt.Errorf("router %s has duplicate prefix %s", router.Id, p)
 			}
-			routerPrefixes[p.GetPrefix()] = struct{}{}
+			routerPrefixes[p.GetRoutePrefix()] = struct{}{}
 		}
 		for _, peer := range cfg.GetPeers(router.Id) {
 			if cfg.IsClient(peer) {
 				client := cfg.GetClient(peer)
 				for _, cp := range client.Prefixes {
-					if _, ok := routerPrefixes[cp.GetPrefix()]; ok {
+					if _, ok := routerPrefixes[cp.GetRoutePrefix()]; ok {
 						return fmt.Errorf("router %s has duplicate prefix %s (provided by client %s)", router.Id, cp, client.Id)
 					}
-					routerPrefixes[cp.GetPrefix()] = struct{}{}
+					routerPrefixes[cp.GetRoutePrefix()] = struct{}{}
 				}
 			}
 		}
 	}
 
+	// an anycast prefix must be anycast on every router that advertises it
+	anycast := cfg.GetAnycastPrefixes()
+	for _, node := range cfg.GetNodes() {
+		for _, p := range node.Prefixes {
+			if _, ok := anycast[p.GetRoutePrefix()]; !ok {
+				continue
+			}
+			if _, ok := p.PrefixHealth.(*AnycastPrefixHealth); !ok {
+				return fmt.Errorf("node %s advertises anycast prefix %s with a different type", node.Id, p.GetPrefix())
+			}
+		}
+	}
+
 	if cfg.Dist != nil {
 		// validate repos
 		for _, repo := range cfg.Dist.Repos {
@@ -149,6 +209,25 @@ func CentralConfigValidator(cfg *CentralCfg) error {
 			return fmt.Errorf("invalid prefix %s", p)
 		}
 	}
+	if err := validatePolicies(cfg); err != nil {
+		return err
+	}
+	if cfg.Dampening != nil {
+		if err := validateDampening(cfg.Dampening.withDefaults()); err != nil {
+			return err
+		}
+	}
+	if err := validateACL(cfg); err != nil {
+		return err
+	}
+	if err := validateQoS(cfg); err != nil {
+		return err
+	}
+	for _, rule := range cfg.Duplicate {
+		if err := validateDuplicateRule(rule); err != nil {
+			return err
+		}
+	}
 	// validate prefixes
 	phs := make([]PrefixHealthWrapper, 0)
 	for _, c := range cfg.Clients {
@@ -161,6 +240,9 @@ func CentralConfigValidator(cfg *CentralCfg) error {
 		if !p.GetPrefix().IsValid() {
 			return fmt.Errorf("invalid prefix %s", p.GetPrefix())
 		}
+		if p.Src.IsValid() && p.Src.Addr().Is4() != p.GetPrefix().Addr().Is4() {
+			return fmt.Errorf("source prefix %s must be in the same address family as prefix %s", p.Src, p.GetPrefix())
+		}
 		switch v := p.PrefixHealth.(type) {
 		case *StaticPrefixHealth:
 			// ok
@@ -182,6 +264,23 @@ func CentralConfigValidator(cfg *CentralCfg) error {
 			if v.Delay != nil && *v.Delay <= 0 {
 				return fmt.Errorf("HTTP delay must be greater than 0 for prefix %s", p.GetPrefix())
 			}
+		case *AnycastPrefixHealth:
+			if v.Addr.IsValid() && v.URL != "" {
+				return fmt.Errorf("anycast prefix %s can be checked with either addr or url, not both", p.GetPrefix())
+			}
+			if _, err := url.Parse(v.URL); err != nil {
+				return fmt.Errorf("invalid HTTP URL %s for prefix %s: %v", v.URL, p.GetPrefix(), err)
+			}
+			if v.Delay != nil && *v.Delay <= 0 {
+				return fmt.Errorf("anycast delay must be greater than 0 for prefix %s", p.GetPrefix())
+			}
+			if v.MaxFailures != nil && *v.MaxFailures <= 0 {
+				return fmt.Errorf("anycast max_failures must be greater than 0 for prefix %s", p.GetPrefix())
+			}
+		case *BabelPrefixHealth, *BGPPrefixHealth:
+			if p.Src.IsValid() {
+				return fmt.Errorf("imported prefix %s cannot have a source prefix", p.GetPrefix())
+			}
 		default:
 			return fmt.Errorf("unknown prefix health type for prefix %s", p.GetPrefix())
 		}
@@ -200,3 +299,187 @@ func CentralConfigValidator(cfg *CentralCfg) error {
 	}
 	return nil
 }
+
+func validateDampening(cfg DampeningCfg) error {
+	if cfg.HalfLife < 0 || cfg.MaxSuppress < 0 {
+		return fmt.Errorf("dampening half_life and max_suppress must be positive")
+	}
+	if cfg.Reuse <= 0 || cfg.Suppress <= cfg.Reuse {
+		return fmt.Errorf("dampening suppress threshold %v must be greater than the reuse threshold %v", cfg.Suppress, cfg.Reuse)
+	}
+	return nil
+}
+
+func validateACL(cfg *CentralCfg) error {
+	for _, node := range cfg.GetNodes() {
+		for _, tag := range node.Tags {
+			if err := NameValidator(tag); err != nil {
+				return fmt.Errorf("invalid tag of node %s: %v", node.Id, err)
+			}
+		}
+	}
+	if cfg.ACL == nil {
+		return nil
+	}
+	switch cfg.ACL.Default {
+	case "", ACLAccept, ACLDeny:
+	default:
+		return fmt.Errorf("invalid acl: unknown default action %q", cfg.ACL.Default)
+	}
+	for _, rule := range cfg.ACL.Rules {
+		if rule.Action != ACLAccept && rule.Action != ACLDeny {
+			return fmt.Errorf("invalid acl: unknown action %q", rule.Action)
+		}
+		for _, ports := range rule.Ports {
+			if ports.First > ports.Last {
+				return fmt.Errorf("invalid acl: invalid port range %s", ports)
+			}
+		}
+	}
+	// resolves every node, group and tag referenced by the rules
+	_, err := cfg.CompileACL()
+	return err
+}
+
+func validateQoS(cfg *CentralCfg) error {
+	if cfg.QoS == nil {
+		return nil
+	}
+	names := make(map[string]struct{})
+	for _, class := range cfg.QoS.Classes {
+		if err := NameValidator(class.Name); err != nil {
+			return fmt.Errorf("invalid qos class name: %v", err)
+		}
+		if _, ok := names[class.Name]; ok {
+			return fmt.Errorf("qos class %s is defined more than once", class.Name)
+		}
+		names[class.Name] = struct{}{}
+		switch class.Priority {
+		case "", QoSNormal, QoSMedium, QoSHigh:
+		default:
+			return fmt.Errorf("qos class %s has unknown priority %q", class.Name, class.Priority)
+		}
+		if len(class.Match) == 0 {
+			return fmt.Errorf("qos class %s must match packets", class.Name)
+		}
+		for _, match := range class.Match {
+			for _, p := range match.Prefixes {
+				if !p.IsValid() {
+					return fmt.Errorf("invalid prefix %s in qos class %s", p, class.Name)
+				}
+			}
+			for _, dscp := range match.DSCP {
+				if dscp > MaxDSCP {
+					return fmt.Errorf("dscp %d of qos class %s must be at most %d", dscp, class.Name, MaxDSCP)
+				}
+			}
+		}
+	}
+	_, err := cfg.CompileQoS()
+	return err
+}
+
+func validateDuplicateRule(rule DuplicateRule) error {
+	if len(rule.Prefixes) == 0 && len(rule.DSCP) == 0 {
+		return fmt.Errorf("duplicate rule must match a prefix or a dscp")
+	}
+	for _, p := range rule.Prefixes {
+		if !p.IsValid() {
+			return fmt.Errorf("invalid duplicate prefix %s", p)
+		}
+	}
+	for _, dscp := range rule.DSCP {
+		if dscp > MaxDSCP {
+			return fmt.Errorf("duplicate dscp %d must be at most %d", dscp, MaxDSCP)
+		}
+	}
+	return nil
+}
+
+func validateDetect(cfg DetectCfg) error {
+	if cfg.Interval < MinDetectInterval {
+		return fmt.Errorf("detect interval must be at least %s", MinDetectInterval)
+	}
+	if cfg.Multiplier < 0 {
+		return fmt.Errorf("detect multiplier must not be negative")
+	}
+	return nil
+}
+
+func validateEndpointAttrs(attrs EndpointAttrs) error {
+	switch attrs.Class {
+	case "", EndpointPrimary, EndpointBackup:
+		return nil
+	default:
+		return fmt.Errorf("unknown endpoint class %q, expected %s or %s", attrs.Class, EndpointPrimary, EndpointBackup)
+	}
+}
+
+func validateBGP(cfg *BGPCfg) error {
+	if cfg.ASN == 0 {
+		return fmt.Errorf("bgp asn must be set")
+	}
+	if cfg.RouterId.IsValid() && !cfg.RouterId.Is4() {
+		return fmt.Errorf("bgp router id must be an IPv4 address")
+	}
+	if cfg.Listen != "" {
+		if _, _, err := net.SplitHostPort(cfg.Listen); err != nil {
+			return fmt.Errorf("bgp listen address must be a valid host:port: %v", err)
+		}
+	}
+	for _, peer := range cfg.Peers {
+		if !peer.Addr.IsValid() {
+			return fmt.Errorf("bgp peer address must be set")
+		}
+		if peer.ASN == 0 {
+			return fmt.Errorf("asn of bgp peer %s must be set", peer.Addr)
+		}
+		if peer.Passive && cfg.Listen == "" {
+			return fmt.Errorf("bgp peer %s is passive, but bgp has no listen address", peer.Addr)
+		}
+		families := make(map[bool]struct{})
+		for _, nh := range peer.NextHops {
+			if !nh.IsValid() {
+				return fmt.Errorf("invalid next hop for bgp peer %s", peer.Addr)
+			}
+			if _, ok := families[nh.Is4()]; ok {
+				return fmt.Errorf("bgp peer %s has more than one next hop of the same address family", peer.Addr)
+			}
+			families[nh.Is4()] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func validatePolicies(cfg *CentralCfg) error {
+	for _, policy := range cfg.Policies {
+		if len(policy.Nodes) == 0 {
+			return fmt.Errorf("invalid policy: nodes must not be empty")
+		}
+		for _, rule := range append(slices.Clone(policy.Import), policy.Export...) {
+			switch rule.Action {
+			case PolicyAccept, PolicyReject:
+				if rule.Offset != 0 {
+					return fmt.Errorf("invalid policy: offset is only allowed with the %s action", PolicyOffset)
+				}
+			case PolicyOffset:
+				if rule.Offset == 0 {
+					return fmt.Errorf("invalid policy: the %s action requires a non-zero offset", PolicyOffset)
+				}
+			default:
+				return fmt.Errorf("invalid policy: unknown action %q", rule.Action)
+			}
+			for _, p := range rule.Prefixes {
+				if !p.IsValid() {
+					return fmt.Errorf("invalid policy: invalid prefix %s", p)
+				}
+			}
+			if rule.MinMetric != nil && rule.MaxMetric != nil && *rule.MinMetric > *rule.MaxMetric {
+				return fmt.Errorf("invalid policy: min_metric %d is greater than max_metric %d", *rule.MinMetric, *rule.MaxMetric)
+			}
+		}
+	}
+	// resolves every node and group referenced by the policies
+	_, err := cfg.CompileRoutePolicy("")
+	return err
+}
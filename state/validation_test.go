@@ -55,6 +55,25 @@ func TestNodeConfigValidator_DnsResolver(t *testing.T) {
 	}))
 }
 
+func TestNodeConfigValidator_BGP(t *testing.T) {
+	node := func(bgp *BGPCfg) *LocalCfg {
+		return &LocalCfg{Id: "node", Port: 5, Key: [32]byte{1}, BGP: bgp}
+	}
+	peer := BGPPeerCfg{Addr: netip.MustParseAddr("192.0.2.1"), ASN: 65002}
+	assert.NoError(t, NodeConfigValidator(nil, node(&BGPCfg{ASN: 65001, Peers: []BGPPeerCfg{peer}})))
+	assert.Error(t, NodeConfigValidator(nil, node(&BGPCfg{Peers: []BGPPeerCfg{peer}})))
+	assert.Error(t, NodeConfigValidator(nil, node(&BGPCfg{ASN: 65001, RouterId: netip.MustParseAddr("fd00::1")})))
+
+	passive := peer
+	passive.Passive = true
+	assert.Error(t, NodeConfigValidator(nil, node(&BGPCfg{ASN: 65001, Peers: []BGPPeerCfg{passive}})))
+	assert.NoError(t, NodeConfigValidator(nil, node(&BGPCfg{ASN: 65001, Listen: ":179", Peers: []BGPPeerCfg{passive}})))
+
+	nextHops := peer
+	nextHops.NextHops = []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")}
+	assert.Error(t, NodeConfigValidator(nil, node(&BGPCfg{ASN: 65001, Peers: []BGPPeerCfg{nextHops}})))
+}
+
 func TestCentralConfigValidator_OverlappingPrefix(t *testing.T) {
 	cfg := &CentralCfg{
 		Routers: []RouterCfg{
@@ -64,19 +83,19 @@ func TestCentralConfigValidator_OverlappingPrefix(t *testing.T) {
 					PubKey: NyPublicKey{},
 					Prefixes: []PrefixHealthWrapper{
 						{
-							&StaticPrefixHealth{
+							PrefixHealth: &StaticPrefixHealth{
 								Prefix: netip.MustParsePrefix("10.5.0.1/32"),
 								Metric: 0,
 							},
 						},
 						{
-							&StaticPrefixHealth{
+							PrefixHealth: &StaticPrefixHealth{
 								Prefix: netip.MustParsePrefix("10.5.0.0/24"),
 								Metric: 0,
 							},
 						},
 						{
-							&StaticPrefixHealth{
+							PrefixHealth: &StaticPrefixHealth{
 								Prefix: netip.MustParsePrefix("10.5.0.1/8"),
 								Metric: 0,
 							},
@@ -93,20 +112,157 @@ func TestCentralConfigValidator_Endpoint(t *testing.T) {
 	cfg := &CentralCfg{
 		Routers: []RouterCfg{{
 			NodeCfg: NodeCfg{Id: "node1"},
-			Endpoints: []string{
-				"example.com",
-				"example.com:57175",
-				"192.0.2.1",
-				"[2001:db8::1]:57175",
+			Endpoints: []EndpointCfg{
+				{Address: "example.com"},
+				{Address: "example.com:57175"},
+				{Address: "192.0.2.1"},
+				{Address: "[2001:db8::1]:57175", EndpointAttrs: EndpointAttrs{Class: EndpointBackup, Metered: true}},
 			},
 		}},
 	}
 	assert.NoError(t, CentralConfigValidator(cfg))
 
-	cfg.Routers[0].Endpoints = []string{"http://example.com"}
+	cfg.Routers[0].Endpoints[0].Class = "standby"
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "unknown endpoint class")
+
+	cfg.Routers[0].Endpoints = []EndpointCfg{{Address: "http://example.com"}}
 	assert.ErrorContains(t, CentralConfigValidator(cfg), "invalid endpoint")
 }
 
+func TestCentralConfigValidator_LinkEndpoint(t *testing.T) {
+	cfg := &CentralCfg{
+		Routers: []RouterCfg{
+			{NodeCfg: NodeCfg{Id: "node1"}, Endpoints: []EndpointCfg{{Address: "192.0.2.1:57175"}}},
+			{NodeCfg: NodeCfg{Id: "node2"}, Endpoints: []EndpointCfg{{Address: "192.0.2.2:57175"}}},
+			{NodeCfg: NodeCfg{Id: "node3"}, Endpoints: []EndpointCfg{{Address: "192.0.2.3:57175"}}},
+		},
+		Graph: []string{"node1, node2 [endpoint=192.0.2.2:57175]"},
+	}
+	assert.NoError(t, CentralConfigValidator(cfg))
+
+	// the endpoint of a router that is not on the link
+	cfg.Graph = []string{"node1, node2 [endpoint=192.0.2.3:57175]"}
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "is not an endpoint of node1 or node2")
+}
+
+func TestCentralConfigValidator_Dampening(t *testing.T) {
+	cfg := &CentralCfg{
+		Routers:   []RouterCfg{{NodeCfg: NodeCfg{Id: "node1"}}},
+		Dampening: &DampeningCfg{},
+	}
+	assert.NoError(t, CentralConfigValidator(cfg))
+
+	cfg.Dampening.Suppress = 500
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "reuse threshold")
+}
+
+func TestCentralConfigValidator_Duplicate(t *testing.T) {
+	cfg := &CentralCfg{
+		Routers:   []RouterCfg{{NodeCfg: NodeCfg{Id: "node1"}}},
+		Duplicate: []DuplicateRule{{DSCP: []uint8{46}}},
+	}
+	assert.NoError(t, CentralConfigValidator(cfg))
+
+	cfg.Duplicate[0].DSCP = []uint8{64}
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "at most 63")
+
+	cfg.Duplicate[0].DSCP = nil
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "must match a prefix or a dscp")
+}
+
+func TestCentralConfigValidator_ACL(t *testing.T) {
+	cfg := aclTestConfig(&ACLCfg{Rules: []ACLRule{{Action: ACLAccept, Src: []string{"servers"}, Dst: []string{"tag:web"}}}})
+	assert.NoError(t, CentralConfigValidator(cfg))
+
+	cfg.ACL.Rules[0].Action = "allow"
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "unknown action")
+
+	cfg.ACL.Rules[0].Action = ACLAccept
+	cfg.ACL.Rules[0].Src = []string{"carol"}
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "not a valid node, group, tag or prefix")
+
+	cfg.ACL.Rules[0].Src = nil
+	cfg.ACL.Rules[0].Proto = "gre"
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "unknown protocol")
+
+	cfg.ACL = nil
+	cfg.Routers[0].Tags = []string{"Web Servers"}
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "invalid tag")
+}
+
+func TestCentralConfigValidator_QoS(t *testing.T) {
+	cfg := &CentralCfg{
+		Routers: []RouterCfg{{NodeCfg: NodeCfg{Id: "node1"}}},
+		QoS:     &QoSCfg{Classes: []QoSClass{{Name: "voice", Priority: QoSHigh, Match: []QoSMatch{{DSCP: []uint8{46}}}}}},
+	}
+	assert.NoError(t, CentralConfigValidator(cfg))
+
+	cfg.QoS.Classes[0].Priority = "urgent"
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "unknown priority")
+
+	cfg.QoS.Classes[0].Priority = QoSHigh
+	cfg.QoS.Classes[0].Match[0].Proto = "gre"
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "unknown protocol")
+
+	cfg.QoS.Classes[0].Match = nil
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "must match packets")
+
+	cfg.QoS.Classes = []QoSClass{cfg.QoS.Classes[0], cfg.QoS.Classes[0]}
+	cfg.QoS.Classes[0].Match = []QoSMatch{{DSCP: []uint8{46}}}
+	cfg.QoS.Classes[1].Match = cfg.QoS.Classes[0].Match
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "more than once")
+}
+
+func TestNodeConfigValidator_PortForwards(t *testing.T) {
+	central := &CentralCfg{Routers: []RouterCfg{
+		{NodeCfg: NodeCfg{Id: "node1", Addresses: []netip.Addr{netip.MustParseAddr("10.0.0.1")}}},
+		{NodeCfg: NodeCfg{Id: "node2", Addresses: []netip.Addr{netip.MustParseAddr("10.0.0.2")}}},
+	}}
+	node := &LocalCfg{Id: "node1", Port: 57175, Key: GenerateKey(), PortForwards: []PortForward{
+		{Proto: "tcp", Listen: netip.MustParseAddrPort("10.0.0.1:8080"), To: netip.MustParseAddrPort("192.168.1.10:80")},
+	}}
+	assert.NoError(t, NodeConfigValidator(central, node))
+
+	node.PortForwards[0].Proto = "sctp"
+	assert.ErrorContains(t, NodeConfigValidator(central, node), "expected tcp or udp")
+
+	node.PortForwards[0].Proto = "tcp"
+	node.PortForwards[0].Listen = netip.MustParseAddrPort("10.0.0.2:8080")
+	assert.ErrorContains(t, NodeConfigValidator(central, node), "not an address of node1")
+
+	node.PortForwards[0].Listen = netip.MustParseAddrPort("10.0.0.1:8080")
+	node.PortForwards[0].To = netip.MustParseAddrPort("[fd00::10]:80")
+	assert.ErrorContains(t, NodeConfigValidator(central, node), "must not mix ipv4 and ipv6")
+
+	node.PortForwards[0].To = netip.MustParseAddrPort("192.168.1.10:80")
+	node.PortForwards = append(node.PortForwards, node.PortForwards[0])
+	assert.ErrorContains(t, NodeConfigValidator(central, node), "forwarded more than once")
+
+	// a public port of the host is forwarded to a service in the mesh
+	node.PortForwards = []PortForward{{Proto: "tcp", Listen: netip.MustParseAddrPort("0.0.0.0:443"), To: netip.MustParseAddrPort("192.168.1.10:80")}}
+	assert.ErrorContains(t, NodeConfigValidator(central, node), "must be an address in the mesh")
+	node.PortForwards[0].To = netip.MustParseAddrPort("10.0.0.2:8443")
+	assert.NoError(t, NodeConfigValidator(central, node))
+}
+
+func TestCentralConfigValidator_AnycastPrefixType(t *testing.T) {
+	vip := netip.MustParsePrefix("10.53.0.53/32")
+	cfg := &CentralCfg{
+		Routers: []RouterCfg{
+			{NodeCfg: NodeCfg{Id: "node1", Prefixes: []PrefixHealthWrapper{{PrefixHealth: &AnycastPrefixHealth{Prefix: vip, Weight: 10}}}}},
+			{NodeCfg: NodeCfg{Id: "node2", Prefixes: []PrefixHealthWrapper{{PrefixHealth: &AnycastPrefixHealth{Prefix: vip, Addr: vip.Addr()}}}}},
+		},
+	}
+	assert.NoError(t, CentralConfigValidator(cfg))
+
+	cfg.Routers[1].Prefixes[0].PrefixHealth.(*AnycastPrefixHealth).URL = "http://10.53.0.53/health"
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "either addr or url")
+
+	// every advertiser must agree that the prefix is anycast
+	cfg.Routers[1].Prefixes[0].PrefixHealth = &StaticPrefixHealth{Prefix: vip}
+	assert.ErrorContains(t, CentralConfigValidator(cfg), "different type")
+}
+
 func TestCentralConfigValidator_PassiveClientNonStaticPrefix(t *testing.T) {
 	cfg := &CentralCfg{
 		Clients: []ClientCfg{
@@ -146,19 +302,19 @@ func TestCentralConfigValidator_DuplicatePrefix(t *testing.T) {
 					PubKey: NyPublicKey{},
 					Prefixes: []PrefixHealthWrapper{
 						{
-							&StaticPrefixHealth{
+							PrefixHealth: &StaticPrefixHealth{
 								Prefix: netip.MustParsePrefix("10.5.0.1/32"),
 								Metric: 0,
 							},
 						},
 						{
-							&StaticPrefixHealth{
+							PrefixHealth: &StaticPrefixHealth{
 								Prefix: netip.MustParsePrefix("10.5.0.1/24"),
 								Metric: 0,
 							},
 						},
 						{
-							&StaticPrefixHealth{
+							PrefixHealth: &StaticPrefixHealth{
 								Prefix: netip.MustParsePrefix("10.5.0.1/32"),
 								Metric: 0,
 							},
@@ -181,7 +337,7 @@ func TestCentralConfigValidator_AnycastPrefix(t *testing.T) {
 					PubKey: NyPublicKey{},
 					Prefixes: []PrefixHealthWrapper{
 						{
-							&StaticPrefixHealth{
+							PrefixHealth: &StaticPrefixHealth{
 								Prefix: netip.MustParsePrefix("10.5.0.1/32"),
 								Metric: 0,
 							},
@@ -195,7 +351,7 @@ func TestCentralConfigValidator_AnycastPrefix(t *testing.T) {
 					PubKey: NyPublicKey{},
 					Prefixes: []PrefixHealthWrapper{
 						{
-							&StaticPrefixHealth{
+							PrefixHealth: &StaticPrefixHealth{
 								Prefix: netip.MustParsePrefix("10.5.0.1/32"), // same prefix as node1 - this is valid for anycast
 								Metric: 0,
 							},